@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syscleaner/pkg/cleaner"
+
+	"github.com/spf13/cobra"
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <path>",
+	Short: "Show what is using the space below a directory",
+	Long: `Walks a directory and lists its largest directories and files, the space
+used per file extension and the largest files that have not been accessed for
+a while. Nothing is deleted; use the Disk Usage tab of the GUI to quarantine
+or delete what you find.
+
+The walk uses the same protections as cleaning: symlinks and junctions are not
+followed, mounted filesystems are not entered unless --cross-filesystems is
+given, and the walk stops after --timeout (or Ctrl+C) with what it has seen
+so far. Such a partial analysis exits with code 2.
+
+Access times are only as accurate as the filesystem keeps them: with the
+relatime or noatime mount options, files may look older than they are.
+
+Examples:
+  syscleaner analyze ~/Downloads
+  syscleaner analyze / --top 50 --older-than 52w
+  syscleaner analyze D:\ --output json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var opts cleaner.AnalyzeOptions
+		opts.Top, _ = cmd.Flags().GetInt("top")
+		if opts.Top < 1 {
+			fail(errors.New("--top must be at least 1"))
+			return
+		}
+		opts.CrossFilesystems, _ = cmd.Flags().GetBool("cross-filesystems")
+		for flag, field := range map[string]*time.Duration{
+			"older-than": &opts.OldAfter,
+			"timeout":    &opts.Timeout,
+		} {
+			s, _ := cmd.Flags().GetString(flag)
+			if s == "" {
+				continue
+			}
+			d, err := cleaner.ParseAge(s)
+			if err != nil {
+				fail(fmt.Errorf("--%s: %w", flag, err))
+				return
+			}
+			*field = d
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+
+		if !machineOutput() {
+			fmt.Printf("Analyzing %s...\n", args[0])
+		}
+		a, err := cleaner.Analyze(ctx, args[0], opts)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if a.Status != cleaner.StatusCompleted {
+			setExitCode(exitPartial)
+		}
+		if machineOutput() {
+			emit(a)
+			return
+		}
+		printAnalysis(a)
+	},
+}
+
+func printAnalysis(a *cleaner.Analysis) {
+	fmt.Println()
+	fmt.Printf("=== Disk Usage: %s ===\n", a.Root)
+	fmt.Printf("  Total:  %s in %d files and %d directories (%dms)\n", cleaner.FormatBytes(a.Bytes), a.Files, a.Dirs, a.DurationMS)
+	if a.Status != cleaner.StatusCompleted {
+		fmt.Println("  Stopped early: the lists below only cover what was walked.")
+	}
+
+	printEntries := func(title string, entries []cleaner.SizeEntry) {
+		if len(entries) == 0 {
+			return
+		}
+		fmt.Println()
+		fmt.Println(title + ":")
+		for _, e := range entries {
+			line := fmt.Sprintf("  %10s  %s", cleaner.FormatBytes(e.Size), relativeTo(a.Root, e.Path))
+			if e.Dir {
+				line += fmt.Sprintf("%c (%d files)", filepath.Separator, e.Files)
+			} else if e.Accessed != nil {
+				line += "  (accessed " + e.Accessed.Local().Format("2006-01-02") + ")"
+			}
+			fmt.Println(line)
+		}
+	}
+	printEntries("Largest directories", a.LargestDirs)
+	printEntries("Largest files", a.LargestFiles)
+
+	if len(a.Extensions) > 0 {
+		fmt.Println()
+		fmt.Println("By extension:")
+		for _, e := range a.Extensions {
+			ext := e.Extension
+			if ext == "" {
+				ext = "(none)"
+			}
+			fmt.Printf("  %10s  %-12s %d files\n", cleaner.FormatBytes(e.Bytes), ext, e.Files)
+		}
+	}
+
+	if a.OldAfterDays > 0 {
+		fmt.Println()
+		fmt.Printf("Not accessed for %d days: %d files, %s\n", a.OldAfterDays, a.OldCount, cleaner.FormatBytes(a.OldBytes))
+		printEntries("Largest of them", a.OldFiles)
+	}
+
+	if len(a.SkippedLinks)+len(a.SkippedMounts) > 0 {
+		fmt.Printf("\nNot followed: %d links, %d mounted filesystems\n", len(a.SkippedLinks), len(a.SkippedMounts))
+	}
+	if len(a.Errors) > 0 {
+		fmt.Printf("\nCould not read %d paths:\n", len(a.Errors))
+		for i, e := range a.Errors {
+			if i == 10 {
+				fmt.Printf("  ... and %d more\n", len(a.Errors)-10)
+				break
+			}
+			fmt.Printf("  %s\n", e.Message)
+		}
+	}
+}
+
+// relativeTo shortens path for display when it lies below root.
+func relativeTo(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+func init() {
+	analyzeCmd.Flags().Int("top", cleaner.DefaultAnalyzeTop, "Number of entries in each list")
+	analyzeCmd.Flags().String("older-than", "180d", "List files not accessed for this long (empty to skip)")
+	analyzeCmd.Flags().String("timeout", "", "Stop the walk after this long (default 5m)")
+	analyzeCmd.Flags().Bool("cross-filesystems", false, "Also walk filesystems mounted below the path")
+	rootCmd.AddCommand(analyzeCmd)
+}
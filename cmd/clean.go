@@ -1,9 +1,19 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
 
 	"github.com/spf13/cobra"
 )
@@ -13,173 +23,334 @@ var cleanCmd = &cobra.Command{
 	Short: "Clean system junk files and free disk space",
 	Long: `Remove temporary files, browser caches, log files, prefetch data, and thumbnails.
 
-You can select specific categories or use group flags like --all, --system, --browsers, --apps.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		all, _ := cmd.Flags().GetBool("all")
-		systemGroup, _ := cmd.Flags().GetBool("system")
-		browsersGroup, _ := cmd.Flags().GetBool("browsers")
-		appsGroup, _ := cmd.Flags().GetBool("apps")
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
+You can select specific categories or use group flags like --all, --system, --browsers, --apps.
+--all covers the system, browser and application groups; the privacy,
+developer, custom and winapp2 groups have to be selected with their own flag.
 
-		opts := cleaner.CleanOptions{DryRun: dryRun}
+Custom rules are loaded from the "rules" folder in the config directory and
+can be selected with --custom or by ID with --category. Use --list to see
+every available category ID.
 
-		// Group flags
-		if all {
-			systemGroup = true
-			browsersGroup = true
-			appsGroup = true
-		}
-
-		if systemGroup {
-			opts.WindowsTemp = true
-			opts.UserTemp = true
-			opts.WindowsUpdate = true
-			opts.WindowsInstaller = true
-			opts.Prefetch = true
-			opts.CrashDumps = true
-			opts.ErrorReports = true
-			opts.ThumbnailCache = true
-			opts.IconCache = true
-			opts.FontCache = true
-			opts.ShaderCache = true
-			opts.DNSCache = true
-			opts.WindowsLogs = true
-			opts.EventLogs = true
-			opts.DeliveryOptimization = true
-			opts.RecycleBin = true
-		}
-
-		if browsersGroup {
-			opts.ChromeCache = true
-			opts.FirefoxCache = true
-			opts.EdgeCache = true
-			opts.BraveCache = true
-			opts.OperaCache = true
-		}
-
-		if appsGroup {
-			opts.DiscordCache = true
-			opts.SpotifyCache = true
-			opts.SteamCache = true
-			opts.TeamsCache = true
-			opts.VSCodeCache = true
-			opts.JavaCache = true
-		}
+To review before deleting, write a plan with --plan-out plan.json, inspect or
+edit it, then apply it with --plan-in plan.json. Files that changed since the
+scan are skipped.
 
-		// Individual flags override groups
-		if cmd.Flags().Changed("win-temp") {
-			opts.WindowsTemp, _ = cmd.Flags().GetBool("win-temp")
-		}
-		if cmd.Flags().Changed("user-temp") {
-			opts.UserTemp, _ = cmd.Flags().GetBool("user-temp")
-		}
-		if cmd.Flags().Changed("wupdate") {
-			opts.WindowsUpdate, _ = cmd.Flags().GetBool("wupdate")
-		}
-		if cmd.Flags().Changed("installer") {
-			opts.WindowsInstaller, _ = cmd.Flags().GetBool("installer")
-		}
-		if cmd.Flags().Changed("prefetch") {
-			opts.Prefetch, _ = cmd.Flags().GetBool("prefetch")
-		}
-		if cmd.Flags().Changed("crashdumps") {
-			opts.CrashDumps, _ = cmd.Flags().GetBool("crashdumps")
-		}
-		if cmd.Flags().Changed("wer") {
-			opts.ErrorReports, _ = cmd.Flags().GetBool("wer")
-		}
-		if cmd.Flags().Changed("thumbcache") {
-			opts.ThumbnailCache, _ = cmd.Flags().GetBool("thumbcache")
-		}
-		if cmd.Flags().Changed("iconcache") {
-			opts.IconCache, _ = cmd.Flags().GetBool("iconcache")
-		}
-		if cmd.Flags().Changed("fontcache") {
-			opts.FontCache, _ = cmd.Flags().GetBool("fontcache")
-		}
-		if cmd.Flags().Changed("shadercache") {
-			opts.ShaderCache, _ = cmd.Flags().GetBool("shadercache")
-		}
-		if cmd.Flags().Changed("dnscache") {
-			opts.DNSCache, _ = cmd.Flags().GetBool("dnscache")
-		}
-		if cmd.Flags().Changed("winlogs") {
-			opts.WindowsLogs, _ = cmd.Flags().GetBool("winlogs")
+The privacy categories (--privacy) remove browsing history, cookies, form data
+and sessions. History, cookies and form data are removed from the browsers'
+databases, which are edited in place; close the browser first. Cookies of the
+domains in privacy_keep_domains (config) and --keep-domain are always kept.
+
+Browser and application categories are left alone while their application is
+running, since cleaning under a running browser fails on locked files or
+corrupts its profile. --if-running warn cleans anyway and notes it, and
+--if-running close asks on the terminal whether to close the application
+first. running_apps in the config sets the default and per-category policies.
+
+Browser profiles are read from Chromium's Local State and Firefox's
+profiles.ini, so profiles in custom locations are cleaned too. Use
+--list-browser-profiles to see them, and --browser-profile or
+--exclude-browser-profile (or browser_profiles in the config) to pick which
+are cleaned, by name ("Work"), "Browser:Name" ("Chrome:Work"), directory
+name or full path.
+
+The Developer categories (--dev) clean toolchain caches: Go, npm, Yarn,
+pnpm, pip, Cargo, Gradle and Maven. Each cache is found the way its tool
+finds it, from environment variables and the tool's config files before the
+default location. --node-modules removes the node_modules folders of projects
+below developer.workspace_roots (config) or --workspace-root that have not
+been touched for developer.stale_days (default 90) or --stale-days.
+
+--steam-games reads Steam's libraryfolders.vdf and app manifests to clean
+shader caches, download leftovers and the workshop content of uninstalled
+games in every library; --epic-games cleans the download staging of the games
+in the Epic Games Launcher manifests. Run with --dry-run to see each game's
+reclaimable size first.
+
+Log categories (--winlogs) can archive before deleting: with archive.enabled
+in the config or --archive, the files are compressed into a dated zip or
+tar.gz bundle per category in archive.dir (default: the "archives" folder in
+the config directory) and then removed. Bundles beyond archive.keep per
+category or archive.max_size_mb in total are rotated out, oldest first. The
+summary shows the bytes archived and the net space reclaimed.
+
+Retention policies narrow down what a category removes: a minimum age by
+modification or access time, a minimum size, the newest N files to keep per
+folder, or a size cap. Manage them with 'syscleaner retention'.
+
+Files that are in use cannot be removed. They are queued in the config
+directory and retried at the start of the next clean (and when the GUI
+starts); files still in use after 30 days are dropped. --pending shows the
+queue and how long each file has waited. On Windows, --delete-on-reboot (or
+pending.delete_on_reboot in the config) also schedules the queued files for
+deletion at the next reboot, which needs administrator rights.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, err := range config.RegisterUserRules() {
+			warnf("skipped custom rule: %v", err)
 		}
-		if cmd.Flags().Changed("eventlogs") {
-			opts.EventLogs, _ = cmd.Flags().GetBool("eventlogs")
+
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			if machineOutput() {
+				emit(categoryList())
+				return
+			}
+			printCategoryList()
+			return
 		}
-		if cmd.Flags().Changed("deliveryopt") {
-			opts.DeliveryOptimization, _ = cmd.Flags().GetBool("deliveryopt")
+
+		if list, _ := cmd.Flags().GetBool("list-browser-profiles"); list {
+			profiles := cleaner.DiscoverBrowserProfiles()
+			if machineOutput() {
+				emit(append([]cleaner.BrowserProfile{}, profiles...))
+				return
+			}
+			printBrowserProfiles(profiles)
+			return
 		}
-		if cmd.Flags().Changed("recyclebin") {
-			opts.RecycleBin, _ = cmd.Flags().GetBool("recyclebin")
+
+		onReboot, _ := cmd.Flags().GetBool("delete-on-reboot")
+		if onReboot && runtime.GOOS != "windows" {
+			fail(cleaner.ErrRebootDeleteUnsupported)
+			return
 		}
-		if cmd.Flags().Changed("chrome") {
-			opts.ChromeCache, _ = cmd.Flags().GetBool("chrome")
+		if pending, _ := cmd.Flags().GetBool("pending"); pending {
+			showPending(onReboot)
+			return
 		}
-		if cmd.Flags().Changed("firefox") {
-			opts.FirefoxCache, _ = cmd.Flags().GetBool("firefox")
+
+		all, _ := cmd.Flags().GetBool("all")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		categoryIDs, _ := cmd.Flags().GetStringSlice("category")
+		planOut, _ := cmd.Flags().GetString("plan-out")
+		planIn, _ := cmd.Flags().GetString("plan-in")
+
+		if planOut != "" && planIn != "" {
+			fail(errors.New("--plan-out and --plan-in cannot be combined"))
+			return
 		}
-		if cmd.Flags().Changed("edge") {
-			opts.EdgeCache, _ = cmd.Flags().GetBool("edge")
+
+		// Applying a saved plan ignores the category selection flags.
+		var plan *cleaner.Plan
+		if planIn != "" {
+			var err error
+			if plan, err = cleaner.LoadPlan(planIn); err != nil {
+				fail(err)
+				return
+			}
 		}
-		if cmd.Flags().Changed("brave") {
-			opts.BraveCache, _ = cmd.Flags().GetBool("brave")
+
+		opts := cleaner.CleanOptions{DryRun: dryRun}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			warnf("%v (using defaults)", err)
+			cfg = config.DefaultConfig()
 		}
-		if cmd.Flags().Changed("opera") {
-			opts.OperaCache, _ = cmd.Flags().GetBool("opera")
+
+		// Trash age: --trash-age overrides the saved default
+		opts.TrashMinAge = cfg.DefaultCleanOptions.TrashMinAge
+		config.ApplyExclusions(cfg, &opts)
+		opts.CrossFilesystems, _ = cmd.Flags().GetBool("cross-filesystems")
+		keepDomains, _ := cmd.Flags().GetStringSlice("keep-domain")
+		opts.KeepDomains = append(opts.KeepDomains, keepDomains...)
+		includeProfiles, _ := cmd.Flags().GetStringSlice("browser-profile")
+		opts.BrowserProfiles = append(opts.BrowserProfiles, includeProfiles...)
+		excludeProfiles, _ := cmd.Flags().GetStringSlice("exclude-browser-profile")
+		opts.ExcludeBrowserProfiles = append(opts.ExcludeBrowserProfiles, excludeProfiles...)
+		if cmd.Flags().Changed("if-running") {
+			s, _ := cmd.Flags().GetString("if-running")
+			if opts.RunningPolicy, err = cleaner.ParseRunningPolicy(s); err != nil {
+				fail(fmt.Errorf("--if-running: %w", err))
+				return
+			}
+		}
+		config.ApplyRunningPolicy(cfg, &opts)
+		workspaceRoots, _ := cmd.Flags().GetStringSlice("workspace-root")
+		opts.WorkspaceRoots = append(opts.WorkspaceRoots, workspaceRoots...)
+		if cmd.Flags().Changed("stale-days") {
+			days, _ := cmd.Flags().GetInt("stale-days")
+			if days <= 0 {
+				fail(errors.New("--stale-days must be at least 1"))
+				return
+			}
+			opts.StaleProjectAge = time.Duration(days) * 24 * time.Hour
+		}
+		config.ApplyDeveloper(cfg, &opts)
+		config.ApplyRetention(cfg, &opts)
+		opts.Timeout = cfg.DefaultCleanOptions.Timeout
+		opts.DirTimeout = cfg.DefaultCleanOptions.DirTimeout
+		for flag, field := range map[string]*time.Duration{
+			"trash-age":   &opts.TrashMinAge,
+			"timeout":     &opts.Timeout,
+			"dir-timeout": &opts.DirTimeout,
+		} {
+			if !cmd.Flags().Changed(flag) {
+				continue
+			}
+			s, _ := cmd.Flags().GetString(flag)
+			d, err := cleaner.ParseAge(s)
+			if err != nil {
+				fail(fmt.Errorf("--%s: %w", flag, err))
+				return
+			}
+			*field = d
 		}
-		if cmd.Flags().Changed("discord") {
-			opts.DiscordCache, _ = cmd.Flags().GetBool("discord")
+
+		// Group flags; --all leaves out the opt-in groups
+		if all {
+			opts.EnableAll()
 		}
-		if cmd.Flags().Changed("spotify") {
-			opts.SpotifyCache, _ = cmd.Flags().GetBool("spotify")
+		for _, g := range cleaner.Groups {
+			if on, _ := cmd.Flags().GetBool(string(g)); on {
+				for _, c := range cleaner.CategoriesInGroup(g) {
+					opts.Enable(c.ID)
+				}
+			}
 		}
-		if cmd.Flags().Changed("steam") {
-			opts.SteamCache, _ = cmd.Flags().GetBool("steam")
+
+		// Categories selected by ID
+		for _, id := range categoryIDs {
+			if _, ok := cleaner.LookupCategory(id); !ok {
+				fail(fmt.Errorf("unknown category %q, run 'syscleaner clean --list' to see available IDs", id))
+				return
+			}
+			opts.Enable(id)
 		}
-		if cmd.Flags().Changed("teams") {
-			opts.TeamsCache, _ = cmd.Flags().GetBool("teams")
+
+		// Individual flags override groups
+		for _, c := range cleaner.Categories() {
+			if c.Flag == "" || !cmd.Flags().Changed(c.Flag) {
+				continue
+			}
+			if on, _ := cmd.Flags().GetBool(c.Flag); on {
+				opts.Enable(c.ID)
+			} else {
+				opts.Disable(c.ID)
+			}
 		}
-		if cmd.Flags().Changed("vscode") {
-			opts.VSCodeCache, _ = cmd.Flags().GetBool("vscode")
+
+		// Quarantine: per-category defaults from the registry and config,
+		// --quarantine for every selected category, --no-quarantine to delete
+		if noQuarantine, _ := cmd.Flags().GetBool("no-quarantine"); !noQuarantine && !dryRun {
+			if err := config.ApplyQuarantine(cfg, &opts); err != nil {
+				fail(fmt.Errorf("cannot open quarantine store: %w", err))
+				return
+			}
+			if everything, _ := cmd.Flags().GetBool("quarantine"); everything {
+				for id := range opts.Categories {
+					opts.QuarantineCategories[id] = true
+				}
+			}
 		}
-		if cmd.Flags().Changed("java") {
-			opts.JavaCache, _ = cmd.Flags().GetBool("java")
+
+		// Archive: log categories are compressed into the archive directory
+		// before removal when archive.enabled is set or with --archive
+		archive, _ := cmd.Flags().GetBool("archive")
+		if noArchive, _ := cmd.Flags().GetBool("no-archive"); (archive || cfg.Archive.Enabled) && !noArchive && !dryRun {
+			if err := config.ApplyArchive(cfg, &opts); err != nil {
+				fail(fmt.Errorf("cannot prepare the log archive: %w", err))
+				return
+			}
 		}
 
 		// Check if any category is selected
-		hasSelection := opts.WindowsTemp || opts.UserTemp || opts.WindowsUpdate ||
-			opts.WindowsInstaller || opts.Prefetch || opts.CrashDumps ||
-			opts.ErrorReports || opts.ThumbnailCache || opts.IconCache ||
-			opts.FontCache || opts.ShaderCache || opts.DNSCache ||
-			opts.WindowsLogs || opts.EventLogs || opts.DeliveryOptimization ||
-			opts.RecycleBin || opts.ChromeCache || opts.FirefoxCache ||
-			opts.EdgeCache || opts.BraveCache || opts.OperaCache ||
-			opts.DiscordCache || opts.SpotifyCache || opts.SteamCache ||
-			opts.TeamsCache || opts.VSCodeCache || opts.JavaCache
-
-		if !hasSelection {
+		if plan == nil && !opts.HasSelection() {
+			if machineOutput() {
+				fail(errors.New("no cleaning targets specified"))
+				return
+			}
+			setExitCode(exitFailure)
 			fmt.Println("No cleaning targets specified.")
 			fmt.Println("\nGroup flags:")
-			fmt.Println("  --all         : Clean everything")
-			fmt.Println("  --system      : All system categories")
-			fmt.Println("  --browsers    : All browser categories")
-			fmt.Println("  --apps        : All application categories")
+			fmt.Printf("  --all         : %s\n", allFlagHelp())
+			for _, g := range cleaner.Groups {
+				fmt.Printf("  --%-11s : %s\n", g, groupFlagHelp[g])
+			}
 			fmt.Println("\nRun 'syscleaner clean --help' for a full list of categories.")
 			return
 		}
 
-		if dryRun {
+		if planOut != "" {
+			if !machineOutput() {
+				fmt.Println("Scanning files without deleting...")
+				fmt.Println()
+			}
+			plan := cleaner.Scan(opts)
+			if err := plan.Save(planOut); err != nil {
+				fail(err)
+				return
+			}
+			if machineOutput() {
+				emit(newPlanOutput(plan, planOut))
+				return
+			}
+			printPlanSummary(plan)
+			fmt.Printf("Plan written to %s\n", planOut)
+			fmt.Printf("Review it, then apply it with 'syscleaner clean --plan-in %s'.\n", planOut)
+			return
+		}
+
+		if dryRun && !machineOutput() {
 			fmt.Println("[DRY RUN] Scanning files without deleting...")
 			fmt.Println()
 		}
 
-		fmt.Println("Starting system cleanup...")
-		fmt.Println()
+		// Ctrl+C stops the clean where it is instead of killing the process
+		// mid-file; a second Ctrl+C exits immediately.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+
+		// Files earlier cleans found in use are retried first, so that the
+		// summary below only counts this run.
+		if !dryRun {
+			retryPending(ctx, cfg)
+		}
 
-		result := cleaner.PerformClean(opts)
+		line := newProgressLine()
+		if line != nil {
+			opts.Events = line.event
+		}
+		opts.ConfirmClose = confirmClose(line)
+
+		var result cleaner.CleanResult
+		if plan != nil {
+			if !machineOutput() {
+				fmt.Printf("Applying plan %s (created %s)...\n", planIn, plan.Created.Format("2006-01-02 15:04"))
+				fmt.Println()
+			}
+			result = cleaner.ExecuteContext(ctx, plan, opts)
+		} else {
+			if !machineOutput() {
+				fmt.Println("Starting system cleanup... (Ctrl+C to stop)")
+				fmt.Println()
+			}
+			result = cleaner.PerformCleanContext(ctx, opts)
+		}
+		line.done()
+
+		if err := config.RecordHistory(cfg, result, dryRun); err != nil {
+			warnf("could not record the run in the history: %v", err)
+		}
+		queued := false
+		if !dryRun {
+			if onReboot {
+				cfg.Pending.DeleteOnReboot = true
+			}
+			if err := config.QueueLocked(cfg, result); err != nil {
+				warnf("could not queue the files in use for a retry: %v", err)
+			} else {
+				queued = len(result.Locked) > 0
+			}
+		}
+
+		setExitCode(outcomeExitCode(result.Outcome()))
+		if machineOutput() {
+			emit(cleaner.NewReport(result, dryRun))
+			return
+		}
 
 		fmt.Println("=== Cleanup Summary ===")
 		if dryRun {
@@ -187,7 +358,24 @@ You can select specific categories or use group flags like --all, --system, --br
 		}
 		fmt.Printf("  Files deleted: %d\n", result.FilesDeleted)
 		fmt.Printf("  Files skipped: %d\n", result.SkippedFiles)
+		if result.DirsRemoved > 0 {
+			fmt.Printf("  Empty folders: %d removed\n", result.DirsRemoved)
+		}
 		fmt.Printf("  Space freed:   %s\n", cleaner.FormatBytes(result.SpaceFreed))
+		fmt.Printf("  On disk:       %s reclaimed\n", cleaner.FormatBytes(result.SpaceReclaimed))
+		if !dryRun && result.DiskFreeDelta != 0 {
+			fmt.Printf("  Free space:    %s\n", formatDelta(result.DiskFreeDelta))
+		}
+		if result.FilesQuarantined > 0 {
+			fmt.Printf("  Quarantined:   %d files (%s)\n", result.FilesQuarantined, cleaner.FormatBytes(result.SpaceQuarantined))
+		}
+		if result.RecordsDeleted > 0 {
+			fmt.Printf("  Records deleted: %d\n", result.RecordsDeleted)
+		}
+		if result.FilesArchived > 0 {
+			fmt.Printf("  Archived:      %d files (%s) into %s\n", result.FilesArchived, cleaner.FormatBytes(result.SpaceArchived), cleaner.FormatBytes(result.ArchiveSize))
+			fmt.Printf("  Net reclaimed: %s\n", formatDelta(result.NetReclaimed()))
+		}
 		fmt.Printf("  Time taken:    %s\n", result.Duration.Round(1e6))
 		if result.LockedFiles > 0 {
 			fmt.Printf("  Skipped (in use): %d\n", result.LockedFiles)
@@ -195,58 +383,400 @@ You can select specific categories or use group flags like --all, --system, --br
 		if result.PermissionFiles > 0 {
 			fmt.Printf("  Permission errors: %d\n", result.PermissionFiles)
 		}
-		if len(result.Errors) > 0 {
-			fmt.Printf("  Other errors:  %d\n", len(result.Errors))
+		if result.ChangedFiles > 0 {
+			fmt.Printf("  Changed since plan: %d\n", result.ChangedFiles)
+		}
+		if len(result.SkippedLinks) > 0 {
+			fmt.Printf("  Links not followed: %d\n", len(result.SkippedLinks))
+		}
+		if len(result.SkippedMounts) > 0 {
+			fmt.Printf("  Mounts not entered: %d\n", len(result.SkippedMounts))
+		}
+		protected := result.ErrorsOfType(cleaner.ErrorProtected)
+		excluded := result.ErrorsOfType(cleaner.ErrorExcluded)
+		if len(excluded) > 0 {
+			fmt.Printf("  Excluded:      %d\n", len(excluded))
+		}
+		if len(protected) > 0 {
+			fmt.Printf("  Refused (protected): %d\n", len(protected))
+		}
+		if other := len(result.Errors) - len(protected) - len(excluded); other > 0 {
+			fmt.Printf("  Other errors:  %d\n", other)
 		}
 		fmt.Println()
+		var unfinished, running []cleaner.CategoryStatus
+		for _, st := range result.Categories {
+			switch {
+			case st.Status == cleaner.StatusSkipped || st.Reason != "":
+				running = append(running, st)
+			case st.Status != cleaner.StatusCompleted:
+				unfinished = append(unfinished, st)
+			}
+		}
+		if len(unfinished) > 0 {
+			fmt.Println("Stopped before finishing:")
+			for _, st := range unfinished {
+				fmt.Printf("  %-28s %s\n", st.Name, st.Status)
+			}
+			fmt.Println()
+		}
+		if len(running) > 0 {
+			fmt.Println("Applications running:")
+			for _, st := range running {
+				fmt.Printf("  %-28s %s\n", st.Name, st.Reason)
+			}
+			fmt.Println()
+		}
+		if len(result.BrowserProfiles) > 0 {
+			printProfileResults(result.BrowserProfiles)
+		}
+		if len(result.Games) > 0 {
+			printGameResults(result.Games)
+		}
+		if len(protected) > 0 {
+			fmt.Println("Refused to clean protected paths:")
+			for _, ce := range protected {
+				fmt.Printf("  %v\n", ce)
+			}
+			fmt.Println()
+		}
+		if dryRun && len(result.DryRunItems) > 0 {
+			fmt.Println("Would purge:")
+			for _, item := range result.DryRunItems {
+				fmt.Printf("  %s\n", item)
+			}
+			fmt.Println()
+		}
 		if dryRun {
 			fmt.Println("Run without --dry-run to actually delete files.")
 		} else {
-			fmt.Println("Cleanup complete!")
+			if len(unfinished) > 0 {
+				fmt.Println("Cleanup stopped early.")
+			} else {
+				fmt.Println("Cleanup complete!")
+			}
+			if result.QuarantineRun != "" {
+				fmt.Printf("Undo with 'syscleaner quarantine restore %s'.\n", result.QuarantineRun)
+			}
+			for _, a := range result.Archives {
+				fmt.Printf("Archived logs: %s\n", a)
+			}
+			if queued {
+				fmt.Printf("%d file(s) in use were queued for the next clean; see 'syscleaner clean --pending'.\n", len(result.Locked))
+			}
 		}
 	},
 }
 
+// groupFlagHelp is the help text for each group flag.
+var groupFlagHelp = map[cleaner.Group]string{
+	cleaner.GroupSystem:       "All system categories",
+	cleaner.GroupBrowsers:     "All browser categories",
+	cleaner.GroupPrivacy:      "All browser privacy categories (history, cookies, form data, sessions)",
+	cleaner.GroupApplications: "All application categories",
+	cleaner.GroupDeveloper:    "All developer toolchain caches and stale node_modules",
+	cleaner.GroupCustom:       "All custom rule categories",
+	cleaner.GroupWinapp2:      "All imported winapp2 categories",
+}
+
+// allFlagHelp describes --all, naming the opt-in groups it leaves out.
+func allFlagHelp() string {
+	var optIn []string
+	for _, g := range cleaner.Groups {
+		if !g.InAll() {
+			optIn = append(optIn, "--"+string(g))
+		}
+	}
+	return fmt.Sprintf("Clean everything except the opt-in groups (%s)", strings.Join(optIn, ", "))
+}
+
+// confirmClose returns the prompt asking whether a running application may
+// be closed before its category is cleaned, or nil when nobody can answer
+// (machine output or no terminal on stdin), in which case the category is
+// skipped.
+func confirmClose(line *progressLine) cleaner.ConfirmCloseFunc {
+	if machineOutput() {
+		return nil
+	}
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	in := bufio.NewReader(os.Stdin)
+	return func(c cleaner.Category, procs []cleaner.RunningProcess) bool {
+		line.done()
+		names := map[string]bool{}
+		for _, p := range procs {
+			names[p.Name] = true
+		}
+		list := make([]string, 0, len(names))
+		for n := range names {
+			list = append(list, n)
+		}
+		sort.Strings(list)
+		count := fmt.Sprintf("%d processes", len(procs))
+		if len(procs) == 1 {
+			count = "1 process"
+		}
+		fmt.Printf("%s is running (%s). Close it to clean %s? [y/N] ", strings.Join(list, ", "), count, c.Name)
+		answer, _ := in.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+}
+
+// printBrowserProfiles prints the discovered browser profiles for
+// --list-browser-profiles.
+func printBrowserProfiles(profiles []cleaner.BrowserProfile) {
+	if len(profiles) == 0 {
+		fmt.Println("No browser profiles found.")
+		return
+	}
+	for _, p := range profiles {
+		fmt.Printf("  %-28s %s\n", p.Label(), p.Dir)
+	}
+}
+
+// printProfileResults prints what the browser categories did per profile,
+// with the categories added up.
+func printProfileResults(results []cleaner.BrowserProfileResult) {
+	fmt.Println("Browser profiles:")
+	for _, t := range cleaner.ProfileTotals(results) {
+		line := fmt.Sprintf("  %-28s %6d files  %10s", t.Browser+": "+t.Profile, t.FilesDeleted, cleaner.FormatBytes(t.BytesFreed))
+		if t.RecordsDeleted > 0 {
+			line += fmt.Sprintf("  %d records", t.RecordsDeleted)
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
+
+// printGameResults prints what the game launcher categories did per game.
+func printGameResults(games []cleaner.GameResult) {
+	fmt.Println("Games:")
+	for _, g := range games {
+		fmt.Printf("  %-40s %6d files  %10s\n", g.Label(), g.FilesDeleted, cleaner.FormatBytes(g.BytesFreed))
+	}
+	fmt.Println()
+}
+
+// progressLine keeps a single status line up to date while a clean runs. It
+// is only used for text output to a terminal.
+type progressLine struct {
+	last  time.Time
+	name  string
+	width int
+}
+
+// progressInterval limits how often the line is redrawn.
+const progressInterval = 100 * time.Millisecond
+
+func newProgressLine() *progressLine {
+	if machineOutput() {
+		return nil
+	}
+	if fi, err := os.Stdout.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return &progressLine{}
+}
+
+func (p *progressLine) event(e cleaner.ProgressEvent) {
+	if e.Kind == cleaner.EventCategoryStarted {
+		p.name = e.Name
+	}
+	if e.Kind != cleaner.EventCategoryFinished && time.Since(p.last) < progressInterval {
+		return
+	}
+	p.last = time.Now()
+
+	text := fmt.Sprintf("%d files, %s", e.Files, cleaner.FormatBytes(e.Bytes))
+	if f := e.Fraction(); f >= 0 {
+		text = fmt.Sprintf("[%3.0f%%] %d/%d files, %s of %s", f*100,
+			e.Files, e.TotalFiles, cleaner.FormatBytes(e.Bytes), cleaner.FormatBytes(e.TotalBytes))
+	}
+	if p.name != "" {
+		text += "  " + p.name
+	}
+	p.draw(text)
+}
+
+// draw overwrites the line, padding with spaces to clear a longer previous one.
+func (p *progressLine) draw(text string) {
+	pad := p.width - len(text)
+	if pad < 0 {
+		pad = 0
+	}
+	fmt.Printf("\r%s%*s", text, pad, "")
+	p.width = len(text)
+}
+
+// done clears the line. It is a no-op on a nil progressLine.
+func (p *progressLine) done() {
+	if p == nil || p.width == 0 {
+		return
+	}
+	p.draw("")
+	fmt.Print("\r")
+}
+
+// formatDelta formats a change in bytes with its sign.
+func formatDelta(n int64) string {
+	if n < 0 {
+		return "-" + cleaner.FormatBytes(-n)
+	}
+	return "+" + cleaner.FormatBytes(n)
+}
+
+// printPlanSummary prints the number and size of candidates per category.
+func printPlanSummary(plan *cleaner.Plan) {
+	fmt.Println("=== Clean Plan ===")
+	for _, pc := range plan.Categories {
+		if pc.Action != "" {
+			fmt.Printf("  %-28s %s\n", pc.Name, pc.Action)
+			continue
+		}
+		fmt.Printf("  %-28s %6d files  %10s\n", pc.Name, len(pc.Files), cleaner.FormatBytes(pc.Size()))
+	}
+	files, size := plan.Totals()
+	fmt.Printf("  %-28s %6d files  %10s\n", "Total", files, cleaner.FormatBytes(size))
+	fmt.Println()
+}
+
+// outcomeExitCode maps the outcome of a clean to the process exit code.
+func outcomeExitCode(o cleaner.Outcome) int {
+	switch o {
+	case cleaner.OutcomeFailed:
+		return exitFailure
+	case cleaner.OutcomePartial:
+		return exitPartial
+	default:
+		return exitOK
+	}
+}
+
+// planOutput is the machine-readable summary of a plan written by
+// --plan-out. The plan file itself lists every file.
+type planOutput struct {
+	Path       string               `json:"path"`
+	Created    time.Time            `json:"created"`
+	Categories []planCategoryOutput `json:"categories"`
+	TotalFiles int64                `json:"total_files"`
+	TotalBytes int64                `json:"total_bytes"`
+}
+
+type planCategoryOutput struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Action string `json:"action,omitempty"`
+	Files  int    `json:"files"`
+	Bytes  int64  `json:"bytes"`
+}
+
+func newPlanOutput(plan *cleaner.Plan, path string) planOutput {
+	out := planOutput{Path: path, Created: plan.Created, Categories: []planCategoryOutput{}}
+	for _, pc := range plan.Categories {
+		out.Categories = append(out.Categories, planCategoryOutput{
+			ID:     pc.ID,
+			Name:   pc.Name,
+			Action: pc.Action,
+			Files:  len(pc.Files),
+			Bytes:  pc.Size(),
+		})
+	}
+	out.TotalFiles, out.TotalBytes = plan.Totals()
+	return out
+}
+
+// categoryOutput is the machine-readable form of a registered category.
+type categoryOutput struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Group       string `json:"group"`
+	Flag        string `json:"flag,omitempty"`
+	Description string `json:"description"`
+	Risk        string `json:"risk"`
+	Supported   bool   `json:"supported"`
+}
+
+// categoryList returns every registered category in --list order.
+func categoryList() []categoryOutput {
+	out := []categoryOutput{}
+	for _, g := range cleaner.Groups {
+		for _, c := range cleaner.CategoriesInGroup(g) {
+			out = append(out, categoryOutput{
+				ID:          c.ID,
+				Name:        c.Name,
+				Group:       string(g),
+				Flag:        c.Flag,
+				Description: c.Description,
+				Risk:        c.Risk.String(),
+				Supported:   c.Supported(),
+			})
+		}
+	}
+	return out
+}
+
+// printCategoryList prints every registered category grouped by section.
+func printCategoryList() {
+	for _, g := range cleaner.Groups {
+		cats := cleaner.CategoriesInGroup(g)
+		if len(cats) == 0 {
+			continue
+		}
+		fmt.Printf("%s (--%s):\n", g.DisplayName(), g)
+		for _, c := range cats {
+			flag := "-"
+			if c.Flag != "" {
+				flag = "--" + c.Flag
+			}
+			note := ""
+			if !c.Supported() {
+				note = " [not available on this platform]"
+			}
+			fmt.Printf("  %-24s %-15s %s%s\n", c.ID, flag, c.Description, note)
+		}
+		fmt.Println()
+	}
+}
+
 func init() {
 	// Group flags
-	cleanCmd.Flags().Bool("all", false, "Clean everything")
-	cleanCmd.Flags().Bool("system", false, "All system categories")
-	cleanCmd.Flags().Bool("browsers", false, "All browser categories")
-	cleanCmd.Flags().Bool("apps", false, "All application categories")
-
-	// System category flags
-	cleanCmd.Flags().Bool("win-temp", false, "Windows Temp directory")
-	cleanCmd.Flags().Bool("user-temp", false, "User Temp directories")
-	cleanCmd.Flags().Bool("wupdate", false, "Windows Update cache")
-	cleanCmd.Flags().Bool("installer", false, "Windows Installer cache")
-	cleanCmd.Flags().Bool("prefetch", false, "Prefetch data (files older than 30 days)")
-	cleanCmd.Flags().Bool("crashdumps", false, "Crash dump files")
-	cleanCmd.Flags().Bool("wer", false, "Windows Error Reports")
-	cleanCmd.Flags().Bool("thumbcache", false, "Thumbnail cache")
-	cleanCmd.Flags().Bool("iconcache", false, "Icon cache")
-	cleanCmd.Flags().Bool("fontcache", false, "Font cache")
-	cleanCmd.Flags().Bool("shadercache", false, "DirectX shader cache")
-	cleanCmd.Flags().Bool("dnscache", false, "DNS cache (flush)")
-	cleanCmd.Flags().Bool("winlogs", false, "Windows log files")
-	cleanCmd.Flags().Bool("eventlogs", false, "Windows Event Logs")
-	cleanCmd.Flags().Bool("deliveryopt", false, "Delivery Optimization cache")
-	cleanCmd.Flags().Bool("recyclebin", false, "Recycle Bin")
-
-	// Application category flags
-	cleanCmd.Flags().Bool("chrome", false, "Chrome cache")
-	cleanCmd.Flags().Bool("firefox", false, "Firefox cache")
-	cleanCmd.Flags().Bool("edge", false, "Edge cache")
-	cleanCmd.Flags().Bool("brave", false, "Brave cache")
-	cleanCmd.Flags().Bool("opera", false, "Opera cache")
-	cleanCmd.Flags().Bool("discord", false, "Discord cache")
-	cleanCmd.Flags().Bool("spotify", false, "Spotify cache")
-	cleanCmd.Flags().Bool("steam", false, "Steam cache")
-	cleanCmd.Flags().Bool("teams", false, "Teams cache")
-	cleanCmd.Flags().Bool("vscode", false, "VS Code cache")
-	cleanCmd.Flags().Bool("java", false, "Java cache")
+	cleanCmd.Flags().Bool("all", false, allFlagHelp())
+	for _, g := range cleaner.Groups {
+		cleanCmd.Flags().Bool(string(g), false, groupFlagHelp[g])
+	}
+
+	// Category flags, one per built-in registry entry
+	for _, c := range cleaner.Categories() {
+		if c.Flag != "" {
+			cleanCmd.Flags().Bool(c.Flag, false, c.Description)
+		}
+	}
+	cleanCmd.Flags().StringSlice("category", nil, "Category IDs to clean, comma-separated (see --list)")
+	cleanCmd.Flags().Bool("list", false, "List all category IDs, including custom rules")
+	cleanCmd.Flags().Bool("list-browser-profiles", false, "List the browser profiles the browser and privacy categories clean")
 
 	// Execution options
 	cleanCmd.Flags().Bool("dry-run", false, "Show what would be cleaned without deleting")
+	cleanCmd.Flags().Bool("quarantine", false, "Move files into the quarantine store instead of deleting them")
+	cleanCmd.Flags().Bool("no-quarantine", false, "Delete files even for categories that quarantine by default")
+	cleanCmd.Flags().Bool("archive", false, "Compress the files of log categories into the archive directory before removing them")
+	cleanCmd.Flags().Bool("no-archive", false, "Do not archive log files, even when archive.enabled is set in the config")
+	cleanCmd.Flags().Bool("pending", false, "Show the files queued because they were in use, and how long they have waited")
+	cleanCmd.Flags().Bool("delete-on-reboot", false, "Windows: also schedule the files in use for deletion at the next reboot (administrator rights)")
+	cleanCmd.Flags().String("timeout", "", "Stop the whole clean after this long (default 5m)")
+	cleanCmd.Flags().String("dir-timeout", "", "Stop walking a single directory after this long (default 30s)")
+	cleanCmd.Flags().Bool("cross-filesystems", false, "Also clean filesystems mounted below cleaned directories")
+	cleanCmd.Flags().String("plan-out", "", "Scan only and write the files that would be removed to this JSON file")
+	cleanCmd.Flags().String("plan-in", "", "Remove exactly the files listed in a plan written by --plan-out")
+	cleanCmd.Flags().String("trash-age", "", "Only purge Trash items deleted longer ago than this (e.g. 30d)")
+	cleanCmd.Flags().String("if-running", "", "When a category's application is running: skip, warn (clean anyway) or close (ask to close it first); default from running_apps in the config")
+	cleanCmd.Flags().StringSlice("browser-profile", nil, "Only clean these browser profiles (name, Browser:Name, directory name or path), in addition to browser_profiles.include in the config")
+	cleanCmd.Flags().StringSlice("exclude-browser-profile", nil, "Never clean these browser profiles, in addition to browser_profiles.exclude in the config")
+	cleanCmd.Flags().StringSlice("workspace-root", nil, "Search these directories for stale node_modules, in addition to developer.workspace_roots in the config")
+	cleanCmd.Flags().Int("stale-days", 0, "Remove node_modules of projects untouched for this many days (default from developer.stale_days, 90)")
+	cleanCmd.Flags().StringSlice("keep-domain", nil, "Never remove cookies of these domains or their subdomains, in addition to privacy_keep_domains in the config")
 
 	rootCmd.AddCommand(cleanCmd)
 }
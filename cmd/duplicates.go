@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
+	"syscleaner/pkg/dupes"
+
+	"github.com/spf13/cobra"
+)
+
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates <dir>...",
+	Short: "Find duplicate files and remove the extra copies",
+	Long: `Searches the given directories for files with identical content. Files are
+grouped by size first, then compared by a hash of their first 16 KB, and only
+files that still match are hashed in full, so unique files are barely read.
+
+One copy of every group is kept:
+  --keep oldest     the copy modified longest ago (default)
+  --keep newest     the most recently modified copy
+  --keep priority   the copy below the first matching --prefer directory,
+                    then the oldest
+
+Without --delete the groups are only listed. With --delete the other copies
+are removed like any cleaned file: protected paths and exclusions are refused,
+--dry-run only counts and --quarantine makes the removal restorable. A copy
+that changed since the search is left alone.
+
+Examples:
+  syscleaner duplicates ~/Downloads ~/Documents
+  syscleaner duplicates ~/Downloads ~/Documents --prefer ~/Documents --delete --dry-run
+  syscleaner duplicates D:\Assets --min-size 1MB --keep newest --delete --quarantine`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := dupes.Options{Roots: args}
+		keep, _ := cmd.Flags().GetString("keep")
+		opts.Priority, _ = cmd.Flags().GetStringSlice("prefer")
+		if !cmd.Flags().Changed("keep") && len(opts.Priority) > 0 {
+			keep = string(dupes.KeepPriority)
+		}
+		var err error
+		if opts.Keep, err = dupes.ParseKeepRule(keep); err != nil {
+			fail(err)
+			return
+		}
+		minSize, _ := cmd.Flags().GetString("min-size")
+		if opts.MinSize, err = cleaner.ParseSize(minSize); err != nil {
+			fail(fmt.Errorf("--min-size: %w", err))
+			return
+		}
+		timeout, _ := cmd.Flags().GetString("timeout")
+		if opts.Timeout, err = cleaner.ParseAge(timeout); err != nil {
+			fail(fmt.Errorf("--timeout: %w", err))
+			return
+		}
+		opts.CrossFilesystems, _ = cmd.Flags().GetBool("cross-filesystems")
+		remove, _ := cmd.Flags().GetBool("delete")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		quarantine, _ := cmd.Flags().GetBool("quarantine")
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+
+		line := newProgressLine()
+		if line != nil {
+			opts.Events = line.event
+		}
+		res, err := dupes.Find(ctx, opts)
+		line.done()
+		if err != nil {
+			fail(err)
+			return
+		}
+		if res.Status != cleaner.StatusCompleted {
+			setExitCode(exitPartial)
+		}
+
+		out := duplicatesOutput{Result: res}
+		if remove && ctx.Err() == nil {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				warnf("%v (using defaults)", err)
+				cfg = config.DefaultConfig()
+			}
+			cleanOpts := cleaner.CleanOptions{
+				DryRun:     dryRun,
+				Timeout:    cfg.DefaultCleanOptions.Timeout,
+				DirTimeout: cfg.DefaultCleanOptions.DirTimeout,
+			}
+			config.ApplyExclusions(cfg, &cleanOpts)
+			if quarantine && !dryRun {
+				if err := config.ApplyQuarantine(cfg, &cleanOpts); err != nil {
+					fail(fmt.Errorf("cannot open quarantine store: %w", err))
+					return
+				}
+				cleanOpts.QuarantineCategories[dupes.CategoryID] = true
+			}
+			if line = newProgressLine(); line != nil {
+				cleanOpts.Events = line.event
+			}
+			result := dupes.Remove(ctx, res.Groups, cleanOpts)
+			line.done()
+			if err := config.RecordHistory(cfg, result, dryRun); err != nil {
+				warnf("could not record the run in the history: %v", err)
+			}
+			setExitCode(outcomeExitCode(result.Outcome()))
+			report := cleaner.NewReport(result, dryRun)
+			out.Removal = &report
+		}
+
+		if machineOutput() {
+			emit(out)
+			return
+		}
+		printDuplicates(res)
+		if out.Removal != nil {
+			printDuplicateRemoval(*out.Removal)
+		} else if len(res.Groups) > 0 {
+			fmt.Println("Run with --delete to remove the extra copies (add --dry-run to preview).")
+		}
+	},
+}
+
+// duplicatesOutput is the machine-readable output of the duplicates command.
+type duplicatesOutput struct {
+	*dupes.Result
+	Removal *cleaner.Report `json:"removal,omitempty"` // With --delete
+}
+
+func printDuplicates(res *dupes.Result) {
+	for _, g := range res.Groups {
+		fmt.Printf("%s x %d (%s wasted)\n", cleaner.FormatBytes(g.Size), len(g.Duplicates)+1, cleaner.FormatBytes(g.Wasted()))
+		fmt.Printf("  keep    %s  (%s)\n", g.Keep.Path, g.Keep.ModTime.Local().Format("2006-01-02 15:04"))
+		for _, d := range g.Duplicates {
+			fmt.Printf("  remove  %s  (%s)\n", d.Path, d.ModTime.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("=== Duplicate Search ===")
+	fmt.Printf("  Files scanned:   %d (%s)\n", res.FilesScanned, cleaner.FormatBytes(res.BytesScanned))
+	fmt.Printf("  Read to compare: %s\n", cleaner.FormatBytes(res.BytesHashed))
+	fmt.Printf("  Duplicates:      %d files in %d groups, %s\n", res.DuplicateFiles, len(res.Groups), cleaner.FormatBytes(res.DuplicateBytes))
+	fmt.Printf("  Keep rule:       %s\n", res.Keep)
+	fmt.Printf("  Time taken:      %s\n", (time.Duration(res.DurationMS) * time.Millisecond).String())
+	if len(res.Errors) > 0 {
+		fmt.Printf("  Unreadable:      %d\n", len(res.Errors))
+	}
+	if res.Status != cleaner.StatusCompleted {
+		fmt.Println("  Stopped early: only the groups confirmed so far are listed.")
+	}
+	fmt.Println()
+}
+
+func printDuplicateRemoval(r cleaner.Report) {
+	if r.DryRun {
+		fmt.Printf("Would remove %d files (%s).\n", r.FilesDeleted, cleaner.FormatBytes(r.BytesFreed))
+		fmt.Println("Run without --dry-run to actually delete them.")
+		return
+	}
+	fmt.Printf("Removed %d files (%s, %s on disk).\n", r.FilesDeleted, cleaner.FormatBytes(r.BytesFreed), cleaner.FormatBytes(r.BytesReclaimed))
+	if r.FilesQuarantined > 0 {
+		fmt.Printf("Quarantined %d files (%s). Undo with 'syscleaner quarantine restore %s'.\n",
+			r.FilesQuarantined, cleaner.FormatBytes(r.BytesQuarantined), r.QuarantineRun)
+	}
+	if len(r.Errors) > 0 {
+		fmt.Printf("Not removed (%d):\n", len(r.Errors))
+		for _, e := range r.Errors {
+			fmt.Printf("  %s\n", e.Message)
+		}
+	}
+}
+
+func init() {
+	duplicatesCmd.Flags().String("keep", string(dupes.KeepOldest), "Copy to keep: oldest, newest or priority")
+	duplicatesCmd.Flags().StringSlice("prefer", nil, "Directories whose copies are kept first, most preferred first (implies --keep priority)")
+	duplicatesCmd.Flags().String("min-size", "1", "Ignore files smaller than this (e.g. 64K, 1MB)")
+	duplicatesCmd.Flags().Bool("delete", false, "Remove every copy except the kept one")
+	duplicatesCmd.Flags().Bool("dry-run", false, "With --delete, show what would be removed without deleting")
+	duplicatesCmd.Flags().Bool("quarantine", false, "With --delete, move the copies into the quarantine store instead")
+	duplicatesCmd.Flags().String("timeout", "", "Stop the search after this long (default 5m)")
+	duplicatesCmd.Flags().Bool("cross-filesystems", false, "Also search filesystems mounted below the directories")
+	rootCmd.AddCommand(duplicatesCmd)
+}
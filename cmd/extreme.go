@@ -21,14 +21,20 @@ WARNING: This mode removes the desktop shell. Use the GUI launcher to start game
 		showStatus, _ := cmd.Flags().GetBool("status")
 
 		if enable {
-			fmt.Println("Enabling extreme performance mode...")
-			fmt.Println()
-			fmt.Println("WARNING: This will stop Windows Explorer (no desktop/taskbar).")
-			fmt.Println("Use 'syscleaner extreme --disable' to restore.")
-			fmt.Println()
+			if !machineOutput() {
+				fmt.Println("Enabling extreme performance mode...")
+				fmt.Println()
+				fmt.Println("WARNING: This will stop Windows Explorer (no desktop/taskbar).")
+				fmt.Println("Use 'syscleaner extreme --disable' to restore.")
+				fmt.Println()
+			}
 
 			if err := gaming.EnableExtremeMode(); err != nil {
-				fmt.Printf("  Error: %v\n", err)
+				fail(err)
+				return
+			}
+			if machineOutput() {
+				emit(getExtremeStatus())
 				return
 			}
 
@@ -40,11 +46,17 @@ WARNING: This mode removes the desktop shell. Use the GUI launcher to start game
 			fmt.Println()
 			fmt.Println("EXTREME PERFORMANCE MODE is now ACTIVE")
 		} else if disable {
-			fmt.Println("Disabling extreme performance mode...")
-			fmt.Println()
+			if !machineOutput() {
+				fmt.Println("Disabling extreme performance mode...")
+				fmt.Println()
+			}
 
 			if err := gaming.DisableExtremeMode(); err != nil {
-				fmt.Printf("  Error: %v\n", err)
+				fail(err)
+				return
+			}
+			if machineOutput() {
+				emit(getExtremeStatus())
 				return
 			}
 
@@ -62,11 +74,30 @@ WARNING: This mode removes the desktop shell. Use the GUI launcher to start game
 	},
 }
 
+// extremeStatus is the machine-readable output of the extreme command.
+type extremeStatus struct {
+	ExtremeMode bool `json:"extreme_mode"`
+	GamingMode  bool `json:"gaming_mode"`
+}
+
+func getExtremeStatus() extremeStatus {
+	return extremeStatus{
+		ExtremeMode: gaming.IsExtremeModeActive(),
+		GamingMode:  gaming.IsEnabled(),
+	}
+}
+
 func printExtremeStatus() {
+	status := getExtremeStatus()
+	if machineOutput() {
+		emit(status)
+		return
+	}
+
 	fmt.Println("--- Extreme Performance Mode Status ---")
 	fmt.Println()
 
-	if gaming.IsExtremeModeActive() {
+	if status.ExtremeMode {
 		fmt.Println("  Status:  ACTIVE")
 		fmt.Println()
 		fmt.Println("  Windows Explorer: STOPPED")
@@ -77,7 +108,7 @@ func printExtremeStatus() {
 	}
 	fmt.Println()
 
-	if gaming.IsEnabled() {
+	if status.GamingMode {
 		fmt.Println("  Gaming Mode:      ACTIVE")
 	} else {
 		fmt.Println("  Gaming Mode:      INACTIVE")
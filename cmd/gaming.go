@@ -21,16 +21,23 @@ var gamingCmd = &cobra.Command{
 		cpuBoost, _ := cmd.Flags().GetInt("cpu-boost")
 		ramReserve, _ := cmd.Flags().GetInt("ram-reserve")
 
+		// In JSON/YAML mode every action prints the resulting gaming.Status.
 		if enable {
-			fmt.Println("Enabling gaming mode...")
-			fmt.Println()
+			if !machineOutput() {
+				fmt.Println("Enabling gaming mode...")
+				fmt.Println()
+			}
 			config := gaming.Config{
 				AutoDetectGames: autoDetect,
 				CPUBoost:        cpuBoost,
 				RAMReserveGB:    ramReserve,
 			}
 			if err := gaming.Enable(config); err != nil {
-				fmt.Printf("  Error: %v\n", err)
+				fail(err)
+				return
+			}
+			if machineOutput() {
+				emit(gaming.GetStatus())
 				return
 			}
 			fmt.Println("  Stopped background services")
@@ -42,10 +49,16 @@ var gamingCmd = &cobra.Command{
 			fmt.Println()
 			fmt.Println("Gaming mode is now ACTIVE")
 		} else if disable {
-			fmt.Println("Disabling gaming mode...")
-			fmt.Println()
+			if !machineOutput() {
+				fmt.Println("Disabling gaming mode...")
+				fmt.Println()
+			}
 			if err := gaming.Disable(); err != nil {
-				fmt.Printf("  Error: %v\n", err)
+				fail(err)
+				return
+			}
+			if machineOutput() {
+				emit(gaming.GetStatus())
 				return
 			}
 			fmt.Println("  Restarted background services")
@@ -63,6 +76,10 @@ var gamingCmd = &cobra.Command{
 
 func printGamingStatus() {
 	status := gaming.GetStatus()
+	if machineOutput() {
+		emit(status)
+		return
+	}
 
 	fmt.Println("--- Gaming Mode Status ---")
 	fmt.Println()
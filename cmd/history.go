@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show what past cleans removed, per category and per week",
+	Long: `Every clean, including dry runs, is recorded in history.jsonl in the config
+directory. This command sums the real runs per category, so you can see which
+categories are worth running, and shows the space reclaimed per week.
+
+Examples:
+  syscleaner history
+  syscleaner history --weeks 26
+  syscleaner history --output json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		weeks, _ := cmd.Flags().GetInt("weeks")
+		if weeks < 1 {
+			fail(errors.New("--weeks must be at least 1"))
+			return
+		}
+
+		records, err := config.LoadHistory()
+		if err != nil {
+			fail(err)
+			return
+		}
+		out := historyOutput{
+			Categories: cleaner.CategoryTotals(records),
+			Weeks:      cleaner.WeeklyTotals(records, weeks, time.Now()),
+		}
+		for _, rec := range records {
+			if rec.DryRun {
+				out.DryRuns++
+				continue
+			}
+			out.Runs++
+			out.FilesDeleted += rec.FilesDeleted
+			out.BytesFreed += rec.BytesFreed
+			out.BytesReclaimed += rec.BytesReclaimed
+		}
+		if len(records) > 0 {
+			out.Since = &records[0].Time
+		}
+		if machineOutput() {
+			emit(out)
+			return
+		}
+
+		if len(records) == 0 {
+			fmt.Println("No cleans recorded yet.")
+			return
+		}
+		printHistory(out)
+	},
+}
+
+// historyOutput is the machine-readable output of the history command.
+type historyOutput struct {
+	Since          *time.Time              `json:"since,omitempty"` // First recorded run
+	Runs           int                     `json:"runs"`
+	DryRuns        int                     `json:"dry_runs"`
+	FilesDeleted   int64                   `json:"files_deleted"`
+	BytesFreed     int64                   `json:"bytes_freed"`
+	BytesReclaimed int64                   `json:"bytes_reclaimed"`
+	Categories     []cleaner.CategoryTotal `json:"categories"`
+	Weeks          []cleaner.WeekTotal     `json:"weeks"`
+}
+
+// historyBarWidth is the width of the longest bar in the weekly view.
+const historyBarWidth = 40
+
+func printHistory(out historyOutput) {
+	fmt.Println("=== Cleaning History ===")
+	fmt.Printf("  Since:         %s\n", out.Since.Format("2006-01-02"))
+	fmt.Printf("  Cleans:        %d (plus %d dry runs)\n", out.Runs, out.DryRuns)
+	fmt.Printf("  Files deleted: %d\n", out.FilesDeleted)
+	fmt.Printf("  Space freed:   %s (%s on disk)\n", cleaner.FormatBytes(out.BytesFreed), cleaner.FormatBytes(out.BytesReclaimed))
+	fmt.Println()
+
+	if len(out.Categories) > 0 {
+		fmt.Println("Per category:")
+		fmt.Printf("  %-28s %5s %10s %10s  %s\n", "Category", "Runs", "Files", "Freed", "Last run")
+		fmt.Println("  " + strings.Repeat("-", 76))
+		for _, c := range out.Categories {
+			name := c.Name
+			if c.Incomplete > 0 {
+				name += fmt.Sprintf(" (%d incomplete)", c.Incomplete)
+			}
+			fmt.Printf("  %-28s %5d %10d %10s  %s\n",
+				name, c.Runs, c.FilesDeleted, cleaner.FormatBytes(c.BytesFreed), c.LastRun.Local().Format("2006-01-02"))
+		}
+		fmt.Println()
+	}
+
+	var most int64
+	for _, w := range out.Weeks {
+		if w.BytesReclaimed > most {
+			most = w.BytesReclaimed
+		}
+	}
+	fmt.Println("Space reclaimed per week:")
+	for _, w := range out.Weeks {
+		bar := ""
+		if most > 0 {
+			bar = strings.Repeat("#", int(w.BytesReclaimed*historyBarWidth/most))
+		}
+		line := fmt.Sprintf("  %s  %3d runs %10s  %s", w.Start.Format("2006-01-02"), w.Runs, cleaner.FormatBytes(w.BytesReclaimed), bar)
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+func init() {
+	historyCmd.Flags().Int("weeks", 12, "Number of weeks in the weekly view")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
 	"syscleaner/pkg/optimizer"
@@ -23,10 +24,35 @@ var optimizeCmd = &cobra.Command{
 		}
 
 		if !startup && !network && !disk {
+			if machineOutput() {
+				fail(errors.New("no optimization targets specified, use --all, --startup, --network or --disk"))
+				return
+			}
+			setExitCode(exitFailure)
 			fmt.Println("No optimization targets specified. Use --all or specify targets (--startup, --network, --disk)")
 			return
 		}
 
+		// In JSON/YAML mode the results of the selected targets are printed
+		// as one document; targets that were not run are omitted.
+		if machineOutput() {
+			var out optimizeOutput
+			if startup {
+				r := optimizer.OptimizeStartup()
+				out.Startup = &r
+			}
+			if network {
+				r := optimizer.OptimizeNetwork()
+				out.Network = &r
+			}
+			if disk {
+				r := optimizer.OptimizeDisk()
+				out.Disk = &r
+			}
+			emit(out)
+			return
+		}
+
 		fmt.Println("Starting system optimization...")
 		fmt.Println()
 
@@ -55,6 +81,13 @@ var optimizeCmd = &cobra.Command{
 	},
 }
 
+// optimizeOutput is the machine-readable output of the optimize command.
+type optimizeOutput struct {
+	Startup *optimizer.StartupResult `json:"startup,omitempty"`
+	Network *optimizer.NetworkResult `json:"network,omitempty"`
+	Disk    *optimizer.DiskResult    `json:"disk,omitempty"`
+}
+
 func init() {
 	optimizeCmd.Flags().Bool("all", false, "Run all optimizations")
 	optimizeCmd.Flags().Bool("startup", false, "Optimize startup programs")
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit codes. Scripts rely on them, so they are documented in the README
+// and must not change.
+const (
+	exitOK      = 0 // Everything the command was asked to do was done
+	exitFailure = 1 // Nothing was done: invalid flags, an error before any work, or every step failed
+	exitPartial = 2 // Some of the work was done; the output says what was not
+)
+
+// Values of the global --output flag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+var (
+	outputFormat = outputText
+
+	// exitCode is set by commands that did not fully succeed. Execute exits
+	// with it once the command returns.
+	exitCode = exitOK
+)
+
+// machineOutput reports whether the command should write a single JSON or
+// YAML document to stdout instead of text. Progress and warnings then go to
+// stderr so the document stays parseable.
+func machineOutput() bool {
+	return outputFormat != outputText
+}
+
+// emit writes v to stdout in the selected machine-readable format. YAML uses
+// the same field names as JSON. The JSON tags of every type emitted, here or
+// in the packages it comes from, are part of the same contract as the exit
+// codes: scripts parse them, so they must not change.
+func emit(v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err == nil && outputFormat == outputYAML {
+		data, err = jsonToYAML(data)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: encoding output: %v\n", err)
+		setExitCode(exitFailure)
+		return
+	}
+	os.Stdout.Write(data)
+	if outputFormat == outputJSON {
+		fmt.Println()
+	}
+}
+
+// jsonToYAML converts a JSON document to block-style YAML, keeping the key
+// order of the JSON encoding.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	var plain func(n *yaml.Node)
+	plain = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			plain(c)
+		}
+	}
+	plain(&node)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// errorOutput is the document written when a command fails before it
+// produces a result.
+type errorOutput struct {
+	Error string `json:"error"`
+}
+
+// fail reports err and sets the exit code to exitFailure.
+func fail(err error) {
+	setExitCode(exitFailure)
+	if machineOutput() {
+		emit(errorOutput{Error: err.Error()})
+		return
+	}
+	fmt.Printf("Error: %v\n", err)
+}
+
+// warnf prints a warning that does not stop the command.
+func warnf(format string, args ...any) {
+	if machineOutput() {
+		fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+		return
+	}
+	fmt.Printf("Warning: "+format+"\n", args...)
+}
+
+// setExitCode records code unless a worse one is already set.
+func setExitCode(code int) {
+	if code == exitFailure || exitCode == exitOK {
+		exitCode = code
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputText, "Output format: text, json or yaml")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		switch outputFormat {
+		case outputText, outputJSON, outputYAML:
+			return nil
+		}
+		return fmt.Errorf("invalid --output %q: use text, json or yaml", outputFormat)
+	}
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
+)
+
+// pendingOutput is the machine-readable form of clean --pending.
+type pendingOutput struct {
+	Path       string              `json:"path"`
+	Files      []pendingFileOutput `json:"files"`
+	TotalBytes int64               `json:"total_bytes"`
+	Scheduled  int                 `json:"scheduled,omitempty"` // With --delete-on-reboot
+}
+
+type pendingFileOutput struct {
+	cleaner.PendingFile
+	AgeSeconds int64 `json:"age_seconds"`
+}
+
+// showPending prints the pending queue with the age of each file, after
+// scheduling it for deletion at the next reboot when onReboot is set.
+func showPending(onReboot bool) {
+	path, err := config.PendingPath()
+	if err != nil {
+		fail(err)
+		return
+	}
+	scheduled := 0
+	if onReboot {
+		if scheduled, err = config.SchedulePendingOnReboot(); err != nil {
+			if errors.Is(err, cleaner.ErrRebootDeleteUnsupported) {
+				fail(err)
+				return
+			}
+			setExitCode(exitPartial)
+			warnf("could not schedule every file: %v", err)
+		}
+	}
+	queue, err := config.LoadPending()
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	now := time.Now()
+	if machineOutput() {
+		out := pendingOutput{Path: path, Files: []pendingFileOutput{}, Scheduled: scheduled}
+		for _, f := range queue {
+			out.Files = append(out.Files, pendingFileOutput{f, int64(now.Sub(f.Queued) / time.Second)})
+			out.TotalBytes += f.Size
+		}
+		emit(out)
+		return
+	}
+
+	if onReboot {
+		fmt.Printf("Scheduled %d file(s) for deletion at the next reboot.\n\n", scheduled)
+	}
+	if len(queue) == 0 {
+		fmt.Println("No files are waiting to be retried.")
+		return
+	}
+	fmt.Printf("%-8s %-10s %8s %-20s %s\n", "Age", "Size", "Attempts", "Category", "Path")
+	fmt.Println(strings.Repeat("-", 80))
+	var total int64
+	var oldest time.Time
+	for _, f := range queue {
+		path := f.Path
+		if f.OnReboot {
+			path += " (at reboot)"
+		}
+		fmt.Printf("%-8s %-10s %8d %-20s %s\n", queueAge(now.Sub(f.Queued)), cleaner.FormatBytes(f.Size), f.Attempts, f.Category, path)
+		total += f.Size
+		if oldest.IsZero() || f.Queued.Before(oldest) {
+			oldest = f.Queued
+		}
+	}
+	fmt.Printf("\nTotal: %d file(s), %s, oldest queued %s ago\n", len(queue), cleaner.FormatBytes(total), queueAge(now.Sub(oldest)))
+	fmt.Printf("Files still in use after %s are dropped from the queue.\n", cleaner.FormatAge(cleaner.PendingExpiry))
+}
+
+// queueAge formats how long a file has been queued in its largest unit.
+func queueAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+// retryPending retries the files earlier cleans found in use and notes the
+// outcome. Machine output only gets a note on stderr when something failed.
+func retryPending(ctx context.Context, cfg *config.Config) {
+	result, err := config.RetryPending(ctx, cfg)
+	if err != nil {
+		warnf("could not retry the files left in use earlier: %v", err)
+	}
+	if result.FilesDeleted == 0 && result.LockedFiles == 0 || machineOutput() {
+		return
+	}
+	fmt.Printf("Retried files left in use earlier: %d removed (%s), %d still in use\n\n",
+		result.FilesDeleted, cleaner.FormatBytes(result.SpaceFreed), result.LockedFiles)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -31,7 +32,11 @@ Examples:
 		if listFlag {
 			entries, err := priority.ListConfiguredPriorities()
 			if err != nil {
-				fmt.Printf("Error listing priorities: %v\n", err)
+				fail(fmt.Errorf("listing priorities: %w", err))
+				return
+			}
+			if machineOutput() {
+				emit(append([]priority.PriorityEntry{}, entries...))
 				return
 			}
 
@@ -57,7 +62,11 @@ Examples:
 		// Remove priority settings
 		if removeProcess != "" {
 			if err := priority.RemoveProcessPriority(removeProcess); err != nil {
-				fmt.Printf("Error removing priority for %s: %v\n", removeProcess, err)
+				fail(fmt.Errorf("removing priority for %s: %w", removeProcess, err))
+				return
+			}
+			if machineOutput() {
+				emit(priorityRemoved{ProcessName: removeProcess, Removed: true})
 				return
 			}
 			fmt.Printf("Successfully removed priority settings for %s\n", removeProcess)
@@ -82,7 +91,19 @@ Examples:
 			pageVal := priority.ParsePagePriorityName(pagePriority)
 
 			if err := priority.SetProcessPriority(setProcess, cpuVal, ioVal, pageVal); err != nil {
-				fmt.Printf("Error setting priority for %s: %v\n", setProcess, err)
+				fail(fmt.Errorf("setting priority for %s: %w", setProcess, err))
+				return
+			}
+			if machineOutput() {
+				emit(priority.PriorityEntry{
+					ProcessName:      setProcess,
+					CpuPriority:      cpuVal,
+					IoPriority:       ioVal,
+					PagePriority:     pageVal,
+					CpuPriorityName:  priority.GetCpuPriorityName(cpuVal),
+					IoPriorityName:   priority.GetIoPriorityName(ioVal),
+					PagePriorityName: priority.GetPagePriorityName(pageVal),
+				})
 				return
 			}
 
@@ -95,11 +116,22 @@ Examples:
 		}
 
 		// No action specified
+		if machineOutput() {
+			fail(errors.New("no action specified, use --list, --set or --remove"))
+			return
+		}
+		setExitCode(exitFailure)
 		fmt.Println("No action specified. Use --list, --set, or --remove.")
 		fmt.Println("Run 'syscleaner priority --help' for usage information.")
 	},
 }
 
+// priorityRemoved is the machine-readable output of --remove.
+type priorityRemoved struct {
+	ProcessName string `json:"process_name"`
+	Removed     bool   `json:"removed"`
+}
+
 func init() {
 	priorityCmd.Flags().Bool("list", false, "List all configured process priorities")
 	priorityCmd.Flags().String("set", "", "Set priority for a process (e.g., LeagueClient.exe)")
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var quarantineCmd = &cobra.Command{
+	Use:   "quarantine",
+	Short: "List, restore or purge quarantined files",
+	Long: `Files cleaned in quarantine mode are moved into a size-capped store in the
+config directory instead of being deleted. Each clean is stored as a run that
+can be restored as a whole.
+
+Examples:
+  syscleaner quarantine list
+  syscleaner quarantine list 20240301-102030
+  syscleaner quarantine restore 20240301-102030
+  syscleaner quarantine purge --older-than 7d`,
+}
+
+var quarantineListCmd = &cobra.Command{
+	Use:   "list [run-id]",
+	Short: "List quarantine runs, or the files of one run",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		q, ok := openQuarantine()
+		if !ok {
+			return
+		}
+
+		if len(args) == 1 {
+			entries, err := q.Entries(args[0])
+			if err != nil {
+				fail(err)
+				return
+			}
+			if machineOutput() {
+				emit(append([]cleaner.QuarantineEntry{}, entries...))
+				return
+			}
+			fmt.Printf("%-12s %-20s %s\n", "Size", "Category", "Original Path")
+			fmt.Println(strings.Repeat("-", 80))
+			for _, e := range entries {
+				fmt.Printf("%-12s %-20s %s\n", cleaner.FormatBytes(e.Size), e.Category, e.Original)
+			}
+			return
+		}
+
+		runs, err := q.Runs()
+		if err != nil {
+			fail(err)
+			return
+		}
+		if machineOutput() {
+			emit(append([]cleaner.QuarantineRun{}, runs...))
+			return
+		}
+		if len(runs) == 0 {
+			fmt.Println("The quarantine is empty.")
+			return
+		}
+		fmt.Printf("%-20s %-20s %8s %12s  %s\n", "Run ID", "Created", "Files", "Size", "Categories")
+		fmt.Println(strings.Repeat("-", 80))
+		var total int64
+		for _, r := range runs {
+			fmt.Printf("%-20s %-20s %8d %12s  %s\n",
+				r.ID, r.Created.Format("2006-01-02 15:04"), r.Files, cleaner.FormatBytes(r.Size), strings.Join(r.Categories, ", "))
+			total += r.Size
+		}
+		fmt.Printf("\nTotal: %s in %s\n", cleaner.FormatBytes(total), q.Dir())
+	},
+}
+
+var quarantineRestoreCmd = &cobra.Command{
+	Use:   "restore <run-id>",
+	Short: "Move every file of a run back to its original location",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		q, ok := openQuarantine()
+		if !ok {
+			return
+		}
+
+		restored, errs := q.Restore(args[0])
+		if len(errs) > 0 {
+			if restored > 0 {
+				setExitCode(exitPartial)
+			} else {
+				setExitCode(exitFailure)
+			}
+		}
+		if machineOutput() {
+			out := restoreOutput{Run: args[0], Restored: restored, Errors: []string{}}
+			for _, err := range errs {
+				out.Errors = append(out.Errors, err.Error())
+			}
+			emit(out)
+			return
+		}
+		for _, err := range errs {
+			fmt.Printf("  Not restored: %v\n", err)
+		}
+		fmt.Printf("Restored %d file(s) from run %s\n", restored, args[0])
+		if len(errs) > 0 {
+			fmt.Println("Files that could not be restored remain in the quarantine.")
+		}
+	},
+}
+
+var quarantinePurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently delete old quarantine runs",
+	Run: func(cmd *cobra.Command, args []string) {
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		age, err := cleaner.ParseAge(olderThan)
+		if err != nil {
+			fail(fmt.Errorf("--older-than: %w", err))
+			return
+		}
+
+		q, ok := openQuarantine()
+		if !ok {
+			return
+		}
+		runs, freed, err := q.Purge(age)
+		if err != nil {
+			if runs > 0 {
+				setExitCode(exitPartial)
+			} else {
+				setExitCode(exitFailure)
+			}
+		}
+		if machineOutput() {
+			out := purgeOutput{Runs: runs, BytesFreed: freed}
+			if err != nil {
+				out.Error = err.Error()
+			}
+			emit(out)
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		fmt.Printf("Purged %d run(s), freed %s\n", runs, cleaner.FormatBytes(freed))
+	},
+}
+
+// openQuarantine opens the store configured in the config file, printing any
+// error.
+func openQuarantine() (*cleaner.Quarantine, bool) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		warnf("%v (using defaults)", err)
+		cfg = config.DefaultConfig()
+	}
+	q, err := config.OpenQuarantine(cfg)
+	if err != nil {
+		fail(fmt.Errorf("cannot open quarantine store: %w", err))
+		return nil, false
+	}
+	return q, true
+}
+
+// restoreOutput is the machine-readable output of "quarantine restore".
+type restoreOutput struct {
+	Run      string   `json:"run"`
+	Restored int      `json:"restored"`
+	Errors   []string `json:"errors"` // Files left in the quarantine
+}
+
+// purgeOutput is the machine-readable output of "quarantine purge".
+type purgeOutput struct {
+	Runs       int    `json:"runs"`
+	BytesFreed int64  `json:"bytes_freed"`
+	Error      string `json:"error,omitempty"`
+}
+
+func init() {
+	quarantinePurgeCmd.Flags().String("older-than", "7d", "Only purge runs older than this (e.g. 7d, 2w, 0 for all)")
+
+	quarantineCmd.AddCommand(quarantineListCmd, quarantineRestoreCmd, quarantinePurgeCmd)
+	rootCmd.AddCommand(quarantineCmd)
+}
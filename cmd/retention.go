@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var retentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "Show or change per-category retention policies",
+	Long: `Retention policies narrow down which files a category removes from the
+folders it cleans:
+
+  --min-age      keep files younger than this (e.g. 14d); measured by
+                 modification time, or by last access with --age-by atime
+  --min-size     keep files smaller than this (e.g. 1M)
+  --keep-newest  keep the newest N files of every folder
+  --max-size     only remove files, oldest first, until the folder is no
+                 larger than this (e.g. 500M)
+
+A category without a policy of its own keeps its built-in age: 30 days for
+Prefetch and Windows logs, none for the rest. Policies are stored in the
+config file, or in a saved profile with --profile.
+
+Examples:
+  syscleaner retention list
+  syscleaner retention set windows_logs --min-age 14d --max-size 500M
+  syscleaner retention set user_temp --min-age 7d --age-by atime
+  syscleaner retention clear windows_logs`,
+}
+
+var retentionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the retention policy of every category that has one",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		registerRules()
+		profile, _ := cmd.Flags().GetString("profile")
+		policies, err := config.LoadRetention(profile)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		out := []retentionOutput{}
+		for _, c := range cleaner.Categories() {
+			p, set := policies[c.ID]
+			if !set && c.MaxAge == 0 {
+				continue
+			}
+			out = append(out, retentionOutput{ID: c.ID, Name: c.Name, Policy: p.WithDefaultAge(c.MaxAge), BuiltIn: !set})
+		}
+		// Policies of categories that are not registered in this run.
+		var unknown []string
+		for id := range policies {
+			if _, ok := cleaner.LookupCategory(id); !ok {
+				unknown = append(unknown, id)
+			}
+		}
+		sort.Strings(unknown)
+		for _, id := range unknown {
+			out = append(out, retentionOutput{ID: id, Name: id, Policy: policies[id]})
+		}
+
+		if machineOutput() {
+			emit(out)
+			return
+		}
+		fmt.Printf("%-24s %s\n", "Category", "Policy")
+		fmt.Println(strings.Repeat("-", 80))
+		for _, r := range out {
+			note := ""
+			if r.BuiltIn {
+				note = " (built-in)"
+			}
+			fmt.Printf("%-24s %s%s\n", r.ID, r.Policy, note)
+		}
+	},
+}
+
+var retentionSetCmd = &cobra.Command{
+	Use:   "set <category>",
+	Short: "Set or change the retention policy of a category",
+	Long: `Set the retention policy of a category. Only the given flags change; the
+rest of an existing policy is kept. Pass 0 to unset a value.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, ok := retentionCategory(args[0])
+		if !ok {
+			return
+		}
+		profile, _ := cmd.Flags().GetString("profile")
+		policies, err := config.LoadRetention(profile)
+		if err != nil {
+			fail(err)
+			return
+		}
+		policy := policies[id]
+
+		if cmd.Flags().Changed("min-age") {
+			s, _ := cmd.Flags().GetString("min-age")
+			if policy.MinAge, err = cleaner.ParseAge(s); err != nil {
+				fail(fmt.Errorf("--min-age: %w", err))
+				return
+			}
+		}
+		if cmd.Flags().Changed("age-by") {
+			s, _ := cmd.Flags().GetString("age-by")
+			if policy.AgeBy, err = cleaner.ParseAgeBasis(s); err != nil {
+				fail(fmt.Errorf("--age-by: %w", err))
+				return
+			}
+		}
+		for flag, field := range map[string]*int64{"min-size": &policy.MinSize, "max-size": &policy.MaxSize} {
+			if !cmd.Flags().Changed(flag) {
+				continue
+			}
+			s, _ := cmd.Flags().GetString(flag)
+			if *field, err = cleaner.ParseSize(s); err != nil {
+				fail(fmt.Errorf("--%s: %w", flag, err))
+				return
+			}
+		}
+		if cmd.Flags().Changed("keep-newest") {
+			if policy.KeepNewest, _ = cmd.Flags().GetInt("keep-newest"); policy.KeepNewest < 0 {
+				fail(errors.New("--keep-newest must not be negative"))
+				return
+			}
+		}
+
+		if err := config.SetRetention(profile, id, policy); err != nil {
+			fail(err)
+			return
+		}
+		printRetentionChange(id, policy)
+	},
+}
+
+var retentionClearCmd = &cobra.Command{
+	Use:   "clear <category>",
+	Short: "Remove the retention policy of a category",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, ok := retentionCategory(args[0])
+		if !ok {
+			return
+		}
+		profile, _ := cmd.Flags().GetString("profile")
+		if err := config.SetRetention(profile, id, cleaner.RetentionPolicy{}); err != nil {
+			fail(err)
+			return
+		}
+		printRetentionChange(id, cleaner.RetentionPolicy{})
+	},
+}
+
+// retentionOutput is the machine-readable form of a category's policy.
+// BuiltIn is set for categories using their built-in age.
+type retentionOutput struct {
+	ID      string                  `json:"id"`
+	Name    string                  `json:"name"`
+	Policy  cleaner.RetentionPolicy `json:"policy"`
+	BuiltIn bool                    `json:"built_in"`
+}
+
+// registerRules registers the custom rules so their IDs can be used, warning
+// about rules that could not be loaded.
+func registerRules() {
+	for _, err := range config.RegisterUserRules() {
+		warnf("skipped custom rule: %v", err)
+	}
+}
+
+// retentionCategory checks that id names a registered category.
+func retentionCategory(id string) (string, bool) {
+	registerRules()
+	if _, ok := cleaner.LookupCategory(id); !ok {
+		fail(fmt.Errorf("unknown category %q, run 'syscleaner clean --list' to see available IDs", id))
+		return "", false
+	}
+	return id, true
+}
+
+// printRetentionChange reports the policy a category now has.
+func printRetentionChange(id string, policy cleaner.RetentionPolicy) {
+	c, _ := cleaner.LookupCategory(id)
+	effective := policy.WithDefaultAge(c.MaxAge)
+	if machineOutput() {
+		emit(retentionOutput{ID: id, Name: c.Name, Policy: effective, BuiltIn: policy.IsZero()})
+		return
+	}
+	if policy.IsZero() {
+		fmt.Printf("Cleared the retention policy of %s (now: %s)\n", id, effective)
+		return
+	}
+	fmt.Printf("Retention policy of %s: %s\n", id, effective)
+}
+
+func init() {
+	retentionCmd.PersistentFlags().String("profile", "", "Change this saved profile instead of the config file")
+	retentionSetCmd.Flags().String("min-age", "", "Keep files younger than this (e.g. 14d, 36h)")
+	retentionSetCmd.Flags().String("age-by", "", "Measure age by modification (mtime) or access time (atime)")
+	retentionSetCmd.Flags().String("min-size", "", "Keep files smaller than this (e.g. 64K, 1M)")
+	retentionSetCmd.Flags().Int("keep-newest", 0, "Keep the newest N files of every folder")
+	retentionSetCmd.Flags().String("max-size", "", "Remove files, oldest first, only until the folder is no larger than this (e.g. 500M)")
+
+	retentionCmd.AddCommand(retentionListCmd, retentionSetCmd, retentionClearCmd)
+	rootCmd.AddCommand(retentionCmd)
+}
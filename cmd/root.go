@@ -17,12 +17,22 @@ Features:
   - Gaming mode (auto-detects games, boosts CPU/RAM priority)
   - Extreme mode (stops Explorer shell, maximum performance)
   - System optimizer (startup, network, disk optimizations)
-  - CPU priority manager (permanent per-process priority settings)`,
+  - CPU priority manager (permanent per-process priority settings)
+
+Every command accepts --output json or --output yaml to print one
+machine-readable document instead of text.
+
+Exit codes:
+  0  success
+  1  total failure: invalid flags, an error before any work was done, or
+     nothing could be done
+  2  partial failure: some of the work was done; the output lists what was not`,
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitFailure)
 	}
+	os.Exit(exitCode)
 }
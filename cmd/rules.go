@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+
+	"syscleaner/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage custom cleaning rules",
+	Long: `Manage custom cleaning rules stored in the "rules" folder of the config directory.
+
+JSON rules are written by hand; community definitions can be imported from a
+CCleaner-style winapp2.ini file.`,
+}
+
+var rulesImportCmd = &cobra.Command{
+	Use:   "import <winapp2.ini>",
+	Short: "Import cleaning definitions from a winapp2.ini file",
+	Long: `Parse a winapp2.ini file, report which entries SysCleaner understood and
+which were skipped, and copy it into the rules folder so its entries are
+available as cleaning categories (see 'syscleaner clean --list').
+
+Only file cleaning is supported. Registry-only entries are skipped.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+
+		entries, skipped, dest, err := config.ImportWinapp2(args[0])
+		if err != nil && entries == nil {
+			fail(err)
+			return
+		}
+		if err != nil {
+			setExitCode(exitFailure)
+		}
+
+		if machineOutput() {
+			out := importOutput{Source: args[0], Entries: []importedEntry{}, Skipped: []skippedEntry{}}
+			for _, e := range entries {
+				out.Entries = append(out.Entries, importedEntry{ID: e.ID(), Name: e.Name()})
+			}
+			for _, s := range skipped {
+				out.Skipped = append(out.Skipped, skippedEntry{Section: s.Section, Reason: s.Reason})
+			}
+			if err != nil {
+				out.Error = err.Error()
+			} else {
+				out.ImportedTo = dest
+			}
+			emit(out)
+			return
+		}
+
+		fmt.Printf("Parsed %s: %d entries understood, %d skipped\n", args[0], len(entries), len(skipped))
+		fmt.Println()
+
+		if verbose && len(entries) > 0 {
+			fmt.Println("Understood:")
+			for _, e := range entries {
+				fmt.Printf("  %-40s %s\n", e.Name(), e.ID())
+			}
+			fmt.Println()
+		}
+
+		if len(skipped) > 0 {
+			fmt.Println("Skipped:")
+			for _, s := range skipped {
+				fmt.Printf("  %-40s %s\n", s.Section, s.Reason)
+			}
+			fmt.Println()
+		}
+
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Imported to %s\n", dest)
+		fmt.Println("Run 'syscleaner clean --list' to see the imported categories.")
+	},
+}
+
+// importOutput is the machine-readable output of "rules import". Entries
+// are only available as categories when ImportedTo is set.
+type importOutput struct {
+	Source     string          `json:"source"`
+	ImportedTo string          `json:"imported_to,omitempty"`
+	Entries    []importedEntry `json:"entries"`
+	Skipped    []skippedEntry  `json:"skipped"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type importedEntry struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type skippedEntry struct {
+	Section string `json:"section"`
+	Reason  string `json:"reason"`
+}
+
+func init() {
+	rulesImportCmd.Flags().BoolP("verbose", "v", false, "List every understood entry")
+	rulesCmd.AddCommand(rulesImportCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
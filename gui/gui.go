@@ -3,7 +3,9 @@
 package gui
 
 import (
+	"context"
 	"image/color"
+	"log"
 	"sync"
 
 	"fyne.io/fyne/v2"
@@ -13,6 +15,8 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	"syscleaner/gui/views"
+	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
 	"syscleaner/pkg/gaming"
 )
 
@@ -90,11 +94,32 @@ func Run() {
 	w.CenterOnScreen()
 	w.SetMaster()
 
+	// Files earlier cleans found in use are usually free again by the next
+	// start, so retry them in the background.
+	go retryPending()
+
 	mainContainer := createMainInterface(w)
 	w.SetContent(mainContainer)
 	w.ShowAndRun()
 }
 
+// retryPending retries the pending queue of files left in use and logs the
+// outcome.
+func retryPending() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	result, err := config.RetryPending(context.Background(), cfg)
+	if err != nil {
+		log.Printf("[SysCleaner] Could not retry the files left in use: %v", err)
+	}
+	if result.FilesDeleted > 0 || result.LockedFiles > 0 {
+		log.Printf("[SysCleaner] Retried files left in use: %d removed (%s), %d still in use",
+			result.FilesDeleted, cleaner.FormatBytes(result.SpaceFreed), result.LockedFiles)
+	}
+}
+
 // lazyTab creates a tab whose content is built on first selection.
 // This avoids initializing heavy panels (monitors, process lists) at startup.
 func lazyTab(name string, icon fyne.Resource, builder func() fyne.CanvasObject) *container.TabItem {
@@ -139,14 +164,20 @@ func createMainInterface(w fyne.Window) fyne.CanvasObject {
 	extremeTab := lazyTab("Extreme Mode", theme.WarningIcon(), func() fyne.CanvasObject {
 		return views.NewExtremeModePanel(w)
 	})
-	cleanTab := lazyTab("Clean", theme.DeleteIcon(), views.NewCleanPanel)
+	cleanTab := lazyTab("Clean", theme.DeleteIcon(), func() fyne.CanvasObject {
+		return views.NewCleanPanel(w)
+	})
 	optimizeTab := lazyTab("Optimize", theme.SettingsIcon(), views.NewOptimizePanel)
 	cpuTab := lazyTab("CPU Priority", theme.MediaPlayIcon(), func() fyne.CanvasObject {
 		return views.NewPriorityPanel(w)
 	})
 	monitorTab := lazyTab("Monitor", theme.InfoIcon(), views.NewMonitorPanel)
+	diskTab := lazyTab("Disk Usage", theme.StorageIcon(), func() fyne.CanvasObject {
+		return views.NewAnalyzePanel(w)
+	})
+	historyTab := lazyTab("History", theme.HistoryIcon(), views.NewHistoryPanel)
 
-	tabs := container.NewAppTabs(dashTab, extremeTab, cleanTab, optimizeTab, cpuTab, monitorTab)
+	tabs := container.NewAppTabs(dashTab, extremeTab, cleanTab, diskTab, optimizeTab, cpuTab, monitorTab, historyTab)
 	tabs.SetTabLocation(container.TabLocationLeading)
 
 	// Trigger lazy content initialization when a tab is selected
@@ -0,0 +1,295 @@
+//go:build gui
+
+package views
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
+)
+
+// analyzeOldAfter is the access age above which files are listed as old.
+const analyzeOldAfter = 180 * 24 * time.Hour
+
+// NewAnalyzePanel creates the disk usage view: the largest directories and
+// files below a folder and the files not used for a long time. Ticked
+// entries can be quarantined or deleted through the cleaner.
+func NewAnalyzePanel(w fyne.Window) fyne.CanvasObject {
+	pathEntry := widget.NewEntry()
+	pathEntry.SetPlaceHolder("Folder to analyze")
+	if home, err := os.UserHomeDir(); err == nil {
+		pathEntry.SetText(home)
+	}
+
+	statusLabel := widget.NewLabel("Choose a folder and press Analyze.")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	progressBar := widget.NewProgressBarInfinite()
+	progressBar.Stop()
+	progressBar.Hide()
+
+	dirsBox := container.NewVBox()
+	filesBox := container.NewVBox()
+	oldBox := container.NewVBox()
+	extLabel := widget.NewLabel("")
+
+	// selected maps ticked paths to their size; the same file can be ticked
+	// in the largest and the old files lists.
+	var (
+		selMu    sync.Mutex
+		selected = map[string]int64{}
+	)
+	selectionLabel := widget.NewLabel("")
+	updateSelection := func() {
+		selMu.Lock()
+		var total int64
+		for _, size := range selected {
+			total += size
+		}
+		n := len(selected)
+		selMu.Unlock()
+		selectionLabel.SetText(fmt.Sprintf("%d selected (%s)", n, cleaner.FormatBytes(total)))
+	}
+
+	// checks tracks the checkboxes per path so ticking a file in one list
+	// ticks it in the other as well.
+	checks := map[string][]*widget.Check{}
+	fill := func(box *fyne.Container, root string, entries []cleaner.SizeEntry) {
+		box.Objects = nil
+		for _, e := range entries {
+			e := e
+			label := fmt.Sprintf("%10s   %s", cleaner.FormatBytes(e.Size), relPath(root, e.Path))
+			if e.Dir {
+				label += fmt.Sprintf("%c  (%d files)", filepath.Separator, e.Files)
+			}
+			var check *widget.Check
+			check = widget.NewCheck(label, func(on bool) {
+				selMu.Lock()
+				if on {
+					selected[e.Path] = e.Size
+				} else {
+					delete(selected, e.Path)
+				}
+				selMu.Unlock()
+				for _, other := range checks[e.Path] {
+					if other != check && other.Checked != on {
+						other.SetChecked(on)
+					}
+				}
+				updateSelection()
+			})
+			checks[e.Path] = append(checks[e.Path], check)
+			box.Add(check)
+		}
+		if len(entries) == 0 {
+			box.Add(widget.NewLabel("Nothing found."))
+		}
+		box.Refresh()
+	}
+
+	analyzeBtn := widget.NewButton("Analyze", nil)
+	cancelBtn := widget.NewButton("Cancel", nil)
+	cancelBtn.Disable()
+	quarantineBtn := widget.NewButton("Quarantine Selected", nil)
+	deleteBtn := widget.NewButton("Delete Selected", nil)
+
+	var (
+		runMu     sync.Mutex
+		cancelRun context.CancelFunc
+	)
+	startRun := func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		runMu.Lock()
+		cancelRun = cancel
+		runMu.Unlock()
+		analyzeBtn.Disable()
+		quarantineBtn.Disable()
+		deleteBtn.Disable()
+		cancelBtn.Enable()
+		progressBar.Show()
+		progressBar.Start()
+		return ctx
+	}
+	endRun := func() {
+		runMu.Lock()
+		if cancelRun != nil {
+			cancelRun()
+			cancelRun = nil
+		}
+		runMu.Unlock()
+		progressBar.Stop()
+		progressBar.Hide()
+		cancelBtn.Disable()
+		analyzeBtn.Enable()
+		quarantineBtn.Enable()
+		deleteBtn.Enable()
+	}
+	cancelBtn.OnTapped = func() {
+		runMu.Lock()
+		if cancelRun != nil {
+			cancelRun()
+		}
+		runMu.Unlock()
+		statusLabel.SetText("Stopping...")
+	}
+
+	analyzeBtn.OnTapped = func() {
+		root := strings.TrimSpace(pathEntry.Text)
+		if root == "" {
+			return
+		}
+		statusLabel.SetText("Analyzing " + root + "...")
+		ctx := startRun()
+		go func() {
+			defer endRun()
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				cfg = config.DefaultConfig()
+			}
+			a, err := cleaner.Analyze(ctx, root, cleaner.AnalyzeOptions{
+				OldAfter: analyzeOldAfter,
+				Timeout:  cfg.DefaultCleanOptions.Timeout,
+			})
+			if err != nil {
+				statusLabel.SetText(fmt.Sprintf("Could not analyze %s: %v", root, err))
+				return
+			}
+
+			selMu.Lock()
+			selected = map[string]int64{}
+			selMu.Unlock()
+			checks = map[string][]*widget.Check{}
+			fill(dirsBox, a.Root, a.LargestDirs)
+			fill(filesBox, a.Root, a.LargestFiles)
+			fill(oldBox, a.Root, a.OldFiles)
+			updateSelection()
+
+			var ext strings.Builder
+			for _, e := range a.Extensions {
+				name := e.Extension
+				if name == "" {
+					name = "(none)"
+				}
+				fmt.Fprintf(&ext, "%10s   %-12s %d files\n", cleaner.FormatBytes(e.Bytes), name, e.Files)
+			}
+			extLabel.SetText(strings.TrimRight(ext.String(), "\n"))
+
+			status := fmt.Sprintf("%s in %d files and %d folders. %d files (%s) not opened for %d days.",
+				cleaner.FormatBytes(a.Bytes), a.Files, a.Dirs, a.OldCount, cleaner.FormatBytes(a.OldBytes), a.OldAfterDays)
+			if a.Status != cleaner.StatusCompleted {
+				status = "Stopped early; the lists only cover what was walked. " + status
+			}
+			statusLabel.SetText(status)
+		}()
+	}
+
+	// remove hands the ticked paths to the cleaner, which applies the same
+	// protections and exclusions as a normal clean.
+	remove := func(quarantine bool) {
+		selMu.Lock()
+		paths := make([]string, 0, len(selected))
+		for p := range selected {
+			paths = append(paths, p)
+		}
+		selMu.Unlock()
+		if len(paths) == 0 {
+			dialog.ShowInformation("No Selection", "Tick the folders and files to remove first.", w)
+			return
+		}
+		sort.Strings(paths)
+
+		verb, doing := "Delete", "Deleting"
+		if quarantine {
+			verb, doing = "Quarantine", "Quarantining"
+		}
+		dialog.ShowConfirm(verb+" Selected",
+			fmt.Sprintf("%s %d selected items?", verb, len(paths)),
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				statusLabel.SetText(doing + " selected items...")
+				ctx := startRun()
+				go func() {
+					defer endRun()
+					cfg, err := config.LoadConfig()
+					if err != nil {
+						cfg = config.DefaultConfig()
+					}
+					opts := cleaner.CleanOptions{Timeout: cfg.DefaultCleanOptions.Timeout, DirTimeout: cfg.DefaultCleanOptions.DirTimeout}
+					config.ApplyExclusions(cfg, &opts)
+					if quarantine {
+						if err := config.ApplyQuarantine(cfg, &opts); err != nil {
+							statusLabel.SetText(fmt.Sprintf("Quarantine unavailable: %v", err))
+							return
+						}
+						opts.QuarantineCategories[cleaner.SelectionCategoryID] = true
+					}
+					result := cleaner.RemoveSelection(ctx, paths, opts)
+					if err := config.RecordHistory(cfg, result, false); err != nil {
+						log.Printf("[SysCleaner] Could not record the run in the history: %v", err)
+					}
+
+					text := fmt.Sprintf("Removed %d files (%s).", result.FilesDeleted, cleaner.FormatBytes(result.SpaceFreed))
+					if quarantine {
+						text = fmt.Sprintf("Quarantined %d files (%s). Restore with: syscleaner quarantine restore %s",
+							result.FilesQuarantined, cleaner.FormatBytes(result.SpaceQuarantined), result.QuarantineRun)
+					}
+					if skipped := result.SkippedFiles + int64(len(result.Errors)); skipped > 0 {
+						text += fmt.Sprintf(" %d could not be removed (in use, protected or excluded).", skipped)
+					}
+					statusLabel.SetText(text + " Analyze again to update the lists.")
+				}()
+			}, w)
+	}
+	quarantineBtn.OnTapped = func() { remove(true) }
+	deleteBtn.OnTapped = func() { remove(false) }
+
+	section := func(title string, content fyne.CanvasObject) fyne.CanvasObject {
+		return container.NewBorder(
+			widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), nil, nil, nil,
+			container.NewVScroll(content))
+	}
+	extLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	lists := container.NewAppTabs(
+		container.NewTabItem("Largest Folders", section("Largest folders", dirsBox)),
+		container.NewTabItem("Largest Files", section("Largest files", filesBox)),
+		container.NewTabItem("Old Files", section(fmt.Sprintf("Largest files not opened for %d days", int(analyzeOldAfter/(24*time.Hour))), oldBox)),
+		container.NewTabItem("By Type", section("Space per file extension", extLabel)),
+	)
+
+	top := container.NewVBox(
+		widget.NewLabelWithStyle("Disk Usage", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewBorder(nil, nil, nil, container.NewHBox(analyzeBtn, cancelBtn), pathEntry),
+		progressBar,
+		statusLabel,
+		widget.NewSeparator(),
+	)
+	bottom := container.NewVBox(
+		widget.NewSeparator(),
+		container.NewHBox(selectionLabel, quarantineBtn, deleteBtn),
+	)
+	updateSelection()
+	return container.NewPadded(container.NewBorder(top, bottom, nil, nil, lists))
+}
+
+// relPath shortens path for display when it lies below root.
+func relPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
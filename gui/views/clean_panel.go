@@ -3,17 +3,24 @@
 package views
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 
 	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
 )
 
 // NewCleanPanel creates the cleaning interface with granular category options.
-func NewCleanPanel() fyne.CanvasObject {
+func NewCleanPanel(w fyne.Window) fyne.CanvasObject {
 	statusLabel := widget.NewLabel("Ready to clean.")
 	statusLabel.Wrapping = fyne.TextWrapWord
 
@@ -26,66 +33,33 @@ func NewCleanPanel() fyne.CanvasObject {
 	progressBar.Stop()
 	progressBar.Hide()
 
-	// System categories
-	winTempCheck := widget.NewCheck("Windows Temp", nil)
-	winTempCheck.SetChecked(true)
-	userTempCheck := widget.NewCheck("User Temp", nil)
-	userTempCheck.SetChecked(true)
-	prefetchCheck := widget.NewCheck("Prefetch (30+ days)", nil)
-	prefetchCheck.SetChecked(true)
-	crashDumpCheck := widget.NewCheck("Crash Dumps", nil)
-	crashDumpCheck.SetChecked(true)
-	errorReportsCheck := widget.NewCheck("Error Reports", nil)
-	errorReportsCheck.SetChecked(true)
-	thumbCacheCheck := widget.NewCheck("Thumbnail Cache", nil)
-	thumbCacheCheck.SetChecked(true)
-	iconCacheCheck := widget.NewCheck("Icon Cache", nil)
-	shaderCacheCheck := widget.NewCheck("Shader Cache", nil)
-	shaderCacheCheck.SetChecked(true)
-	dnsCacheCheck := widget.NewCheck("DNS Cache", nil)
-	winLogsCheck := widget.NewCheck("Windows Logs", nil)
-	winLogsCheck.SetChecked(true)
-	eventLogsCheck := widget.NewCheck("Event Logs", nil)
-	deliveryOptCheck := widget.NewCheck("Delivery Optimization", nil)
-	recycleBinCheck := widget.NewCheck("Recycle Bin", nil)
-	winUpdateCheck := widget.NewCheck("Windows Update Cache", nil)
-	winInstallerCheck := widget.NewCheck("Windows Installer Cache", nil)
-	fontCacheCheck := widget.NewCheck("Font Cache", nil)
-
-	// Browser categories
-	chromeCheck := widget.NewCheck("Chrome", nil)
-	chromeCheck.SetChecked(true)
-	firefoxCheck := widget.NewCheck("Firefox", nil)
-	firefoxCheck.SetChecked(true)
-	edgeCheck := widget.NewCheck("Edge", nil)
-	edgeCheck.SetChecked(true)
-	braveCheck := widget.NewCheck("Brave", nil)
-	braveCheck.SetChecked(true)
-	operaCheck := widget.NewCheck("Opera", nil)
-	operaCheck.SetChecked(true)
-
-	// Application categories
-	discordCheck := widget.NewCheck("Discord", nil)
-	discordCheck.SetChecked(true)
-	spotifyCheck := widget.NewCheck("Spotify", nil)
-	spotifyCheck.SetChecked(true)
-	steamCheck := widget.NewCheck("Steam", nil)
-	steamCheck.SetChecked(true)
-	teamsCheck := widget.NewCheck("Teams", nil)
-	teamsCheck.SetChecked(true)
-	vscodeCheck := widget.NewCheck("VS Code", nil)
-	vscodeCheck.SetChecked(true)
-	javaCheck := widget.NewCheck("Java", nil)
-	javaCheck.SetChecked(true)
-
-	systemChecks := []*widget.Check{
-		winTempCheck, userTempCheck, prefetchCheck, crashDumpCheck,
-		errorReportsCheck, thumbCacheCheck, iconCacheCheck, shaderCacheCheck,
-		dnsCacheCheck, winLogsCheck, eventLogsCheck, deliveryOptCheck,
-		recycleBinCheck, winUpdateCheck, winInstallerCheck, fontCacheCheck,
+	// Once the pre-scan has produced totals, the determinate bar replaces the
+	// infinite one and the log lists categories and skipped files as they go.
+	fileBar := widget.NewProgressBar()
+	fileBar.Hide()
+	runLog := widget.NewMultiLineEntry()
+	runLog.Disable()
+	runLog.SetMinRowsVisible(6)
+	runLog.Hide()
+
+	// Custom rules must be registered before the checkboxes are built.
+	for _, err := range config.RegisterUserRules() {
+		log.Printf("[SysCleaner] Skipped custom rule: %v", err)
+	}
+
+	// One checkbox per supported and installed registry category, grouped by
+	// section. Low-risk categories are pre-selected.
+	categoryChecks := map[string]*widget.Check{}
+	groupChecks := map[cleaner.Group][]*widget.Check{}
+	for _, c := range cleaner.Categories() {
+		if !c.Supported() || !c.Detected() {
+			continue
+		}
+		check := widget.NewCheck(c.Name, nil)
+		check.SetChecked(c.Risk == cleaner.RiskLow)
+		categoryChecks[c.ID] = check
+		groupChecks[c.Group] = append(groupChecks[c.Group], check)
 	}
-	browserChecks := []*widget.Check{chromeCheck, firefoxCheck, edgeCheck, braveCheck, operaCheck}
-	appChecks := []*widget.Check{discordCheck, spotifyCheck, steamCheck, teamsCheck, vscodeCheck, javaCheck}
 
 	makeSelectAll := func(checks []*widget.Check, val bool) func() {
 		return func() {
@@ -95,44 +69,231 @@ func NewCleanPanel() fyne.CanvasObject {
 		}
 	}
 
+	// One checkbox per discovered browser profile. Unchecked profiles are
+	// left alone by the browser and privacy categories.
+	profileChecks := map[string]*widget.Check{}
+	var profileOrder []*widget.Check
+	for _, p := range cleaner.DiscoverBrowserProfiles() {
+		check := widget.NewCheck(p.Label(), nil)
+		check.SetChecked(true)
+		profileChecks[p.Dir] = check
+		profileOrder = append(profileOrder, check)
+	}
+
+	quarantineCheck := widget.NewCheck("Quarantine instead of deleting (restorable)", nil)
+	archiveCheck := widget.NewCheck("Archive log files before removing them", nil)
+	if cfg, err := config.LoadConfig(); err == nil {
+		archiveCheck.SetChecked(cfg.Archive.Enabled)
+	}
+
+	// What to do with categories whose application (a browser, Discord,
+	// ...) is running. "Use settings" keeps the policies from the config.
+	runningPolicies := map[string]cleaner.RunningPolicy{
+		"Skip it":               cleaner.RunningSkip,
+		"Clean anyway (warn)":   cleaner.RunningWarn,
+		"Ask to close it first": cleaner.RunningClose,
+	}
+	runningSelect := widget.NewSelect([]string{"Use settings", "Skip it", "Clean anyway (warn)", "Ask to close it first"}, nil)
+	runningSelect.SetSelected("Use settings")
+
+	// confirmClose asks, from a cleaning worker, whether a running
+	// application may be closed. The worker waits for the answer.
+	confirmClose := func(c cleaner.Category, procs []cleaner.RunningProcess) bool {
+		names := map[string]bool{}
+		var list []string
+		for _, p := range procs {
+			if !names[p.Name] {
+				names[p.Name] = true
+				list = append(list, p.Name)
+			}
+		}
+		answer := make(chan bool, 1)
+		dialog.ShowConfirm("Close "+strings.Join(list, ", ")+"?",
+			fmt.Sprintf("%s is running. Close it to clean %s?\n\nIt is asked to close as if you clicked its close button.", strings.Join(list, ", "), c.Name),
+			func(ok bool) { answer <- ok }, w)
+		return <-answer
+	}
+
 	// Build options from checkboxes
 	buildOpts := func(dryRun bool) cleaner.CleanOptions {
-		return cleaner.CleanOptions{
-			WindowsTemp:          winTempCheck.Checked,
-			UserTemp:             userTempCheck.Checked,
-			Prefetch:             prefetchCheck.Checked,
-			CrashDumps:           crashDumpCheck.Checked,
-			ErrorReports:         errorReportsCheck.Checked,
-			ThumbnailCache:       thumbCacheCheck.Checked,
-			IconCache:            iconCacheCheck.Checked,
-			ShaderCache:          shaderCacheCheck.Checked,
-			DNSCache:             dnsCacheCheck.Checked,
-			WindowsLogs:          winLogsCheck.Checked,
-			EventLogs:            eventLogsCheck.Checked,
-			DeliveryOptimization: deliveryOptCheck.Checked,
-			RecycleBin:           recycleBinCheck.Checked,
-			WindowsUpdate:        winUpdateCheck.Checked,
-			WindowsInstaller:     winInstallerCheck.Checked,
-			FontCache:            fontCacheCheck.Checked,
-			ChromeCache:          chromeCheck.Checked,
-			FirefoxCache:         firefoxCheck.Checked,
-			EdgeCache:            edgeCheck.Checked,
-			BraveCache:           braveCheck.Checked,
-			OperaCache:           operaCheck.Checked,
-			DiscordCache:         discordCheck.Checked,
-			SpotifyCache:         spotifyCheck.Checked,
-			SteamCache:           steamCheck.Checked,
-			TeamsCache:           teamsCheck.Checked,
-			VSCodeCache:          vscodeCheck.Checked,
-			JavaCache:            javaCheck.Checked,
-			DryRun:               dryRun,
+		opts := cleaner.CleanOptions{DryRun: dryRun}
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			cfg = config.DefaultConfig()
+		}
+		opts.TrashMinAge = cfg.DefaultCleanOptions.TrashMinAge
+		opts.Timeout = cfg.DefaultCleanOptions.Timeout
+		opts.DirTimeout = cfg.DefaultCleanOptions.DirTimeout
+		config.ApplyExclusions(cfg, &opts)
+		for dir, check := range profileChecks {
+			if !check.Checked {
+				opts.ExcludeBrowserProfiles = append(opts.ExcludeBrowserProfiles, dir)
+			}
+		}
+		if p, ok := runningPolicies[runningSelect.Selected]; ok {
+			opts.RunningPolicy = p
+			opts.RunningPolicies = map[string]cleaner.RunningPolicy{}
 		}
+		config.ApplyRunningPolicy(cfg, &opts)
+		config.ApplyDeveloper(cfg, &opts)
+		config.ApplyRetention(cfg, &opts)
+		opts.ConfirmClose = confirmClose
+		if !dryRun {
+			if err := config.ApplyQuarantine(cfg, &opts); err != nil {
+				log.Printf("[SysCleaner] Quarantine unavailable: %v", err)
+			}
+			if archiveCheck.Checked {
+				if err := config.ApplyArchive(cfg, &opts); err != nil {
+					log.Printf("[SysCleaner] Log archive unavailable: %v", err)
+				}
+			}
+		}
+		for id, check := range categoryChecks {
+			if check.Checked {
+				opts.Enable(id)
+				if quarantineCheck.Checked && opts.Quarantine != nil {
+					opts.QuarantineCategories[id] = true
+				}
+			}
+		}
+		return opts
 	}
 
 	// Analyze button (preview / dry run)
 	analyzeBtn := widget.NewButton("Analyze (Preview)", nil)
 	cleanBtn := widget.NewButton("Clean Now", nil)
 
+	cancelBtn := widget.NewButton("Cancel", nil)
+	cancelBtn.Disable()
+
+	// The running analysis or clean can be cancelled; it stops within
+	// milliseconds and reports what it got through.
+	var (
+		runMu     sync.Mutex
+		cancelRun context.CancelFunc
+	)
+	startRun := func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		runMu.Lock()
+		cancelRun = cancel
+		runMu.Unlock()
+		cancelBtn.Enable()
+		return ctx
+	}
+	endRun := func() {
+		runMu.Lock()
+		if cancelRun != nil {
+			cancelRun()
+			cancelRun = nil
+		}
+		runMu.Unlock()
+		cancelBtn.Disable()
+	}
+	cancelBtn.OnTapped = func() {
+		runMu.Lock()
+		if cancelRun != nil {
+			cancelRun()
+		}
+		runMu.Unlock()
+		statusLabel.SetText("Stopping...")
+	}
+
+	// Progress events arrive one at a time from the cleaner's workers.
+	const maxLogLines = 200
+	var (
+		logLines   []string
+		lastUpdate time.Time
+	)
+	appendLog := func(line string) {
+		logLines = append(logLines, line)
+		if len(logLines) > maxLogLines {
+			logLines = logLines[len(logLines)-maxLogLines:]
+		}
+		runLog.SetText(strings.Join(logLines, "\n"))
+		runLog.CursorRow = len(logLines)
+	}
+	onEvent := func(e cleaner.ProgressEvent) {
+		switch e.Kind {
+		case cleaner.EventCategoryStarted:
+			appendLog("Cleaning " + e.Name + "...")
+		case cleaner.EventFileSkipped:
+			appendLog(fmt.Sprintf("  Skipped %s: %s", e.Path, e.Reason))
+		case cleaner.EventCategoryFinished:
+			if e.Reason != "" {
+				appendLog(fmt.Sprintf("%s: %s (%s)", e.Name, e.Status, e.Reason))
+			} else {
+				appendLog(fmt.Sprintf("%s: %s", e.Name, e.Status))
+			}
+		}
+		if f := e.Fraction(); f >= 0 && (time.Since(lastUpdate) > 50*time.Millisecond || f == 1) {
+			lastUpdate = time.Now()
+			if !fileBar.Visible() {
+				progressBar.Stop()
+				progressBar.Hide()
+				fileBar.Show()
+			}
+			fileBar.SetValue(f)
+			statusLabel.SetText(fmt.Sprintf("Cleaning... %d of %d files, %s of %s",
+				e.Files, e.TotalFiles, cleaner.FormatBytes(e.Bytes), cleaner.FormatBytes(e.TotalBytes)))
+		}
+	}
+
+	// recordHistory adds a finished run to the history shown in the History tab.
+	recordHistory := func(result cleaner.CleanResult, dryRun bool) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			cfg = config.DefaultConfig()
+		}
+		if err := config.RecordHistory(cfg, result, dryRun); err != nil {
+			log.Printf("[SysCleaner] Could not record the run in the history: %v", err)
+		}
+	}
+
+	// retryPending retries the files earlier cleans found in use, and
+	// queueLocked queues the files this clean found in use for the next.
+	retryPending := func(ctx context.Context) cleaner.CleanResult {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			cfg = config.DefaultConfig()
+		}
+		result, err := config.RetryPending(ctx, cfg)
+		if err != nil {
+			log.Printf("[SysCleaner] Could not retry the files left in use: %v", err)
+		}
+		return result
+	}
+	queueLocked := func(result cleaner.CleanResult) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			cfg = config.DefaultConfig()
+		}
+		if err := config.QueueLocked(cfg, result); err != nil {
+			log.Printf("[SysCleaner] Could not queue the files in use: %v", err)
+		}
+	}
+
+	// unfinished describes the categories a cancelled run did not complete
+	// and those whose application was running.
+	unfinished := func(result cleaner.CleanResult) string {
+		stopped, running := "", ""
+		for _, st := range result.Categories {
+			switch {
+			case st.Status == cleaner.StatusSkipped || st.Reason != "":
+				running += fmt.Sprintf("\n  %s: %s", st.Name, st.Reason)
+			case st.Status != cleaner.StatusCompleted:
+				stopped += fmt.Sprintf("\n  %s: %s", st.Name, st.Status)
+			}
+		}
+		text := ""
+		if stopped != "" {
+			text += "\n\nStopped before finishing:" + stopped
+		}
+		if running != "" {
+			text += "\n\nApplications running:" + running
+		}
+		return text
+	}
+
 	allBtns := []*widget.Button{analyzeBtn, cleanBtn}
 	disableAll := func() {
 		for _, b := range allBtns {
@@ -150,20 +311,37 @@ func NewCleanPanel() fyne.CanvasObject {
 		progressBar.Show()
 		progressBar.Start()
 		statusLabel.SetText("Analyzing system for cleanable files...")
+		ctx := startRun()
 
 		go func() {
 			defer enableAll()
+			defer endRun()
 			opts := buildOpts(true)
-			result := cleaner.PerformClean(opts)
+			result := cleaner.PerformCleanContext(ctx, opts)
+			recordHistory(result, true)
 			progressBar.Stop()
 			progressBar.Hide()
 
-			statusLabel.SetText("Analysis complete.")
-			resultText.SetText(fmt.Sprintf(
+			if ctx.Err() != nil {
+				statusLabel.SetText("Analysis stopped.")
+			} else {
+				statusLabel.SetText("Analysis complete.")
+			}
+			text := fmt.Sprintf(
 				"Files found: %d\nSpace reclaimable: %s\nDuration: %s\n\nRun 'Clean Now' to remove these files.",
 				result.FilesDeleted,
 				cleaner.FormatBytes(result.SpaceFreed),
-				result.Duration))
+				result.Duration)
+			if len(result.DryRunItems) > 0 {
+				text += "\n\nWould purge:"
+				for _, item := range result.DryRunItems {
+					text += "\n  " + item
+				}
+			}
+			text += profileSummary(result.BrowserProfiles)
+			text += gameSummary(result.Games)
+			text += unfinished(result)
+			resultText.SetText(text)
 		}()
 	}
 
@@ -172,94 +350,169 @@ func NewCleanPanel() fyne.CanvasObject {
 		disableAll()
 		progressBar.Show()
 		progressBar.Start()
-		statusLabel.SetText("Cleaning system...")
+		statusLabel.SetText("Counting files to clean...")
+		ctx := startRun()
+		logLines = nil
+		runLog.SetText("")
+		runLog.Show()
+		fileBar.SetValue(0)
 
 		go func() {
 			defer enableAll()
+			defer endRun()
+			retried := retryPending(ctx)
 			opts := buildOpts(false)
-			result := cleaner.PerformClean(opts)
+			opts.Events = onEvent
+			result := cleaner.PerformCleanContext(ctx, opts)
+			recordHistory(result, false)
+			queueLocked(result)
 			progressBar.Stop()
 			progressBar.Hide()
+			fileBar.Hide()
 
-			statusLabel.SetText("Cleaning complete!")
-			text := fmt.Sprintf("Files removed: %d\nSpace freed: %s\nDuration: %s",
+			if ctx.Err() != nil {
+				statusLabel.SetText("Cleaning stopped.")
+			} else {
+				statusLabel.SetText("Cleaning complete!")
+			}
+			text := fmt.Sprintf("Files removed: %d\nSpace freed: %s (%s on disk)\nDuration: %s",
 				result.FilesDeleted,
 				cleaner.FormatBytes(result.SpaceFreed),
+				cleaner.FormatBytes(result.SpaceReclaimed),
 				result.Duration)
+			if retried.FilesDeleted > 0 {
+				text += fmt.Sprintf("\nLeft in use earlier, now removed: %d files (%s)",
+					retried.FilesDeleted, cleaner.FormatBytes(retried.SpaceFreed))
+			}
+			if result.FilesQuarantined > 0 {
+				text += fmt.Sprintf("\nQuarantined: %d files (%s)\nRestore with: syscleaner quarantine restore %s",
+					result.FilesQuarantined, cleaner.FormatBytes(result.SpaceQuarantined), result.QuarantineRun)
+			}
+			if result.DirsRemoved > 0 {
+				text += fmt.Sprintf("\nEmpty folders removed: %d", result.DirsRemoved)
+			}
+			if result.RecordsDeleted > 0 {
+				text += fmt.Sprintf("\nBrowser records removed: %d", result.RecordsDeleted)
+			}
+			if result.FilesArchived > 0 {
+				text += fmt.Sprintf("\nArchived: %d files (%s) into %s, net reclaimed %s\nArchives: %s",
+					result.FilesArchived, cleaner.FormatBytes(result.SpaceArchived), cleaner.FormatBytes(result.ArchiveSize),
+					cleaner.FormatBytes(result.NetReclaimed()), strings.Join(result.Archives, ", "))
+			}
+			text += profileSummary(result.BrowserProfiles)
+			text += gameSummary(result.Games)
 			if result.LockedFiles > 0 || result.PermissionFiles > 0 || len(result.Errors) > 0 {
 				text += "\n"
 				if result.LockedFiles > 0 {
 					text += fmt.Sprintf("\nSkipped (in use): %d", result.LockedFiles)
 				}
+				if len(result.Locked) > 0 {
+					text += fmt.Sprintf("\nQueued for retry: %d files in use", len(result.Locked))
+				}
 				if result.PermissionFiles > 0 {
 					text += fmt.Sprintf("\nPermission errors: %d", result.PermissionFiles)
 				}
-				if len(result.Errors) > 0 {
-					text += fmt.Sprintf("\nOther errors: %d", len(result.Errors))
+				protected := result.ErrorsOfType(cleaner.ErrorProtected)
+				excluded := result.ErrorsOfType(cleaner.ErrorExcluded)
+				if len(excluded) > 0 {
+					text += fmt.Sprintf("\nExcluded: %d", len(excluded))
+				}
+				if other := len(result.Errors) - len(protected) - len(excluded); other > 0 {
+					text += fmt.Sprintf("\nOther errors: %d", other)
+				}
+				for _, ce := range protected {
+					text += fmt.Sprintf("\nRefused: %v", ce)
 				}
 			}
+			if n := len(result.SkippedLinks) + len(result.SkippedMounts); n > 0 {
+				text += fmt.Sprintf("\nLinks and mounts not followed: %d", n)
+			}
+			text += unfinished(result)
 			resultText.SetText(text)
 		}()
 	}
 	cleanBtn.Importance = widget.HighImportance
 
-	buttonRow := container.NewGridWithColumns(2, analyzeBtn, cleanBtn)
-
-	// System section with select all/deselect all
-	sysSelectAll := widget.NewButton("Select All", makeSelectAll(systemChecks, true))
-	sysDeselectAll := widget.NewButton("Deselect All", makeSelectAll(systemChecks, false))
-	systemHeader := container.NewHBox(
-		widget.NewLabelWithStyle("System", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-		sysSelectAll, sysDeselectAll,
-	)
-	systemGrid := container.NewGridWithColumns(4,
-		winTempCheck, userTempCheck, prefetchCheck, crashDumpCheck,
-		errorReportsCheck, thumbCacheCheck, iconCacheCheck, shaderCacheCheck,
-		dnsCacheCheck, winLogsCheck, eventLogsCheck, deliveryOptCheck,
-		recycleBinCheck, winUpdateCheck, winInstallerCheck, fontCacheCheck,
-	)
-
-	// Browser section
-	browserSelectAll := widget.NewButton("Select All", makeSelectAll(browserChecks, true))
-	browserDeselectAll := widget.NewButton("Deselect All", makeSelectAll(browserChecks, false))
-	browserHeader := container.NewHBox(
-		widget.NewLabelWithStyle("Browsers", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-		browserSelectAll, browserDeselectAll,
-	)
-	browserGrid := container.NewGridWithColumns(5,
-		chromeCheck, firefoxCheck, edgeCheck, braveCheck, operaCheck,
-	)
-
-	// Apps section
-	appSelectAll := widget.NewButton("Select All", makeSelectAll(appChecks, true))
-	appDeselectAll := widget.NewButton("Deselect All", makeSelectAll(appChecks, false))
-	appHeader := container.NewHBox(
-		widget.NewLabelWithStyle("Applications", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-		appSelectAll, appDeselectAll,
-	)
-	appGrid := container.NewGridWithColumns(3,
-		discordCheck, spotifyCheck, steamCheck,
-		teamsCheck, vscodeCheck, javaCheck,
-	)
+	buttonRow := container.NewGridWithColumns(3, analyzeBtn, cleanBtn, cancelBtn)
 
 	content := container.NewVBox(
 		widget.NewLabelWithStyle("System Cleaning", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		widget.NewSeparator(),
-		systemHeader,
-		systemGrid,
-		widget.NewSeparator(),
-		browserHeader,
-		browserGrid,
-		widget.NewSeparator(),
-		appHeader,
-		appGrid,
-		widget.NewSeparator(),
-		buttonRow,
-		widget.NewSeparator(),
-		statusLabel,
-		progressBar,
-		resultText,
 	)
 
+	// Each group gets a header with select all/deselect all and a grid of checks
+	for _, g := range cleaner.Groups {
+		checks := groupChecks[g]
+		if len(checks) == 0 {
+			continue
+		}
+		header := container.NewHBox(
+			widget.NewLabelWithStyle(g.DisplayName(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewButton("Select All", makeSelectAll(checks, true)),
+			widget.NewButton("Deselect All", makeSelectAll(checks, false)),
+		)
+		columns := len(checks)
+		if columns > 4 {
+			columns = 4
+		}
+		grid := container.NewGridWithColumns(columns)
+		for _, check := range checks {
+			grid.Add(check)
+		}
+		content.Add(header)
+		content.Add(grid)
+		content.Add(widget.NewSeparator())
+	}
+
+	if len(profileOrder) > 0 {
+		content.Add(widget.NewLabelWithStyle("Browser Profiles", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		grid := container.NewGridWithColumns(3)
+		for _, check := range profileOrder {
+			grid.Add(check)
+		}
+		content.Add(grid)
+		content.Add(widget.NewSeparator())
+	}
+
+	content.Add(quarantineCheck)
+	content.Add(archiveCheck)
+	content.Add(container.NewHBox(widget.NewLabel("If an application is running:"), runningSelect))
+	content.Add(container.NewHBox(widget.NewButton("Retention Policies...", func() { showRetentionDialog(w) })))
+	content.Add(buttonRow)
+	content.Add(widget.NewSeparator())
+	content.Add(statusLabel)
+	content.Add(progressBar)
+	content.Add(fileBar)
+	content.Add(runLog)
+	content.Add(resultText)
+
 	return container.NewScroll(container.NewPadded(content))
 }
+
+// profileSummary lists the files and records removed per browser profile.
+func profileSummary(results []cleaner.BrowserProfileResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+	text := "\n\nBrowser profiles:"
+	for _, t := range cleaner.ProfileTotals(results) {
+		text += fmt.Sprintf("\n  %s: %d files, %s", t.Browser+": "+t.Profile, t.FilesDeleted, cleaner.FormatBytes(t.BytesFreed))
+		if t.RecordsDeleted > 0 {
+			text += fmt.Sprintf(", %d records", t.RecordsDeleted)
+		}
+	}
+	return text
+}
+
+// gameSummary lists the files removed per game by the game launcher
+// categories.
+func gameSummary(games []cleaner.GameResult) string {
+	if len(games) == 0 {
+		return ""
+	}
+	text := "\n\nGames:"
+	for _, g := range games {
+		text += fmt.Sprintf("\n  %s: %d files, %s", g.Label(), g.FilesDeleted, cleaner.FormatBytes(g.BytesFreed))
+	}
+	return text
+}
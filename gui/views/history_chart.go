@@ -0,0 +1,136 @@
+//go:build gui
+
+package views
+
+import (
+	"image/color"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syscleaner/pkg/cleaner"
+)
+
+// WeeklyChart is a bar chart of the space reclaimed per week.
+type WeeklyChart struct {
+	widget.BaseWidget
+	mu    sync.Mutex
+	weeks []cleaner.WeekTotal
+}
+
+// NewWeeklyChart creates an empty weekly chart.
+func NewWeeklyChart() *WeeklyChart {
+	c := &WeeklyChart{}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetWeeks replaces the charted weeks, oldest first.
+func (c *WeeklyChart) SetWeeks(weeks []cleaner.WeekTotal) {
+	c.mu.Lock()
+	c.weeks = weeks
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+// CreateRenderer creates the widget renderer
+func (c *WeeklyChart) CreateRenderer() fyne.WidgetRenderer {
+	r := &weeklyChartRenderer{chart: c, axis: canvas.NewRectangle(theme.Color(theme.ColorNameSeparator))}
+	r.Refresh()
+	return r
+}
+
+type weeklyChartRenderer struct {
+	chart  *WeeklyChart
+	weeks  []cleaner.WeekTotal
+	axis   *canvas.Rectangle
+	bars   []*canvas.Rectangle
+	values []*canvas.Text
+	labels []*canvas.Text
+}
+
+// Space kept below the bars for the week labels and above them for values.
+const (
+	chartLabelHeight = 18
+	chartValueHeight = 16
+)
+
+func (r *weeklyChartRenderer) Layout(size fyne.Size) {
+	n := len(r.bars)
+	if n == 0 {
+		return
+	}
+	plot := size.Height - chartLabelHeight - chartValueHeight
+	r.axis.Move(fyne.NewPos(0, chartValueHeight+plot))
+	r.axis.Resize(fyne.NewSize(size.Width, 1))
+
+	var most int64
+	for _, w := range r.weeks {
+		if w.BytesReclaimed > most {
+			most = w.BytesReclaimed
+		}
+	}
+	slot := size.Width / float32(n)
+	barWidth := slot * 0.7
+	for i, w := range r.weeks {
+		h := float32(0)
+		if most > 0 {
+			h = plot * float32(w.BytesReclaimed) / float32(most)
+		}
+		x := slot*float32(i) + (slot-barWidth)/2
+		r.bars[i].Move(fyne.NewPos(x, chartValueHeight+plot-h))
+		r.bars[i].Resize(fyne.NewSize(barWidth, h))
+
+		value := r.values[i].MinSize()
+		r.values[i].Move(fyne.NewPos(slot*float32(i)+(slot-value.Width)/2, plot-h))
+		r.values[i].Resize(value)
+
+		label := r.labels[i].MinSize()
+		r.labels[i].Move(fyne.NewPos(slot*float32(i)+(slot-label.Width)/2, chartValueHeight+plot+2))
+		r.labels[i].Resize(label)
+	}
+}
+
+func (r *weeklyChartRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(float32(len(r.bars))*48, 220)
+}
+
+func (r *weeklyChartRenderer) Refresh() {
+	r.chart.mu.Lock()
+	r.weeks = append([]cleaner.WeekTotal(nil), r.chart.weeks...)
+	r.chart.mu.Unlock()
+
+	barColor := theme.Color(theme.ColorNamePrimary)
+	textColor := theme.Color(theme.ColorNameForeground)
+	r.bars, r.values, r.labels = nil, nil, nil
+	for _, w := range r.weeks {
+		r.bars = append(r.bars, canvas.NewRectangle(barColor))
+
+		value := ""
+		if w.Runs > 0 {
+			value = cleaner.FormatBytes(w.BytesReclaimed)
+		}
+		v := canvas.NewText(value, textColor)
+		v.TextSize = 10
+		r.values = append(r.values, v)
+
+		l := canvas.NewText(w.Start.Format("Jan 2"), color.Gray{Y: 160})
+		l.TextSize = 11
+		r.labels = append(r.labels, l)
+	}
+	r.Layout(r.chart.Size())
+	canvas.Refresh(r.chart)
+}
+
+func (r *weeklyChartRenderer) Objects() []fyne.CanvasObject {
+	objects := []fyne.CanvasObject{r.axis}
+	for i := range r.bars {
+		objects = append(objects, r.bars[i], r.values[i], r.labels[i])
+	}
+	return objects
+}
+
+func (r *weeklyChartRenderer) Destroy() {}
@@ -0,0 +1,110 @@
+//go:build gui
+
+package views
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
+)
+
+// historyWeeks is the number of weeks shown in the chart.
+const historyWeeks = 12
+
+// NewHistoryPanel creates the cleaning history view: space reclaimed per week
+// and what each category removed over all recorded cleans.
+func NewHistoryPanel() fyne.CanvasObject {
+	summary := widget.NewLabel("")
+	summary.Wrapping = fyne.TextWrapWord
+	chart := NewWeeklyChart()
+
+	var totals []cleaner.CategoryTotal
+	headers := []string{"Category", "Runs", "Files", "Freed", "Last run"}
+	table := widget.NewTable(
+		func() (int, int) { return len(totals) + 1, len(headers) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			label := cell.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(headers[id.Col])
+				return
+			}
+			label.TextStyle = fyne.TextStyle{}
+			t := totals[id.Row-1]
+			switch id.Col {
+			case 0:
+				label.SetText(t.Name)
+			case 1:
+				label.SetText(strconv.Itoa(t.Runs))
+			case 2:
+				label.SetText(strconv.FormatInt(t.FilesDeleted, 10))
+			case 3:
+				label.SetText(cleaner.FormatBytes(t.BytesFreed))
+			case 4:
+				label.SetText(t.LastRun.Local().Format("2006-01-02"))
+			}
+		},
+	)
+	table.SetColumnWidth(0, 260)
+	for col := 1; col < len(headers); col++ {
+		table.SetColumnWidth(col, 110)
+	}
+
+	refresh := func() {
+		records, err := config.LoadHistory()
+		if err != nil {
+			summary.SetText(fmt.Sprintf("Could not read the history: %v", err))
+			return
+		}
+		var (
+			runs             int
+			since            time.Time
+			freed, reclaimed int64
+		)
+		for _, rec := range records {
+			if rec.DryRun {
+				continue
+			}
+			if runs == 0 {
+				since = rec.Time
+			}
+			runs++
+			freed += rec.BytesFreed
+			reclaimed += rec.BytesReclaimed
+		}
+		if runs == 0 {
+			summary.SetText("No cleans recorded yet. Every clean is added here once it finishes.")
+		} else {
+			summary.SetText(fmt.Sprintf("%d cleans since %s freed %s (%s on disk).",
+				runs, since.Local().Format("2006-01-02"), cleaner.FormatBytes(freed), cleaner.FormatBytes(reclaimed)))
+		}
+		totals = cleaner.CategoryTotals(records)
+		chart.SetWeeks(cleaner.WeeklyTotals(records, historyWeeks, time.Now()))
+		table.Refresh()
+	}
+	refresh()
+
+	refreshBtn := widget.NewButton("Refresh", refresh)
+
+	top := container.NewVBox(
+		container.NewHBox(
+			widget.NewLabelWithStyle("Cleaning History", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			refreshBtn,
+		),
+		widget.NewSeparator(),
+		summary,
+		widget.NewLabelWithStyle("Space reclaimed per week", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		chart,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Per category", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+	)
+	return container.NewPadded(container.NewBorder(top, nil, nil, nil, table))
+}
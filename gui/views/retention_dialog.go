@@ -0,0 +1,125 @@
+//go:build gui
+
+package views
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syscleaner/pkg/cleaner"
+	"syscleaner/pkg/config"
+)
+
+// showRetentionDialog edits the retention policy of one category at a time
+// and saves it in the config file. Empty fields are unset; a category whose
+// fields are all empty loses its policy and keeps its built-in age.
+func showRetentionDialog(w fyne.Window) {
+	policies, err := config.LoadRetention("")
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	var names []string
+	ids := map[string]string{}
+	for _, c := range cleaner.Categories() {
+		if c.Supported() {
+			names = append(names, c.Name)
+			ids[c.Name] = c.ID
+		}
+	}
+
+	minAge := widget.NewEntry()
+	minAge.SetPlaceHolder("e.g. 14d")
+	ageBy := widget.NewRadioGroup([]string{"Modified", "Last read"}, nil)
+	ageBy.Horizontal = true
+	minSize := widget.NewEntry()
+	minSize.SetPlaceHolder("e.g. 1M")
+	keepNewest := widget.NewEntry()
+	keepNewest.SetPlaceHolder("files per folder")
+	maxSize := widget.NewEntry()
+	maxSize.SetPlaceHolder("e.g. 500M")
+	builtIn := widget.NewLabel("")
+
+	// Selecting a category shows its saved policy.
+	category := widget.NewSelect(names, func(name string) {
+		c, _ := cleaner.LookupCategory(ids[name])
+		p := policies[c.ID]
+		minAge.SetText("")
+		if p.MinAge > 0 {
+			minAge.SetText(cleaner.FormatAge(p.MinAge))
+		}
+		if p.AgeBy == cleaner.AgeAccessed {
+			ageBy.SetSelected("Last read")
+		} else {
+			ageBy.SetSelected("Modified")
+		}
+		minSize.SetText(sizeText(p.MinSize))
+		maxSize.SetText(sizeText(p.MaxSize))
+		keepNewest.SetText("")
+		if p.KeepNewest > 0 {
+			keepNewest.SetText(strconv.Itoa(p.KeepNewest))
+		}
+		builtIn.SetText("")
+		if c.MaxAge > 0 {
+			builtIn.SetText("Without a minimum age, files younger than " + cleaner.FormatAge(c.MaxAge) + " are kept.")
+		}
+	})
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Category", category),
+		widget.NewFormItem("Minimum age", minAge),
+		widget.NewFormItem("Age by", ageBy),
+		widget.NewFormItem("Minimum size", minSize),
+		widget.NewFormItem("Keep newest", keepNewest),
+		widget.NewFormItem("Size cap", maxSize),
+		widget.NewFormItem("", builtIn),
+	}
+	dialog.ShowForm("Retention Policies", "Save", "Cancel", items, func(ok bool) {
+		id := ids[category.Selected]
+		if !ok || id == "" {
+			return
+		}
+		var (
+			p   cleaner.RetentionPolicy
+			err error
+		)
+		if p.MinAge, err = cleaner.ParseAge(minAge.Text); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if ageBy.Selected == "Last read" {
+			p.AgeBy = cleaner.AgeAccessed
+		}
+		if p.MinSize, err = cleaner.ParseSize(minSize.Text); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if p.MaxSize, err = cleaner.ParseSize(maxSize.Text); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if s := strings.TrimSpace(keepNewest.Text); s != "" {
+			if p.KeepNewest, err = strconv.Atoi(s); err != nil || p.KeepNewest < 0 {
+				dialog.ShowError(errors.New("keep newest must be a number of files"), w)
+				return
+			}
+		}
+		if err := config.SetRetention("", id, p); err != nil {
+			dialog.ShowError(err, w)
+		}
+	}, w)
+}
+
+// sizeText formats a policy size for an entry, empty when unset.
+func sizeText(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return cleaner.FormatSize(n)
+}
@@ -0,0 +1,14 @@
+//go:build !windows && !linux
+
+package cleaner
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime falls back to the modification time where the access time is
+// not read from the stat information.
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,18 @@
+package cleaner
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns when the file was last read. NTFS updates it lazily
+// (within an hour) and not at all when last-access updates are disabled,
+// in which case it stays at the creation time.
+func accessTime(info os.FileInfo) time.Time {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(0, data.LastAccessTime.Nanoseconds())
+}
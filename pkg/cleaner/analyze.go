@@ -0,0 +1,381 @@
+package cleaner
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnalyzeOptions configures a disk usage analysis.
+type AnalyzeOptions struct {
+	Top              int           // Entries in each list (default DefaultAnalyzeTop)
+	OldAfter         time.Duration // Files not accessed for this long are old (0 = no old-file list)
+	CrossFilesystems bool          // Walk into mounted filesystems
+	Timeout          time.Duration // Overall deadline (default DefaultTimeout)
+}
+
+// DefaultAnalyzeTop is the default length of the lists in an Analysis.
+const DefaultAnalyzeTop = 20
+
+// Analysis reports where the space below a directory went. Sizes are
+// apparent file sizes; a directory's size includes everything below it.
+type Analysis struct {
+	Root       string    `json:"root"`
+	Status     RunStatus `json:"status"` // partial when cancelled or stopped by the deadline
+	Files      int64     `json:"files"`
+	Dirs       int64     `json:"dirs"`
+	Bytes      int64     `json:"bytes"`
+	DurationMS int64     `json:"duration_ms"`
+
+	LargestDirs  []SizeEntry      `json:"largest_dirs"`
+	LargestFiles []SizeEntry      `json:"largest_files"`
+	Extensions   []ExtensionTotal `json:"extensions"` // Most bytes first
+
+	// OldFiles lists the largest files not accessed for OldAfter; OldCount
+	// and OldBytes count all of them.
+	OldAfterDays int         `json:"old_after_days"`
+	OldFiles     []SizeEntry `json:"old_files"`
+	OldCount     int64       `json:"old_count"`
+	OldBytes     int64       `json:"old_bytes"`
+
+	SkippedLinks  []string      `json:"skipped_links"`
+	SkippedMounts []string      `json:"skipped_mounts"`
+	Errors        []ErrorReport `json:"errors"`
+}
+
+// SizeEntry is a file or directory in an Analysis.
+type SizeEntry struct {
+	Path     string     `json:"path"`
+	Size     int64      `json:"size"`
+	Dir      bool       `json:"dir,omitempty"`
+	Files    int64      `json:"files,omitempty"`    // Directories only
+	Accessed *time.Time `json:"accessed,omitempty"` // Files only
+}
+
+// ExtensionTotal sums the files with one extension. Extension is lower case
+// with its dot, or "" for files without one.
+type ExtensionTotal struct {
+	Extension string `json:"extension"`
+	Files     int64  `json:"files"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// Analyze walks root and reports its largest directories and files, the
+// space per file extension and files that have not been accessed for
+// opts.OldAfter. It only reads: protected paths can be analyzed. The
+// subdirectories of root are walked concurrently with the cleaner's walker,
+// so links are not followed and mounts are not entered, and the walk stops
+// at the deadline or when ctx is cancelled with what it has seen so far.
+func Analyze(ctx context.Context, root string, opts AnalyzeOptions) (*Analysis, error) {
+	start := time.Now()
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	if opts.Top <= 0 {
+		opts.Top = DefaultAnalyzeTop
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Loose files in root are counted here; every subdirectory is one job
+	// for the worker pool.
+	total := newAnalysisPart(root, opts, start)
+	var subdirs []string
+	for _, e := range entries {
+		path := filepath.Join(root, e.Name())
+		if e.IsDir() {
+			subdirs = append(subdirs, path)
+			continue
+		}
+		if e.Type()&(os.ModeSymlink|os.ModeIrregular) != 0 {
+			// Left to the walker so links are handled in one place.
+			subdirs = append(subdirs, path)
+			continue
+		}
+		if fi, err := e.Info(); err == nil {
+			total.addFile(path, fi)
+		}
+	}
+
+	jobs := make(chan string, len(subdirs))
+	for _, d := range subdirs {
+		jobs <- d
+	}
+	close(jobs)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for i := 0; i < maxCleanWorkers && i < len(subdirs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			part := newAnalysisPart(root, opts, start)
+			w := walker{ctx: ctx, crossFS: opts.CrossFilesystems, result: &part.result}
+			for sub := range jobs {
+				if ctx.Err() != nil {
+					part.result.incomplete = true
+					break
+				}
+				part.walk(w, sub)
+			}
+			mu.Lock()
+			total.merge(part)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("[SysCleaner] Analysis of %s stopped after the %s deadline", root, timeout)
+		total.result.Errors = append(total.result.Errors, fmt.Errorf("analysis stopped after the %s deadline", timeout))
+	}
+	a := total.analysis()
+	a.DurationMS = time.Since(start).Milliseconds()
+	return a, nil
+}
+
+// analysisPart accumulates what one worker has seen.
+type analysisPart struct {
+	root     string
+	oldAfter time.Duration
+	now      time.Time
+
+	files, dirs, bytes int64
+	dirSizes           map[string]int64
+	dirFiles           map[string]int64
+	largest, old       *topEntries
+	oldCount, oldBytes int64
+	extensions         map[string]*ExtensionTotal
+	result             CleanResult // Errors, skipped links and mounts, incompleteness
+}
+
+func newAnalysisPart(root string, opts AnalyzeOptions, now time.Time) *analysisPart {
+	return &analysisPart{
+		root:       root,
+		oldAfter:   opts.OldAfter,
+		now:        now,
+		dirSizes:   map[string]int64{},
+		dirFiles:   map[string]int64{},
+		largest:    newTopEntries(opts.Top),
+		old:        newTopEntries(opts.Top),
+		extensions: map[string]*ExtensionTotal{},
+	}
+}
+
+// walk adds the tree at path, a subdirectory or link directly below root.
+func (p *analysisPart) walk(w walker, path string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		p.result.Errors = append(p.result.Errors, ClassifyError(path, err))
+		return
+	}
+	if !info.IsDir() {
+		// A link in root: a file link is counted as the link itself.
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(path); err == nil && target.IsDir() {
+				p.result.SkippedLinks = append(p.result.SkippedLinks, path)
+				return
+			}
+		} else if info.Mode()&os.ModeIrregular != 0 {
+			p.result.SkippedLinks = append(p.result.SkippedLinks, path)
+			return
+		}
+		p.addFile(path, info)
+		return
+	}
+
+	p.dirs++
+	skipDir := func(string) bool {
+		p.dirs++
+		return false
+	}
+	w.walk(path, skipDir, p.addFile)
+}
+
+// addFile counts a file towards root, every directory above it, its
+// extension and, if it qualifies, the old files.
+func (p *analysisPart) addFile(path string, info os.FileInfo) {
+	size := info.Size()
+	p.files++
+	p.bytes += size
+	for dir := filepath.Dir(path); len(dir) > len(p.root); dir = filepath.Dir(dir) {
+		p.dirSizes[dir] += size
+		p.dirFiles[dir]++
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	t := p.extensions[ext]
+	if t == nil {
+		t = &ExtensionTotal{Extension: ext}
+		p.extensions[ext] = t
+	}
+	t.Files++
+	t.Bytes += size
+
+	accessed := accessTime(info)
+	entry := SizeEntry{Path: path, Size: size, Accessed: &accessed}
+	p.largest.offer(entry)
+	if p.oldAfter > 0 && p.now.Sub(accessed) >= p.oldAfter {
+		p.oldCount++
+		p.oldBytes += size
+		p.old.offer(entry)
+	}
+}
+
+func (p *analysisPart) merge(o *analysisPart) {
+	p.files += o.files
+	p.dirs += o.dirs
+	p.bytes += o.bytes
+	for dir, size := range o.dirSizes {
+		p.dirSizes[dir] += size
+	}
+	for dir, n := range o.dirFiles {
+		p.dirFiles[dir] += n
+	}
+	for _, e := range o.largest.h {
+		p.largest.offer(e)
+	}
+	for _, e := range o.old.h {
+		p.old.offer(e)
+	}
+	p.oldCount += o.oldCount
+	p.oldBytes += o.oldBytes
+	for ext, t := range o.extensions {
+		if mine := p.extensions[ext]; mine != nil {
+			mine.Files += t.Files
+			mine.Bytes += t.Bytes
+		} else {
+			p.extensions[ext] = t
+		}
+	}
+	p.result.merge(o.result)
+}
+
+func (p *analysisPart) analysis() *Analysis {
+	a := &Analysis{
+		Root:          p.root,
+		Status:        StatusCompleted,
+		Files:         p.files,
+		Dirs:          p.dirs,
+		Bytes:         p.bytes,
+		LargestFiles:  p.largest.sorted(),
+		OldAfterDays:  int(p.oldAfter / (24 * time.Hour)),
+		OldFiles:      p.old.sorted(),
+		OldCount:      p.oldCount,
+		OldBytes:      p.oldBytes,
+		Extensions:    []ExtensionTotal{},
+		SkippedLinks:  append([]string{}, p.result.SkippedLinks...),
+		SkippedMounts: append([]string{}, p.result.SkippedMounts...),
+		Errors:        ErrorReports(p.result.Errors),
+	}
+	if p.result.incomplete {
+		a.Status = StatusPartial
+	}
+
+	dirs := newTopEntries(p.largest.n)
+	for dir, size := range p.dirSizes {
+		dirs.offer(SizeEntry{Path: dir, Size: size, Dir: true, Files: p.dirFiles[dir]})
+	}
+	a.LargestDirs = dirs.sorted()
+
+	for _, t := range p.extensions {
+		a.Extensions = append(a.Extensions, *t)
+	}
+	sort.Slice(a.Extensions, func(i, j int) bool {
+		if a.Extensions[i].Bytes != a.Extensions[j].Bytes {
+			return a.Extensions[i].Bytes > a.Extensions[j].Bytes
+		}
+		return a.Extensions[i].Extension < a.Extensions[j].Extension
+	})
+	if len(a.Extensions) > p.largest.n {
+		a.Extensions = a.Extensions[:p.largest.n]
+	}
+	return a
+}
+
+// topEntries keeps the n largest entries offered to it in a min-heap, so
+// memory stays bounded however many files are walked.
+type topEntries struct {
+	n int
+	h entryHeap
+}
+
+func newTopEntries(n int) *topEntries {
+	return &topEntries{n: n}
+}
+
+func (t *topEntries) offer(e SizeEntry) {
+	switch {
+	case len(t.h) < t.n:
+		heap.Push(&t.h, e)
+	case e.Size > t.h[0].Size:
+		t.h[0] = e
+		heap.Fix(&t.h, 0)
+	}
+}
+
+// sorted returns the entries, largest first. It never returns nil.
+func (t *topEntries) sorted() []SizeEntry {
+	out := append([]SizeEntry{}, t.h...)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Size != out[j].Size {
+			return out[i].Size > out[j].Size
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+type entryHeap []SizeEntry
+
+func (h entryHeap) Len() int            { return len(h) }
+func (h entryHeap) Less(i, j int) bool  { return h[i].Size < h[j].Size }
+func (h entryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *entryHeap) Push(x interface{}) { *h = append(*h, x.(SizeEntry)) }
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// SelectionCategoryID is the category under which RemoveSelection records
+// files, in the quarantine store, the history and progress events.
+const SelectionCategoryID = "selection"
+
+// RemoveSelection deletes or quarantines paths picked by hand, such as
+// entries of an Analysis, with RemovePaths. Files go to the quarantine when
+// opts.QuarantineCategories enables SelectionCategoryID.
+func RemoveSelection(ctx context.Context, paths []string, opts CleanOptions) CleanResult {
+	c := Category{ID: SelectionCategoryID, Name: "Selected items"}
+	return RemovePaths(ctx, c, paths, "selected by hand", opts)
+}
@@ -0,0 +1,187 @@
+package cleaner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---------- Analyze tests ----------
+
+func TestAnalyze_SizesAndTopLists(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "loose.txt"), "1")
+	writeFile(t, filepath.Join(root, "big", "a.iso"), strings.Repeat("x", 500))
+	writeFile(t, filepath.Join(root, "big", "deep", "b.ISO"), strings.Repeat("x", 300))
+	writeFile(t, filepath.Join(root, "small", "c.log"), strings.Repeat("x", 20))
+	writeFile(t, filepath.Join(root, "small", "Makefile"), strings.Repeat("x", 10))
+
+	a, err := Analyze(context.Background(), root, AnalyzeOptions{Top: 2})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if a.Status != StatusCompleted {
+		t.Errorf("expected a completed analysis, got %s", a.Status)
+	}
+	if a.Files != 5 || a.Bytes != 831 || a.Dirs != 3 {
+		t.Errorf("expected 5 files / 831 bytes / 3 dirs, got %d / %d / %d", a.Files, a.Bytes, a.Dirs)
+	}
+
+	if len(a.LargestDirs) != 2 {
+		t.Fatalf("expected the top 2 directories, got %+v", a.LargestDirs)
+	}
+	if d := a.LargestDirs[0]; d.Path != filepath.Join(root, "big") || d.Size != 800 || d.Files != 2 || !d.Dir {
+		t.Errorf("expected big/ with 800 bytes in 2 files first, got %+v", d)
+	}
+	if d := a.LargestDirs[1]; d.Path != filepath.Join(root, "big", "deep") || d.Size != 300 {
+		t.Errorf("expected big/deep with 300 bytes second, got %+v", d)
+	}
+
+	if len(a.LargestFiles) != 2 || a.LargestFiles[0].Size != 500 || a.LargestFiles[1].Size != 300 {
+		t.Errorf("expected the 500 and 300 byte files, got %+v", a.LargestFiles)
+	}
+
+	if len(a.Extensions) != 2 || a.Extensions[0].Extension != ".iso" || a.Extensions[0].Files != 2 || a.Extensions[0].Bytes != 800 {
+		t.Errorf("expected .iso (both cases) to lead the top 2 extensions, got %+v", a.Extensions)
+	}
+	if a.Extensions[1].Extension != ".log" {
+		t.Errorf("expected .log second, got %+v", a.Extensions[1])
+	}
+}
+
+func TestAnalyze_OldFiles(t *testing.T) {
+	root := t.TempDir()
+	old := filepath.Join(root, "sub", "old.bin")
+	writeFile(t, old, strings.Repeat("x", 40))
+	writeFile(t, filepath.Join(root, "sub", "new.bin"), strings.Repeat("x", 80))
+	past := time.Now().Add(-400 * 24 * time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Analyze(context.Background(), root, AnalyzeOptions{OldAfter: 180 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if a.OldCount != 1 || a.OldBytes != 40 || len(a.OldFiles) != 1 || a.OldFiles[0].Path != old {
+		t.Errorf("expected only old.bin to be old, got %d / %d / %+v", a.OldCount, a.OldBytes, a.OldFiles)
+	}
+	if a.OldAfterDays != 180 {
+		t.Errorf("expected old_after_days 180, got %d", a.OldAfterDays)
+	}
+}
+
+func TestAnalyze_DoesNotFollowLinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "huge.bin"), strings.Repeat("x", 1000))
+	writeFile(t, filepath.Join(root, "sub", "f"), "x")
+	symlink(t, outside, filepath.Join(root, "link"))
+	symlink(t, outside, filepath.Join(root, "sub", "link"))
+
+	a, err := Analyze(context.Background(), root, AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if a.Bytes != 1 {
+		t.Errorf("linked directories must not be counted, got %d bytes", a.Bytes)
+	}
+	if len(a.SkippedLinks) != 2 {
+		t.Errorf("expected both links to be reported as skipped, got %v", a.SkippedLinks)
+	}
+}
+
+func TestAnalyze_CancelledIsPartial(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	os.Mkdir(sub, 0755)
+	createTempFiles(t, sub, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	a, err := Analyze(ctx, root, AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if a.Status != StatusPartial {
+		t.Errorf("expected a partial analysis after cancellation, got %s", a.Status)
+	}
+}
+
+func TestAnalyze_RejectsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "f")
+	writeFile(t, file, "x")
+	if _, err := Analyze(context.Background(), file, AnalyzeOptions{}); err == nil {
+		t.Error("expected an error for a file root")
+	}
+}
+
+// ---------- RemoveSelection tests ----------
+
+func TestRemoveSelection_FilesAndDirs(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "a.bin")
+	dir := filepath.Join(root, "dir")
+	writeFile(t, file, "aaaa")
+	writeFile(t, filepath.Join(dir, "sub", "b"), "bb")
+	keep := filepath.Join(root, "keep")
+	writeFile(t, keep, "k")
+
+	result := RemoveSelection(context.Background(), []string{file, dir}, CleanOptions{})
+	if result.FilesDeleted != 2 || result.SpaceFreed != 6 {
+		t.Errorf("expected 2 files / 6 bytes deleted, got %d / %d", result.FilesDeleted, result.SpaceFreed)
+	}
+	if exists(file) || exists(dir) {
+		t.Error("selected file and directory should be gone")
+	}
+	if !exists(keep) {
+		t.Error("unselected file must be left alone")
+	}
+	if len(result.Categories) != 1 || result.Categories[0].ID != SelectionCategoryID {
+		t.Errorf("expected one %s category status, got %+v", SelectionCategoryID, result.Categories)
+	}
+}
+
+func TestRemoveSelection_DryRunAndGuard(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "a.bin")
+	writeFile(t, file, "aaaa")
+	home, _ := os.UserHomeDir()
+
+	result := RemoveSelection(context.Background(), []string{file, home}, CleanOptions{DryRun: true})
+	if result.FilesDeleted != 1 || !exists(file) {
+		t.Errorf("dry run should count the file and keep it, got %d deleted", result.FilesDeleted)
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("expected the home directory to be refused, got %v", result.Errors)
+	}
+}
+
+func TestRemoveSelection_Quarantine(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "a.bin")
+	writeFile(t, file, "aaaa")
+	q, err := OpenQuarantine(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := RemoveSelection(context.Background(), []string{file}, CleanOptions{
+		Quarantine:           q,
+		QuarantineCategories: map[string]bool{SelectionCategoryID: true},
+	})
+	if result.FilesQuarantined != 1 || exists(file) {
+		t.Errorf("expected the file to be quarantined, got %d", result.FilesQuarantined)
+	}
+	if runs, err := q.Runs(); err != nil || len(runs) != 1 || len(runs[0].Categories) != 1 || runs[0].Categories[0] != SelectionCategoryID {
+		t.Fatalf("expected one run recording the selection, got %+v (%v)", runs, err)
+	} else if _, errs := q.Restore(runs[0].ID); len(errs) > 0 {
+		t.Fatalf("Restore failed: %v", errs)
+	}
+	if !exists(file) {
+		t.Error("restoring the run should bring the file back")
+	}
+}
@@ -0,0 +1,436 @@
+package cleaner
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveFormat is the container files are archived into.
+type ArchiveFormat string
+
+const (
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+)
+
+// ParseArchiveFormat parses "zip", "tar.gz" or "tgz". The empty string means
+// zip.
+func ParseArchiveFormat(s string) (ArchiveFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "zip":
+		return ArchiveZip, nil
+	case "tar.gz", "tgz":
+		return ArchiveTarGz, nil
+	default:
+		return "", fmt.Errorf("unknown archive format %q (want zip or tar.gz)", s)
+	}
+}
+
+// Archiver compresses files into dated bundles before they are removed, so
+// that logs deleted by a clean are still there when something breaks. Each
+// run writes one bundle per category:
+//
+//	<dir>/<category>-<yyyymmdd-hhmmss>.zip
+//
+// Closing the archiver finishes the bundles and rotates old ones: beyond
+// keep bundles per category, or beyond maxBytes for the whole directory, the
+// oldest are removed. Bundles written by the run itself are never rotated.
+type Archiver struct {
+	dir      string
+	format   ArchiveFormat
+	keep     int   // Bundles kept per category; 0 = no limit
+	maxBytes int64 // Total size of the directory; 0 = no cap
+
+	mu      sync.Mutex
+	stamp   string
+	bundles map[string]*archiveBundle
+	order   []string // Categories in the order their bundle was started
+	closed  bool
+}
+
+// archiveBundle is the open bundle of one category.
+type archiveBundle struct {
+	path      string
+	file      *os.File
+	zw        *zip.Writer
+	gz        *gzip.Writer
+	tw        *tar.Writer
+	written   map[string]bool // Entries started in the bundle
+	discarded map[string]bool // Entries of files that were not removed or not copied whole
+}
+
+const archiveRunTime = "20060102-150405"
+
+// archiveName matches the bundles an Archiver writes.
+var archiveName = regexp.MustCompile(`^(.+)-(\d{8}-\d{6})(?:-\d+)?\.(zip|tar\.gz)$`)
+
+// NewArchiver prepares archiving into dir, which may use the same variables
+// as custom rules. Bundles are created on the first Add of each category.
+func NewArchiver(dir string, format ArchiveFormat, keep int, maxBytes int64) (*Archiver, error) {
+	expanded, ok := expandPath(dir, os.LookupEnv)
+	if !ok {
+		return nil, fmt.Errorf("archive directory %q is not an absolute path", dir)
+	}
+	dir = expanded
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating archive directory: %w", err)
+	}
+	return &Archiver{
+		dir:      dir,
+		format:   format,
+		keep:     keep,
+		maxBytes: maxBytes,
+		stamp:    time.Now().Format(archiveRunTime),
+		bundles:  map[string]*archiveBundle{},
+	}, nil
+}
+
+// Dir returns the archive directory.
+func (a *Archiver) Dir() string {
+	return a.dir
+}
+
+// Add compresses a file into the bundle of category. The file is left in
+// place; the caller removes it once Add succeeded, and calls Discard when
+// Add or the removal fails.
+func (a *Archiver) Add(category, path string, info os.FileInfo) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return errors.New("archive already closed")
+	}
+	b, err := a.bundle(category)
+	if err != nil {
+		return err
+	}
+
+	// An entry cut off by a read error cannot be taken back out of the
+	// stream; it is discarded and dropped when the bundle is finished.
+	name := archiveEntryName(path)
+	if b.zw != nil {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name, hdr.Method = name, zip.Deflate
+		w, err := b.zw.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", b.path, err)
+		}
+		b.written[name] = true
+		if _, err := io.Copy(w, src); err != nil {
+			b.discard(name)
+			return fmt.Errorf("archiving %s: %w", path, err)
+		}
+		return nil
+	}
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing %s: %w", b.path, err)
+	}
+	b.written[name] = true
+	// The header promised info.Size() bytes; a file that grew is cut off and
+	// one that shrank, or failed to read, is padded, so the stream stays
+	// usable for the next entry.
+	n, readErr := io.Copy(b.tw, io.LimitReader(src, hdr.Size))
+	if n < hdr.Size {
+		if _, err := io.CopyN(b.tw, zeroReader{}, hdr.Size-n); err != nil {
+			return fmt.Errorf("writing %s: %w", b.path, err)
+		}
+	}
+	if readErr != nil {
+		b.discard(name)
+		return fmt.Errorf("archiving %s: %w", path, readErr)
+	}
+	return nil
+}
+
+// Discard takes a file added to the bundle of category back out, because it
+// could not be removed or Add failed, and it is still in place. Compressed streams cannot be
+// truncated, so the entry is dropped when Close finishes the bundle.
+func (a *Archiver) Discard(category, path string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if b, ok := a.bundles[category]; ok && !a.closed {
+		b.discard(archiveEntryName(path))
+	}
+}
+
+// discard marks an entry of the bundle for dropping. Names that were never
+// written are ignored.
+func (b *archiveBundle) discard(name string) {
+	if b.written[name] {
+		b.discarded[name] = true
+	}
+}
+
+// bundle returns the bundle of category, creating it on first use. Callers
+// hold a.mu.
+func (a *Archiver) bundle(category string) (*archiveBundle, error) {
+	if b, ok := a.bundles[category]; ok {
+		return b, nil
+	}
+	base := category + "-" + a.stamp
+	path := filepath.Join(a.dir, base+"."+string(a.format))
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(a.dir, fmt.Sprintf("%s-%d.%s", base, i, a.format))
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("creating archive: %w", err)
+	}
+	b := &archiveBundle{path: path, file: f, written: map[string]bool{}, discarded: map[string]bool{}}
+	if a.format == ArchiveTarGz {
+		b.gz = gzip.NewWriter(f)
+		b.tw = tar.NewWriter(b.gz)
+	} else {
+		b.zw = zip.NewWriter(f)
+	}
+	a.bundles[category] = b
+	a.order = append(a.order, category)
+	log.Printf("[SysCleaner] Archiving %s files into %s", category, path)
+	return b, nil
+}
+
+// Close finishes the bundles, rotates old ones and returns the bundles
+// written with their total size on disk.
+func (a *Archiver) Close() ([]string, int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil, 0, nil
+	}
+	a.closed = true
+
+	var (
+		paths []string
+		size  int64
+		errs  []error
+	)
+	current := map[string]bool{}
+	for _, category := range a.order {
+		b := a.bundles[category]
+		if err := b.close(); err != nil {
+			errs = append(errs, fmt.Errorf("finishing archive %s: %w", b.path, err))
+		}
+		if len(b.discarded) > 0 {
+			if len(b.discarded) == len(b.written) {
+				os.Remove(b.path)
+				continue
+			}
+			if err := b.drop(a.format); err != nil {
+				errs = append(errs, fmt.Errorf("dropping files left in place from %s: %w", b.path, err))
+			}
+		}
+		if info, err := os.Stat(b.path); err == nil {
+			size += info.Size()
+		}
+		paths = append(paths, b.path)
+		current[filepath.Base(b.path)] = true
+	}
+	if len(paths) > 0 {
+		a.rotate(current)
+	}
+	return paths, size, errors.Join(errs...)
+}
+
+func (b *archiveBundle) close() error {
+	var errs []error
+	if b.zw != nil {
+		errs = append(errs, b.zw.Close())
+	} else {
+		errs = append(errs, b.tw.Close(), b.gz.Close())
+	}
+	errs = append(errs, b.file.Close())
+	return errors.Join(errs...)
+}
+
+// drop rewrites the finished bundle without its discarded entries.
+func (b *archiveBundle) drop(format ArchiveFormat) error {
+	tmp := b.path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if format == ArchiveTarGz {
+		err = b.dropTar(out)
+	} else {
+		err = b.dropZip(out)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, b.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+func (b *archiveBundle) dropZip(out io.Writer) error {
+	zr, err := zip.OpenReader(b.path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	zw := zip.NewWriter(out)
+	for _, f := range zr.File {
+		if b.discarded[f.Name] {
+			continue
+		}
+		if err := zw.Copy(f); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (b *archiveBundle) dropTar(out io.Writer) error {
+	in, err := os.Open(b.path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	gr, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gr)
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if b.discarded[hdr.Name] {
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// rotate removes the oldest bundles beyond the per-category count and the
+// size cap, keeping the bundles named in current. Only bundles of registered
+// categories and of the categories archived by this run count: the archive
+// directory may be a folder the user keeps other dated archives in. Callers
+// hold a.mu.
+func (a *Archiver) rotate(current map[string]bool) {
+	type bundleFile struct {
+		name, category string
+		size           int64
+		mtime          time.Time
+	}
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return
+	}
+	var files []bundleFile
+	var total int64
+	for _, e := range entries {
+		m := archiveName.FindStringSubmatch(e.Name())
+		if m == nil || !e.Type().IsRegular() {
+			continue
+		}
+		if _, ours := a.bundles[m[1]]; !ours {
+			if _, registered := LookupCategory(m[1]); !registered {
+				continue
+			}
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, bundleFile{e.Name(), m[1], info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	// Newest first, so the count limit keeps the head of each category.
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].mtime.Equal(files[j].mtime) {
+			return files[i].mtime.After(files[j].mtime)
+		}
+		return files[i].name > files[j].name
+	})
+
+	remove := func(f bundleFile, why string) {
+		if err := os.Remove(filepath.Join(a.dir, f.name)); err == nil {
+			total -= f.size
+			log.Printf("[SysCleaner] Rotated archive %s (%s): %s", f.name, FormatBytes(f.size), why)
+		}
+	}
+	kept := map[string]int{}
+	var survivors []bundleFile
+	for _, f := range files {
+		kept[f.category]++
+		if a.keep > 0 && kept[f.category] > a.keep && !current[f.name] {
+			remove(f, fmt.Sprintf("more than %d archives of %s", a.keep, f.category))
+			continue
+		}
+		survivors = append(survivors, f)
+	}
+	for i := len(survivors) - 1; i >= 0 && a.maxBytes > 0 && total > a.maxBytes; i-- {
+		if !current[survivors[i].name] {
+			remove(survivors[i], "archive directory over "+FormatBytes(a.maxBytes))
+		}
+	}
+}
+
+// archiveEntryName is the name of path inside a bundle: the full path
+// without the volume, so files of different directories do not collide.
+func archiveEntryName(path string) string {
+	path = filepath.Clean(path)
+	rest := strings.TrimLeft(path[len(filepath.VolumeName(path)):], `\/`)
+	if vol := strings.Trim(filepath.VolumeName(path), `\/:`); vol != "" {
+		rest = filepath.Join(vol, rest)
+	}
+	return filepath.ToSlash(rest)
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,285 @@
+package cleaner
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveLogs cleans dir as category c with archiving into a new archiver.
+func archiveLogs(t *testing.T, c Category, dir, archives string, format ArchiveFormat, overrides map[string]bool) CleanResult {
+	t.Helper()
+	a, err := NewArchiver(archives, format, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := CleanOptions{Archive: a, ArchiveCategories: overrides}
+	return RemovePaths(context.Background(), c, []string{dir}, "", opts)
+}
+
+// zipContents returns the entries of a zip archive with their contents.
+func zipContents(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	out := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		out[f.Name] = string(data)
+	}
+	return out
+}
+
+// ---------- Archive tests ----------
+
+func TestArchive_ZipBeforeDelete(t *testing.T) {
+	logs, archives := t.TempDir(), t.TempDir()
+	content := strings.Repeat("2024-03-01 12:00:00 INFO nothing happened\n", 200)
+	writeFile(t, filepath.Join(logs, "CBS", "CBS.log"), content)
+	writeFile(t, filepath.Join(logs, "setup.log"), content)
+	result := archiveLogs(t, Category{ID: "test_archive_zip", Name: "Logs", Logs: true}, logs, archives, ArchiveZip, nil)
+
+	if result.FilesDeleted != 2 || result.FilesArchived != 2 || result.SpaceArchived != int64(2*len(content)) {
+		t.Fatalf("expected 2 files archived and deleted, got %d archived (%d bytes), %d deleted",
+			result.FilesArchived, result.SpaceArchived, result.FilesDeleted)
+	}
+	if exists(filepath.Join(logs, "setup.log")) {
+		t.Error("expected the archived log to be removed")
+	}
+	if len(result.Archives) != 1 || !strings.HasPrefix(filepath.Base(result.Archives[0]), "test_archive_zip-") {
+		t.Fatalf("expected one bundle for the category, got %v", result.Archives)
+	}
+	info, _ := os.Stat(result.Archives[0])
+	if result.ArchiveSize != info.Size() || result.ArchiveSize >= result.SpaceArchived {
+		t.Errorf("expected the compressed bundle size %d, got %d", info.Size(), result.ArchiveSize)
+	}
+	if result.NetReclaimed() != result.SpaceReclaimed-result.ArchiveSize {
+		t.Errorf("net reclaimed must subtract the bundles: %d", result.NetReclaimed())
+	}
+
+	entries := zipContents(t, result.Archives[0])
+	want := archiveEntryName(filepath.Join(logs, "CBS", "CBS.log"))
+	if entries[want] != content || len(entries) != 2 {
+		t.Errorf("expected %s in the bundle, got %d entries", want, len(entries))
+	}
+	if rep := NewReport(result, false); rep.BytesArchived != result.SpaceArchived || rep.NetReclaimed != result.NetReclaimed() {
+		t.Errorf("unexpected report %+v", rep)
+	}
+}
+
+func TestArchive_TarGzAndOverride(t *testing.T) {
+	logs, other, archives := t.TempDir(), t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(logs, "a.log"), "hello")
+	writeFile(t, filepath.Join(other, "b.log"), "world")
+	logCategory := Category{ID: "test_archive_tgz", Name: "Logs", Logs: true}
+	result := archiveLogs(t, logCategory, logs, archives, ArchiveTarGz, nil)
+	if result.FilesArchived != 1 || len(result.Archives) != 1 || !strings.HasSuffix(result.Archives[0], ".tar.gz") {
+		t.Fatalf("expected a tar.gz bundle, got %d files in %v", result.FilesArchived, result.Archives)
+	}
+	f, err := os.Open(result.Archives[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(tr)
+	if hdr.Name != archiveEntryName(filepath.Join(logs, "a.log")) || string(data) != "hello" {
+		t.Errorf("unexpected entry %s: %q", hdr.Name, data)
+	}
+
+	// Overrides archive other categories and switch log categories off.
+	plain := Category{ID: "test_archive_plain", Name: "Plain"}
+	if result := archiveLogs(t, plain, other, archives, ArchiveZip, map[string]bool{plain.ID: true}); result.FilesArchived != 1 {
+		t.Errorf("expected the overridden category archived, got %d files", result.FilesArchived)
+	}
+	writeFile(t, filepath.Join(logs, "c.log"), "x")
+	result = archiveLogs(t, logCategory, logs, archives, ArchiveZip, map[string]bool{logCategory.ID: false})
+	if result.FilesDeleted != 1 || result.FilesArchived != 0 || len(result.Archives) != 0 {
+		t.Errorf("expected the log deleted without archiving, got %+v", result)
+	}
+}
+
+func TestArchive_Rotation(t *testing.T) {
+	archives := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for i, name := range []string{
+		"winlogs-20240101-000000.zip",
+		"winlogs-20240102-000000.zip",
+		"winlogs-20240103-000000.tar.gz",
+		"other-20240101-000000.zip",
+		"notes.txt",
+	} {
+		path := filepath.Join(archives, name)
+		writeFile(t, path, strings.Repeat("x", 100))
+		mtime := old.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(path, mtime, mtime)
+	}
+	src := filepath.Join(t.TempDir(), "new.log")
+	writeFile(t, src, "new")
+	info, _ := os.Stat(src)
+
+	a, err := NewArchiver(archives, ArchiveZip, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Add("winlogs", src, info); err != nil {
+		t.Fatal(err)
+	}
+	written, _, err := a.Close()
+	if err != nil || len(written) != 1 {
+		t.Fatalf("expected one bundle, got %v, %v", written, err)
+	}
+
+	names := readDirNames(archives)
+	sort.Strings(names)
+	want := []string{"notes.txt", "other-20240101-000000.zip", filepath.Base(written[0]), "winlogs-20240103-000000.tar.gz"}
+	sort.Strings(want)
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("expected the 2 newest winlogs bundles kept, got %v", names)
+	}
+
+	// A size cap removes the oldest bundles, never the new one. Dated
+	// archives of unknown categories are not the archiver's and are kept.
+	a, _ = NewArchiver(archives, ArchiveZip, 0, 150)
+	a.Add("winlogs", src, info)
+	written, _, _ = a.Close()
+	names = readDirNames(archives)
+	sort.Strings(names)
+	want = []string{"notes.txt", "other-20240101-000000.zip", filepath.Base(written[0])}
+	sort.Strings(want)
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("expected only the new bundle of winlogs within the size cap, got %v", names)
+	}
+}
+
+func TestArchive_ReadErrorDropsEntry(t *testing.T) {
+	src := t.TempDir()
+	good := filepath.Join(src, "good.log")
+	writeFile(t, good, "good")
+	goodInfo, _ := os.Stat(good)
+	// A directory opens but fails on the first read, like a log that turns
+	// unreadable while it is copied.
+	broken := filepath.Join(src, "broken.log")
+	if err := os.Mkdir(broken, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(src, "size.log"), strings.Repeat("x", 100))
+	brokenInfo, _ := os.Stat(filepath.Join(src, "size.log"))
+
+	for _, format := range []ArchiveFormat{ArchiveZip, ArchiveTarGz} {
+		a, err := NewArchiver(t.TempDir(), format, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Add("test_archive_broken", broken, brokenInfo); err == nil {
+			t.Fatalf("%s: expected the unreadable file to fail", format)
+		}
+		if err := a.Add("test_archive_broken", good, goodInfo); err != nil {
+			t.Fatalf("%s: the next file must still archive: %v", format, err)
+		}
+		written, _, err := a.Close()
+		if err != nil || len(written) != 1 {
+			t.Fatalf("%s: expected one bundle, got %v, %v", format, written, err)
+		}
+
+		entries := map[string]string{}
+		if format == ArchiveZip {
+			entries = zipContents(t, written[0])
+		} else {
+			f, err := os.Open(written[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := tar.NewReader(gz)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%s: unreadable bundle: %v", format, err)
+				}
+				data, _ := io.ReadAll(tr)
+				entries[hdr.Name] = string(data)
+			}
+			f.Close()
+		}
+		if len(entries) != 1 || entries[archiveEntryName(good)] != "good" {
+			t.Errorf("%s: expected only the good file in the bundle, got %v", format, entries)
+		}
+	}
+}
+
+func TestArchive_FileLeftInPlaceIsNotArchived(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("needs a directory the test cannot remove files from")
+	}
+	logs, archives := t.TempDir(), t.TempDir()
+	stuck := filepath.Join(logs, "ro", "stuck.log")
+	writeFile(t, stuck, "stuck")
+	writeFile(t, filepath.Join(logs, "free.log"), "free")
+	if err := os.Chmod(filepath.Dir(stuck), 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Dir(stuck), 0755)
+
+	result := archiveLogs(t, Category{ID: "test_archive_stuck", Name: "Logs", Logs: true}, logs, archives, ArchiveZip, nil)
+	if result.FilesArchived != 1 || result.SpaceArchived != 4 || result.FilesDeleted != 1 {
+		t.Errorf("expected only the removed file counted as archived, got %d archived (%d bytes), %d deleted",
+			result.FilesArchived, result.SpaceArchived, result.FilesDeleted)
+	}
+	if !exists(stuck) || result.SkippedFiles != 1 {
+		t.Fatalf("expected the file in the read-only folder to be skipped, got %d skipped", result.SkippedFiles)
+	}
+	if len(result.Archives) != 1 {
+		t.Fatalf("expected one bundle, got %v", result.Archives)
+	}
+	entries := zipContents(t, result.Archives[0])
+	if _, ok := entries[archiveEntryName(stuck)]; ok || len(entries) != 1 {
+		t.Errorf("expected the file left in place dropped from the bundle, got %v", entries)
+	}
+	for _, f := range result.Locked {
+		if _, ok := entries[archiveEntryName(f.Path)]; ok {
+			t.Errorf("queued file %s must not be in the bundle", f.Path)
+		}
+	}
+
+	// A bundle holding only files left in place is not kept at all.
+	os.Remove(filepath.Join(logs, "free.log"))
+	result = archiveLogs(t, Category{ID: "test_archive_stuck", Name: "Logs", Logs: true}, logs, archives, ArchiveTarGz, nil)
+	if result.FilesArchived != 0 || len(result.Archives) != 0 || result.ArchiveSize != 0 {
+		t.Errorf("expected no bundle, got %d archived in %v", result.FilesArchived, result.Archives)
+	}
+	if names := readDirNames(archives); len(names) != 1 {
+		t.Errorf("expected only the first bundle in the archive directory, got %v", names)
+	}
+}
@@ -0,0 +1,521 @@
+package cleaner
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Browser profile discovery. Chromium-based browsers list their profiles in
+// the "Local State" file of their user data directory and Firefox lists its
+// profiles, which may live anywhere, in profiles.ini and installs.ini. The
+// browser categories clean the profiles found there rather than guessing
+// from directory names, and report what they did per profile.
+
+type browserKind int
+
+const (
+	chromiumBrowser browserKind = iota
+	firefoxBrowser
+)
+
+// browser describes where one browser keeps its profiles.
+type browser struct {
+	name string
+	kind browserKind
+	// roots are the user data directories: the "User Data" directory of a
+	// Chromium-based browser, or the directory holding Firefox's
+	// profiles.ini.
+	roots []PathResolver
+	// cacheRoots mirror roots for the disk caches, which some platforms keep
+	// apart from the profile (~/.cache on Linux, LOCALAPPDATA for Firefox on
+	// Windows). A profile's cache is at the same relative path below them.
+	cacheRoots []PathResolver
+	// single browsers keep their only profile directly in the root (Opera).
+	single bool
+}
+
+var browsers = []browser{
+	{
+		name:       "Chrome",
+		roots:      []PathResolver{envPath("LOCALAPPDATA", "Google", "Chrome", "User Data"), xdgConfig("google-chrome")},
+		cacheRoots: []PathResolver{xdgCache("google-chrome")},
+	},
+	{
+		name:       "Chromium",
+		roots:      []PathResolver{envPath("LOCALAPPDATA", "Chromium", "User Data"), xdgConfig("chromium")},
+		cacheRoots: []PathResolver{xdgCache("chromium")},
+	},
+	{
+		name:       "Edge",
+		roots:      []PathResolver{envPath("LOCALAPPDATA", "Microsoft", "Edge", "User Data"), xdgConfig("microsoft-edge")},
+		cacheRoots: []PathResolver{xdgCache("microsoft-edge")},
+	},
+	{
+		name:       "Brave",
+		roots:      []PathResolver{envPath("LOCALAPPDATA", "BraveSoftware", "Brave-Browser", "User Data"), xdgConfig("BraveSoftware", "Brave-Browser")},
+		cacheRoots: []PathResolver{xdgCache("BraveSoftware", "Brave-Browser")},
+	},
+	{
+		name:       "Opera",
+		single:     true,
+		roots:      []PathResolver{envPath("APPDATA", "Opera Software", "Opera Stable")},
+		cacheRoots: []PathResolver{envPath("LOCALAPPDATA", "Opera Software", "Opera Stable")},
+	},
+	{
+		name:       "Opera GX",
+		single:     true,
+		roots:      []PathResolver{envPath("APPDATA", "Opera Software", "Opera GX Stable")},
+		cacheRoots: []PathResolver{envPath("LOCALAPPDATA", "Opera Software", "Opera GX Stable")},
+	},
+	{
+		name:       "Firefox",
+		kind:       firefoxBrowser,
+		roots:      []PathResolver{envPath("APPDATA", "Mozilla", "Firefox"), homePath(".mozilla", "firefox"), xdgConfig("mozilla", "firefox")},
+		cacheRoots: []PathResolver{envPath("LOCALAPPDATA", "Mozilla", "Firefox"), xdgCache("mozilla", "firefox")},
+	},
+}
+
+// Cache directories, relative to a profile or its cache twin.
+var (
+	chromiumCacheSubdirs = []string{"Cache", "Code Cache", "GPUCache", "Service Worker", "ShaderCache"}
+	firefoxCacheSubdirs  = []string{"cache2", "startupCache"}
+)
+
+// BrowserProfile is one profile of an installed browser.
+type BrowserProfile struct {
+	Browser string `json:"browser"` // "Chrome", "Firefox", ...
+	Name    string `json:"name"`    // The name the browser shows for the profile
+	Dir     string `json:"dir"`
+
+	kind browserKind
+	// rel is Dir relative to its root, used to find the cache twin; it is
+	// empty for profiles outside the root.
+	rel        string
+	cacheRoots []PathResolver
+}
+
+// Label returns the profile as shown in results, such as "Chrome: Work".
+func (p BrowserProfile) Label() string {
+	return p.Browser + ": " + p.Name
+}
+
+// Matches reports whether a profile include or exclude pattern selects p.
+// Patterns are compared case-insensitively with the profile name, its
+// directory name, "Browser:Name" and the full directory; shell wildcards
+// are allowed except in full directories.
+func (p BrowserProfile) Matches(pattern string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return false
+	}
+	if filepath.IsAbs(pattern) {
+		return sameDir(filepath.Clean(pattern), p.Dir)
+	}
+	pattern = strings.ToLower(pattern)
+	for _, s := range []string{p.Name, filepath.Base(p.Dir), p.Browser + ":" + p.Name} {
+		s = strings.ToLower(s)
+		if ok, _ := filepath.Match(pattern, s); ok || pattern == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sameDir compares two cleaned directories, ignoring case on Windows where
+// the filesystem does.
+func sameDir(a, b string) bool {
+	if filepath.Separator == '\\' {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// cacheDirs returns the cache directories of the profile, in the profile
+// itself and in its cache twins.
+func (p BrowserProfile) cacheDirs() []string {
+	subdirs := chromiumCacheSubdirs
+	if p.kind == firefoxBrowser {
+		subdirs = firefoxCacheSubdirs
+	}
+	bases := []string{p.Dir}
+	if p.rel != "" {
+		for _, resolve := range p.cacheRoots {
+			for _, root := range resolve() {
+				bases = append(bases, filepath.Join(root, p.rel))
+			}
+		}
+	}
+	var dirs []string
+	for _, base := range dedup(bases) {
+		for _, sub := range subdirs {
+			dirs = append(dirs, filepath.Join(base, sub))
+		}
+	}
+	return dirs
+}
+
+// DiscoverBrowserProfiles returns the profiles of every installed browser,
+// grouped by browser.
+func DiscoverBrowserProfiles() []BrowserProfile {
+	return discoverProfiles(nil)
+}
+
+// discoverProfiles returns the profiles of the named browsers, or of all
+// browsers when names is empty.
+func discoverProfiles(names []string) []BrowserProfile {
+	seen := map[string]bool{}
+	var out []BrowserProfile
+	for _, b := range browsers {
+		if len(names) > 0 && !containsString(names, b.name) {
+			continue
+		}
+		for _, resolve := range b.roots {
+			for _, root := range resolve() {
+				for _, p := range b.profilesIn(root) {
+					key := filepath.Clean(p.Dir)
+					if filepath.Separator == '\\' {
+						key = strings.ToLower(key)
+					}
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					p.Browser, p.kind, p.cacheRoots = b.name, b.kind, b.cacheRoots
+					out = append(out, p)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// profilesIn returns the profiles of b found in one of its roots.
+func (b browser) profilesIn(root string) []BrowserProfile {
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil
+	}
+	switch {
+	case b.single:
+		return []BrowserProfile{{Name: b.name, Dir: root, rel: "."}}
+	case b.kind == firefoxBrowser:
+		return firefoxProfilesIn(root)
+	default:
+		return chromiumProfilesIn(root)
+	}
+}
+
+// chromiumProfilesIn reads the profile list of a Chromium "User Data"
+// directory from its Local State file. Without a readable Local State, the
+// "Default" and "Profile N" directories are taken.
+func chromiumProfilesIn(userData string) []BrowserProfile {
+	var state struct {
+		Profile struct {
+			InfoCache map[string]struct {
+				Name string `json:"name"`
+			} `json:"info_cache"`
+		} `json:"profile"`
+	}
+	data, err := os.ReadFile(filepath.Join(userData, "Local State"))
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil || len(state.Profile.InfoCache) == 0 {
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("[SysCleaner] Cannot read the profile list in %s: %v", userData, err)
+		}
+		var out []BrowserProfile
+		for _, dir := range chromiumProfileDirs(userData) {
+			out = append(out, BrowserProfile{Name: filepath.Base(dir), Dir: dir, rel: filepath.Base(dir)})
+		}
+		return out
+	}
+
+	keys := make([]string, 0, len(state.Profile.InfoCache))
+	for key := range state.Profile.InfoCache {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var out []BrowserProfile
+	for _, key := range keys {
+		dir := filepath.Join(userData, key)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		name := state.Profile.InfoCache[key].Name
+		if name == "" {
+			name = key
+		}
+		out = append(out, BrowserProfile{Name: name, Dir: dir, rel: key})
+	}
+	return out
+}
+
+// chromiumProfileDirs returns the profile directories ("Default",
+// "Profile 1", ...) inside a Chromium-style "User Data" directory.
+func chromiumProfileDirs(userDataDir string) []string {
+	entries, err := os.ReadDir(userDataDir)
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if name == "Default" || strings.HasPrefix(name, "Profile ") {
+			dirs = append(dirs, filepath.Join(userDataDir, name))
+		}
+	}
+	return dirs
+}
+
+// firefoxProfileMarkers are files every Firefox profile directory has; the
+// fallback scan only takes directories holding one of them.
+var firefoxProfileMarkers = []string{"prefs.js", "times.json"}
+
+// firefoxProfilesIn reads the profiles listed in root's profiles.ini, plus
+// the default profiles of installs.ini that it does not list. Relative paths
+// are below root; absolute ones may be anywhere. Without a profiles.ini the
+// directories of root and root/Profiles that look like profiles are taken.
+func firefoxProfilesIn(root string) []BrowserProfile {
+	sections, err := readINI(filepath.Join(root, "profiles.ini"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[SysCleaner] Cannot read %s: %v", filepath.Join(root, "profiles.ini"), err)
+		}
+		return firefoxScanProfiles(root)
+	}
+
+	var out []BrowserProfile
+	seen := map[string]bool{}
+	add := func(name, path string, relative bool) {
+		if path == "" {
+			return
+		}
+		p := BrowserProfile{Name: name}
+		if relative {
+			p.rel = filepath.FromSlash(path)
+			p.Dir = filepath.Join(root, p.rel)
+		} else {
+			p.Dir = filepath.Clean(path)
+		}
+		if seen[p.Dir] {
+			return
+		}
+		if info, err := os.Stat(p.Dir); err != nil || !info.IsDir() {
+			return
+		}
+		seen[p.Dir] = true
+		if p.Name == "" {
+			p.Name = filepath.Base(p.Dir)
+		}
+		out = append(out, p)
+	}
+
+	for _, sec := range sections {
+		if strings.HasPrefix(sec.name, "Profile") {
+			add(sec.values["Name"], sec.values["Path"], sec.values["IsRelative"] != "0")
+		}
+	}
+	installs, _ := readINI(filepath.Join(root, "installs.ini"))
+	for _, sec := range append(sections, installs...) {
+		if strings.HasPrefix(sec.name, "Profile") || sec.name == "General" {
+			continue
+		}
+		// Install sections name the install's default profile.
+		if path := sec.values["Default"]; path != "" {
+			add("", path, !filepath.IsAbs(path))
+		}
+	}
+	return out
+}
+
+// firefoxScanProfiles finds profiles of a Firefox root without profiles.ini.
+func firefoxScanProfiles(root string) []BrowserProfile {
+	var out []BrowserProfile
+	for _, dir := range []string{filepath.Join(root, "Profiles"), root} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			for _, marker := range firefoxProfileMarkers {
+				if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
+					rel, _ := filepath.Rel(root, path)
+					out = append(out, BrowserProfile{Name: entry.Name(), Dir: path, rel: rel})
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// iniSection is one section of an INI file.
+type iniSection struct {
+	name   string
+	values map[string]string
+}
+
+// readINI reads a Mozilla-style INI file. Comments, blank lines and lines
+// outside a section are ignored.
+func readINI(path string) ([]iniSection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sections []iniSection
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" || strings.HasPrefix(text, ";") || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			sections = append(sections, iniSection{name: strings.TrimSpace(text[1 : len(text)-1]), values: map[string]string{}})
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok || len(sections) == 0 {
+			continue
+		}
+		sections[len(sections)-1].values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return sections, scanner.Err()
+}
+
+// BrowserProfileSelected reports whether the browser categories may clean
+// profile p under the include and exclude patterns of o.
+func (o CleanOptions) BrowserProfileSelected(p BrowserProfile) bool {
+	for _, pattern := range o.ExcludeBrowserProfiles {
+		if p.Matches(pattern) {
+			return false
+		}
+	}
+	if len(o.BrowserProfiles) == 0 {
+		return true
+	}
+	for _, pattern := range o.BrowserProfiles {
+		if p.Matches(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedProfiles returns the profiles of the named browsers (all when
+// names is empty) that opts allows a category to clean.
+func selectedProfiles(opts CleanOptions, names ...string) []BrowserProfile {
+	var out []BrowserProfile
+	for _, p := range discoverProfiles(names) {
+		if !opts.BrowserProfileSelected(p) {
+			log.Printf("[SysCleaner] Skipping browser profile %s (%s): not selected", p.Label(), p.Dir)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// browserCacheDirs resolves to the cache directories of every profile of the
+// named browsers, regardless of the profile selection. Other categories use
+// it to leave those directories to the browser category.
+func browserCacheDirs(names ...string) PathResolver {
+	return func() []string {
+		var dirs []string
+		for _, p := range discoverProfiles(names) {
+			dirs = append(dirs, p.cacheDirs()...)
+		}
+		return dirs
+	}
+}
+
+// cleanBrowserCache returns a CleanFunc cleaning the cache directories of
+// the selected profiles of the named browsers.
+func cleanBrowserCache(names ...string) CleanFunc {
+	return func(c Category, opts CleanOptions) CleanResult {
+		result := CleanResult{}
+		sw := newSweeper(c, opts)
+		for _, p := range selectedProfiles(opts, names...) {
+			if sw.stopped(&result) {
+				break
+			}
+			part := CleanResult{}
+			for _, dir := range p.cacheDirs() {
+				part.merge(cleanDirectoryFiltered(dir, fileFilter{MaxAge: c.MaxAge}, sw))
+			}
+			result.mergeProfile(c, p, part)
+		}
+		return result
+	}
+}
+
+// BrowserProfileResult is what one category did in one browser profile.
+type BrowserProfileResult struct {
+	Category       string `json:"category"`
+	Browser        string `json:"browser"`
+	Profile        string `json:"profile"`
+	Dir            string `json:"dir"`
+	FilesDeleted   int64  `json:"files_deleted"` // Removed or quarantined
+	BytesFreed     int64  `json:"bytes_freed"`
+	RecordsDeleted int64  `json:"records_deleted"`
+}
+
+// mergeProfile merges the part of a result that category c produced in
+// profile p and records it per profile.
+func (r *CleanResult) mergeProfile(c Category, p BrowserProfile, part CleanResult) {
+	r.merge(part)
+	r.BrowserProfiles = append(r.BrowserProfiles, BrowserProfileResult{
+		Category:       c.ID,
+		Browser:        p.Browser,
+		Profile:        p.Name,
+		Dir:            p.Dir,
+		FilesDeleted:   part.FilesDeleted + part.FilesQuarantined,
+		BytesFreed:     part.SpaceFreed + part.SpaceQuarantined,
+		RecordsDeleted: part.RecordsDeleted,
+	})
+}
+
+// ProfileTotals adds up the results of all categories per profile, in the
+// order the profiles were first cleaned. The totals have no Category.
+func ProfileTotals(results []BrowserProfileResult) []BrowserProfileResult {
+	var out []BrowserProfileResult
+	index := map[string]int{}
+	for _, pr := range results {
+		i, ok := index[pr.Dir]
+		if !ok {
+			i = len(out)
+			index[pr.Dir] = i
+			out = append(out, BrowserProfileResult{Browser: pr.Browser, Profile: pr.Profile, Dir: pr.Dir})
+		}
+		out[i].FilesDeleted += pr.FilesDeleted
+		out[i].BytesFreed += pr.BytesFreed
+		out[i].RecordsDeleted += pr.RecordsDeleted
+	}
+	return out
+}
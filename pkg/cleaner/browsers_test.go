@@ -0,0 +1,171 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// browserHome points HOME and the XDG directories at a temporary directory
+// and returns a Chrome and a Firefox profile directory inside it. The
+// Firefox profile is listed in profiles.ini.
+func browserHome(t *testing.T) (chrome, firefox string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("LOCALAPPDATA", "")
+	t.Setenv("APPDATA", "")
+	chrome = filepath.Join(home, ".config", "google-chrome", "Default")
+	firefox = filepath.Join(home, ".mozilla", "firefox", "abcd1234.default-release")
+	for _, dir := range []string{chrome, firefox} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(home, ".mozilla", "firefox", "profiles.ini"),
+		"[Profile0]\nName=default-release\nIsRelative=1\nPath=abcd1234.default-release\n")
+	return chrome, firefox
+}
+
+// profileLabels returns the labels of profiles, keyed by directory.
+func profileLabels(profiles []BrowserProfile) map[string]string {
+	out := map[string]string{}
+	for _, p := range profiles {
+		out[p.Dir] = p.Label()
+	}
+	return out
+}
+
+// ---------- Browser profile tests ----------
+
+func TestChromiumProfiles_LocalState(t *testing.T) {
+	chrome, _ := browserHome(t)
+	userData := filepath.Dir(chrome)
+	writeFile(t, filepath.Join(userData, "Local State"),
+		`{"profile":{"info_cache":{"Default":{"name":"Personal"},"Profile 3":{"name":"Work"},"Profile 9":{"name":"Deleted"}}}}`)
+	for _, dir := range []string{"Profile 3", "Profile 5", "System Profile"} {
+		if err := os.MkdirAll(filepath.Join(userData, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := profileLabels(discoverProfiles([]string{"Chrome"}))
+	want := map[string]string{
+		chrome:                               "Chrome: Personal",
+		filepath.Join(userData, "Profile 3"): "Chrome: Work",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected the profiles listed in Local State, got %v", got)
+	}
+	for dir, label := range want {
+		if got[dir] != label {
+			t.Errorf("%s: expected %q, got %q", dir, label, got[dir])
+		}
+	}
+}
+
+func TestChromiumProfiles_WithoutLocalState(t *testing.T) {
+	chrome, _ := browserHome(t)
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(chrome), "Crashpad"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	got := discoverProfiles([]string{"Chrome"})
+	if len(got) != 1 || got[0].Dir != chrome || got[0].Name != "Default" {
+		t.Errorf("expected only the Default profile, got %+v", got)
+	}
+}
+
+func TestFirefoxProfiles_ProfilesIni(t *testing.T) {
+	_, firefox := browserHome(t)
+	root := filepath.Dir(firefox)
+	custom := filepath.Join(t.TempDir(), "ff-work")
+	for _, dir := range []string{custom, filepath.Join(root, "xyz.install-default"), filepath.Join(root, "Crash Reports")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(root, "profiles.ini"), "[General]\nStartWithLastProfile=1\n\n"+
+		"[Profile1]\nName=Work\nIsRelative=0\nPath="+custom+"\n\n"+
+		"[Profile0]\nName=default-release\nIsRelative=1\nPath=abcd1234.default-release\nDefault=1\n\n"+
+		"[Profile2]\nName=gone\nIsRelative=1\nPath=missing.profile\n")
+	writeFile(t, filepath.Join(root, "installs.ini"), "[4F96D1932A9F858E]\nDefault=xyz.install-default\nLocked=1\n")
+
+	got := profileLabels(discoverProfiles([]string{"Firefox"}))
+	want := map[string]string{
+		custom:  "Firefox: Work",
+		firefox: "Firefox: default-release",
+		filepath.Join(root, "xyz.install-default"): "Firefox: xyz.install-default",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected the profiles of profiles.ini and installs.ini, got %v", got)
+	}
+	for dir, label := range want {
+		if got[dir] != label {
+			t.Errorf("%s: expected %q, got %q", dir, label, got[dir])
+		}
+	}
+}
+
+func TestFirefoxCache_CustomLocationAndCacheTwin(t *testing.T) {
+	_, firefox := browserHome(t)
+	root := filepath.Dir(firefox)
+	custom := filepath.Join(t.TempDir(), "ff-work")
+	writeFile(t, filepath.Join(root, "profiles.ini"),
+		"[Profile0]\nName=default-release\nIsRelative=1\nPath=abcd1234.default-release\n\n"+
+			"[Profile1]\nName=Work\nIsRelative=0\nPath="+custom+"\n")
+	writeFile(t, filepath.Join(os.Getenv("XDG_CACHE_HOME"), "mozilla", "firefox", "abcd1234.default-release", "cache2", "entries", "A1"), "x")
+	writeFile(t, filepath.Join(custom, "cache2", "entries", "B1"), "xx")
+	writeFile(t, filepath.Join(custom, "cache2", "entries", "B2"), "xx")
+	writeFile(t, filepath.Join(custom, "prefs.js"), "x")
+
+	c, _ := LookupCategory("firefox_cache")
+	result := c.run(CleanOptions{DryRun: true})
+	if result.FilesDeleted != 3 {
+		t.Errorf("expected the 3 cache files of both profiles, got %d", result.FilesDeleted)
+	}
+	byProfile := map[string]int64{}
+	for _, pr := range result.BrowserProfiles {
+		if pr.Category != "firefox_cache" || pr.Browser != "Firefox" {
+			t.Errorf("unexpected profile result %+v", pr)
+		}
+		byProfile[pr.Profile] = pr.FilesDeleted
+	}
+	if byProfile["default-release"] != 1 || byProfile["Work"] != 2 {
+		t.Errorf("expected 1 file in default-release and 2 in Work, got %v", byProfile)
+	}
+	if rep := NewReport(result, true); len(rep.BrowserProfiles) != 2 {
+		t.Errorf("expected both profiles in the report, got %+v", rep.BrowserProfiles)
+	}
+}
+
+func TestBrowserCache_ProfileSelection(t *testing.T) {
+	chrome, _ := browserHome(t)
+	userData := filepath.Dir(chrome)
+	writeFile(t, filepath.Join(userData, "Local State"),
+		`{"profile":{"info_cache":{"Default":{"name":"Personal"},"Profile 1":{"name":"Work"}}}}`)
+	writeFile(t, filepath.Join(chrome, "Cache", "a"), "x")
+	writeFile(t, filepath.Join(userData, "Profile 1", "Cache", "b"), "x")
+	writeFile(t, filepath.Join(userData, "Profile 1", "GPUCache", "c"), "x")
+
+	c, _ := LookupCategory("chrome_cache")
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             int64
+	}{
+		{"all", nil, nil, 3},
+		{"exclude by name", nil, []string{"work"}, 1},
+		{"include by browser and name", []string{"Chrome:Work"}, nil, 2},
+		{"include by directory name", []string{"Default"}, nil, 1},
+		{"exclude wins", []string{"*"}, []string{"Profile 1"}, 1},
+		{"exclude by path", nil, []string{chrome}, 2},
+	}
+	for _, tc := range tests {
+		opts := CleanOptions{DryRun: true, BrowserProfiles: tc.include, ExcludeBrowserProfiles: tc.exclude}
+		if got := c.run(opts).FilesDeleted; got != tc.want {
+			t.Errorf("%s: expected %d files, got %d", tc.name, tc.want, got)
+		}
+	}
+}
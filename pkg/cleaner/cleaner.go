@@ -2,11 +2,13 @@ package cleaner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,40 +16,171 @@ import (
 
 // CleanOptions specifies what to clean with fine-grained control
 type CleanOptions struct {
-	// System categories
-	WindowsTemp         bool
-	UserTemp            bool
-	WindowsUpdate       bool
-	WindowsInstaller    bool
-	Prefetch            bool
-	CrashDumps          bool
-	ErrorReports        bool
-	ThumbnailCache      bool
-	IconCache           bool
-	FontCache           bool
-	ShaderCache         bool
-	DNSCache            bool
-	WindowsLogs         bool
-	EventLogs           bool
-	DeliveryOptimization bool
-	RecycleBin          bool
-
-	// Application categories
-	ChromeCache   bool
-	FirefoxCache  bool
-	EdgeCache     bool
-	BraveCache    bool
-	OperaCache    bool
-	DiscordCache  bool
-	SpotifyCache  bool
-	SteamCache    bool
-	TeamsCache    bool
-	VSCodeCache   bool
-	JavaCache     bool
+	// Categories holds the IDs of the registry categories to clean.
+	Categories map[string]bool
+
+	// Quarantine, when set, is the store files are moved into for categories
+	// that quarantine instead of deleting (see Quarantined).
+	Quarantine *Quarantine
+	// QuarantineCategories overrides Category.Quarantine per category ID.
+	QuarantineCategories map[string]bool
+
+	// Archive, when set, compresses the files of log categories into dated
+	// bundles before they are removed (see Archived).
+	Archive *Archiver
+	// ArchiveCategories overrides Category.Logs per category ID.
+	ArchiveCategories map[string]bool
+
+	// Retention overrides, per category ID, which of the files a category
+	// selects in a directory are removed (see RetentionPolicy).
+	Retention map[string]RetentionPolicy
+
+	// TrashMinAge only purges Trash items deleted at least this long ago
+	// (0 = the category default).
+	TrashMinAge time.Duration
+
+	// ProtectedPaths are never cleaned, in addition to the built-in
+	// filesystem roots, home and system directories. Exclude holds glob
+	// patterns for files and directories that no category may remove.
+	ProtectedPaths []string
+	Exclude        []string
+	// KeepDomains are domains whose cookies the browser privacy categories
+	// never remove, including those of their subdomains.
+	KeepDomains []string
+	// BrowserProfiles, when set, limits the browser and privacy categories
+	// to the browser profiles matching one of these patterns, and
+	// ExcludeBrowserProfiles leaves the matching ones alone (see
+	// BrowserProfile.Matches).
+	BrowserProfiles        []string
+	ExcludeBrowserProfiles []string
+
+	// RunningPolicy is what happens to a category whose owning application
+	// is running (empty = RunningSkip); RunningPolicies overrides it per
+	// category ID. ConfirmClose is asked before RunningClose closes an
+	// application; without it the category is skipped.
+	RunningPolicy   RunningPolicy
+	RunningPolicies map[string]RunningPolicy
+	ConfirmClose    ConfirmCloseFunc
+
+	// WorkspaceRoots are the directories searched for projects whose
+	// node_modules the stale node_modules category removes once the project
+	// is untouched for StaleProjectAge (0 = DefaultStaleProjectAge).
+	WorkspaceRoots  []string
+	StaleProjectAge time.Duration
+
+	// CrossFilesystems lets the walker enter filesystems mounted below a
+	// cleaned directory. By default it stays on the directory's filesystem.
+	CrossFilesystems bool
 
 	// Execution options
 	DryRun   bool
 	Progress ProgressFunc
+	// Events, when set, receives a ProgressEvent for every category and
+	// file. A real (non dry-run) clean pre-scans the selected categories
+	// first so that events carry the run's totals.
+	Events EventFunc
+
+	// Timeout bounds the whole run and DirTimeout the walk of each
+	// directory (0 = DefaultTimeout / DefaultDirTimeout). A category that
+	// hits either stops where it is and is reported as partial.
+	Timeout    time.Duration
+	DirTimeout time.Duration
+
+	// collect is set by Scan to record every candidate file.
+	collect *planCollector
+	// guard is built once per run from ProtectedPaths and Exclude.
+	guard *pathGuard
+	// space accounts for reclaimed allocation across the categories of a run.
+	space *spaceTracker
+	// ctx is the run's context; category code stops when it is done.
+	ctx context.Context
+	// events delivers Events with the run's running totals.
+	events *eventEmitter
+	// running is the run's process list, for Category.Processes.
+	running *processTable
+	// readOnly is set by categories whose tool makes its files and folders
+	// read-only (the Go module cache).
+	readOnly bool
+}
+
+// Enable selects the given categories.
+func (o *CleanOptions) Enable(ids ...string) {
+	if o.Categories == nil {
+		o.Categories = make(map[string]bool, len(ids))
+	}
+	for _, id := range ids {
+		o.Categories[id] = true
+	}
+}
+
+// EnableAll selects the categories of every group that Group.InAll
+// includes.
+func (o *CleanOptions) EnableAll() {
+	for _, g := range Groups {
+		if !g.InAll() {
+			continue
+		}
+		for _, c := range CategoriesInGroup(g) {
+			o.Enable(c.ID)
+		}
+	}
+}
+
+// Disable deselects the given categories.
+func (o *CleanOptions) Disable(ids ...string) {
+	for _, id := range ids {
+		delete(o.Categories, id)
+	}
+}
+
+// Enabled reports whether a category is selected.
+func (o CleanOptions) Enabled(id string) bool {
+	return o.Categories[id]
+}
+
+// Quarantined reports whether files of c are moved into the quarantine
+// store rather than deleted.
+func (o CleanOptions) Quarantined(c Category) bool {
+	if o.Quarantine == nil {
+		return false
+	}
+	if on, ok := o.QuarantineCategories[c.ID]; ok {
+		return on
+	}
+	return c.Quarantine
+}
+
+// Archived reports whether files of c are archived before they are removed.
+// Quarantined files are kept anyway and are not archived.
+func (o CleanOptions) Archived(c Category) bool {
+	if o.Archive == nil || o.Quarantined(c) {
+		return false
+	}
+	if on, ok := o.ArchiveCategories[c.ID]; ok {
+		return on
+	}
+	return c.Logs
+}
+
+// RunningPolicyFor returns the running-application policy for category c.
+func (o CleanOptions) RunningPolicyFor(c Category) RunningPolicy {
+	if p, ok := o.RunningPolicies[c.ID]; ok && p != "" {
+		return p
+	}
+	if o.RunningPolicy != "" {
+		return o.RunningPolicy
+	}
+	return RunningSkip
+}
+
+// HasSelection reports whether at least one registered category is selected.
+func (o CleanOptions) HasSelection() bool {
+	for id, on := range o.Categories {
+		if _, known := LookupCategory(id); on && known {
+			return true
+		}
+	}
+	return false
 }
 
 // ProgressFunc is called to report progress during cleaning
@@ -62,6 +195,8 @@ const (
 	ErrorTimeout                           // Operation timed out
 	ErrorNotFound                          // File not found
 	ErrorOther                             // Other errors
+	ErrorProtected                         // Refused: protected path
+	ErrorExcluded                          // Refused: matches an exclusion glob
 )
 
 // CleanError is a categorized error for cleaning operations
@@ -75,8 +210,12 @@ func (e *CleanError) Error() string {
 	return fmt.Sprintf("%s: %v", e.Path, e.Err)
 }
 
-// classifyError categorizes an OS error into a CleanError type
-func classifyError(path string, err error) *CleanError {
+func (e *CleanError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError categorizes an OS error into a CleanError type
+func ClassifyError(path string, err error) *CleanError {
 	ce := &CleanError{Path: path, Err: err}
 	errMsg := strings.ToLower(err.Error())
 	switch {
@@ -96,26 +235,126 @@ func classifyError(path string, err error) *CleanError {
 	return ce
 }
 
+// ErrorsOfType returns the CleanErrors of the given type in r.Errors.
+func (r CleanResult) ErrorsOfType(t ErrorType) []*CleanError {
+	var out []*CleanError
+	for _, err := range r.Errors {
+		var ce *CleanError
+		if errors.As(err, &ce) && ce.Type == t {
+			out = append(out, ce)
+		}
+	}
+	return out
+}
+
 // CleanResult holds the result of a cleaning operation
 type CleanResult struct {
 	FilesDeleted    int64
 	SkippedFiles    int64
-	SpaceFreed      int64
-	LockedFiles     int64
+	SpaceFreed      int64 // Apparent size of the removed files
+	LockedFiles     int64 // Skipped because they were in use; see Locked
 	PermissionFiles int64
 	Duration        time.Duration
 	Errors          []error
+
+	// SpaceReclaimed is the disk allocation actually released: allocated
+	// blocks rather than file sizes (sparse files count less, small files a
+	// whole block), and hardlinked files only once their last link is gone.
+	SpaceReclaimed int64
+	// DiskFreeDelta is the growth in free space measured on every volume
+	// files were removed from. Other activity on those volumes shows up in
+	// it too. It is not set for dry runs.
+	DiskFreeDelta int64
+
+	// Locked lists the files left in place because they were in use, for
+	// the pending queue that retries them on the next run (see AddPending).
+	// Browser databases edited in place and Trash items are not listed.
+	Locked []PendingFile
+
+	// DirsRemoved counts the folders removed because the run left them
+	// empty. They are not included in FilesDeleted. Dry runs do not count
+	// them.
+	DirsRemoved int64
+
+	// ChangedFiles counts planned files skipped by Execute because their
+	// size or mtime changed since the scan. They are included in SkippedFiles.
+	ChangedFiles int64
+
+	// Files moved into the quarantine store instead of being deleted. They
+	// are not included in FilesDeleted and SpaceFreed.
+	FilesQuarantined int64
+	SpaceQuarantined int64
+	QuarantineRun    string // Run ID to pass to "quarantine restore"
+
+	// Files compressed into archive bundles before they were removed. They
+	// are included in FilesDeleted and SpaceFreed; ArchiveSize is what the
+	// bundles written by the run take up (see NetReclaimed).
+	FilesArchived int64
+	SpaceArchived int64
+	ArchiveSize   int64
+	Archives      []string // Bundles written by the run
+
+	// RecordsDeleted counts the rows removed from browser databases that
+	// privacy categories edit in place. Dry runs count the rows they would
+	// remove.
+	RecordsDeleted int64
+
+	// DryRunItems lists what a dry run would remove, for categories that
+	// remove whole items rather than loose files (such as the Trash).
+	DryRunItems []string
+
+	// BrowserProfiles breaks the browser and privacy categories down by
+	// browser profile.
+	BrowserProfiles []BrowserProfileResult
+
+	// Games breaks the game launcher categories down by game.
+	Games []GameResult
+
+	// SkippedLinks lists directory symlinks and junctions that were not
+	// followed; SkippedMounts lists mounted filesystems that were not
+	// entered. Links to files are removed as links and not listed.
+	SkippedLinks  []string
+	SkippedMounts []string
+
+	// Categories reports, in run order, how far each category got.
+	Categories []CategoryStatus
+
+	// incomplete is set when cancellation or a deadline cut work short.
+	incomplete bool
+}
+
+// RunStatus says how far a category got before the run ended.
+type RunStatus string
+
+const (
+	StatusCompleted RunStatus = "completed" // Ran to the end
+	StatusPartial   RunStatus = "partial"   // Stopped by cancellation or a deadline
+	StatusCancelled RunStatus = "cancelled" // Never started
+	StatusSkipped   RunStatus = "skipped"   // Left alone because its application was running
+)
+
+// CategoryStatus is the outcome of one category in a run.
+type CategoryStatus struct {
+	ID           string
+	Name         string
+	Status       RunStatus
+	FilesDeleted int64
+	SpaceFreed   int64
+	Duration     time.Duration
+	// Reason explains a skipped category, or notes that its application was
+	// running or closed when it was cleaned.
+	Reason string
 }
 
 const (
-	dirTimeout       = 30 * time.Second // Per-directory timeout
-	defaultOpTimeout = 5 * time.Minute  // Overall operation timeout
+	DefaultDirTimeout = 30 * time.Second // Per-directory deadline
+	DefaultTimeout    = 5 * time.Minute  // Overall deadline
 )
 
 // cleanTask represents a single cleaning category to execute
 type cleanTask struct {
-	name string
-	fn   func(CleanOptions) CleanResult
+	category Category
+	fn       func(CleanOptions) CleanResult
 }
 
 // maxCleanWorkers is the number of concurrent cleaning goroutines.
@@ -125,104 +364,123 @@ const maxCleanWorkers = 4
 // PerformClean orchestrates all cleaning operations based on options.
 // Independent categories run concurrently via a worker pool for faster execution.
 func PerformClean(opts CleanOptions) CleanResult {
-	start := time.Now()
-	result := CleanResult{}
-
-	ctx, cancel := context.WithTimeout(context.Background(), defaultOpTimeout)
-	defer cancel()
+	return PerformCleanContext(context.Background(), opts)
+}
 
+// PerformCleanContext is PerformClean with cancellation. Cancelling ctx stops
+// directory walks and removals within milliseconds; categories that had not
+// started are reported as cancelled, those that were interrupted as partial.
+func PerformCleanContext(ctx context.Context, opts CleanOptions) CleanResult {
 	// Build list of enabled categories
 	var tasks []cleanTask
-	if opts.WindowsTemp {
-		tasks = append(tasks, cleanTask{"Windows Temp", cleanWindowsTemp})
-	}
-	if opts.UserTemp {
-		tasks = append(tasks, cleanTask{"User Temp", cleanUserTemp})
-	}
-	if opts.WindowsUpdate {
-		tasks = append(tasks, cleanTask{"Windows Update Cache", cleanWindowsUpdate})
-	}
-	if opts.WindowsInstaller {
-		tasks = append(tasks, cleanTask{"Windows Installer Cache", cleanWindowsInstaller})
-	}
-	if opts.Prefetch {
-		tasks = append(tasks, cleanTask{"Prefetch", cleanPrefetch})
-	}
-	if opts.CrashDumps {
-		tasks = append(tasks, cleanTask{"Crash Dumps", cleanCrashDumps})
-	}
-	if opts.ErrorReports {
-		tasks = append(tasks, cleanTask{"Error Reports", cleanErrorReports})
-	}
-	if opts.ThumbnailCache {
-		tasks = append(tasks, cleanTask{"Thumbnail Cache", cleanThumbnailCache})
-	}
-	if opts.IconCache {
-		tasks = append(tasks, cleanTask{"Icon Cache", cleanIconCache})
-	}
-	if opts.FontCache {
-		tasks = append(tasks, cleanTask{"Font Cache", cleanFontCache})
-	}
-	if opts.ShaderCache {
-		tasks = append(tasks, cleanTask{"Shader Cache", cleanShaderCache})
-	}
-	if opts.DNSCache {
-		tasks = append(tasks, cleanTask{"DNS Cache", cleanDNSCache})
-	}
-	if opts.WindowsLogs {
-		tasks = append(tasks, cleanTask{"Windows Log Files", cleanWindowsLogs})
-	}
-	if opts.EventLogs {
-		tasks = append(tasks, cleanTask{"Event Logs", cleanEventLogs})
-	}
-	if opts.DeliveryOptimization {
-		tasks = append(tasks, cleanTask{"Delivery Optimization", cleanDeliveryOptimization})
-	}
-	if opts.RecycleBin {
-		tasks = append(tasks, cleanTask{"Recycle Bin", cleanRecycleBin})
-	}
-	if opts.ChromeCache {
-		tasks = append(tasks, cleanTask{"Chrome Cache", cleanChromeCache})
-	}
-	if opts.FirefoxCache {
-		tasks = append(tasks, cleanTask{"Firefox Cache", cleanFirefoxCache})
-	}
-	if opts.EdgeCache {
-		tasks = append(tasks, cleanTask{"Edge Cache", cleanEdgeCache})
-	}
-	if opts.BraveCache {
-		tasks = append(tasks, cleanTask{"Brave Cache", cleanBraveCache})
-	}
-	if opts.OperaCache {
-		tasks = append(tasks, cleanTask{"Opera Cache", cleanOperaCache})
+	for _, c := range Categories() {
+		if opts.Enabled(c.ID) && c.Supported() && c.Detected() {
+			tasks = append(tasks, cleanTask{c, c.run})
+		}
 	}
-	if opts.DiscordCache {
-		tasks = append(tasks, cleanTask{"Discord Cache", cleanDiscordCache})
+
+	if opts.Events != nil && opts.events == nil {
+		var files, bytes int64
+		if !opts.DryRun {
+			scan := opts
+			scan.Events, scan.Progress = nil, nil
+			files, bytes = ScanContext(ctx, scan).Totals()
+		}
+		opts.events = newEventEmitter(opts.Events, files, bytes)
 	}
-	if opts.SpotifyCache {
-		tasks = append(tasks, cleanTask{"Spotify Cache", cleanSpotifyCache})
+	return runTasks(ctx, tasks, opts)
+}
+
+// RemovePaths deletes or quarantines a given list of paths as category c,
+// through the same pipeline as category cleaning: protected paths and
+// exclusions are refused, dry runs only count, files go to the quarantine
+// when opts.Quarantined(c), and events are reported under c. A directory is
+// emptied with the cleaner's walk and then removed with its empty
+// subdirectories. c does not need to be registered. reason explains the
+// selection in scan plans.
+func RemovePaths(ctx context.Context, c Category, paths []string, reason string, opts CleanOptions) CleanResult {
+	if opts.Events != nil && opts.events == nil {
+		// Totals are only known when every path is a file.
+		var files, bytes int64
+		for _, path := range paths {
+			info, err := os.Lstat(path)
+			if err != nil || info.IsDir() {
+				files, bytes = 0, 0
+				break
+			}
+			files++
+			bytes += info.Size()
+		}
+		opts.events = newEventEmitter(opts.Events, files, bytes)
 	}
-	if opts.SteamCache {
-		tasks = append(tasks, cleanTask{"Steam Cache", cleanSteamCache})
+
+	task := cleanTask{c, func(opts CleanOptions) CleanResult {
+		result := CleanResult{}
+		sw := newSweeper(c, opts)
+		for _, path := range paths {
+			if sw.stopped(&result) {
+				break
+			}
+			info, err := os.Lstat(path)
+			if err != nil {
+				result.Errors = append(result.Errors, ClassifyError(path, err))
+				continue
+			}
+			if !info.IsDir() {
+				sw.remove(path, info, reason, &result)
+				continue
+			}
+			dirResult := cleanDirectoryFiltered(path, fileFilter{}, sw)
+			result.merge(dirResult)
+			if !sw.dryRun && !dirResult.incomplete && sw.guard.check(path) == nil {
+				result.DirsRemoved += int64(removeEmptyDirs(path, true))
+			}
+		}
+		return result
+	}}
+	return runTasks(ctx, []cleanTask{task}, opts)
+}
+
+// runTasks runs cleaning tasks on the worker pool and merges their results.
+func runTasks(ctx context.Context, tasks []cleanTask, opts CleanOptions) CleanResult {
+	start := time.Now()
+	result := CleanResult{}
+	if opts.guard == nil {
+		opts.guard = newPathGuard(opts.ProtectedPaths, opts.Exclude)
 	}
-	if opts.TeamsCache {
-		tasks = append(tasks, cleanTask{"Teams Cache", cleanTeamsCache})
+	if opts.space == nil {
+		opts.space = newSpaceTracker()
 	}
-	if opts.VSCodeCache {
-		tasks = append(tasks, cleanTask{"VS Code Cache", cleanVSCodeCache})
+	if opts.running == nil {
+		opts.running = newProcessTable()
 	}
-	if opts.JavaCache {
-		tasks = append(tasks, cleanTask{"Java Cache", cleanJavaCache})
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
 	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	opts.ctx = ctx
 
 	if len(tasks) == 0 {
 		result.Duration = time.Since(start)
 		return result
 	}
 
-	// Run categories concurrently via worker pool
-	taskCh := make(chan cleanTask, len(tasks))
-	resultCh := make(chan CleanResult, len(tasks))
+	// Run categories concurrently via worker pool. Each category runs on
+	// its worker and stops by itself once ctx is done, so nothing is left
+	// running in the background when runTasks returns.
+	type indexedTask struct {
+		i int
+		cleanTask
+	}
+	type indexedResult struct {
+		i int
+		CleanResult
+	}
+	taskCh := make(chan indexedTask, len(tasks))
+	resultCh := make(chan indexedResult, len(tasks))
 
 	workers := maxCleanWorkers
 	if len(tasks) < workers {
@@ -235,13 +493,13 @@ func PerformClean(opts CleanOptions) CleanResult {
 		go func() {
 			defer wg.Done()
 			for task := range taskCh {
-				resultCh <- cleanCategory(ctx, task.name, task.fn, opts)
+				resultCh <- indexedResult{task.i, cleanCategory(ctx, task.cleanTask, opts)}
 			}
 		}()
 	}
 
-	for _, t := range tasks {
-		taskCh <- t
+	for i, t := range tasks {
+		taskCh <- indexedTask{i, t}
 	}
 	close(taskCh)
 
@@ -251,8 +509,35 @@ func PerformClean(opts CleanOptions) CleanResult {
 		close(resultCh)
 	}()
 
+	statuses := make([]CategoryStatus, len(tasks))
 	for r := range resultCh {
-		result.merge(r)
+		statuses[r.i] = r.Categories[0]
+		result.merge(r.CleanResult)
+	}
+	result.Categories = statuses
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("[SysCleaner] Cleanup stopped after the %s deadline", timeout)
+		result.Errors = append(result.Errors, fmt.Errorf("cleanup stopped after the %s deadline", timeout))
+	}
+
+	if opts.Archive != nil {
+		archives, size, err := opts.Archive.Close()
+		result.Archives, result.ArchiveSize = archives, size
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+	}
+	if opts.Quarantine != nil {
+		result.QuarantineRun = opts.Quarantine.RunID()
+		if n := opts.Quarantine.LeftInPlace(); n > 0 {
+			result.Errors = append(result.Errors, fmt.Errorf("%w: %d file(s) were left in place", ErrQuarantineFull, n))
+		}
+	}
+
+	if !opts.DryRun {
+		result.DiskFreeDelta = opts.space.diskFreeDelta()
+		log.Printf("[SysCleaner] Reclaimed %s on disk; free space grew by %s",
+			FormatBytes(result.SpaceReclaimed), FormatBytes(result.DiskFreeDelta))
 	}
 
 	result.Duration = time.Since(start)
@@ -261,636 +546,553 @@ func PerformClean(opts CleanOptions) CleanResult {
 	return result
 }
 
+// NetReclaimed is the disk space the run gained: what removing files
+// reclaimed minus what the archive bundles it wrote take up.
+func (r CleanResult) NetReclaimed() int64 {
+	return r.SpaceReclaimed - r.ArchiveSize
+}
+
 func (r *CleanResult) merge(other CleanResult) {
 	r.FilesDeleted += other.FilesDeleted
 	r.SkippedFiles += other.SkippedFiles
 	r.SpaceFreed += other.SpaceFreed
+	r.SpaceReclaimed += other.SpaceReclaimed
 	r.LockedFiles += other.LockedFiles
 	r.PermissionFiles += other.PermissionFiles
+	r.ChangedFiles += other.ChangedFiles
+	r.DirsRemoved += other.DirsRemoved
+	r.FilesQuarantined += other.FilesQuarantined
+	r.SpaceQuarantined += other.SpaceQuarantined
+	r.RecordsDeleted += other.RecordsDeleted
+	r.FilesArchived += other.FilesArchived
+	r.SpaceArchived += other.SpaceArchived
+	r.ArchiveSize += other.ArchiveSize
+	r.Archives = append(r.Archives, other.Archives...)
+	r.Locked = append(r.Locked, other.Locked...)
 	r.Errors = append(r.Errors, other.Errors...)
+	r.DryRunItems = append(r.DryRunItems, other.DryRunItems...)
+	r.BrowserProfiles = append(r.BrowserProfiles, other.BrowserProfiles...)
+	r.Games = append(r.Games, other.Games...)
+	r.SkippedLinks = append(r.SkippedLinks, other.SkippedLinks...)
+	r.SkippedMounts = append(r.SkippedMounts, other.SkippedMounts...)
+	r.Categories = append(r.Categories, other.Categories...)
+	r.incomplete = r.incomplete || other.incomplete
 }
 
-// cleanCategory runs a category cleaning function with timeout and progress reporting
-func cleanCategory(ctx context.Context, category string, fn func(CleanOptions) CleanResult, opts CleanOptions) CleanResult {
-	log.Printf("[SysCleaner] Cleaning %s...", category)
-
-	if opts.Progress != nil {
-		opts.Progress(category, 0, 100)
+// cleanCategory runs a category cleaning function with progress reporting and
+// records its status. The result always holds exactly one CategoryStatus.
+// A category whose owning application is running is handled by its
+// RunningPolicy first.
+func cleanCategory(ctx context.Context, task cleanTask, opts CleanOptions) CleanResult {
+	id, name := task.category.ID, task.category.Name
+	status := CategoryStatus{ID: id, Name: name, Status: StatusCancelled}
+	finished := func() {
+		opts.events.emit(ProgressEvent{Kind: EventCategoryFinished, Category: id, Name: name, Status: status.Status, Reason: status.Reason})
 	}
-
-	done := make(chan CleanResult, 1)
-	go func() {
-		done <- fn(opts)
-	}()
-
-	select {
-	case result := <-done:
-		if opts.Progress != nil {
-			opts.Progress(category, 100, 100)
-		}
-		return result
-	case <-ctx.Done():
-		log.Printf("[SysCleaner] %s cleaning timed out", category)
-		return CleanResult{Errors: []error{fmt.Errorf("%s cleaning timed out", category)}}
+	if ctx.Err() != nil {
+		finished()
+		return CleanResult{Categories: []CategoryStatus{status}}
 	}
-}
 
-// cleanDirectory removes files in a directory with timeouts and proper error handling
-func cleanDirectory(dir string, maxAge time.Duration, dryRun bool) CleanResult {
-	result := CleanResult{}
-
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		return result
+	run, reason := checkRunning(task.category, opts)
+	status.Reason = reason
+	if !run {
+		status.Status = StatusSkipped
+		finished()
+		return CleanResult{Categories: []CategoryStatus{status}}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), dirTimeout)
-	defer cancel()
-
-	done := make(chan CleanResult, 1)
-	go func() {
-		r := cleanDirectoryInternal(dir, maxAge, dryRun)
-		done <- r
-	}()
-
-	select {
-	case r := <-done:
-		return r
-	case <-ctx.Done():
-		log.Printf("[SysCleaner] Directory cleanup timed out: %s", dir)
-		result.Errors = append(result.Errors, fmt.Errorf("timeout cleaning %s", dir))
-		return result
+	log.Printf("[SysCleaner] Cleaning %s...", name)
+	if opts.Progress != nil {
+		opts.Progress(name, 0, 100)
 	}
-}
+	opts.events.emit(ProgressEvent{Kind: EventCategoryStarted, Category: id, Name: name})
 
-func cleanDirectoryInternal(dir string, maxAge time.Duration, dryRun bool) CleanResult {
-	result := CleanResult{}
-	now := time.Now()
-
-	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			// Skip inaccessible directories gracefully
-			if d != nil && d.IsDir() {
-				log.Printf("[SysCleaner] Skipping inaccessible directory: %s", path)
-				return filepath.SkipDir
-			}
-			result.Errors = append(result.Errors, fmt.Errorf("walk error %s: %w", path, err))
-			return nil
-		}
-
-		if d.IsDir() {
-			return nil
-		}
-
-		info, err := d.Info()
-		if err != nil {
-			result.Errors = append(result.Errors, err)
-			return nil
-		}
-
-		// Skip files newer than maxAge if specified
-		if maxAge > 0 && now.Sub(info.ModTime()) < maxAge {
-			return nil
-		}
-
-		if dryRun {
-			result.FilesDeleted++
-			result.SpaceFreed += info.Size()
-		} else {
-			if err := os.Remove(path); err != nil {
-				ce := classifyError(path, err)
-				switch ce.Type {
-				case ErrorLocked, ErrorTimeout:
-					result.SkippedFiles++
-					result.LockedFiles++
-				case ErrorPermissionDenied:
-					result.SkippedFiles++
-					result.PermissionFiles++
-				default:
-					result.Errors = append(result.Errors, ce)
-				}
-			} else {
-				result.FilesDeleted++
-				result.SpaceFreed += info.Size()
-			}
-		}
-		return nil
-	})
+	start := time.Now()
+	result := task.fn(opts)
+	status.Duration = time.Since(start)
+	status.FilesDeleted = result.FilesDeleted
+	status.SpaceFreed = result.SpaceFreed
+	if result.incomplete {
+		status.Status = StatusPartial
+		log.Printf("[SysCleaner] %s stopped before finishing", name)
+	} else {
+		status.Status = StatusCompleted
+	}
 
-	if err != nil {
-		result.Errors = append(result.Errors, err)
+	if opts.Progress != nil {
+		opts.Progress(name, 100, 100)
 	}
+	finished()
+	result.Categories = []CategoryStatus{status}
 	return result
 }
 
-// System category cleaners
-func cleanWindowsTemp(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	winDir := os.Getenv("WINDIR")
-	if winDir == "" {
-		return CleanResult{}
-	}
-	return cleanDirectory(filepath.Join(winDir, "Temp"), 0, opts.DryRun)
-}
+// fileFilter selects which files inside a cleaned directory are eligible for
+// removal. The zero value matches every file at any depth.
+type fileFilter struct {
+	MaxAge  time.Duration // Only remove files older than this (0 = no filter)
+	Include []string      // Glob patterns a file must match (empty = all files)
+	Exclude []string      // Glob patterns for files and directories to leave alone
+	Shallow bool          // Only consider files directly inside the directory
 
-func cleanUserTemp(opts CleanOptions) CleanResult {
-	result := CleanResult{}
-	tempDirs := []string{os.Getenv("TEMP"), os.Getenv("TMP")}
-	if runtime.GOOS == "windows" {
-		localAppData := os.Getenv("LOCALAPPDATA")
-		if localAppData != "" {
-			tempDirs = append(tempDirs, filepath.Join(localAppData, "Temp"))
-		}
-	}
-	for _, dir := range dedup(tempDirs) {
-		if dir != "" {
-			result.merge(cleanDirectory(dir, 0, opts.DryRun))
-		}
-	}
-	return result
+	// Skip is an optional extra exclusion check on absolute paths.
+	Skip func(path string, isDir bool) bool
 }
 
-func cleanWindowsUpdate(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
+// reason describes why files under root are selected under policy, for scan
+// plans.
+func (f fileFilter) reason(root string, policy RetentionPolicy) string {
+	reason := "in " + root
+	conds := policy.conditions()
+	if len(f.Include) > 0 {
+		conds = append(conds, "matching "+strings.Join(f.Include, ", "))
 	}
-	winDir := os.Getenv("WINDIR")
-	if winDir == "" {
-		return CleanResult{}
+	if len(conds) > 0 {
+		reason += " (" + strings.Join(conds, "; ") + ")"
 	}
-	return cleanDirectory(filepath.Join(winDir, "SoftwareDistribution", "Download"), 0, opts.DryRun)
+	return reason
 }
 
-func cleanWindowsInstaller(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	winDir := os.Getenv("WINDIR")
-	if winDir == "" {
-		return CleanResult{}
-	}
-	return cleanDirectory(filepath.Join(winDir, "Installer", "$PatchCache$"), 0, opts.DryRun)
+// included reports whether a file under root passes the include patterns.
+func (f fileFilter) included(root, path string) bool {
+	return len(f.Include) == 0 || matchGlobs(f.Include, root, path)
 }
 
-func cleanPrefetch(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	winDir := os.Getenv("WINDIR")
-	if winDir == "" {
-		return CleanResult{}
+// excluded reports whether a file or directory under root matches an exclude
+// pattern or the Skip check.
+func (f fileFilter) excluded(root, path string, isDir bool) bool {
+	if f.Skip != nil && f.Skip(path, isDir) {
+		return true
 	}
-	// Only clean prefetch files older than 30 days
-	return cleanDirectory(filepath.Join(winDir, "Prefetch"), 30*24*time.Hour, opts.DryRun)
+	return len(f.Exclude) > 0 && matchGlobs(f.Exclude, root, path)
 }
 
-func cleanCrashDumps(opts CleanOptions) CleanResult {
-	result := CleanResult{}
-	if runtime.GOOS != "windows" {
-		return result
+// matchGlobs matches patterns against both the base name and the slash-separated
+// path relative to root, so "*.log" and "logs/*.txt" both work. Matching is
+// case-insensitive on Windows.
+func matchGlobs(patterns []string, root, path string) bool {
+	name := filepath.Base(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = name
 	}
-	localAppData := os.Getenv("LOCALAPPDATA")
-	winDir := os.Getenv("WINDIR")
-
-	dirs := []string{}
-	if localAppData != "" {
-		dirs = append(dirs, filepath.Join(localAppData, "CrashDumps"))
+	rel = filepath.ToSlash(rel)
+	if runtime.GOOS == "windows" {
+		name = strings.ToLower(name)
+		rel = strings.ToLower(rel)
 	}
-	if winDir != "" {
-		dirs = append(dirs, filepath.Join(winDir, "Minidump"))
-		// Windows memory dump file
-		memoryDump := filepath.Join(winDir, "MEMORY.DMP")
-		if info, err := os.Stat(memoryDump); err == nil {
-			if opts.DryRun {
-				result.FilesDeleted++
-				result.SpaceFreed += info.Size()
-			} else {
-				if err := os.Remove(memoryDump); err == nil {
-					result.FilesDeleted++
-					result.SpaceFreed += info.Size()
-				}
-			}
+	for _, p := range patterns {
+		p = filepath.ToSlash(p)
+		if runtime.GOOS == "windows" {
+			p = strings.ToLower(p)
+		}
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
 		}
 	}
-
-	for _, dir := range dirs {
-		result.merge(cleanDirectory(dir, 0, opts.DryRun))
-	}
-	return result
+	return false
 }
 
-func cleanErrorReports(opts CleanOptions) CleanResult {
-	result := CleanResult{}
-	if runtime.GOOS != "windows" {
-		return result
-	}
-	localAppData := os.Getenv("LOCALAPPDATA")
-	programData := os.Getenv("ProgramData")
+// sweeper removes the files a category selects, either permanently or by
+// moving them into the quarantine store, and records the outcome.
+type sweeper struct {
+	dryRun     bool
+	category   string
+	quarantine *Quarantine    // nil = delete permanently
+	archive    *Archiver      // Archive before deleting; nil = don't
+	collect    *planCollector // Records dry-run candidates for Scan
+	guard      *pathGuard     // nil = built-in protections only
+	crossFS    bool           // Walk into mounted filesystems
+	space      *spaceTracker  // nil = no hardlink dedup across calls
+	ctx        context.Context
+	dirTimeout time.Duration
+	events     *eventEmitter
+	retention  RetentionPolicy // Applied by cleanDirectoryFiltered
+	readOnly   bool            // Add write permission just before removing
+}
 
-	dirs := []string{}
-	if localAppData != "" {
-		dirs = append(dirs, filepath.Join(localAppData, "Microsoft", "Windows", "WER"))
+func newSweeper(c Category, opts CleanOptions) sweeper {
+	guard := opts.guard
+	if guard == nil {
+		guard = newPathGuard(opts.ProtectedPaths, opts.Exclude)
 	}
-	if programData != "" {
-		dirs = append(dirs, filepath.Join(programData, "Microsoft", "Windows", "WER"))
+	sw := sweeper{dryRun: opts.DryRun, category: c.ID, collect: opts.collect, guard: guard, crossFS: opts.CrossFilesystems, space: opts.space}
+	sw.ctx, sw.dirTimeout, sw.events = opts.ctx, opts.DirTimeout, opts.events
+	if opts.Quarantined(c) {
+		sw.quarantine = opts.Quarantine
 	}
-
-	for _, dir := range dirs {
-		result.merge(cleanDirectory(dir, 0, opts.DryRun))
+	if opts.Archived(c) {
+		sw.archive = opts.Archive
 	}
-	return result
+	sw.retention = opts.Retention[c.ID]
+	sw.readOnly = opts.readOnly
+	return sw
 }
 
-func cleanThumbnailCache(opts CleanOptions) CleanResult {
-	result := CleanResult{}
-	if runtime.GOOS != "windows" {
-		return result
-	}
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		return result
-	}
-
-	thumbDir := filepath.Join(localAppData, "Microsoft", "Windows", "Explorer")
-	entries, err := os.ReadDir(thumbDir)
-	if err != nil {
-		return result
+// remove deletes or quarantines a single file and accounts for it in result.
+// reason explains why the file was selected and ends up in scan plans.
+func (s sweeper) remove(path string, info os.FileInfo, reason string, result *CleanResult) {
+	if s.stopped(result) || !s.allowed(path, result) {
+		return
+	}
+	if s.dryRun {
+		result.FilesDeleted++
+		result.SpaceFreed += info.Size()
+		result.SpaceReclaimed += s.space.reclaim(s.space.prepare(path, info, false))
+		if s.collect != nil {
+			s.collect.add(s.category, PlanFile{Path: path, Size: info.Size(), ModTime: info.ModTime(), Reason: reason})
+		}
+		s.removed(path, info.Size())
+		return
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() && (strings.HasPrefix(entry.Name(), "thumbcache_") || strings.HasPrefix(entry.Name(), "iconcache_")) {
-			fpath := filepath.Join(thumbDir, entry.Name())
-			info, err := entry.Info()
-			if err != nil {
-				result.Errors = append(result.Errors, err)
-				continue
-			}
-			if opts.DryRun {
-				result.FilesDeleted++
-				result.SpaceFreed += info.Size()
-			} else {
-				if err := os.Remove(fpath); err != nil {
-					if strings.Contains(err.Error(), "timeout") {
-						result.SkippedFiles++
-					} else {
-						result.Errors = append(result.Errors, err)
-					}
-				} else {
-					result.FilesDeleted++
-					result.SpaceFreed += info.Size()
-				}
-			}
+	if s.readOnly {
+		addWritePermission(filepath.Dir(path))
+		if info.Mode().IsRegular() {
+			addWritePermission(path)
 		}
 	}
-	return result
-}
-
-func cleanIconCache(opts CleanOptions) CleanResult {
-	result := CleanResult{}
-	if runtime.GOOS != "windows" {
-		return result
+	var (
+		err   error
+		alloc fileAlloc
+	)
+	if s.quarantine != nil {
+		err = s.quarantine.Add(s.category, path, info)
+	} else if s.archive != nil {
+		if err = s.archive.Add(s.category, path, info); err == nil {
+			alloc = s.space.prepare(path, info, true)
+			err = os.Remove(path)
+		}
+		if err != nil {
+			// The file stays; so must not its copy, or the next clean
+			// archives it a second time.
+			s.archive.Discard(s.category, path)
+		}
+	} else {
+		alloc = s.space.prepare(path, info, true)
+		err = os.Remove(path)
 	}
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		return result
+	if err != nil {
+		if errors.Is(err, ErrQuarantineFull) {
+			result.SkippedFiles++
+			s.skipped(path, info.Size(), err.Error())
+			return
+		}
+		ce := ClassifyError(path, err)
+		switch ce.Type {
+		case ErrorLocked, ErrorTimeout:
+			result.SkippedFiles++
+			result.LockedFiles++
+			result.Locked = append(result.Locked, PendingFile{Path: path, Category: s.category, Size: info.Size()})
+		case ErrorPermissionDenied:
+			result.SkippedFiles++
+			result.PermissionFiles++
+		default:
+			result.Errors = append(result.Errors, ce)
+		}
+		s.skipped(path, info.Size(), ce.Err.Error())
+		return
 	}
 
-	iconCacheFile := filepath.Join(localAppData, "IconCache.db")
-	if info, err := os.Stat(iconCacheFile); err == nil {
-		if opts.DryRun {
-			result.FilesDeleted++
-			result.SpaceFreed += info.Size()
-		} else {
-			if err := os.Remove(iconCacheFile); err == nil {
-				result.FilesDeleted++
-				result.SpaceFreed += info.Size()
-			}
+	if s.quarantine != nil {
+		result.FilesQuarantined++
+		result.SpaceQuarantined += info.Size()
+	} else {
+		if s.archive != nil {
+			result.FilesArchived++
+			result.SpaceArchived += info.Size()
 		}
+		result.FilesDeleted++
+		result.SpaceFreed += info.Size()
+		result.SpaceReclaimed += s.space.reclaim(alloc)
 	}
-	return result
+	s.removed(path, info.Size())
 }
 
-func cleanFontCache(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	winDir := os.Getenv("WINDIR")
-	if winDir == "" {
-		return CleanResult{}
-	}
-	return cleanDirectory(filepath.Join(winDir, "ServiceProfiles", "LocalService", "AppData", "Local", "FontCache"), 0, opts.DryRun)
+// removed and skipped report the outcome of a candidate to the event stream.
+func (s sweeper) removed(path string, size int64) {
+	s.events.emit(ProgressEvent{Kind: EventFileRemoved, Category: s.category, Path: path, Size: size})
 }
 
-func cleanShaderCache(opts CleanOptions) CleanResult {
-	result := CleanResult{}
-	if runtime.GOOS != "windows" {
-		return result
-	}
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		return result
-	}
+func (s sweeper) skipped(path string, size int64, reason string) {
+	s.events.emit(ProgressEvent{Kind: EventFileSkipped, Category: s.category, Path: path, Size: size, Reason: reason})
+}
 
-	shaderDirs := []string{
-		filepath.Join(localAppData, "D3DSCache"),
-		filepath.Join(localAppData, "NVIDIA", "DXCache"),
-		filepath.Join(localAppData, "NVIDIA", "GLCache"),
-		filepath.Join(localAppData, "AMD", "DxCache"),
+// context returns the context the sweeper works under.
+func (s sweeper) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
 	}
+	return s.ctx
+}
 
-	for _, dir := range shaderDirs {
-		result.merge(cleanDirectory(dir, 0, opts.DryRun))
+// stopped reports whether the run was cancelled or hit a deadline, marking
+// result as incomplete if so. Loops over candidates check it per item.
+func (s sweeper) stopped(result *CleanResult) bool {
+	if s.context().Err() != nil {
+		result.incomplete = true
+		return true
 	}
-	return result
+	return false
 }
 
-func cleanDNSCache(opts CleanOptions) CleanResult {
-	result := CleanResult{}
-	if runtime.GOOS != "windows" || opts.DryRun {
-		return result
+// allowed checks path against the guard and records a refusal in result.
+func (s sweeper) allowed(path string, result *CleanResult) bool {
+	if ce := s.guard.check(path); ce != nil {
+		if ce.Type == ErrorProtected {
+			log.Printf("[SysCleaner] %v", ce)
+		}
+		result.Errors = append(result.Errors, ce)
+		s.skipped(path, 0, ce.Err.Error())
+		return false
 	}
+	return true
+}
 
-	if err := flushDNSCacheNative(); err != nil {
-		result.Errors = append(result.Errors, fmt.Errorf("failed to flush DNS cache: %w", err))
-	} else {
-		log.Println("[SysCleaner] DNS cache flushed")
-	}
-	return result
+// cleanDirectory removes files in a directory with timeouts and proper error handling
+func cleanDirectory(dir string, maxAge time.Duration, dryRun bool) CleanResult {
+	return cleanDirectoryFiltered(dir, fileFilter{MaxAge: maxAge}, sweeper{dryRun: dryRun, guard: newPathGuard(nil, nil)})
 }
 
-func cleanWindowsLogs(opts CleanOptions) CleanResult {
+// cleanDirectoryFiltered is cleanDirectory with full control over which files
+// are removed and how. If dir itself is a symlink (a cache moved to another
+// disk, say) its target is cleaned; links below it are never followed.
+func cleanDirectoryFiltered(dir string, filter fileFilter, sw sweeper) CleanResult {
 	result := CleanResult{}
-	if runtime.GOOS != "windows" {
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		return result
 	}
-	winDir := os.Getenv("WINDIR")
-	if winDir == "" {
+	if !sw.allowed(dir, &result) {
 		return result
 	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil && resolved != dir {
+		if !sw.allowed(resolved, &result) {
+			return result
+		}
+		log.Printf("[SysCleaner] %s is a link, cleaning %s", dir, resolved)
+		dir = resolved
+	}
 
-	logDirs := []string{
-		filepath.Join(winDir, "Logs"),
-		filepath.Join(winDir, "Debug"),
-		filepath.Join(winDir, "Panther"),
+	parent := sw.context()
+	timeout := sw.dirTimeout
+	if timeout <= 0 {
+		timeout = DefaultDirTimeout
 	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	sw.ctx = ctx
 
-	for _, dir := range logDirs {
-		result.merge(cleanDirectory(dir, 30*24*time.Hour, opts.DryRun))
+	result = cleanDirectoryInternal(dir, filter, sw)
+	if ctx.Err() != nil && parent.Err() == nil {
+		log.Printf("[SysCleaner] Directory cleanup timed out: %s", dir)
+		result.Errors = append(result.Errors, fmt.Errorf("timeout cleaning %s after %s", dir, timeout))
 	}
 	return result
 }
 
-func cleanEventLogs(opts CleanOptions) CleanResult {
+// cleanDirectoryInternal removes the files of dir that pass filter and the
+// sweeper's retention policy. The filter's MaxAge is the policy's default
+// MinAge. Without KeepNewest or MaxSize files are removed as they are found;
+// otherwise the whole directory is walked first. The folders left empty are
+// removed afterwards, dir itself excepted.
+func cleanDirectoryInternal(dir string, filter fileFilter, sw sweeper) CleanResult {
 	result := CleanResult{}
-	if runtime.GOOS != "windows" || opts.DryRun {
-		return result
+	policy := sw.retention.WithDefaultAge(filter.MaxAge)
+	sel := newRetentionSelector(policy)
+	reason := filter.reason(dir, policy)
+	emptied := emptiedDirs{}
+	remove := func(path string, info os.FileInfo) bool {
+		if !sw.dryRun {
+			emptied.note(path)
+		}
+		before := result.FilesDeleted + result.FilesQuarantined
+		sw.remove(path, info, reason, &result)
+		return result.FilesDeleted+result.FilesQuarantined > before
 	}
 
-	// NOTE: Security event log is intentionally excluded — clearing it is an
-	// anti-forensics indicator that triggers AV heuristics.
-	for _, logName := range []string{"System", "Application"} {
-		if err := clearEventLogNative(logName); err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("failed to clear %s event log: %w", logName, err))
-		} else {
-			log.Printf("[SysCleaner] Cleared %s event log", logName)
+	skipDir := func(path string) bool {
+		return filter.Shallow || filter.excluded(dir, path, true) || !sw.allowed(path, &result)
+	}
+	walker{ctx: sw.context(), crossFS: sw.crossFS, result: &result}.walk(dir, skipDir, func(path string, info os.FileInfo) {
+		sel.total += info.Size()
+		if !filter.included(dir, path) || filter.excluded(dir, path, false) {
+			return
 		}
+		if !sel.streaming() {
+			sel.add(path, info)
+		} else if sel.eligible(info) {
+			remove(path, info)
+		}
+	})
+	sel.selected(remove)
+	if len(emptied) > 0 && !result.incomplete {
+		result.DirsRemoved += emptied.prune(dir, filter, policy, sw)
 	}
 	return result
 }
 
-func cleanDeliveryOptimization(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	winDir := os.Getenv("WINDIR")
-	if winDir == "" {
-		return CleanResult{}
+// removeEmptyDirs removes empty directories below root, deepest first, and
+// root itself when removeRoot is set. Directories that still contain files
+// are left alone. It returns the number of directories removed.
+func removeEmptyDirs(root string, removeRoot bool) int {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() && (removeRoot || path != root) {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+
+	removed := 0
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Remove(dirs[i]); err == nil {
+			removed++
+		}
 	}
-	return cleanDirectory(filepath.Join(winDir, "SoftwareDistribution", "DeliveryOptimization"), 0, opts.DryRun)
+	return removed
 }
 
-func cleanRecycleBin(opts CleanOptions) CleanResult {
+// Special-case category cleaners. Categories that only clear directories are
+// declared entirely in the registry; the functions below handle the rest.
+
+func cleanCrashDumps(c Category, opts CleanOptions) CleanResult {
 	result := CleanResult{}
-	if runtime.GOOS != "windows" || opts.DryRun {
-		return result
-	}
+	sw := newSweeper(c, opts)
 
-	if err := emptyRecycleBinNative(); err != nil {
-		result.Errors = append(result.Errors, fmt.Errorf("failed to clear recycle bin: %w", err))
-	} else {
-		log.Println("[SysCleaner] Recycle Bin cleared")
+	// Windows memory dump file
+	if winDir := os.Getenv("WINDIR"); winDir != "" {
+		memoryDump := filepath.Join(winDir, "MEMORY.DMP")
+		if info, err := os.Stat(memoryDump); err == nil {
+			sw.remove(memoryDump, info, "Windows memory dump", &result)
+		}
 	}
+
+	result.merge(cleanPaths(c, opts))
 	return result
 }
 
-// Application category cleaners
-func cleanChromiumProfiles(userDataDir string, dryRun bool) CleanResult {
+func cleanThumbnailCache(c Category, opts CleanOptions) CleanResult {
 	result := CleanResult{}
-	if _, err := os.Stat(userDataDir); os.IsNotExist(err) {
-		return result
-	}
-
-	cacheSubdirs := []string{"Cache", "Code Cache", "GPUCache", "Service Worker", "ShaderCache"}
+	sw := newSweeper(c, opts)
 
-	entries, err := os.ReadDir(userDataDir)
-	if err != nil {
-		return result
-	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for _, thumbDir := range c.ResolvePaths() {
+		entries, err := os.ReadDir(thumbDir)
+		if err != nil {
 			continue
 		}
-		name := entry.Name()
-		if name == "Default" || strings.HasPrefix(name, "Profile ") {
-			for _, sub := range cacheSubdirs {
-				cacheDir := filepath.Join(userDataDir, name, sub)
-				result.merge(cleanDirectory(cacheDir, 0, dryRun))
+
+		for _, entry := range entries {
+			if !entry.IsDir() && (strings.HasPrefix(entry.Name(), "thumbcache_") || strings.HasPrefix(entry.Name(), "iconcache_")) {
+				fpath := filepath.Join(thumbDir, entry.Name())
+				info, err := entry.Info()
+				if err != nil {
+					result.Errors = append(result.Errors, err)
+					continue
+				}
+				sw.remove(fpath, info, "Explorer thumbnail cache database", &result)
 			}
 		}
 	}
 	return result
 }
 
-func cleanChromeCache(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
+func cleanIconCache(c Category, opts CleanOptions) CleanResult {
+	result := CleanResult{}
 	localAppData := os.Getenv("LOCALAPPDATA")
 	if localAppData == "" {
-		return CleanResult{}
-	}
-	return cleanChromiumProfiles(filepath.Join(localAppData, "Google", "Chrome", "User Data"), opts.DryRun)
-}
-
-func cleanFirefoxCache(opts CleanOptions) CleanResult {
-	result := CleanResult{}
-	if runtime.GOOS != "windows" {
-		return result
-	}
-	appData := os.Getenv("APPDATA")
-	if appData == "" {
-		return result
-	}
-
-	profilesDir := filepath.Join(appData, "Mozilla", "Firefox", "Profiles")
-	entries, err := os.ReadDir(profilesDir)
-	if err != nil {
 		return result
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			result.merge(cleanDirectory(filepath.Join(profilesDir, entry.Name(), "cache2"), 0, opts.DryRun))
-			result.merge(cleanDirectory(filepath.Join(profilesDir, entry.Name(), "startupCache"), 0, opts.DryRun))
-		}
+	iconCacheFile := filepath.Join(localAppData, "IconCache.db")
+	if info, err := os.Stat(iconCacheFile); err == nil {
+		newSweeper(c, opts).remove(iconCacheFile, info, "Explorer icon cache database", &result)
 	}
 	return result
 }
 
-func cleanEdgeCache(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		return CleanResult{}
-	}
-	return cleanChromiumProfiles(filepath.Join(localAppData, "Microsoft", "Edge", "User Data"), opts.DryRun)
-}
-
-func cleanBraveCache(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		return CleanResult{}
-	}
-	return cleanChromiumProfiles(filepath.Join(localAppData, "BraveSoftware", "Brave-Browser", "User Data"), opts.DryRun)
-}
-
-func cleanOperaCache(opts CleanOptions) CleanResult {
+func cleanDNSCache(c Category, opts CleanOptions) CleanResult {
 	result := CleanResult{}
-	if runtime.GOOS != "windows" {
-		return result
-	}
-	appData := os.Getenv("APPDATA")
-	if appData == "" {
+	if opts.DryRun {
 		return result
 	}
 
-	operaDirs := []string{
-		filepath.Join(appData, "Opera Software", "Opera Stable"),
-		filepath.Join(appData, "Opera Software", "Opera GX Stable"),
-	}
-
-	for _, dir := range operaDirs {
-		result.merge(cleanChromiumProfiles(dir, opts.DryRun))
+	if err := flushDNSCacheNative(); err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to flush DNS cache: %w", err))
+	} else {
+		log.Println("[SysCleaner] DNS cache flushed")
 	}
 	return result
 }
 
-func cleanDiscordCache(opts CleanOptions) CleanResult {
+func cleanEventLogs(c Category, opts CleanOptions) CleanResult {
 	result := CleanResult{}
-	if runtime.GOOS != "windows" {
+	if opts.DryRun {
 		return result
 	}
-	appData := os.Getenv("APPDATA")
-	if appData == "" {
-		return result
-	}
-
-	discordDirs := []string{
-		filepath.Join(appData, "discord", "Cache"),
-		filepath.Join(appData, "discord", "Code Cache"),
-		filepath.Join(appData, "discord", "GPUCache"),
-	}
 
-	for _, dir := range discordDirs {
-		result.merge(cleanDirectory(dir, 0, opts.DryRun))
+	// NOTE: Security event log is intentionally excluded — clearing it is an
+	// anti-forensics indicator that triggers AV heuristics.
+	for _, logName := range []string{"System", "Application"} {
+		if err := clearEventLogNative(logName); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to clear %s event log: %w", logName, err))
+		} else {
+			log.Printf("[SysCleaner] Cleared %s event log", logName)
+		}
 	}
 	return result
 }
 
-func cleanSpotifyCache(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		return CleanResult{}
-	}
-	return cleanDirectory(filepath.Join(localAppData, "Spotify", "Storage"), 0, opts.DryRun)
-}
-
-func cleanSteamCache(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		return CleanResult{}
-	}
-	return cleanDirectory(filepath.Join(localAppData, "Steam", "htmlcache"), 0, opts.DryRun)
-}
-
-func cleanTeamsCache(opts CleanOptions) CleanResult {
+func cleanRecycleBin(c Category, opts CleanOptions) CleanResult {
 	result := CleanResult{}
-	if runtime.GOOS != "windows" {
+	if opts.DryRun {
 		return result
 	}
-	appData := os.Getenv("APPDATA")
-	if appData == "" {
-		return result
-	}
-
-	teamsDirs := []string{
-		filepath.Join(appData, "Microsoft", "Teams", "Cache"),
-		filepath.Join(appData, "Microsoft", "Teams", "blob_storage"),
-		filepath.Join(appData, "Microsoft", "Teams", "GPUCache"),
-	}
 
-	for _, dir := range teamsDirs {
-		result.merge(cleanDirectory(dir, 0, opts.DryRun))
+	if err := emptyRecycleBinNative(); err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to clear recycle bin: %w", err))
+	} else {
+		log.Println("[SysCleaner] Recycle Bin cleared")
 	}
 	return result
 }
 
-func cleanVSCodeCache(opts CleanOptions) CleanResult {
-	result := CleanResult{}
-	if runtime.GOOS != "windows" {
-		return result
-	}
-	appData := os.Getenv("APPDATA")
-	if appData == "" {
-		return result
-	}
-
-	vscodeDirs := []string{
-		filepath.Join(appData, "Code", "Cache"),
-		filepath.Join(appData, "Code", "CachedData"),
-		filepath.Join(appData, "Code", "CachedExtensions"),
+// cleanXDGCache cleans $XDG_CACHE_HOME while leaving alone directories that
+// another selected category (thumbnails, browser caches, ...) is cleaning in
+// the same run, so two workers never walk the same tree.
+func cleanXDGCache(c Category, opts CleanOptions) CleanResult {
+	owned := map[string]bool{}
+	for _, other := range Categories() {
+		if other.ID != c.ID && opts.Enabled(other.ID) && other.Supported() {
+			for _, p := range other.ResolvePaths() {
+				owned[filepath.Clean(p)] = true
+			}
+		}
 	}
 
-	for _, dir := range vscodeDirs {
-		result.merge(cleanDirectory(dir, 0, opts.DryRun))
+	result := CleanResult{}
+	for _, dir := range c.ResolvePaths() {
+		filter := fileFilter{
+			MaxAge: c.MaxAge,
+			Skip: func(path string, isDir bool) bool {
+				return isDir && owned[path]
+			},
+		}
+		result.merge(cleanDirectoryFiltered(dir, filter, newSweeper(c, opts)))
 	}
 	return result
 }
 
-func cleanJavaCache(opts CleanOptions) CleanResult {
-	if runtime.GOOS != "windows" {
-		return CleanResult{}
-	}
-	userProfile := os.Getenv("USERPROFILE")
-	if userProfile == "" {
-		return CleanResult{}
-	}
-	return cleanDirectory(filepath.Join(userProfile, "AppData", "LocalLow", "Sun", "Java", "Deployment", "cache"), 0, opts.DryRun)
-}
-
 // FormatBytes formats a byte count into a human-readable string
 func FormatBytes(bytes int64) string {
 	const (
@@ -910,6 +1112,66 @@ func FormatBytes(bytes int64) string {
 	}
 }
 
+// ParseAge parses an age such as "36h", "7d" or "2w". In addition to the
+// units accepted by time.ParseDuration it understands days (d) and weeks (w).
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// FormatAge is the inverse of ParseAge. Whole days are written as "Nd".
+func FormatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d == 0:
+		return "0"
+	case d%day == 0:
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	default:
+		return d.String()
+	}
+}
+
+// ParseSize parses a size such as "500", "64K", "1.5MB" or "2G". Units are
+// powers of 1024, as in FormatBytes; "B" and a trailing "B" or "iB" are
+// optional.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	n := strings.ToUpper(s)
+	n = strings.TrimSuffix(strings.TrimSuffix(n, "IB"), "B")
+	unit := int64(1)
+	if len(n) > 0 {
+		if i := strings.IndexByte("KMGT", n[len(n)-1]); i >= 0 {
+			unit = 1 << (10 * (i + 1))
+			n = n[:len(n)-1]
+		}
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(v * float64(unit)), nil
+}
+
 func dedup(ss []string) []string {
 	seen := map[string]bool{}
 	out := []string{}
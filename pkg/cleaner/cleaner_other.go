@@ -1,3 +1,66 @@
 //go:build !windows
 
 package cleaner
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func flushDNSCacheNative() error {
+	return fmt.Errorf("DNS cache flush not available on this platform")
+}
+
+func clearEventLogNative(channelPath string) error {
+	return fmt.Errorf("event log clearing not available on this platform")
+}
+
+func emptyRecycleBinNative() error {
+	return fmt.Errorf("recycle bin not available on this platform")
+}
+
+func registryKeyExists(path string) bool {
+	return false
+}
+
+func registryString(path, name string) string {
+	return ""
+}
+
+func deleteOnReboot(path string) error {
+	return ErrRebootDeleteUnsupported
+}
+
+// deviceID returns the ID of the filesystem info lives on.
+func deviceID(info os.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
+
+// fileAllocation reads the allocated blocks and link count from the stat
+// information. st_blocks is always in 512-byte units.
+func fileAllocation(path string, info os.FileInfo) (fileAlloc, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileAlloc{}, false
+	}
+	return fileAlloc{
+		key:       fileKey{dev: uint64(st.Dev), ino: uint64(st.Ino)},
+		links:     uint64(st.Nlink),
+		allocated: int64(st.Blocks) * 512,
+	}, true
+}
+
+// diskFree returns the bytes available to unprivileged users on the
+// filesystem holding path.
+func diskFree(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
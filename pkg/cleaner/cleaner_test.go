@@ -1,6 +1,7 @@
 package cleaner
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
@@ -117,11 +118,11 @@ func TestCleanDirectory_SubdirFiles(t *testing.T) {
 	}
 }
 
-// ---------- classifyError tests ----------
+// ---------- ClassifyError tests ----------
 
 func TestClassifyError_PermissionDenied(t *testing.T) {
 	err := os.ErrPermission
-	ce := classifyError("/some/path", err)
+	ce := ClassifyError("/some/path", err)
 
 	if ce.Type != ErrorPermissionDenied {
 		t.Errorf("expected ErrorPermissionDenied, got %d", ce.Type)
@@ -133,7 +134,7 @@ func TestClassifyError_PermissionDenied(t *testing.T) {
 
 func TestClassifyError_NotExist(t *testing.T) {
 	err := os.ErrNotExist
-	ce := classifyError("/missing/file", err)
+	ce := ClassifyError("/missing/file", err)
 
 	if ce.Type != ErrorNotFound {
 		t.Errorf("expected ErrorNotFound, got %d", ce.Type)
@@ -142,7 +143,7 @@ func TestClassifyError_NotExist(t *testing.T) {
 
 func TestClassifyError_Locked(t *testing.T) {
 	err := errors.New("the file is used by another process")
-	ce := classifyError("/locked/file", err)
+	ce := ClassifyError("/locked/file", err)
 
 	if ce.Type != ErrorLocked {
 		t.Errorf("expected ErrorLocked, got %d", ce.Type)
@@ -151,7 +152,7 @@ func TestClassifyError_Locked(t *testing.T) {
 
 func TestClassifyError_LockedSharingViolation(t *testing.T) {
 	err := errors.New("sharing violation on resource")
-	ce := classifyError("/locked/file2", err)
+	ce := ClassifyError("/locked/file2", err)
 
 	if ce.Type != ErrorLocked {
 		t.Errorf("expected ErrorLocked for sharing violation, got %d", ce.Type)
@@ -160,7 +161,7 @@ func TestClassifyError_LockedSharingViolation(t *testing.T) {
 
 func TestClassifyError_Timeout(t *testing.T) {
 	err := errors.New("operation timeout")
-	ce := classifyError("/slow/file", err)
+	ce := ClassifyError("/slow/file", err)
 
 	if ce.Type != ErrorTimeout {
 		t.Errorf("expected ErrorTimeout, got %d", ce.Type)
@@ -169,7 +170,7 @@ func TestClassifyError_Timeout(t *testing.T) {
 
 func TestClassifyError_Other(t *testing.T) {
 	err := errors.New("some random failure")
-	ce := classifyError("/other/file", err)
+	ce := ClassifyError("/other/file", err)
 
 	if ce.Type != ErrorOther {
 		t.Errorf("expected ErrorOther, got %d", ce.Type)
@@ -252,3 +253,226 @@ func TestCleanResult_Merge(t *testing.T) {
 		t.Errorf("expected 2 errors, got %d", len(a.Errors))
 	}
 }
+
+// ---------- Category registry tests ----------
+
+func TestBuiltinCategories_UniqueIDsAndFlags(t *testing.T) {
+	ids := map[string]bool{}
+	flags := map[string]bool{}
+	for _, c := range Categories() {
+		if c.ID == "" || c.Name == "" || c.Flag == "" {
+			t.Errorf("category %+v is missing an ID, name or flag", c)
+		}
+		if ids[c.ID] {
+			t.Errorf("duplicate category ID %q", c.ID)
+		}
+		if flags[c.Flag] {
+			t.Errorf("duplicate category flag %q", c.Flag)
+		}
+		ids[c.ID] = true
+		flags[c.Flag] = true
+	}
+}
+
+func TestEnableAll_LeavesOutOptInGroups(t *testing.T) {
+	var opts CleanOptions
+	opts.EnableAll()
+	if !opts.HasSelection() {
+		t.Fatal("expected --all to select categories")
+	}
+	for _, c := range Categories() {
+		if !c.Group.InAll() && opts.Enabled(c.ID) {
+			t.Errorf("--all must not select the %s category %s", c.Group, c.ID)
+		}
+	}
+	if !opts.Enabled("windows_temp") {
+		t.Error("expected --all to select the system categories")
+	}
+	for _, g := range []Group{GroupPrivacy, GroupDeveloper, GroupCustom, GroupWinapp2} {
+		if g.InAll() {
+			t.Errorf("expected the %s group to be opt-in", g)
+		}
+	}
+}
+
+func TestRegister_RejectsDuplicateID(t *testing.T) {
+	if err := Register(Category{ID: "windows_temp", Name: "Duplicate"}); err == nil {
+		t.Error("expected error registering a duplicate category ID")
+	}
+}
+
+func TestLookupCategory(t *testing.T) {
+	c, ok := LookupCategory("prefetch")
+	if !ok {
+		t.Fatal("expected prefetch category to be registered")
+	}
+	if c.MaxAge != 30*24*time.Hour {
+		t.Errorf("expected prefetch MaxAge=30 days, got %s", c.MaxAge)
+	}
+	if _, ok := LookupCategory("does_not_exist"); ok {
+		t.Error("expected lookup of unknown category to fail")
+	}
+}
+
+func TestPerformClean_RunsOnlySelectedCategories(t *testing.T) {
+	selected := t.TempDir()
+	unselected := t.TempDir()
+	createTempFiles(t, selected, 2)
+	createTempFiles(t, unselected, 2)
+
+	for id, dir := range map[string]string{"test_selected": selected, "test_unselected": unselected} {
+		dir := dir
+		if err := Register(Category{
+			ID:    id,
+			Name:  id,
+			Group: GroupApplications,
+			Paths: []PathResolver{func() []string { return []string{dir} }},
+		}); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+
+	opts := CleanOptions{}
+	opts.Enable("test_selected")
+	result := PerformClean(opts)
+
+	if result.FilesDeleted != 2 {
+		t.Errorf("expected 2 files deleted, got %d", result.FilesDeleted)
+	}
+	if entries, _ := os.ReadDir(unselected); len(entries) != 2 {
+		t.Errorf("expected unselected category to be untouched, found %d files", len(entries))
+	}
+}
+
+// ---------- Linux category tests ----------
+
+func TestXDGPath_FallsBackToHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Setenv("XDG_CACHE_HOME", "relative/cache")
+	if got := xdgCache("thumbnails")(); len(got) != 1 || got[0] != filepath.Join(home, ".cache", "thumbnails") {
+		t.Errorf("expected fallback below $HOME for a relative XDG_CACHE_HOME, got %v", got)
+	}
+
+	custom := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", custom)
+	if got := xdgCache("thumbnails")(); len(got) != 1 || got[0] != filepath.Join(custom, "thumbnails") {
+		t.Errorf("expected path below XDG_CACHE_HOME, got %v", got)
+	}
+}
+
+func TestCleanXDGCache_SkipsDirsOfOtherSelectedCategories(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	writeFile(t, filepath.Join(cache, "someapp", "a.bin"), "x")
+	writeFile(t, filepath.Join(cache, "someapp", "b.bin"), "x")
+	writeFile(t, filepath.Join(cache, "thumbnails", "normal", "c.png"), "x")
+
+	c, ok := LookupCategory("xdg_cache")
+	if !ok {
+		t.Fatal("expected xdg_cache category to be registered")
+	}
+
+	opts := CleanOptions{DryRun: true}
+	opts.Enable("xdg_cache")
+	if result := c.run(opts); result.FilesDeleted != 3 {
+		t.Errorf("expected 3 files on its own, got %d", result.FilesDeleted)
+	}
+
+	opts.Enable("xdg_thumbnails")
+	if result := c.run(opts); result.FilesDeleted != 2 {
+		t.Errorf("expected thumbnails to be left to their own category, got %d files", result.FilesDeleted)
+	}
+}
+
+func TestChromiumCache_LinuxLayout(t *testing.T) {
+	browserHome(t)
+	config, cache := os.Getenv("XDG_CONFIG_HOME"), os.Getenv("XDG_CACHE_HOME")
+	writeFile(t, filepath.Join(config, "chromium", "Default", "GPUCache", "data_0"), "x")
+	writeFile(t, filepath.Join(config, "chromium", "Profile 1", "Code Cache", "js", "index"), "x")
+	writeFile(t, filepath.Join(config, "chromium", "Default", "Preferences"), "x")
+	writeFile(t, filepath.Join(cache, "chromium", "Default", "Cache", "Cache_Data", "f_000001"), "x")
+
+	c, _ := LookupCategory("chromium_cache")
+	if result := c.run(CleanOptions{DryRun: true}); result.FilesDeleted != 3 {
+		t.Errorf("expected only the 3 cache files, got %d", result.FilesDeleted)
+	}
+}
+
+// ---------- cancellation tests ----------
+
+func TestPerformCleanContext_CancelledBeforeStart(t *testing.T) {
+	dir := t.TempDir()
+	createTempFiles(t, dir, 3)
+	registerDirCategory(t, "test_cancel_before", dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := CleanOptions{}
+	opts.Enable("test_cancel_before")
+	result := PerformCleanContext(ctx, opts)
+
+	if result.FilesDeleted != 0 {
+		t.Errorf("expected nothing deleted, got %d", result.FilesDeleted)
+	}
+	if len(result.Categories) != 1 || result.Categories[0].Status != StatusCancelled {
+		t.Errorf("expected the category to be cancelled, got %+v", result.Categories)
+	}
+}
+
+func TestPerformCleanContext_CancelStopsWalk(t *testing.T) {
+	dir := t.TempDir()
+	createTempFiles(t, dir, 3)
+	registerDirCategory(t, "test_cancel_during", dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := CleanOptions{Progress: func(category string, current, total int64) {
+		if current == 0 {
+			cancel()
+		}
+	}}
+	opts.Enable("test_cancel_during")
+	result := PerformCleanContext(ctx, opts)
+
+	if result.FilesDeleted != 0 {
+		t.Errorf("expected the walk to stop before removing anything, got %d", result.FilesDeleted)
+	}
+	if len(result.Categories) != 1 || result.Categories[0].Status != StatusPartial {
+		t.Errorf("expected the category to be partial, got %+v", result.Categories)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Errorf("expected all files to remain, found %d", len(entries))
+	}
+}
+
+func TestPerformClean_CategoryStatuses(t *testing.T) {
+	dir := t.TempDir()
+	createTempFiles(t, dir, 2)
+	registerDirCategory(t, "test_status_completed", dir)
+
+	opts := CleanOptions{}
+	opts.Enable("test_status_completed")
+	result := PerformClean(opts)
+
+	if len(result.Categories) != 1 {
+		t.Fatalf("expected one category status, got %+v", result.Categories)
+	}
+	st := result.Categories[0]
+	if st.ID != "test_status_completed" || st.Status != StatusCompleted || st.FilesDeleted != 2 {
+		t.Errorf("unexpected status %+v", st)
+	}
+}
+
+func TestCleanDirectoryFiltered_DirDeadline(t *testing.T) {
+	dir := t.TempDir()
+	createTempFiles(t, dir, 3)
+
+	sw := sweeper{guard: newPathGuard(nil, nil), dirTimeout: time.Nanosecond}
+	result := cleanDirectoryFiltered(dir, fileFilter{}, sw)
+
+	if !result.incomplete || len(result.Errors) != 1 {
+		t.Errorf("expected the walk to stop at the deadline with one error, got incomplete=%v errors=%v", result.incomplete, result.Errors)
+	}
+}
@@ -4,9 +4,12 @@ package cleaner
 
 import (
 	"fmt"
+	"os"
+	"strings"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 )
 
 var (
@@ -73,3 +76,134 @@ func emptyRecycleBinNative() error {
 	}
 	return nil
 }
+
+// registryRoots maps the root names used by winapp2.ini Detect keys.
+var registryRoots = map[string]registry.Key{
+	"HKCU":                registry.CURRENT_USER,
+	"HKEY_CURRENT_USER":   registry.CURRENT_USER,
+	"HKLM":                registry.LOCAL_MACHINE,
+	"HKEY_LOCAL_MACHINE":  registry.LOCAL_MACHINE,
+	"HKCR":                registry.CLASSES_ROOT,
+	"HKEY_CLASSES_ROOT":   registry.CLASSES_ROOT,
+	"HKU":                 registry.USERS,
+	"HKEY_USERS":          registry.USERS,
+	"HKCC":                registry.CURRENT_CONFIG,
+	"HKEY_CURRENT_CONFIG": registry.CURRENT_CONFIG,
+}
+
+// registryKeyExists reports whether a key such as HKCU\Software\Foo exists in
+// either the 64-bit or 32-bit registry view.
+func registryKeyExists(path string) bool {
+	rootName, sub, _ := strings.Cut(path, `\`)
+	root, ok := registryRoots[strings.ToUpper(rootName)]
+	if !ok {
+		return false
+	}
+	for _, view := range []uint32{registry.WOW64_64KEY, registry.WOW64_32KEY} {
+		key, err := registry.OpenKey(root, sub, registry.QUERY_VALUE|view)
+		if err == nil {
+			key.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// registryString reads a string value such as HKCU\Software\Valve\Steam
+// SteamPath from either registry view, or returns "" when it is missing.
+func registryString(path, name string) string {
+	rootName, sub, _ := strings.Cut(path, `\`)
+	root, ok := registryRoots[strings.ToUpper(rootName)]
+	if !ok {
+		return ""
+	}
+	for _, view := range []uint32{registry.WOW64_64KEY, registry.WOW64_32KEY} {
+		key, err := registry.OpenKey(root, sub, registry.QUERY_VALUE|view)
+		if err != nil {
+			continue
+		}
+		value, _, err := key.GetStringValue(name)
+		key.Close()
+		if err == nil && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// deleteOnReboot asks Windows to delete path when it next starts, before
+// any application can lock it again. It needs administrator rights.
+func deleteOnReboot(path string) error {
+	from, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	if err := windows.MoveFileEx(from, nil, windows.MOVEFILE_DELAY_UNTIL_REBOOT); err != nil {
+		return fmt.Errorf("MoveFileEx failed: %w", err)
+	}
+	return nil
+}
+
+// deviceID is not available on Windows: os.FileInfo carries no volume serial
+// number. Mount points there are reparse points, which the walker skips as
+// links.
+func deviceID(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// fileStandardInfo is FILE_STANDARD_INFO.
+type fileStandardInfo struct {
+	AllocationSize int64
+	EndOfFile      int64
+	NumberOfLinks  uint32
+	DeletePending  bool
+	Directory      bool
+}
+
+// fileAllocation opens the file (not following reparse points) to read its
+// allocation size, link count and volume/file ID.
+func fileAllocation(path string, info os.FileInfo) (fileAlloc, bool) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return fileAlloc{}, false
+	}
+	h, err := windows.CreateFile(p, windows.FILE_READ_ATTRIBUTES,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil,
+		windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if err != nil {
+		return fileAlloc{}, false
+	}
+	defer windows.CloseHandle(h)
+
+	var id windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &id); err != nil {
+		return fileAlloc{}, false
+	}
+	var std fileStandardInfo
+	if err := windows.GetFileInformationByHandleEx(h, windows.FileStandardInfo,
+		(*byte)(unsafe.Pointer(&std)), uint32(unsafe.Sizeof(std))); err != nil {
+		return fileAlloc{}, false
+	}
+	return fileAlloc{
+		key: fileKey{
+			dev: uint64(id.VolumeSerialNumber),
+			ino: uint64(id.FileIndexHigh)<<32 | uint64(id.FileIndexLow),
+		},
+		links:     uint64(std.NumberOfLinks),
+		allocated: std.AllocationSize,
+	}, true
+}
+
+// diskFree returns the bytes available to the current user on the volume
+// holding path.
+func diskFree(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
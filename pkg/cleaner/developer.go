@@ -0,0 +1,376 @@
+package cleaner
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Developer toolchain caches. Every tool lets its cache be moved, through an
+// environment variable or its own config file, so each location is resolved
+// the way the tool resolves it: the environment first, then the tool's
+// config, then the built-in default.
+
+// DefaultStaleProjectAge is how long a project must be untouched before the
+// stale node_modules category removes its dependencies.
+const DefaultStaleProjectAge = 90 * 24 * time.Hour
+
+// maxWorkspaceDepth bounds how deep below a workspace root projects are
+// looked for.
+const maxWorkspaceDepth = 6
+
+// firstOf resolves to the first resolver that yields a path, so an explicit
+// setting replaces the default instead of adding to it.
+func firstOf(resolvers ...PathResolver) PathResolver {
+	return func() []string {
+		for _, resolve := range resolvers {
+			if paths := resolve(); len(paths) > 0 {
+				return paths
+			}
+		}
+		return nil
+	}
+}
+
+// subPath resolves to elem below every path of base.
+func subPath(base PathResolver, elem ...string) PathResolver {
+	return func() []string {
+		var out []string
+		for _, p := range base() {
+			out = append(out, filepath.Join(append([]string{p}, elem...)...))
+		}
+		return out
+	}
+}
+
+// envSetting resolves to the path in an environment variable, with ~ and
+// variables expanded. Unset variables and relative paths resolve to nothing.
+func envSetting(key string) PathResolver {
+	return func() []string {
+		if p, ok := expandPath(os.Getenv(key), os.LookupEnv); ok {
+			return []string{p}
+		}
+		return nil
+	}
+}
+
+// fileSetting resolves to the path set for key in the first of the config
+// files that sets it. See configValue for the formats understood.
+func fileSetting(key string, files ...PathResolver) PathResolver {
+	return func() []string {
+		for _, resolve := range files {
+			for _, file := range resolve() {
+				if v := configValue(file, key); v != "" {
+					if p, ok := expandPath(v, os.LookupEnv); ok {
+						return []string{p}
+					}
+					log.Printf("[SysCleaner] Ignoring %s in %s: %q is not an absolute path", key, file, v)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// configValue returns the last value of key in a line-based config file:
+// "key=value" (go env, .npmrc, pnpm rc, pip.conf), "key value" (.yarnrc) or
+// "key: value" (.yarnrc.yml). Sections are ignored and quotes are removed.
+func configValue(path, key string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	value := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, key) {
+			continue
+		}
+		rest := line[len(key):]
+		trimmed := strings.TrimLeft(rest, " \t")
+		switch {
+		case strings.HasPrefix(trimmed, "="), strings.HasPrefix(trimmed, ":"):
+			rest = trimmed[1:]
+		case len(trimmed) < len(rest) && trimmed != "":
+			rest = trimmed
+		default:
+			continue // A longer key that starts with this one
+		}
+		value = strings.Trim(strings.TrimSpace(rest), `"'`)
+	}
+	return value
+}
+
+// javaProperty resolves to the path set with -Dname=value in the JVM
+// options of one of the environment variables.
+func javaProperty(name string, envKeys ...string) PathResolver {
+	return func() []string {
+		for _, key := range envKeys {
+			for _, field := range strings.Fields(os.Getenv(key)) {
+				if v, ok := strings.CutPrefix(field, "-D"+name+"="); ok {
+					if p, ok := expandPath(strings.Trim(v, `"'`), os.LookupEnv); ok {
+						return []string{p}
+					}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// Go: GOCACHE, GOMODCACHE and GOPATH come from the environment or the go
+// env file written by "go env -w".
+var (
+	goEnvFile = firstOf(envSetting("GOENV"), envPath("APPDATA", "go", "env"), xdgConfig("go", "env"))
+	goPath    = firstOf(goPathSetting(), homePath("go"))
+)
+
+func goSetting(key string) PathResolver {
+	return firstOf(envSetting(key), fileSetting(key, goEnvFile))
+}
+
+// goPathSetting resolves to the first entry of a GOPATH list.
+func goPathSetting() PathResolver {
+	return func() []string {
+		value := os.Getenv("GOPATH")
+		if value == "" {
+			for _, file := range goEnvFile() {
+				value = configValue(file, "GOPATH")
+			}
+		}
+		for _, entry := range filepath.SplitList(value) {
+			if p, ok := expandPath(entry, os.LookupEnv); ok {
+				return []string{p}
+			}
+		}
+		return nil
+	}
+}
+
+// npm reads its settings from npm_config_* variables and the user .npmrc;
+// pnpm also reads its own rc file.
+var (
+	npmrc    = firstOf(envSetting("npm_config_userconfig"), envSetting("NPM_CONFIG_USERCONFIG"), homePath(".npmrc"))
+	pnpmrc   = firstOf(envPath("LOCALAPPDATA", "pnpm", "config", "rc"), xdgConfig("pnpm", "rc"))
+	yarnrc   = homePath(".yarnrc")
+	yarnrcV2 = homePath(".yarnrc.yml")
+)
+
+// pipConfigFiles lists pip's config files, the one that wins first.
+var pipConfigFiles = []PathResolver{
+	envSetting("PIP_CONFIG_FILE"),
+	envPath("APPDATA", "pip", "pip.ini"),
+	xdgConfig("pip", "pip.conf"),
+	homePath(".pip", "pip.conf"),
+}
+
+// mavenRepository resolves to Maven's local repository: -Dmaven.repo.local,
+// then <localRepository> in the user and global settings.xml, then
+// ~/.m2/repository.
+func mavenRepository() PathResolver {
+	settings := []PathResolver{
+		homePath(".m2", "settings.xml"),
+		subPath(envSetting("MAVEN_HOME"), "conf", "settings.xml"),
+		subPath(envSetting("M2_HOME"), "conf", "settings.xml"),
+	}
+	fromSettings := func() []string {
+		for _, resolve := range settings {
+			for _, file := range resolve() {
+				if p := mavenLocalRepository(file); p != "" {
+					return []string{p}
+				}
+			}
+		}
+		return nil
+	}
+	return firstOf(javaProperty("maven.repo.local", "MAVEN_OPTS", "MAVEN_ARGS"), fromSettings, homePath(".m2", "repository"))
+}
+
+// mavenLocalRepository reads <localRepository> from a settings.xml.
+// ${user.home} and ${env.VAR} are expanded.
+func mavenLocalRepository(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var settings struct {
+		LocalRepository string `xml:"localRepository"`
+	}
+	if err := xml.Unmarshal(data, &settings); err != nil {
+		log.Printf("[SysCleaner] Cannot read %s: %v", path, err)
+		return ""
+	}
+	repo := strings.TrimSpace(settings.LocalRepository)
+	if repo == "" {
+		return ""
+	}
+	home, _ := os.UserHomeDir()
+	repo = strings.ReplaceAll(repo, "${user.home}", home)
+	repo = strings.ReplaceAll(repo, "${env.", "${")
+	if p, ok := expandPath(repo, os.LookupEnv); ok {
+		return p
+	}
+	return ""
+}
+
+// cleanReadOnlyTree cleans a category whose files are made read-only by
+// their tool (the Go module cache). Each file and its folder are made
+// writable only when the file is about to be removed, so files the clean
+// skips keep their permissions.
+func cleanReadOnlyTree(c Category, opts CleanOptions) CleanResult {
+	opts.readOnly = true
+	return cleanPaths(c, opts)
+}
+
+// executeReadOnlyTree is the Execute counterpart of cleanReadOnlyTree.
+func executeReadOnlyTree(c Category, files []PlanFile, opts CleanOptions) CleanResult {
+	opts.readOnly = true
+	return executeFiles(c, files, opts)
+}
+
+// addWritePermission adds owner write permission to path if it lacks it.
+func addWritePermission(path string) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSymlink == 0 && info.Mode().Perm()&0200 == 0 {
+		os.Chmod(path, info.Mode().Perm()|0200)
+	}
+}
+
+// cleanStaleNodeModules removes the node_modules folders of projects below
+// CleanOptions.WorkspaceRoots that have not been touched for
+// StaleProjectAge. A project is a directory with a package.json and a
+// node_modules folder; it counts as touched when any file outside
+// node_modules changed.
+func cleanStaleNodeModules(c Category, opts CleanOptions) CleanResult {
+	result := CleanResult{}
+	if len(opts.WorkspaceRoots) == 0 {
+		log.Printf("[SysCleaner] %s: no workspace roots configured", c.Name)
+		return result
+	}
+	age := opts.StaleProjectAge
+	if age <= 0 {
+		age = DefaultStaleProjectAge
+	}
+	cutoff := time.Now().Add(-age)
+	sw := newSweeper(c, opts)
+
+	for _, root := range opts.WorkspaceRoots {
+		root, ok := expandPath(root, os.LookupEnv)
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: workspace root %q is not an absolute path", c.Name, root))
+			continue
+		}
+		for _, project := range findProjects(sw, root) {
+			if sw.stopped(&result) {
+				return result
+			}
+			if touchedSince(sw, project, cutoff) {
+				continue
+			}
+			modules := filepath.Join(project, "node_modules")
+			part := cleanDirectoryFiltered(modules, fileFilter{}, sw)
+			if sw.dryRun {
+				if part.FilesDeleted > 0 {
+					part.DryRunItems = append(part.DryRunItems, fmt.Sprintf("%s (project untouched for %s)", modules, FormatAge(age)))
+				}
+			} else if !part.incomplete && sw.guard.check(modules) == nil {
+				part.DirsRemoved += int64(removeEmptyDirs(modules, true))
+			}
+			result.merge(part)
+		}
+	}
+	return result
+}
+
+// executeNodeModules removes planned node_modules files, then the emptied
+// node_modules folders.
+func executeNodeModules(c Category, files []PlanFile, opts CleanOptions) CleanResult {
+	result := executeFiles(c, files, opts)
+	if opts.DryRun || result.incomplete {
+		return result
+	}
+	done := map[string]bool{}
+	for _, f := range files {
+		if modules := nodeModulesRoot(f.Path); modules != "" && !done[modules] {
+			done[modules] = true
+			result.DirsRemoved += int64(removeEmptyDirs(modules, true))
+		}
+	}
+	return result
+}
+
+// nodeModulesRoot returns the outermost node_modules folder containing path.
+func nodeModulesRoot(path string) string {
+	marker := string(filepath.Separator) + "node_modules"
+	if i := strings.Index(path, marker+string(filepath.Separator)); i >= 0 {
+		return path[:i+len(marker)]
+	}
+	return ""
+}
+
+// findProjects returns the directories below root that have a package.json
+// and a node_modules folder. Hidden directories and node_modules folders are
+// not searched; projects nested in a project are found too.
+func findProjects(sw sweeper, root string) []string {
+	var projects []string
+	ctx := sw.context()
+	depth0 := strings.Count(filepath.Clean(root), string(filepath.Separator))
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && (d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "package.json")); err == nil {
+			if info, err := os.Lstat(filepath.Join(path, "node_modules")); err == nil && info.IsDir() {
+				projects = append(projects, path)
+			}
+		}
+		if strings.Count(path, string(filepath.Separator))-depth0 >= maxWorkspaceDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return projects
+}
+
+// touchedSince reports whether a file of the project outside node_modules
+// and .git changed after cutoff. Directory times are ignored since installs
+// change them. The walk stops at the first changed file.
+func touchedSince(sw sweeper, project string, cutoff time.Time) bool {
+	touched := false
+	ctx := sw.context()
+	filepath.WalkDir(project, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			touched = true // Never remove on a partial answer
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && path != project && (d.Name() == "node_modules" || d.Name() == ".git") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(cutoff) {
+			touched = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return touched
+}
@@ -0,0 +1,218 @@
+package cleaner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// devHome points HOME and the XDG directories at a temporary directory and
+// clears every variable the Developer categories read.
+func devHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	for _, key := range []string{
+		"LOCALAPPDATA", "APPDATA", "GOCACHE", "GOENV", "GOPATH", "GOMODCACHE",
+		"npm_config_cache", "NPM_CONFIG_CACHE", "npm_config_userconfig", "NPM_CONFIG_USERCONFIG",
+		"npm_config_store_dir", "npm_config_cache_dir", "YARN_CACHE_FOLDER", "YARN_GLOBAL_FOLDER",
+		"PIP_CACHE_DIR", "PIP_CONFIG_FILE", "CARGO_HOME", "GRADLE_USER_HOME", "GRADLE_OPTS",
+		"MAVEN_OPTS", "MAVEN_ARGS", "MAVEN_HOME", "M2_HOME",
+	} {
+		t.Setenv(key, "")
+	}
+	return home
+}
+
+func resolvedPaths(t *testing.T, id string) []string {
+	t.Helper()
+	c, ok := LookupCategory(id)
+	if !ok {
+		t.Fatalf("category %s is not registered", id)
+	}
+	return c.ResolvePaths()
+}
+
+// ---------- Developer category tests ----------
+
+func TestConfigValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rc")
+	tests := []struct {
+		content, key, want string
+	}{
+		{"registry=https://r.example\ncache=/srv/npm\n", "cache", "/srv/npm"},
+		{"cache = \"/a\"\ncache=/b\n", "cache", "/b"},
+		{"cache-folder \"/srv/yarn\"\n", "cache-folder", "/srv/yarn"},
+		{"cacheFolder: '/srv/berry'\n", "cacheFolder", "/srv/berry"},
+		{"[global]\ncache-dir = /srv/pip\n", "cache-dir", "/srv/pip"},
+		{"cache-max=100\n", "cache", ""},
+	}
+	for _, tc := range tests {
+		writeFile(t, path, tc.content)
+		if got := configValue(path, tc.key); got != tc.want {
+			t.Errorf("configValue(%q, %s) = %q, want %q", tc.content, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestDeveloperPaths_Defaults(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Linux default locations")
+	}
+	home := devHome(t)
+	for id, want := range map[string]string{
+		"go_build_cache":   filepath.Join(home, ".cache", "go-build"),
+		"go_mod_cache":     filepath.Join(home, "go", "pkg", "mod"),
+		"npm_cache":        filepath.Join(home, ".npm"),
+		"pip_cache":        filepath.Join(home, ".cache", "pip"),
+		"gradle_cache":     filepath.Join(home, ".gradle", "caches"),
+		"maven_repository": filepath.Join(home, ".m2", "repository"),
+	} {
+		if got := resolvedPaths(t, id); len(got) != 1 || got[0] != want {
+			t.Errorf("%s: expected %s, got %v", id, want, got)
+		}
+	}
+}
+
+func TestDeveloperPaths_FollowToolSettings(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix absolute paths")
+	}
+	home := devHome(t)
+	writeFile(t, filepath.Join(home, ".config", "go", "env"), "GOCACHE=/srv/go-build\nGOPATH=/srv/gopath\n")
+	writeFile(t, filepath.Join(home, ".npmrc"), "cache=~/npm-cache\n")
+	writeFile(t, filepath.Join(home, ".config", "pip", "pip.conf"), "[global]\ncache-dir = /srv/pip-from-config\n")
+	t.Setenv("PIP_CACHE_DIR", "/srv/pip-from-env")
+	t.Setenv("GRADLE_OPTS", "-Xmx2g -Dgradle.user.home=/srv/gradle")
+	writeFile(t, filepath.Join(home, ".m2", "settings.xml"),
+		"<settings><localRepository>${user.home}/m2repo</localRepository></settings>")
+
+	for id, want := range map[string]string{
+		"go_build_cache":   "/srv/go-build",
+		"go_mod_cache":     filepath.Join("/srv/gopath", "pkg", "mod"),
+		"npm_cache":        filepath.Join(home, "npm-cache"),
+		"pip_cache":        "/srv/pip-from-env",
+		"gradle_cache":     filepath.Join("/srv/gradle", "caches"),
+		"maven_repository": filepath.Join(home, "m2repo"),
+	} {
+		if got := resolvedPaths(t, id); len(got) != 1 || got[0] != want {
+			t.Errorf("%s: expected %s, got %v", id, want, got)
+		}
+	}
+
+	t.Setenv("GOCACHE", filepath.Join(home, "env-cache"))
+	if got := resolvedPaths(t, "go_build_cache"); len(got) != 1 || got[0] != filepath.Join(home, "env-cache") {
+		t.Errorf("GOCACHE in the environment must win over the go env file, got %v", got)
+	}
+}
+
+func TestGoModCache_RemovesReadOnlyTree(t *testing.T) {
+	home := devHome(t)
+	root := filepath.Join(home, "go", "pkg", "mod")
+	module := func(name string) string {
+		dir := filepath.Join(root, "example.com", name)
+		writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/"+name)
+		os.Chmod(filepath.Join(dir, "go.mod"), 0444)
+		os.Chmod(dir, 0555)
+		return dir
+	}
+	removed, kept := module("m@v1.0.0"), module("kept@v1.0.0")
+	os.Chmod(filepath.Join(root, "example.com"), 0555)
+	t.Cleanup(func() {
+		filepath.WalkDir(home, func(path string, d fs.DirEntry, err error) error {
+			os.Chmod(path, 0755)
+			return nil
+		})
+	})
+
+	c, _ := LookupCategory("go_mod_cache")
+	result := c.run(CleanOptions{ProtectedPaths: []string{kept}})
+	if result.FilesDeleted != 1 || exists(removed) {
+		t.Errorf("expected the read-only module removed, got %d files, errors %v", result.FilesDeleted, result.Errors)
+	}
+	// Windows has no write permission on folders to check.
+	if info, err := os.Stat(kept); err != nil || runtime.GOOS != "windows" && info.Mode().Perm() != 0555 {
+		t.Error("a protected module must keep its permissions")
+	}
+	if info, err := os.Stat(filepath.Join(kept, "go.mod")); err != nil || info.Mode().Perm() != 0444 {
+		t.Error("a protected module file must keep its permissions")
+	}
+}
+
+// makeProject creates a project with a node_modules folder whose files were
+// all last changed at mtime.
+func makeProject(t *testing.T, dir string, mtime time.Time) {
+	t.Helper()
+	files := []string{
+		filepath.Join(dir, "package.json"),
+		filepath.Join(dir, "src", "index.js"),
+		filepath.Join(dir, "node_modules", "left-pad", "index.js"),
+		filepath.Join(dir, "node_modules", "left-pad", "package.json"),
+	}
+	for _, f := range files {
+		writeFile(t, f, "x")
+		if err := os.Chtimes(f, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStaleNodeModules(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	makeProject(t, filepath.Join(root, "old-app"), old)
+	makeProject(t, filepath.Join(root, "group", "fresh-app"), time.Now())
+	makeProject(t, filepath.Join(root, ".hidden", "app"), old)
+	// A recent edit outside node_modules keeps a project.
+	makeProject(t, filepath.Join(root, "edited-app"), old)
+	writeFile(t, filepath.Join(root, "edited-app", "README.md"), "x")
+
+	c, _ := LookupCategory("stale_node_modules")
+	opts := CleanOptions{DryRun: true, WorkspaceRoots: []string{root}}
+	dry := c.run(opts)
+	if dry.FilesDeleted != 2 || len(dry.DryRunItems) != 1 {
+		t.Errorf("dry run should find the 2 files of old-app, got %d %v", dry.FilesDeleted, dry.DryRunItems)
+	}
+
+	opts.DryRun = false
+	c.run(opts)
+	if exists(filepath.Join(root, "old-app", "node_modules")) {
+		t.Error("expected the stale node_modules folder to be removed")
+	}
+	if !exists(filepath.Join(root, "old-app", "package.json")) {
+		t.Error("the project itself must be kept")
+	}
+	for _, kept := range []string{"group/fresh-app", ".hidden/app", "edited-app"} {
+		if !exists(filepath.Join(root, filepath.FromSlash(kept), "node_modules", "left-pad", "index.js")) {
+			t.Errorf("%s: node_modules must be kept", kept)
+		}
+	}
+
+	opts.StaleProjectAge = 365 * 24 * time.Hour
+	makeProject(t, filepath.Join(root, "old-app"), old)
+	if result := c.run(opts); result.FilesDeleted != 0 {
+		t.Errorf("a project younger than the stale age must be kept, got %d files", result.FilesDeleted)
+	}
+}
+
+func TestStaleNodeModules_PlanRemovesFolder(t *testing.T) {
+	root := t.TempDir()
+	makeProject(t, filepath.Join(root, "app"), time.Now().Add(-200*24*time.Hour))
+
+	opts := CleanOptions{WorkspaceRoots: []string{root}}
+	opts.Enable("stale_node_modules")
+	plan := Scan(opts)
+	if files, _ := plan.Totals(); files != 2 {
+		t.Fatalf("expected 2 planned files, got %d", files)
+	}
+	Execute(plan, CleanOptions{})
+	if exists(filepath.Join(root, "app", "node_modules")) {
+		t.Error("expected the node_modules folder to be removed with its files")
+	}
+}
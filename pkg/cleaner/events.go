@@ -0,0 +1,88 @@
+package cleaner
+
+import "sync"
+
+// EventKind identifies a ProgressEvent.
+type EventKind int
+
+const (
+	EventCategoryStarted  EventKind = iota // A category began cleaning
+	EventFileRemoved                       // A file or Trash item was deleted, quarantined or (dry run) counted
+	EventFileSkipped                       // A candidate was left in place; Reason says why
+	EventCategoryFinished                  // A category ended; Status says how
+	EventFileScanned                       // A file was read by a scan that removes nothing, such as the duplicate finder
+)
+
+// ProgressEvent is one step of a clean run. Every event carries the run's
+// running totals, so a consumer can draw progress from any single event.
+type ProgressEvent struct {
+	Kind     EventKind
+	Category string // Category ID
+	Name     string // Category display name
+	Path     string // File events only
+	Size     int64  // File events only
+	Reason   string // EventFileSkipped only
+	Status   RunStatus
+
+	// Files and Bytes count the candidates handled so far (removed or
+	// skipped). TotalFiles and TotalBytes come from a pre-scan of the
+	// selected categories and are 0 when unknown (dry runs).
+	Files      int64
+	Bytes      int64
+	TotalFiles int64
+	TotalBytes int64
+}
+
+// Fraction returns the completed fraction of the run in [0, 1], by bytes
+// when the total size is known and by file count otherwise. It returns -1
+// when no totals are known.
+func (e ProgressEvent) Fraction() float64 {
+	var done, total int64
+	switch {
+	case e.TotalBytes > 0:
+		done, total = e.Bytes, e.TotalBytes
+	case e.TotalFiles > 0:
+		done, total = e.Files, e.TotalFiles
+	default:
+		return -1
+	}
+	if done >= total {
+		return 1
+	}
+	return float64(done) / float64(total)
+}
+
+// EventFunc receives progress events. Calls are serialized, so it does not
+// need to be safe for concurrent use, but it should return quickly: the
+// workers wait for it.
+type EventFunc func(ProgressEvent)
+
+// eventEmitter keeps the running totals of a run and hands events to the
+// EventFunc one at a time. A nil emitter drops events.
+type eventEmitter struct {
+	mu         sync.Mutex
+	fn         EventFunc
+	files      int64
+	bytes      int64
+	totalFiles int64
+	totalBytes int64
+}
+
+func newEventEmitter(fn EventFunc, totalFiles, totalBytes int64) *eventEmitter {
+	return &eventEmitter{fn: fn, totalFiles: totalFiles, totalBytes: totalBytes}
+}
+
+func (e *eventEmitter) emit(ev ProgressEvent) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if ev.Kind == EventFileRemoved || ev.Kind == EventFileSkipped {
+		e.files++
+		e.bytes += ev.Size
+	}
+	ev.Files, ev.Bytes = e.files, e.bytes
+	ev.TotalFiles, ev.TotalBytes = e.totalFiles, e.totalBytes
+	e.fn(ev)
+}
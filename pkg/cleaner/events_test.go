@@ -0,0 +1,80 @@
+package cleaner
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// ---------- progress event tests ----------
+
+func TestPerformClean_EmitsEventsWithPrescanTotals(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.tmp"), "aaaa")
+	writeFile(t, filepath.Join(dir, "b.tmp"), "bb")
+	registerDirCategory(t, "test_events", dir)
+
+	var events []ProgressEvent
+	opts := CleanOptions{Events: func(e ProgressEvent) { events = append(events, e) }}
+	opts.Enable("test_events")
+	PerformClean(opts)
+
+	if len(events) != 4 {
+		t.Fatalf("expected start, 2 files and finish, got %+v", events)
+	}
+	if events[0].Kind != EventCategoryStarted || events[0].Name != "test_events" {
+		t.Errorf("unexpected first event %+v", events[0])
+	}
+	for _, e := range events[1:3] {
+		if e.Kind != EventFileRemoved || e.Category != "test_events" || e.Path == "" {
+			t.Errorf("unexpected file event %+v", e)
+		}
+		if e.TotalFiles != 2 || e.TotalBytes != 6 {
+			t.Errorf("expected pre-scan totals 2 files / 6 bytes, got %d / %d", e.TotalFiles, e.TotalBytes)
+		}
+	}
+	last := events[3]
+	if last.Kind != EventCategoryFinished || last.Status != StatusCompleted {
+		t.Errorf("unexpected last event %+v", last)
+	}
+	if last.Files != 2 || last.Bytes != 6 || last.Fraction() != 1 {
+		t.Errorf("expected the run to be complete, got %d files / %d bytes (%.2f)", last.Files, last.Bytes, last.Fraction())
+	}
+}
+
+func TestExecute_EmitsSkippedEventWithReason(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.tmp")
+	writeFile(t, path, "old")
+	registerDirCategory(t, "test_events_skip", dir)
+
+	opts := CleanOptions{}
+	opts.Enable("test_events_skip")
+	plan := Scan(opts)
+	writeFile(t, path, "modified")
+
+	var skipped []ProgressEvent
+	Execute(plan, CleanOptions{Events: func(e ProgressEvent) {
+		if e.Kind == EventFileSkipped {
+			skipped = append(skipped, e)
+		}
+	}})
+
+	if len(skipped) != 1 || skipped[0].Path != path || skipped[0].Reason == "" {
+		t.Errorf("expected one skipped event with a reason, got %+v", skipped)
+	}
+	if skipped[0].TotalFiles != 1 {
+		t.Errorf("expected totals from the plan, got %d", skipped[0].TotalFiles)
+	}
+}
+
+func TestProgressEvent_Fraction(t *testing.T) {
+	if f := (ProgressEvent{}).Fraction(); f != -1 {
+		t.Errorf("expected -1 without totals, got %v", f)
+	}
+	if f := (ProgressEvent{Bytes: 25, TotalBytes: 100}).Fraction(); f != 0.25 {
+		t.Errorf("expected 0.25, got %v", f)
+	}
+	if f := (ProgressEvent{Files: 3, TotalFiles: 4}).Fraction(); f != 0.75 {
+		t.Errorf("expected 0.75 by file count, got %v", f)
+	}
+}
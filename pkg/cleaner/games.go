@@ -0,0 +1,616 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Game launcher cleaning. Steam keeps per-game shader caches, download
+// leftovers and workshop content in every library folder, and Epic keeps a
+// download staging folder in every install. The launchers' own manifests
+// (libraryfolders.vdf and appmanifest_*.acf for Steam, Manifests/*.item for
+// Epic) say where the libraries are and which games are installed, so the
+// game categories clean exactly those folders and report per game.
+
+// GameResult is what one category removed for one game. Library-wide
+// caches such as Steam's depot cache have no ID.
+type GameResult struct {
+	Category     string `json:"category"`
+	Launcher     string `json:"launcher"`
+	ID           string `json:"id"` // Steam app ID or Epic AppName
+	Name         string `json:"name"`
+	Installed    bool   `json:"installed"`
+	FilesDeleted int64  `json:"files_deleted"` // Removed or quarantined
+	BytesFreed   int64  `json:"bytes_freed"`
+}
+
+// Label returns "Launcher: Name", marking games that are no longer installed.
+func (g GameResult) Label() string {
+	label := g.Launcher + ": " + g.Name
+	if g.ID != "" && !g.Installed {
+		label += " (not installed)"
+	}
+	return label
+}
+
+// gameLeftover is a folder or file a launcher left behind for a game.
+type gameLeftover struct {
+	launcher  string
+	id        string
+	name      string
+	installed bool
+	path      string
+	dir       bool
+}
+
+// mergeGame merges the part of a result that category c produced for
+// leftover l and adds it to the game's entry.
+func (r *CleanResult) mergeGame(c Category, l gameLeftover, part CleanResult) {
+	r.merge(part)
+	files, bytes := part.FilesDeleted+part.FilesQuarantined, part.SpaceFreed+part.SpaceQuarantined
+	if files == 0 {
+		return
+	}
+	for i := range r.Games {
+		g := &r.Games[i]
+		if g.Category == c.ID && g.Launcher == l.launcher && g.ID == l.id {
+			g.FilesDeleted += files
+			g.BytesFreed += bytes
+			return
+		}
+	}
+	r.Games = append(r.Games, GameResult{
+		Category:     c.ID,
+		Launcher:     l.launcher,
+		ID:           l.id,
+		Name:         l.name,
+		Installed:    l.installed,
+		FilesDeleted: files,
+		BytesFreed:   bytes,
+	})
+}
+
+// cleanLeftovers returns a CleanFunc removing the leftovers found by find.
+func cleanLeftovers(find func() []gameLeftover) CleanFunc {
+	return func(c Category, opts CleanOptions) CleanResult {
+		result := CleanResult{}
+		sw := newSweeper(c, opts)
+		for _, l := range find() {
+			if sw.stopped(&result) {
+				break
+			}
+			part := CleanResult{}
+			if l.dir {
+				part = cleanDirectoryFiltered(l.path, fileFilter{}, sw)
+				if !sw.dryRun && !part.incomplete && sw.guard.check(l.path) == nil {
+					part.DirsRemoved += l.removeEmptyDirs()
+				}
+			} else if info, err := os.Lstat(l.path); err == nil {
+				sw.remove(l.path, info, fmt.Sprintf("%s leftover of %s", l.launcher, l.name), &part)
+			}
+			result.mergeGame(c, l, part)
+		}
+		return result
+	}
+}
+
+// executeLeftovers returns an Execute function removing planned files and
+// the folders they emptied, and reporting them per game.
+func executeLeftovers(find func() []gameLeftover) func(Category, []PlanFile, CleanOptions) CleanResult {
+	return func(c Category, files []PlanFile, opts CleanOptions) CleanResult {
+		leftovers := find()
+		groups := make([][]PlanFile, len(leftovers))
+		var rest []PlanFile
+	next:
+		for _, f := range files {
+			for i, l := range leftovers {
+				if (l.dir && pathWithin(f.Path, l.path)) || (!l.dir && filepath.Clean(f.Path) == filepath.Clean(l.path)) {
+					groups[i] = append(groups[i], f)
+					continue next
+				}
+			}
+			rest = append(rest, f)
+		}
+
+		result := executeFiles(c, rest, opts)
+		for i, l := range leftovers {
+			if len(groups[i]) == 0 {
+				continue
+			}
+			part := executeFiles(c, groups[i], opts)
+			if l.dir && !opts.DryRun && !part.incomplete {
+				part.DirsRemoved += l.removeEmptyDirs()
+			}
+			result.mergeGame(c, l, part)
+		}
+		return result
+	}
+}
+
+// removeEmptyDirs removes the folders emptied below a leftover folder. A
+// game's own folder goes too; library-wide folders are kept. It returns the
+// number of folders removed.
+func (l gameLeftover) removeEmptyDirs() int64 {
+	return int64(removeEmptyDirs(l.path, l.id != ""))
+}
+
+// ---------------------------------------------------------------------------
+// Steam
+// ---------------------------------------------------------------------------
+
+// steamFullyInstalled is the StateFlags value of an installed app with no
+// update pending or running.
+const steamFullyInstalled = "4"
+
+// steamRootResolvers are the places Steam is installed.
+var steamRootResolvers = []PathResolver{
+	func() []string {
+		if p := registryString(`HKCU\Software\Valve\Steam`, "SteamPath"); p != "" {
+			return []string{filepath.Clean(p)}
+		}
+		return nil
+	},
+	envPath("ProgramFiles(x86)", "Steam"),
+	xdgData("Steam"),
+	homePath(".steam", "steam"),
+	homePath(".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+}
+
+// steamApp is an installed app read from its appmanifest_<id>.acf.
+type steamApp struct {
+	id, name, stateFlags string
+}
+
+// steamLibraries returns every Steam library folder: each Steam install and
+// the libraries listed in its libraryfolders.vdf.
+func steamLibraries() []string {
+	var libs []string
+	seen := map[string]bool{}
+	add := func(dir string) {
+		if dir == "" || !filepath.IsAbs(dir) {
+			return
+		}
+		if info, err := os.Stat(filepath.Join(dir, "steamapps")); err != nil || !info.IsDir() {
+			return
+		}
+		key := dir
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			key = real
+		}
+		if runtime.GOOS == "windows" {
+			key = strings.ToLower(key)
+		}
+		if !seen[key] {
+			seen[key] = true
+			libs = append(libs, filepath.Clean(dir))
+		}
+	}
+
+	for _, resolve := range steamRootResolvers {
+		for _, root := range resolve() {
+			add(root)
+			for _, file := range []string{filepath.Join(root, "steamapps", "libraryfolders.vdf"), filepath.Join(root, "config", "libraryfolders.vdf")} {
+				doc, err := readVDF(file)
+				if err != nil {
+					if !os.IsNotExist(err) {
+						log.Printf("[SysCleaner] Cannot read Steam library list %s: %v", file, err)
+					}
+					continue
+				}
+				folders := doc.child("libraryfolders")
+				if folders == nil {
+					continue
+				}
+				for _, entry := range folders.children {
+					switch {
+					case entry.section:
+						add(entry.get("path"))
+					case isDigits(entry.key):
+						// Before 2021 the file listed the paths directly.
+						add(entry.value)
+					}
+				}
+			}
+		}
+	}
+	return libs
+}
+
+// steamApps reads the app manifests of a library folder, keyed by app ID.
+func steamApps(library string) map[string]steamApp {
+	apps := map[string]steamApp{}
+	manifests, _ := filepath.Glob(filepath.Join(library, "steamapps", "appmanifest_*.acf"))
+	for _, file := range manifests {
+		doc, err := readVDF(file)
+		if err != nil {
+			log.Printf("[SysCleaner] Cannot read Steam app manifest %s: %v", file, err)
+			continue
+		}
+		state := doc.child("AppState")
+		if state == nil {
+			continue
+		}
+		id := state.get("appid")
+		if id == "" {
+			id = strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "appmanifest_"), ".acf")
+		}
+		name := state.get("name")
+		if name == "" {
+			name = state.get("installdir")
+		}
+		apps[id] = steamApp{id: id, name: name, stateFlags: state.get("StateFlags")}
+	}
+	return apps
+}
+
+// steamLeftovers finds what can be removed in every Steam library: the
+// shader cache of every game, download leftovers of games that are not being
+// updated, workshop content of games that are no longer installed, and the
+// depot cache.
+func steamLeftovers() []gameLeftover {
+	libs := steamLibraries()
+	installed := map[string]steamApp{}
+	for _, lib := range libs {
+		for id, app := range steamApps(lib) {
+			installed[id] = app
+		}
+	}
+	leftover := func(id, path string, dir bool) gameLeftover {
+		l := gameLeftover{launcher: "Steam", id: id, name: "app " + id, path: path, dir: dir}
+		if app, ok := installed[id]; ok {
+			l.name, l.installed = app.name, true
+		}
+		return l
+	}
+	updating := func(id string) bool {
+		app, ok := installed[id]
+		return ok && app.stateFlags != steamFullyInstalled
+	}
+
+	var out []gameLeftover
+	for _, lib := range libs {
+		apps := filepath.Join(lib, "steamapps")
+		busy := false
+
+		for _, e := range readDirNames(filepath.Join(apps, "shadercache")) {
+			if isDigits(e) {
+				out = append(out, leftover(e, filepath.Join(apps, "shadercache", e), true))
+			}
+		}
+
+		// downloading holds a folder per app and state_<app>_<depot>.patch files.
+		for _, e := range readDirNames(filepath.Join(apps, "downloading")) {
+			path := filepath.Join(apps, "downloading", e)
+			id, dir := e, true
+			if strings.HasPrefix(e, "state_") {
+				id, _, _ = strings.Cut(strings.TrimPrefix(e, "state_"), "_")
+				dir = false
+			}
+			if !isDigits(id) {
+				continue
+			}
+			if updating(id) {
+				log.Printf("[SysCleaner] Keeping %s: %s is being downloaded or updated", path, installed[id].name)
+				busy = true
+				continue
+			}
+			out = append(out, leftover(id, path, dir))
+		}
+
+		workshop := filepath.Join(apps, "workshop")
+		for _, sub := range []string{"content", "downloads"} {
+			for _, e := range readDirNames(filepath.Join(workshop, sub)) {
+				if _, ok := installed[e]; !ok && isDigits(e) {
+					out = append(out, leftover(e, filepath.Join(workshop, sub, e), true))
+				}
+			}
+		}
+		manifests, _ := filepath.Glob(filepath.Join(workshop, "appworkshop_*.acf"))
+		for _, file := range manifests {
+			id := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "appworkshop_"), ".acf")
+			if _, ok := installed[id]; !ok && isDigits(id) {
+				out = append(out, leftover(id, file, false))
+			}
+		}
+
+		// Updates read the depot manifests, so the cache stays while one runs.
+		if depots := filepath.Join(apps, "depotcache"); !busy {
+			if info, err := os.Stat(depots); err == nil && info.IsDir() {
+				out = append(out, gameLeftover{launcher: "Steam", name: "Depot cache (" + lib + ")", path: depots, dir: true})
+			}
+		}
+	}
+	return out
+}
+
+// readDirNames returns the names of the entries of dir, or nil when it
+// cannot be read.
+func readDirNames(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ---------------------------------------------------------------------------
+// Epic Games Launcher
+// ---------------------------------------------------------------------------
+
+// epicManifestResolvers are the folders holding the launcher's *.item
+// manifests, one per installed game.
+var epicManifestResolvers = []PathResolver{
+	func() []string {
+		if p := registryString(`HKLM\SOFTWARE\Epic Games\EpicGamesLauncher`, "AppDataPath"); p != "" {
+			return []string{filepath.Join(filepath.Clean(p), "Manifests")}
+		}
+		return nil
+	},
+	envPath("ProgramData", "Epic", "EpicGamesLauncher", "Data", "Manifests"),
+}
+
+// epicItem holds the fields of an Epic manifest the cleaner uses.
+type epicItem struct {
+	AppName           string `json:"AppName"`
+	DisplayName       string `json:"DisplayName"`
+	InstallLocation   string `json:"InstallLocation"`
+	StagingLocation   string `json:"StagingLocation"`
+	IncompleteInstall bool   `json:"bIsIncompleteInstall"`
+}
+
+// epicItems reads the manifests of the installed Epic games.
+func epicItems() []epicItem {
+	var items []epicItem
+	seen := map[string]bool{}
+	for _, resolve := range epicManifestResolvers {
+		for _, dir := range resolve() {
+			files, _ := filepath.Glob(filepath.Join(dir, "*.item"))
+			for _, file := range files {
+				data, err := os.ReadFile(file)
+				if err != nil {
+					continue
+				}
+				var item epicItem
+				if err := json.Unmarshal(data, &item); err != nil {
+					log.Printf("[SysCleaner] Cannot read Epic manifest %s: %v", file, err)
+					continue
+				}
+				if item.AppName == "" || seen[item.AppName] {
+					continue
+				}
+				seen[item.AppName] = true
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// epicLeftovers finds the download staging folder of every installed Epic
+// game that is not being installed or updated, and the launcher's web cache.
+func epicLeftovers() []gameLeftover {
+	var out []gameLeftover
+	for _, item := range epicItems() {
+		name := item.DisplayName
+		if name == "" {
+			name = item.AppName
+		}
+		if item.IncompleteInstall {
+			log.Printf("[SysCleaner] Keeping the download staging of %s: it is being installed or updated", name)
+			continue
+		}
+		staging := item.StagingLocation
+		if staging == "" && item.InstallLocation != "" {
+			staging = filepath.Join(item.InstallLocation, ".egstore", "bps")
+		}
+		if staging == "" || !filepath.IsAbs(staging) {
+			continue
+		}
+		staging = filepath.Clean(staging)
+		if info, err := os.Stat(staging); err == nil && info.IsDir() {
+			out = append(out, gameLeftover{launcher: "Epic", id: item.AppName, name: name, installed: true, path: staging, dir: true})
+		}
+	}
+	for _, saved := range envPath("LOCALAPPDATA", "EpicGamesLauncher", "Saved")() {
+		caches, _ := filepath.Glob(filepath.Join(saved, "webcache*"))
+		for _, dir := range caches {
+			out = append(out, gameLeftover{launcher: "Epic", name: "Launcher web cache", path: dir, dir: true})
+		}
+	}
+	return out
+}
+
+// ---------------------------------------------------------------------------
+// VDF
+// ---------------------------------------------------------------------------
+
+// vdfNode is a key of a Valve KeyValues (VDF) text file: either a key-value
+// pair or a section holding further keys. Keys keep their file order.
+type vdfNode struct {
+	key      string
+	value    string
+	section  bool
+	children []*vdfNode
+}
+
+// child returns the first key named key, which Valve compares without case.
+func (n *vdfNode) child(key string) *vdfNode {
+	for _, c := range n.children {
+		if strings.EqualFold(c.key, key) {
+			return c
+		}
+	}
+	return nil
+}
+
+// get returns the value of the key-value pair named key, or "".
+func (n *vdfNode) get(key string) string {
+	if c := n.child(key); c != nil && !c.section {
+		return c.value
+	}
+	return ""
+}
+
+// readVDF parses a VDF file into a section holding its top-level keys.
+func readVDF(path string) (*vdfNode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseVDF(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// parseVDF parses VDF text. Keys and values may be quoted or bare, "//"
+// starts a comment, and platform conditionals such as [$WIN32] are ignored.
+func parseVDF(text string) (*vdfNode, error) {
+	lx := &vdfLexer{text: strings.TrimPrefix(text, "\ufeff"), line: 1}
+	doc := &vdfNode{section: true}
+	if err := lx.parseSection(doc, false); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+type vdfToken int
+
+const (
+	vdfEOF vdfToken = iota
+	vdfString
+	vdfOpen
+	vdfClose
+)
+
+type vdfLexer struct {
+	text string
+	pos  int
+	line int
+}
+
+func (lx *vdfLexer) parseSection(n *vdfNode, nested bool) error {
+	for {
+		kind, key := lx.next()
+		switch kind {
+		case vdfEOF:
+			if nested {
+				return fmt.Errorf("line %d: section %q is not closed", lx.line, n.key)
+			}
+			return nil
+		case vdfClose:
+			if !nested {
+				return fmt.Errorf("line %d: unexpected }", lx.line)
+			}
+			return nil
+		case vdfOpen:
+			return fmt.Errorf("line %d: section without a name", lx.line)
+		}
+
+		switch kind, value := lx.next(); kind {
+		case vdfString:
+			n.children = append(n.children, &vdfNode{key: key, value: value})
+		case vdfOpen:
+			child := &vdfNode{key: key, section: true}
+			if err := lx.parseSection(child, true); err != nil {
+				return err
+			}
+			n.children = append(n.children, child)
+		default:
+			return fmt.Errorf("line %d: key %q has no value", lx.line, key)
+		}
+	}
+}
+
+// next returns the next token, skipping whitespace, comments and
+// conditionals.
+func (lx *vdfLexer) next() (vdfToken, string) {
+	for lx.pos < len(lx.text) {
+		ch := lx.text[lx.pos]
+		switch {
+		case ch == '\n':
+			lx.line++
+			lx.pos++
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			lx.pos++
+		case strings.HasPrefix(lx.text[lx.pos:], "//"):
+			for lx.pos < len(lx.text) && lx.text[lx.pos] != '\n' {
+				lx.pos++
+			}
+		case ch == '{':
+			lx.pos++
+			return vdfOpen, ""
+		case ch == '}':
+			lx.pos++
+			return vdfClose, ""
+		case ch == '"':
+			return vdfString, lx.quoted()
+		default:
+			start := lx.pos
+			for lx.pos < len(lx.text) && !strings.ContainsRune(" \t\r\n{}\"", rune(lx.text[lx.pos])) {
+				lx.pos++
+			}
+			if word := lx.text[start:lx.pos]; !strings.HasPrefix(word, "[") {
+				return vdfString, word
+			}
+		}
+	}
+	return vdfEOF, ""
+}
+
+// quoted reads a quoted string. \\, \", \n and \t are unescaped; other
+// backslashes are kept.
+func (lx *vdfLexer) quoted() string {
+	var b strings.Builder
+	lx.pos++ // opening quote
+	for lx.pos < len(lx.text) {
+		ch := lx.text[lx.pos]
+		lx.pos++
+		switch {
+		case ch == '"':
+			return b.String()
+		case ch == '\\' && lx.pos < len(lx.text):
+			switch esc := lx.text[lx.pos]; esc {
+			case '\\', '"':
+				b.WriteByte(esc)
+				lx.pos++
+			case 'n':
+				b.WriteByte('\n')
+				lx.pos++
+			case 't':
+				b.WriteByte('\t')
+				lx.pos++
+			default:
+				b.WriteByte('\\')
+			}
+		default:
+			if ch == '\n' {
+				lx.line++
+			}
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,218 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// steamHome points HOME and the XDG directories at a temporary directory and
+// returns a Steam install with a second library listed in its
+// libraryfolders.vdf.
+func steamHome(t *testing.T) (steam, library string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	t.Setenv("ProgramFiles(x86)", "")
+	steam = filepath.Join(home, ".local", "share", "Steam")
+	library = filepath.Join(t.TempDir(), "SteamLibrary")
+	for _, dir := range []string{filepath.Join(steam, "steamapps"), filepath.Join(library, "steamapps")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(steam, "steamapps", "libraryfolders.vdf"), `"libraryfolders"
+{
+	"0"
+	{
+		"path"		"`+strings.ReplaceAll(steam, `\`, `\\`)+`"
+		"apps" { "730" "123" }
+	}
+	"1"
+	{
+		"path"		"`+strings.ReplaceAll(library, `\`, `\\`)+`"
+	}
+}
+`)
+	return steam, library
+}
+
+// appManifest writes the appmanifest_<id>.acf of an app in library.
+func appManifest(t *testing.T, library, id, name, stateFlags string) {
+	t.Helper()
+	writeFile(t, filepath.Join(library, "steamapps", "appmanifest_"+id+".acf"),
+		"\"AppState\"\n{\n\t\"appid\"\t\t\""+id+"\"\n\t\"name\"\t\t\""+name+"\"\n\t\"StateFlags\"\t\t\""+stateFlags+"\"\n}\n")
+}
+
+// gameFiles returns the files removed per game label.
+func gameFiles(games []GameResult) map[string]int64 {
+	out := map[string]int64{}
+	for _, g := range games {
+		out[g.Label()] += g.FilesDeleted
+	}
+	return out
+}
+
+// ---------- Game launcher tests ----------
+
+func TestParseVDF(t *testing.T) {
+	doc, err := parseVDF(`// comment
+"LibraryFolders"
+{
+	"TimeNextStatsReport"	"1700000000"
+	"1"		"D:\\Games\\Steam Library"
+	"2"
+	{
+		"path"	"E:\\SteamLibrary" [$WIN32]
+		label ""
+		"apps" {}
+	}
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folders := doc.child("libraryfolders")
+	if folders == nil || len(folders.children) != 3 {
+		t.Fatalf("expected 3 keys in libraryfolders, got %+v", folders)
+	}
+	if got := folders.get("1"); got != `D:\Games\Steam Library` {
+		t.Errorf("expected the escaped path unescaped, got %q", got)
+	}
+	second := folders.child("2")
+	if second == nil || !second.section || second.get("path") != `E:\SteamLibrary` || second.child("apps") == nil {
+		t.Errorf("unexpected section %+v", second)
+	}
+
+	for _, bad := range []string{`"a" { "b" "c"`, `"a" "b" }`, `"a"`} {
+		if _, err := parseVDF(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestSteamLibraries(t *testing.T) {
+	steam, library := steamHome(t)
+	// ~/.steam/steam links to the same install and must not be listed twice.
+	if err := os.MkdirAll(filepath.Join(os.Getenv("HOME"), ".steam"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.Symlink(steam, filepath.Join(os.Getenv("HOME"), ".steam", "steam"))
+
+	got := steamLibraries()
+	if len(got) != 2 || got[0] != steam || got[1] != library {
+		t.Errorf("expected %s and %s, got %v", steam, library, got)
+	}
+}
+
+func TestSteamLeftovers(t *testing.T) {
+	steam, library := steamHome(t)
+	appManifest(t, steam, "730", "Counter-Strike 2", "4")
+	appManifest(t, library, "570", "Dota 2", "1026") // Update running
+	apps, libApps := filepath.Join(steam, "steamapps"), filepath.Join(library, "steamapps")
+
+	writeFile(t, filepath.Join(apps, "shadercache", "730", "fozpipelinesv6", "a.foz"), "xx")
+	writeFile(t, filepath.Join(libApps, "shadercache", "440", "b.foz"), "x")
+	writeFile(t, filepath.Join(apps, "downloading", "999", "chunk"), "x")
+	writeFile(t, filepath.Join(apps, "downloading", "state_999_1000.patch"), "x")
+	writeFile(t, filepath.Join(libApps, "downloading", "570", "chunk"), "keep")
+	writeFile(t, filepath.Join(apps, "workshop", "content", "730", "1", "map.bsp"), "keep")
+	writeFile(t, filepath.Join(apps, "workshop", "content", "440", "2", "item.vpk"), "x")
+	writeFile(t, filepath.Join(apps, "workshop", "appworkshop_440.acf"), "x")
+	writeFile(t, filepath.Join(apps, "workshop", "appworkshop_730.acf"), "keep")
+	writeFile(t, filepath.Join(apps, "depotcache", "731_1.manifest"), "x")
+	writeFile(t, filepath.Join(libApps, "depotcache", "571_1.manifest"), "keep")
+
+	c, _ := LookupCategory("steam_games")
+	dry := c.run(CleanOptions{DryRun: true})
+	want := map[string]int64{
+		"Steam: Counter-Strike 2":            1,
+		"Steam: app 440 (not installed)":     3,
+		"Steam: app 999 (not installed)":     2,
+		"Steam: Depot cache (" + steam + ")": 1,
+	}
+	got := gameFiles(dry.Games)
+	if len(got) != len(want) || dry.FilesDeleted != 7 {
+		t.Fatalf("expected %v (7 files), got %v (%d files)", want, got, dry.FilesDeleted)
+	}
+	for label, files := range want {
+		if got[label] != files {
+			t.Errorf("%s: expected %d files, got %d", label, files, got[label])
+		}
+	}
+
+	c.run(CleanOptions{})
+	for _, gone := range []string{
+		filepath.Join(apps, "shadercache", "730"),
+		filepath.Join(libApps, "shadercache", "440"),
+		filepath.Join(apps, "downloading", "999"),
+		filepath.Join(apps, "workshop", "content", "440"),
+		filepath.Join(apps, "workshop", "appworkshop_440.acf"),
+	} {
+		if exists(gone) {
+			t.Errorf("expected %s to be removed", gone)
+		}
+	}
+	for _, kept := range []string{
+		filepath.Join(libApps, "downloading", "570", "chunk"),
+		filepath.Join(apps, "workshop", "content", "730", "1", "map.bsp"),
+		filepath.Join(apps, "workshop", "appworkshop_730.acf"),
+		filepath.Join(libApps, "depotcache", "571_1.manifest"),
+		filepath.Join(apps, "depotcache"),
+	} {
+		if !exists(kept) {
+			t.Errorf("expected %s to be kept", kept)
+		}
+	}
+}
+
+func TestSteamLeftovers_Plan(t *testing.T) {
+	steam, _ := steamHome(t)
+	appManifest(t, steam, "730", "Counter-Strike 2", "4")
+	writeFile(t, filepath.Join(steam, "steamapps", "shadercache", "730", "a.foz"), "x")
+
+	opts := CleanOptions{}
+	opts.Enable("steam_games")
+	plan := Scan(opts)
+	result := Execute(plan, CleanOptions{})
+	if result.FilesDeleted != 1 || len(result.Games) != 1 || result.Games[0].Name != "Counter-Strike 2" {
+		t.Errorf("expected the shader cache file removed for Counter-Strike 2, got %d files, %+v", result.FilesDeleted, result.Games)
+	}
+	if exists(filepath.Join(steam, "steamapps", "shadercache", "730")) {
+		t.Error("expected the emptied shader cache folder to be removed")
+	}
+}
+
+func TestEpicLeftovers(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("ProgramData", data)
+	t.Setenv("LOCALAPPDATA", "")
+	manifests := filepath.Join(data, "Epic", "EpicGamesLauncher", "Data", "Manifests")
+	games := t.TempDir()
+	item := func(file, app, name, install, extra string) {
+		writeFile(t, filepath.Join(manifests, file), `{"AppName":"`+app+`","DisplayName":"`+name+`",`+
+			`"InstallLocation":"`+filepath.ToSlash(filepath.Join(games, install))+`"`+extra+`}`)
+	}
+	item("A.item", "Fortnite", "Fortnite", "Fortnite", "")
+	item("B.item", "Sugar", "Rocket League", "rocketleague", `,"StagingLocation":"`+filepath.ToSlash(filepath.Join(games, "staging", "rl"))+`"`)
+	item("C.item", "Updating", "Updating Game", "updating", `,"bIsIncompleteInstall":true`)
+	writeFile(t, filepath.Join(games, "Fortnite", ".egstore", "bps", "chunk1"), "xx")
+	writeFile(t, filepath.Join(games, "Fortnite", ".egstore", "manifest.manifest"), "keep")
+	writeFile(t, filepath.Join(games, "staging", "rl", "chunk"), "x")
+	writeFile(t, filepath.Join(games, "updating", ".egstore", "bps", "chunk"), "keep")
+
+	c, _ := LookupCategory("epic_games")
+	result := c.run(CleanOptions{})
+	got := gameFiles(result.Games)
+	if len(got) != 2 || got["Epic: Fortnite"] != 1 || got["Epic: Rocket League"] != 1 {
+		t.Errorf("expected the staging of Fortnite and Rocket League, got %v", got)
+	}
+	if exists(filepath.Join(games, "Fortnite", ".egstore", "bps")) || !exists(filepath.Join(games, "Fortnite", ".egstore", "manifest.manifest")) {
+		t.Error("expected only the staging folder to be removed")
+	}
+	if !exists(filepath.Join(games, "updating", ".egstore", "bps", "chunk")) {
+		t.Error("the staging of a game being updated must be kept")
+	}
+}
@@ -0,0 +1,174 @@
+package cleaner
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrProtectedPath and ErrExcludedPath are wrapped by the CleanErrors
+// reported when the guard refuses to touch a path.
+var (
+	ErrProtectedPath = errors.New("refusing to clean protected path")
+	ErrExcludedPath  = errors.New("excluded by configuration")
+)
+
+// pathGuard is the last line of defence between a category and the
+// filesystem. Every directory is checked before it is walked and every file
+// before it is removed, so a misconfigured environment variable (TEMP set to
+// a drive root, say) or a typo in a custom rule cannot turn into deleting a
+// user's documents.
+//
+// Filesystem roots, the home directory and system directories are protected
+// themselves, together with their ancestors; cleaning below them (C:\Windows\Temp,
+// /var/tmp) stays possible. Configured protected paths are protected with
+// everything below them. Exclusion globs match a file or directory by name
+// or by full path.
+type pathGuard struct {
+	system    []string // Built-in: the directory itself and its ancestors
+	protected []string // Configured: the directory and everything below it
+	exclude   []string
+}
+
+// newPathGuard builds a guard from the built-in protected directories and the
+// configured protected paths and exclusion globs. Protected paths may use the
+// same variables as custom rules; paths that do not expand are ignored.
+func newPathGuard(protected, exclude []string) *pathGuard {
+	g := &pathGuard{system: systemPaths(), exclude: exclude}
+	for _, p := range protected {
+		expanded, ok := expandPath(p, os.LookupEnv)
+		if !ok {
+			log.Printf("[SysCleaner] Ignoring protected path %q: it does not expand to an absolute path", p)
+			continue
+		}
+		g.protected = append(g.protected, expanded)
+	}
+	return g
+}
+
+// systemPaths returns the built-in protected directories for this platform.
+func systemPaths() []string {
+	var paths []string
+	add := func(p string) {
+		if p != "" && filepath.IsAbs(p) {
+			paths = append(paths, filepath.Clean(p))
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	add(home)
+
+	switch runtime.GOOS {
+	case "windows":
+		winDir := os.Getenv("WINDIR")
+		add(winDir)
+		if winDir != "" {
+			add(filepath.Join(winDir, "System32"))
+			add(filepath.Join(winDir, "SysWOW64"))
+		}
+		for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)", "ProgramW6432", "ProgramData",
+			"APPDATA", "LOCALAPPDATA", "PUBLIC", "USERPROFILE"} {
+			add(os.Getenv(env))
+		}
+	default:
+		for _, p := range []string{"/bin", "/boot", "/dev", "/etc", "/lib", "/lib32", "/lib64", "/opt",
+			"/proc", "/root", "/run", "/sbin", "/srv", "/sys", "/usr", "/usr/bin", "/usr/lib",
+			"/usr/local", "/usr/share", "/var", "/var/lib"} {
+			add(p)
+		}
+		if home != "" {
+			for _, dir := range []string{".config", ".local", filepath.Join(".local", "share"), ".ssh", ".gnupg"} {
+				add(filepath.Join(home, dir))
+			}
+		}
+	}
+	if home != "" {
+		add(filepath.Join(home, "Desktop"))
+		add(filepath.Join(home, "Documents"))
+	}
+	return paths
+}
+
+// check returns a CleanError if path must not be cleaned or removed, and nil
+// otherwise. A nil guard applies only the built-in protections.
+func (g *pathGuard) check(path string) *CleanError {
+	if g == nil {
+		g = newPathGuard(nil, nil)
+	}
+	refuse := func(why string) *CleanError {
+		return &CleanError{Path: path, Type: ErrorProtected, Err: fmt.Errorf("%w (%s)", ErrProtectedPath, why)}
+	}
+
+	if !filepath.IsAbs(path) {
+		return refuse("not an absolute path")
+	}
+	path = filepath.Clean(path)
+	if isFilesystemRoot(path) {
+		return refuse("filesystem root")
+	}
+	for _, p := range g.system {
+		if pathWithin(p, path) {
+			return refuse("system or home directory")
+		}
+	}
+	for _, p := range g.protected {
+		if pathWithin(path, p) {
+			return refuse("protected by configuration")
+		}
+	}
+	if len(g.exclude) > 0 && matchPathGlobs(g.exclude, path) {
+		return &CleanError{Path: path, Type: ErrorExcluded, Err: ErrExcludedPath}
+	}
+	return nil
+}
+
+// isFilesystemRoot reports whether a clean, absolute path is a filesystem or
+// volume root such as "/", "C:\" or "\\server\share\".
+func isFilesystemRoot(path string) bool {
+	vol := filepath.VolumeName(path)
+	rest := path[len(vol):]
+	return rest == "" || rest == string(filepath.Separator)
+}
+
+// pathWithin reports whether path is dir or lies below it. Comparison is
+// case-insensitive on Windows.
+func pathWithin(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	if runtime.GOOS == "windows" {
+		path, dir = strings.ToLower(path), strings.ToLower(dir)
+	}
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
+
+// matchPathGlobs matches patterns against the base name and the full
+// slash-separated path, so both "*.kdbx" and "/home/*/keep/*" work.
+func matchPathGlobs(patterns []string, path string) bool {
+	full := filepath.ToSlash(path)
+	name := filepath.Base(path)
+	if runtime.GOOS == "windows" {
+		full, name = strings.ToLower(full), strings.ToLower(name)
+	}
+	for _, p := range patterns {
+		p = filepath.ToSlash(p)
+		if runtime.GOOS == "windows" {
+			p = strings.ToLower(p)
+		}
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, full); ok {
+			return true
+		}
+	}
+	return false
+}
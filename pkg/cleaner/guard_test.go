@@ -0,0 +1,87 @@
+package cleaner
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// ---------- pathGuard tests ----------
+
+func TestPathGuard_BuiltinProtections(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "home", "user")
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	g := newPathGuard(nil, nil)
+
+	for _, path := range []string{
+		string(filepath.Separator),
+		"relative/dir",
+		home,
+		filepath.Dir(home),
+		filepath.Join(home, "Documents"),
+	} {
+		ce := g.check(path)
+		if ce == nil || ce.Type != ErrorProtected || !errors.Is(ce, ErrProtectedPath) {
+			t.Errorf("expected %q to be refused as protected, got %v", path, ce)
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(home, ".cache", "thumbnails"),
+		filepath.Join(home, "Documents", "old.tmp"),
+	} {
+		if ce := g.check(path); ce != nil {
+			t.Errorf("expected %q to be allowed, got %v", path, ce)
+		}
+	}
+}
+
+func TestPathGuard_ConfiguredPathsAndExclusions(t *testing.T) {
+	root := t.TempDir()
+	keep := filepath.Join(root, "keep")
+	g := newPathGuard([]string{keep}, []string{"*.kdbx", filepath.ToSlash(filepath.Join(root, "logs", "*"))})
+
+	if ce := g.check(filepath.Join(keep, "sub", "a.tmp")); ce == nil || ce.Type != ErrorProtected {
+		t.Errorf("expected files below a protected path to be refused, got %v", ce)
+	}
+	if ce := g.check(filepath.Join(root, "vault.kdbx")); ce == nil || ce.Type != ErrorExcluded {
+		t.Errorf("expected name glob to exclude, got %v", ce)
+	}
+	if ce := g.check(filepath.Join(root, "logs", "app.log")); ce == nil || ce.Type != ErrorExcluded {
+		t.Errorf("expected path glob to exclude, got %v", ce)
+	}
+	if ce := g.check(filepath.Join(root, "other.tmp")); ce != nil {
+		t.Errorf("expected other files to be allowed, got %v", ce)
+	}
+}
+
+func TestCleanDirectoryFiltered_ReportsRefusals(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.tmp"), "a")
+	writeFile(t, filepath.Join(root, "vault.kdbx"), "secret")
+	writeFile(t, filepath.Join(root, "keep", "b.tmp"), "b")
+	writeFile(t, filepath.Join(root, "keep", "c.tmp"), "c")
+
+	sw := sweeper{guard: newPathGuard([]string{filepath.Join(root, "keep")}, []string{"*.kdbx"})}
+	result := cleanDirectoryFiltered(root, fileFilter{}, sw)
+
+	if result.FilesDeleted != 1 {
+		t.Errorf("expected only a.tmp to be deleted, got %d", result.FilesDeleted)
+	}
+	if n := len(result.ErrorsOfType(ErrorProtected)); n != 1 {
+		t.Errorf("expected one refusal for the protected directory, got %d", n)
+	}
+	if n := len(result.ErrorsOfType(ErrorExcluded)); n != 1 {
+		t.Errorf("expected one refusal for the excluded file, got %d", n)
+	}
+	for _, kept := range []string{"vault.kdbx", "keep/b.tmp", "keep/c.tmp"} {
+		if !exists(filepath.Join(root, kept)) {
+			t.Errorf("%s should have been kept", kept)
+		}
+	}
+
+	if result := cleanDirectoryFiltered(filepath.Join(root, "keep"), fileFilter{}, sw); len(result.ErrorsOfType(ErrorProtected)) != 1 {
+		t.Errorf("expected cleaning a protected root to be refused, got %v", result.Errors)
+	}
+}
@@ -0,0 +1,237 @@
+package cleaner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// HistoryRecord is one clean run in the history file, stored as one JSON
+// line.
+type HistoryRecord struct {
+	Time             time.Time        `json:"time"`
+	Profile          string           `json:"profile"`
+	DryRun           bool             `json:"dry_run"`
+	Outcome          Outcome          `json:"outcome"`
+	FilesDeleted     int64            `json:"files_deleted"`
+	BytesFreed       int64            `json:"bytes_freed"`
+	BytesReclaimed   int64            `json:"bytes_reclaimed"`
+	FilesQuarantined int64            `json:"files_quarantined"`
+	BytesQuarantined int64            `json:"bytes_quarantined"`
+	Errors           int              `json:"errors"`
+	ErrorCounts      map[string]int   `json:"error_counts,omitempty"` // By ErrorType name
+	DurationMS       int64            `json:"duration_ms"`
+	Categories       []CategoryReport `json:"categories"`
+}
+
+// NewHistoryRecord summarizes a finished run for the history file.
+func NewHistoryRecord(r CleanResult, profile string, dryRun bool, at time.Time) HistoryRecord {
+	rep := NewReport(r, dryRun)
+	return HistoryRecord{
+		Time:             at,
+		Profile:          profile,
+		DryRun:           dryRun,
+		Outcome:          rep.Outcome,
+		FilesDeleted:     rep.FilesDeleted,
+		BytesFreed:       rep.BytesFreed,
+		BytesReclaimed:   rep.BytesReclaimed,
+		FilesQuarantined: rep.FilesQuarantined,
+		BytesQuarantined: rep.BytesQuarantined,
+		Errors:           len(rep.Errors),
+		ErrorCounts:      rep.ErrorCounts,
+		DurationMS:       rep.DurationMS,
+		Categories:       rep.Categories,
+	}
+}
+
+// historyMaxBytes caps the history file. When an append takes it past the
+// cap, the oldest half of the records is dropped.
+const historyMaxBytes = 4 << 20
+
+// AppendHistory appends rec to the history file at path, creating it on
+// first use.
+func AppendHistory(path string, rec HistoryRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing history: %w", err)
+	}
+	info, statErr := f.Stat()
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	if statErr == nil && info.Size() > historyMaxBytes {
+		return trimHistory(path)
+	}
+	return nil
+}
+
+// LoadHistory reads every record in the history file at path, oldest first.
+// A missing file is an empty history.
+func LoadHistory(path string) ([]HistoryRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A torn line from an interrupted write is ignored.
+			continue
+		}
+		records = append(records, rec)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, scanner.Err()
+}
+
+// trimHistory rewrites the history file with the newest half of its records.
+func trimHistory(path string) error {
+	records, err := LoadHistory(path)
+	if err != nil {
+		return err
+	}
+	records = records[len(records)/2:]
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// CategoryTotal sums one category over the real (not dry) runs in a history.
+type CategoryTotal struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Runs         int       `json:"runs"`
+	Incomplete   int       `json:"incomplete"` // Runs the category did not complete
+	FilesDeleted int64     `json:"files_deleted"`
+	BytesFreed   int64     `json:"bytes_freed"`
+	LastRun      time.Time `json:"last_run"`
+}
+
+// CategoryTotals returns the per-category totals of the real runs in
+// records, most space freed first.
+func CategoryTotals(records []HistoryRecord) []CategoryTotal {
+	byID := map[string]*CategoryTotal{}
+	for _, rec := range records {
+		if rec.DryRun {
+			continue
+		}
+		for _, c := range rec.Categories {
+			t := byID[c.ID]
+			if t == nil {
+				t = &CategoryTotal{ID: c.ID}
+				byID[c.ID] = t
+			}
+			t.Name = c.Name
+			t.Runs++
+			if c.Status != StatusCompleted && c.Status != StatusSkipped {
+				t.Incomplete++
+			}
+			t.FilesDeleted += c.FilesDeleted
+			t.BytesFreed += c.BytesFreed
+			if rec.Time.After(t.LastRun) {
+				t.LastRun = rec.Time
+			}
+		}
+	}
+	totals := []CategoryTotal{}
+	for _, t := range byID {
+		totals = append(totals, *t)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].BytesFreed != totals[j].BytesFreed {
+			return totals[i].BytesFreed > totals[j].BytesFreed
+		}
+		return totals[i].ID < totals[j].ID
+	})
+	return totals
+}
+
+// WeekTotal sums the real runs of one week.
+type WeekTotal struct {
+	Start          time.Time `json:"start"` // Monday 00:00, local time
+	Runs           int       `json:"runs"`
+	FilesDeleted   int64     `json:"files_deleted"`
+	BytesFreed     int64     `json:"bytes_freed"`
+	BytesReclaimed int64     `json:"bytes_reclaimed"`
+}
+
+// WeeklyTotals returns one WeekTotal per week for the weeks weeks up to and
+// including the one containing now, oldest first. Weeks without runs are
+// included with zero totals so the result can be charted directly.
+func WeeklyTotals(records []HistoryRecord, weeks int, now time.Time) []WeekTotal {
+	if weeks <= 0 {
+		return []WeekTotal{}
+	}
+	totals := make([]WeekTotal, weeks)
+	last := weekStart(now)
+	for i := range totals {
+		totals[i].Start = last.AddDate(0, 0, -7*(weeks-1-i))
+	}
+	first := totals[0].Start
+	for _, rec := range records {
+		if rec.DryRun {
+			continue
+		}
+		start := weekStart(rec.Time)
+		if start.Before(first) || start.After(last) {
+			continue
+		}
+		// Match week starts rather than dividing durations, which DST
+		// changes would skew.
+		i := 0
+		for totals[i].Start.Before(start) {
+			i++
+		}
+		totals[i].Runs++
+		totals[i].FilesDeleted += rec.FilesDeleted
+		totals[i].BytesFreed += rec.BytesFreed
+		totals[i].BytesReclaimed += rec.BytesReclaimed
+	}
+	return totals
+}
+
+// weekStart returns midnight on the Monday of the week containing t, in
+// local time.
+func weekStart(t time.Time) time.Time {
+	t = t.Local()
+	offset := (int(t.Weekday()) + 6) % 7 // Days since Monday
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.Local)
+}
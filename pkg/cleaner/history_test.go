@@ -0,0 +1,137 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---------- history tests ----------
+
+func TestHistory_AppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	result := CleanResult{
+		FilesDeleted: 2,
+		SpaceFreed:   300,
+		Duration:     1500 * time.Millisecond,
+		Errors:       []error{&CleanError{Path: "/x", Type: ErrorProtected, Err: ErrProtectedPath}},
+		Categories:   []CategoryStatus{{ID: "a", Name: "A", Status: StatusCompleted, FilesDeleted: 2, SpaceFreed: 300}},
+	}
+	at := time.Date(2024, 3, 6, 10, 0, 0, 0, time.UTC)
+	if err := AppendHistory(path, NewHistoryRecord(result, "gaming", false, at.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendHistory(path, NewHistoryRecord(CleanResult{}, "default", true, at)); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if !records[0].DryRun || records[1].Profile != "gaming" {
+		t.Errorf("expected records oldest first, got %+v", records)
+	}
+	got := records[1]
+	if got.FilesDeleted != 2 || got.BytesFreed != 300 || got.DurationMS != 1500 || got.Errors != 1 || got.ErrorCounts["protected"] != 1 {
+		t.Errorf("unexpected record %+v", got)
+	}
+	if len(got.Categories) != 1 || got.Categories[0].BytesFreed != 300 {
+		t.Errorf("unexpected categories %+v", got.Categories)
+	}
+}
+
+func TestLoadHistory_MissingFileAndTornLine(t *testing.T) {
+	dir := t.TempDir()
+	if records, err := LoadHistory(filepath.Join(dir, "none.jsonl")); err != nil || len(records) != 0 {
+		t.Errorf("expected an empty history, got %v, %v", records, err)
+	}
+
+	path := filepath.Join(dir, "history.jsonl")
+	if err := AppendHistory(path, HistoryRecord{Profile: "default"}); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString(`{"time":"2024-`)
+	f.Close()
+	if records, err := LoadHistory(path); err != nil || len(records) != 1 {
+		t.Errorf("expected the torn line to be ignored, got %v, %v", records, err)
+	}
+}
+
+func TestAppendHistory_TrimsOldestRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	big := HistoryRecord{Profile: strings.Repeat("x", 64*1024)}
+	n := historyMaxBytes/len(big.Profile) + 1
+	for i := 0; i < n; i++ {
+		big.Time = time.Unix(int64(i), 0)
+		if err := AppendHistory(path, big); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) >= n || records[len(records)-1].Time.Unix() != int64(n-1) {
+		t.Errorf("expected the oldest records to be dropped, kept %d of %d", len(records), n)
+	}
+}
+
+func TestCategoryTotals(t *testing.T) {
+	t1 := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	records := []HistoryRecord{
+		{Time: t1, Categories: []CategoryReport{
+			{ID: "a", Name: "A", Status: StatusCompleted, FilesDeleted: 1, BytesFreed: 100},
+			{ID: "b", Name: "B", Status: StatusPartial, FilesDeleted: 5, BytesFreed: 500},
+		}},
+		{Time: t1.Add(time.Hour), Categories: []CategoryReport{
+			{ID: "a", Name: "A", Status: StatusCompleted, FilesDeleted: 2, BytesFreed: 200},
+		}},
+		{Time: t1.Add(2 * time.Hour), DryRun: true, Categories: []CategoryReport{
+			{ID: "a", Name: "A", Status: StatusCompleted, FilesDeleted: 9, BytesFreed: 900},
+		}},
+	}
+	totals := CategoryTotals(records)
+	if len(totals) != 2 || totals[0].ID != "b" {
+		t.Fatalf("expected b (most freed) then a, got %+v", totals)
+	}
+	a := totals[1]
+	if a.Runs != 2 || a.FilesDeleted != 3 || a.BytesFreed != 300 || !a.LastRun.Equal(t1.Add(time.Hour)) {
+		t.Errorf("expected dry runs to be ignored, got %+v", a)
+	}
+	if totals[0].Incomplete != 1 {
+		t.Errorf("expected b to have one incomplete run, got %+v", totals[0])
+	}
+}
+
+func TestWeeklyTotals(t *testing.T) {
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.Local) // Wednesday
+	records := []HistoryRecord{
+		{Time: time.Date(2024, 3, 11, 0, 30, 0, 0, time.Local), BytesFreed: 10, BytesReclaimed: 8},   // This week's Monday
+		{Time: time.Date(2024, 3, 10, 23, 30, 0, 0, time.Local), BytesFreed: 20, BytesReclaimed: 16}, // Previous Sunday
+		{Time: time.Date(2024, 3, 12, 9, 0, 0, 0, time.Local), BytesFreed: 99, DryRun: true},
+		{Time: time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local), BytesFreed: 1000}, // Outside the range
+	}
+	weeks := WeeklyTotals(records, 3, now)
+	if len(weeks) != 3 {
+		t.Fatalf("expected 3 weeks, got %d", len(weeks))
+	}
+	if want := time.Date(2024, 3, 11, 0, 0, 0, 0, time.Local); !weeks[2].Start.Equal(want) {
+		t.Errorf("expected the last week to start %v, got %v", want, weeks[2].Start)
+	}
+	if weeks[2].Runs != 1 || weeks[2].BytesReclaimed != 8 {
+		t.Errorf("unexpected current week %+v", weeks[2])
+	}
+	if weeks[1].Runs != 1 || weeks[1].BytesFreed != 20 {
+		t.Errorf("unexpected previous week %+v", weeks[1])
+	}
+	if weeks[0].Runs != 0 {
+		t.Errorf("expected an empty first week, got %+v", weeks[0])
+	}
+}
@@ -0,0 +1,317 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PendingFile is a file a clean could not remove because it was in use. The
+// pending queue keeps such files so that the next clean retries them instead
+// of leaving the same locked cache files behind forever.
+type PendingFile struct {
+	Path     string    `json:"path"`
+	Category string    `json:"category"`
+	Size     int64     `json:"size"`
+	Queued   time.Time `json:"queued"`    // When it was first found in use
+	Attempts int       `json:"attempts"`  // Retries that found it still in use
+	OnReboot bool      `json:"on_reboot"` // Scheduled for deletion at the next reboot
+}
+
+// PendingExpiry is how long a file stays in the pending queue. A file still
+// in use after that is dropped rather than retried forever.
+const PendingExpiry = 30 * 24 * time.Hour
+
+// pendingLockWait is how long an update of the pending queue waits for
+// another run (the GUI and the CLI may run at once) to release it, and
+// pendingLockStale the age at which a lock file is taken to be left behind
+// by a run that died.
+const (
+	pendingLockWait  = 10 * time.Second
+	pendingLockStale = time.Minute
+)
+
+// pendingMu serializes updates of the pending queue within the process;
+// lockPending adds a lock file next to the queue for other processes.
+var pendingMu sync.Mutex
+
+// ErrRebootDeleteUnsupported is returned by SchedulePendingOnReboot where
+// files cannot be scheduled for deletion at the next reboot.
+var ErrRebootDeleteUnsupported = errors.New("deleting files at the next reboot is only supported on Windows")
+
+// pendingKey identifies a queued path, case-insensitively on Windows.
+func pendingKey(path string) string {
+	path = filepath.Clean(path)
+	if runtime.GOOS == "windows" {
+		path = strings.ToLower(path)
+	}
+	return path
+}
+
+// LoadPending reads the pending queue at path. A missing file is an empty
+// queue.
+func LoadPending(path string) ([]PendingFile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading pending queue: %w", err)
+	}
+	var files []PendingFile
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("parsing pending queue %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// lockPending takes the lock on the pending queue at path and returns the
+// function releasing it. Every load-modify-save of the queue runs under it
+// so that one run does not overwrite the files another just queued.
+func lockPending(path string) (func(), error) {
+	pendingMu.Lock()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		pendingMu.Unlock()
+		return nil, fmt.Errorf("locking pending queue: %w", err)
+	}
+	lock := path + ".lock"
+	deadline := time.Now().Add(pendingLockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(lock)
+				pendingMu.Unlock()
+			}, nil
+		}
+		if !os.IsExist(err) {
+			pendingMu.Unlock()
+			return nil, fmt.Errorf("locking pending queue: %w", err)
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > pendingLockStale {
+			log.Printf("[SysCleaner] Removing stale pending queue lock %s", lock)
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			pendingMu.Unlock()
+			return nil, fmt.Errorf("pending queue %s is locked by another run", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// savePending replaces the pending queue at path, removing the file when the
+// queue is empty. The queue is written to a temporary file of its own and
+// renamed over path, so a reader never sees a partly written queue.
+func savePending(path string, files []PendingFile) error {
+	if len(files) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing pending queue: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing pending queue: %w", err)
+	}
+	return nil
+}
+
+// AddPending adds the files a clean found in use (CleanResult.Locked) to the
+// pending queue at path. Files already queued keep their queue time.
+func AddPending(path string, files []PendingFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+	unlock, err := lockPending(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	queue, err := LoadPending(path)
+	if err != nil {
+		return err
+	}
+	queued := map[string]bool{}
+	for _, f := range queue {
+		queued[pendingKey(f.Path)] = true
+	}
+	now := time.Now()
+	for _, f := range files {
+		if key := pendingKey(f.Path); !queued[key] {
+			queued[key] = true
+			f.Queued = now
+			queue = append(queue, f)
+		}
+	}
+	return savePending(path, queue)
+}
+
+// RetryPending retries the files in the pending queue at path, each as its
+// category and through the same pipeline as a clean: protected paths and
+// exclusions are refused and opts.Quarantined applies. Files still in use
+// stay in the queue with one more attempt; every other file leaves it,
+// whether it was removed, is gone or failed otherwise. Files queued longer
+// than PendingExpiry are dropped without a retry. Dry runs leave the queue
+// alone. The queue is not locked while the files are retried; the outcome
+// is merged into the queue as it is then, keeping files queued meanwhile.
+func RetryPending(ctx context.Context, path string, opts CleanOptions) (CleanResult, error) {
+	if opts.DryRun {
+		return CleanResult{}, nil
+	}
+	unlock, err := lockPending(path)
+	if err != nil {
+		return CleanResult{}, err
+	}
+	queue, err := LoadPending(path)
+	unlock()
+	if err != nil || len(queue) == 0 {
+		return CleanResult{}, err
+	}
+
+	now := time.Now()
+	byCategory := map[string][]PendingFile{}
+	var order []string
+	for _, f := range queue {
+		if now.Sub(f.Queued) > PendingExpiry {
+			log.Printf("[SysCleaner] Dropping %s from the pending queue: still in use after %s", f.Path, FormatAge(PendingExpiry))
+			continue
+		}
+		if _, ok := byCategory[f.Category]; !ok {
+			order = append(order, f.Category)
+		}
+		byCategory[f.Category] = append(byCategory[f.Category], f)
+	}
+
+	// Files of a category skipped because its application is running, or
+	// not reached before the run was stopped, are not attempted.
+	var (
+		mu        sync.Mutex
+		attempted = map[string]bool{}
+	)
+	var tasks []cleanTask
+	for _, id := range order {
+		c, ok := LookupCategory(id)
+		if !ok {
+			c = Category{ID: id, Name: id}
+		}
+		files := byCategory[id]
+		tasks = append(tasks, cleanTask{c, func(opts CleanOptions) CleanResult {
+			result := CleanResult{}
+			sw := newSweeper(c, opts)
+			for _, f := range files {
+				if sw.stopped(&result) {
+					break
+				}
+				info, err := os.Lstat(f.Path)
+				if err != nil || info.IsDir() {
+					continue
+				}
+				mu.Lock()
+				attempted[pendingKey(f.Path)] = true
+				mu.Unlock()
+				sw.remove(f.Path, info, fmt.Sprintf("in use since %s", f.Queued.Format("2006-01-02 15:04")), &result)
+			}
+			return result
+		}})
+	}
+	result := runTasks(ctx, tasks, opts)
+
+	retried := map[string]bool{}
+	for _, f := range queue {
+		retried[pendingKey(f.Path)] = true
+	}
+	locked := map[string]bool{}
+	for _, f := range result.Locked {
+		locked[pendingKey(f.Path)] = true
+	}
+
+	unlock, err = lockPending(path)
+	if err != nil {
+		return result, err
+	}
+	defer unlock()
+	current, err := LoadPending(path)
+	if err != nil {
+		return result, err
+	}
+	var keep []PendingFile
+	for _, f := range current {
+		key := pendingKey(f.Path)
+		switch {
+		case !retried[key]:
+			keep = append(keep, f)
+		case now.Sub(f.Queued) > PendingExpiry:
+		case locked[key]:
+			f.Attempts++
+			keep = append(keep, f)
+		case !attempted[key]:
+			if _, err := os.Lstat(f.Path); err == nil {
+				keep = append(keep, f)
+			}
+		}
+	}
+	return result, savePending(path, keep)
+}
+
+// SchedulePendingOnReboot asks Windows to delete the queued files at the
+// next reboot (MoveFileEx with MOVEFILE_DELAY_UNTIL_REBOOT), which needs
+// administrator rights. Files stay in the queue until a retry finds them
+// gone. It returns how many files were newly scheduled.
+func SchedulePendingOnReboot(path string) (int, error) {
+	if runtime.GOOS != "windows" {
+		return 0, ErrRebootDeleteUnsupported
+	}
+	unlock, err := lockPending(path)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	queue, err := LoadPending(path)
+	if err != nil {
+		return 0, err
+	}
+	scheduled := 0
+	var errs []error
+	for i := range queue {
+		if queue[i].OnReboot {
+			continue
+		}
+		if err := deleteOnReboot(queue[i].Path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", queue[i].Path, err))
+			continue
+		}
+		queue[i].OnReboot = true
+		scheduled++
+	}
+	if err := savePending(path, queue); err != nil {
+		errs = append(errs, err)
+	}
+	return scheduled, errors.Join(errs...)
+}
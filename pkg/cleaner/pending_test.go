@@ -0,0 +1,140 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// ---------- Pending queue tests ----------
+
+func TestAddPending_KeepsFirstQueueTime(t *testing.T) {
+	queuePath := filepath.Join(t.TempDir(), "pending.json")
+	if err := AddPending(queuePath, []PendingFile{{Path: "/a", Category: "c", Size: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := LoadPending(queuePath)
+	time.Sleep(10 * time.Millisecond)
+	if err := AddPending(queuePath, []PendingFile{{Path: "/a", Category: "c"}, {Path: "/b", Category: "c"}}); err != nil {
+		t.Fatal(err)
+	}
+	queue, err := LoadPending(queuePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 2 {
+		t.Fatalf("expected 2 queued files, got %d", len(queue))
+	}
+	if !queue[0].Queued.Equal(first[0].Queued) {
+		t.Error("a file queued again must keep its first queue time")
+	}
+}
+
+func TestRetryPending_RemovesFreedFiles(t *testing.T) {
+	dir := t.TempDir()
+	queuePath := filepath.Join(dir, "pending.json")
+	freed := filepath.Join(dir, "cache", "freed.tmp")
+	writeFile(t, freed, "12345")
+	expired := filepath.Join(dir, "cache", "expired.tmp")
+	writeFile(t, expired, "x")
+	if err := AddPending(queuePath, []PendingFile{
+		{Path: freed, Category: "pending_test", Size: 5},
+		{Path: filepath.Join(dir, "cache", "gone.tmp"), Category: "pending_test"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	queue, _ := LoadPending(queuePath)
+	queue = append(queue, PendingFile{Path: expired, Category: "pending_test", Queued: time.Now().Add(-PendingExpiry - time.Hour)})
+	if err := savePending(queuePath, queue); err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := RetryPending(context.Background(), queuePath, CleanOptions{DryRun: true})
+	if err != nil || dry.FilesDeleted != 0 || !exists(freed) {
+		t.Fatalf("a dry run must leave the queue alone, got %d removed, err %v", dry.FilesDeleted, err)
+	}
+
+	result, err := RetryPending(context.Background(), queuePath, CleanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FilesDeleted != 1 || result.SpaceFreed != 5 || exists(freed) {
+		t.Errorf("expected the freed file to be removed, got %d files and %d bytes", result.FilesDeleted, result.SpaceFreed)
+	}
+	if !exists(expired) {
+		t.Error("an expired file must be dropped from the queue, not removed")
+	}
+	if queue, _ := LoadPending(queuePath); len(queue) != 0 || exists(queuePath) {
+		t.Errorf("expected the queue to be empty and removed, got %d files", len(queue))
+	}
+}
+
+func TestRetryPending_KeepsFilesQueuedMeanwhile(t *testing.T) {
+	dir := t.TempDir()
+	queuePath := filepath.Join(dir, "pending.json")
+	freed := filepath.Join(dir, "cache", "freed.tmp")
+	writeFile(t, freed, "x")
+	if err := AddPending(queuePath, []PendingFile{{Path: freed, Category: "pending_test"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another run queues a file while the retry is removing the first.
+	late := filepath.Join(dir, "cache", "late.tmp")
+	var addErr error
+	opts := CleanOptions{}
+	opts.events = newEventEmitter(func(ev ProgressEvent) {
+		if ev.Kind == EventFileRemoved {
+			addErr = AddPending(queuePath, []PendingFile{{Path: late, Category: "pending_test"}})
+		}
+	}, 0, 0)
+	if _, err := RetryPending(context.Background(), queuePath, opts); err != nil {
+		t.Fatal(err)
+	}
+	if addErr != nil {
+		t.Fatal(addErr)
+	}
+	queue, err := LoadPending(queuePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].Path != late {
+		t.Errorf("expected only the file queued during the retry to stay queued, got %v", queue)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "pending.json.*")); len(matches) != 0 {
+		t.Errorf("expected no lock or temporary files left behind, got %v", matches)
+	}
+}
+
+func TestRetryPending_RefusesProtectedFiles(t *testing.T) {
+	dir := t.TempDir()
+	queuePath := filepath.Join(dir, "pending.json")
+	kept := filepath.Join(dir, "keep", "data.tmp")
+	writeFile(t, kept, "x")
+	if err := AddPending(queuePath, []PendingFile{{Path: kept, Category: "pending_test"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := RetryPending(context.Background(), queuePath, CleanOptions{ProtectedPaths: []string{filepath.Join(dir, "keep")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists(kept) || len(result.ErrorsOfType(ErrorProtected)) != 1 {
+		t.Error("expected the protected file to be refused")
+	}
+	if queue, _ := LoadPending(queuePath); len(queue) != 0 {
+		t.Error("a refused file must leave the queue")
+	}
+}
+
+func TestSchedulePendingOnReboot_Unsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("deleting at reboot is supported on Windows")
+	}
+	_, err := SchedulePendingOnReboot(filepath.Join(t.TempDir(), "pending.json"))
+	if !errors.Is(err, ErrRebootDeleteUnsupported) {
+		t.Errorf("expected ErrRebootDeleteUnsupported, got %v", err)
+	}
+}
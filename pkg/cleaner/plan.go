@@ -0,0 +1,223 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Plan is the reviewable result of Scan: every file each selected category
+// would remove. Execute removes exactly the files in a plan, so a plan can be
+// saved, inspected or edited, and applied later.
+type Plan struct {
+	Version    int            `json:"version"`
+	Created    time.Time      `json:"created"`
+	Categories []PlanCategory `json:"categories"`
+}
+
+// PlanCategory holds the candidates of one category. Categories that do not
+// remove files (flushing the DNS cache, clearing event logs, ...) have an
+// Action instead and are run as a whole.
+type PlanCategory struct {
+	ID     string     `json:"id"`
+	Name   string     `json:"name"`
+	Action string     `json:"action,omitempty"`
+	Files  []PlanFile `json:"files,omitempty"`
+}
+
+// PlanFile is a single candidate. Size and ModTime are re-checked before the
+// file is removed; a file that changed since the scan is skipped.
+type PlanFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Dir     bool      `json:"dir,omitempty"` // Removed as a whole tree (Trash items)
+	Reason  string    `json:"reason"`
+}
+
+// planVersion is bumped when the plan format changes incompatibly.
+const planVersion = 1
+
+// Size returns the total size of the category's candidates.
+func (pc PlanCategory) Size() int64 {
+	var size int64
+	for _, f := range pc.Files {
+		size += f.Size
+	}
+	return size
+}
+
+// Totals returns the number of candidate files and their total size.
+func (p *Plan) Totals() (int64, int64) {
+	var files, size int64
+	for _, pc := range p.Categories {
+		files += int64(len(pc.Files))
+		size += pc.Size()
+	}
+	return files, size
+}
+
+// planCollector gathers candidates from concurrently running categories.
+type planCollector struct {
+	mu    sync.Mutex
+	files map[string][]PlanFile
+}
+
+func (pc *planCollector) add(category string, f PlanFile) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.files[category] = append(pc.files[category], f)
+}
+
+// Scan runs every selected category in dry-run mode and returns the files
+// they would remove. The DryRun, Quarantine and Archive options are ignored.
+func Scan(opts CleanOptions) *Plan {
+	return ScanContext(context.Background(), opts)
+}
+
+// ScanContext is Scan with cancellation. A cancelled scan returns the
+// candidates found so far.
+func ScanContext(ctx context.Context, opts CleanOptions) *Plan {
+	collector := &planCollector{files: map[string][]PlanFile{}}
+	opts.DryRun = true
+	opts.Quarantine = nil
+	opts.Archive = nil
+	opts.collect = collector
+	PerformCleanContext(ctx, opts)
+
+	plan := &Plan{Version: planVersion, Created: time.Now()}
+	for _, c := range Categories() {
+		if !opts.Enabled(c.ID) || !c.Supported() || !c.Detected() {
+			continue
+		}
+		pc := PlanCategory{ID: c.ID, Name: c.Name, Action: c.Action, Files: collector.files[c.ID]}
+		if pc.Action != "" || len(pc.Files) > 0 {
+			plan.Categories = append(plan.Categories, pc)
+		}
+	}
+	return plan
+}
+
+// Execute removes the files in plan, using opts for everything except the
+// category selection (quarantine, dry-run, progress). Files that no longer
+// exist are ignored; files whose size or mtime changed since the scan are
+// skipped and counted in ChangedFiles.
+func Execute(plan *Plan, opts CleanOptions) CleanResult {
+	return ExecuteContext(context.Background(), plan, opts)
+}
+
+// ExecuteContext is Execute with cancellation, like PerformCleanContext.
+func ExecuteContext(ctx context.Context, plan *Plan, opts CleanOptions) CleanResult {
+	var (
+		tasks []cleanTask
+		errs  []error
+	)
+	for _, pc := range plan.Categories {
+		c, ok := LookupCategory(pc.ID)
+		if !ok {
+			errs = append(errs, fmt.Errorf("plan contains unknown category %q", pc.ID))
+			continue
+		}
+		if !c.Supported() {
+			errs = append(errs, fmt.Errorf("category %q is not available on this platform", pc.ID))
+			continue
+		}
+		pc := pc
+		tasks = append(tasks, cleanTask{c, func(opts CleanOptions) CleanResult {
+			switch {
+			case pc.Action != "":
+				return c.run(opts)
+			case c.Execute != nil:
+				return c.Execute(c, pc.Files, opts)
+			default:
+				return executeFiles(c, pc.Files, opts)
+			}
+		}})
+	}
+
+	if opts.Events != nil && opts.events == nil {
+		files, bytes := plan.Totals()
+		opts.events = newEventEmitter(opts.Events, files, bytes)
+	}
+	result := runTasks(ctx, tasks, opts)
+	result.Errors = append(errs, result.Errors...)
+	return result
+}
+
+// executeFiles removes planned files through the category's sweeper.
+func executeFiles(c Category, files []PlanFile, opts CleanOptions) CleanResult {
+	result := CleanResult{}
+	sw := newSweeper(c, opts)
+	for _, f := range files {
+		if sw.stopped(&result) {
+			break
+		}
+		info, ok := sw.checkPlanFile(f, &result)
+		if !ok {
+			continue
+		}
+		if f.Dir {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: directories can only be planned by the Trash category", f.Path))
+			continue
+		}
+		sw.remove(f.Path, info, f.Reason, &result)
+	}
+	return result
+}
+
+// checkPlanFile re-checks a planned file before removal. It reports false for
+// files that are gone or that changed since the scan.
+func (s sweeper) checkPlanFile(f PlanFile, result *CleanResult) (os.FileInfo, bool) {
+	info, err := os.Lstat(f.Path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			result.Errors = append(result.Errors, ClassifyError(f.Path, err))
+		}
+		return nil, false
+	}
+
+	size := info.Size()
+	if f.Dir && info.IsDir() {
+		_, size = treeSize(f.Path)
+	}
+	if info.IsDir() != f.Dir || size != f.Size || !info.ModTime().Equal(f.ModTime) {
+		log.Printf("[SysCleaner] Skipping %s: changed since the plan was made", f.Path)
+		result.SkippedFiles++
+		result.ChangedFiles++
+		s.skipped(f.Path, f.Size, "changed since the plan was made")
+		return nil, false
+	}
+	return info, true
+}
+
+// Save writes the plan as indented JSON.
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling plan: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	return nil
+}
+
+// LoadPlan reads a plan written by Save.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+	p := &Plan{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+	if p.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d", p.Version)
+	}
+	return p, nil
+}
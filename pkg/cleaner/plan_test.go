@@ -0,0 +1,132 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helper: registerDirCategory registers a category cleaning dir.
+func registerDirCategory(t *testing.T, id, dir string) {
+	t.Helper()
+	if err := Register(Category{
+		ID:    id,
+		Name:  id,
+		Group: GroupApplications,
+		Paths: []PathResolver{func() []string { return []string{dir} }},
+	}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+}
+
+// ---------- Plan tests ----------
+
+func TestScan_CollectsFilesWithoutRemoving(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.tmp"), "aaaa")
+	writeFile(t, filepath.Join(dir, "sub", "b.tmp"), "bb")
+	registerDirCategory(t, "test_plan_scan", dir)
+
+	opts := CleanOptions{}
+	opts.Enable("test_plan_scan")
+	plan := Scan(opts)
+
+	if len(plan.Categories) != 1 || plan.Categories[0].ID != "test_plan_scan" {
+		t.Fatalf("expected one planned category, got %+v", plan.Categories)
+	}
+	pc := plan.Categories[0]
+	if len(pc.Files) != 2 || pc.Size() != 6 {
+		t.Errorf("expected 2 files / 6 bytes, got %d / %d", len(pc.Files), pc.Size())
+	}
+	for _, f := range pc.Files {
+		if !strings.Contains(f.Reason, dir) {
+			t.Errorf("expected reason to name the cleaned directory, got %q", f.Reason)
+		}
+	}
+	if !exists(filepath.Join(dir, "a.tmp")) || !exists(filepath.Join(dir, "sub", "b.tmp")) {
+		t.Error("Scan must not remove anything")
+	}
+}
+
+func TestPlan_SaveLoadRoundTrip(t *testing.T) {
+	mtime := time.Date(2024, 3, 1, 10, 20, 30, 0, time.UTC)
+	plan := &Plan{Version: planVersion, Created: mtime, Categories: []PlanCategory{
+		{ID: "dns_cache", Name: "DNS Cache", Action: "Flush the DNS resolver cache"},
+		{ID: "x", Name: "X", Files: []PlanFile{{Path: "/tmp/x", Size: 3, ModTime: mtime, Reason: "in /tmp"}}},
+	}}
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := plan.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan failed: %v", err)
+	}
+	if len(loaded.Categories) != 2 || loaded.Categories[0].Action == "" {
+		t.Fatalf("unexpected plan %+v", loaded)
+	}
+	if f := loaded.Categories[1].Files[0]; f.Path != "/tmp/x" || !f.ModTime.Equal(mtime) {
+		t.Errorf("unexpected file %+v", f)
+	}
+
+	writeFile(t, path, `{"version": 99}`)
+	if _, err := LoadPlan(path); err == nil {
+		t.Error("expected an unknown version to be rejected")
+	}
+}
+
+func TestExecute_RemovesOnlyPlannedUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "keep.tmp")
+	changed := filepath.Join(dir, "changed.tmp")
+	writeFile(t, keep, "keep")
+	writeFile(t, changed, "old")
+	registerDirCategory(t, "test_plan_execute", dir)
+
+	opts := CleanOptions{}
+	opts.Enable("test_plan_execute")
+	plan := Scan(opts)
+
+	// Drop keep.tmp from the plan, modify changed.tmp and add a file that
+	// was not scanned.
+	var files []PlanFile
+	for _, f := range plan.Categories[0].Files {
+		if f.Path != keep {
+			files = append(files, f)
+		}
+	}
+	plan.Categories[0].Files = files
+	writeFile(t, changed, "modified")
+	writeFile(t, filepath.Join(dir, "late.tmp"), "late")
+
+	result := Execute(plan, CleanOptions{})
+
+	if result.FilesDeleted != 0 {
+		t.Errorf("expected nothing deleted, got %d", result.FilesDeleted)
+	}
+	if result.ChangedFiles != 1 || result.SkippedFiles != 1 {
+		t.Errorf("expected the modified file to be skipped, got changed=%d skipped=%d", result.ChangedFiles, result.SkippedFiles)
+	}
+	for _, name := range []string{"keep.tmp", "changed.tmp", "late.tmp"} {
+		if !exists(filepath.Join(dir, name)) {
+			t.Errorf("%s should have been kept", name)
+		}
+	}
+
+	os.Remove(changed)
+	writeFile(t, changed, "x")
+	plan = Scan(opts)
+	if result := Execute(plan, CleanOptions{}); result.FilesDeleted != 3 {
+		t.Errorf("expected 3 files deleted, got %d", result.FilesDeleted)
+	}
+}
+
+func TestExecute_UnknownCategory(t *testing.T) {
+	plan := &Plan{Version: planVersion, Categories: []PlanCategory{{ID: "no_such_category"}}}
+	if result := Execute(plan, CleanOptions{}); len(result.Errors) != 1 {
+		t.Errorf("expected an error for an unknown category, got %v", result.Errors)
+	}
+}
@@ -0,0 +1,356 @@
+package cleaner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Browser privacy categories. History, cookies and form data live in SQLite
+// databases that also hold bookmarks, settings and the rows the user chose
+// to keep, so they are edited in place instead of deleted: the matching rows
+// are removed and the database is compacted. Session files are removed like
+// any other file.
+
+// sqliteDriver names the database/sql driver used to edit browser databases.
+// It is set by privacy_sqlite.go and empty in builds without cgo, in which
+// case the database categories report an error instead of running.
+var sqliteDriver string
+
+// sqliteBusyTimeoutMS is how long an edit waits for a browser holding the
+// database before the database is reported as locked.
+const sqliteBusyTimeoutMS = 2000
+
+// dbStep removes rows from one table. Where is an optional SQL condition.
+// Steps on tables a browser version does not have are skipped.
+type dbStep struct {
+	table string
+	where string
+	// hostColumn, when set, holds cookie hosts; rows whose host belongs to
+	// one of CleanOptions.KeepDomains are kept.
+	hostColumn string
+}
+
+// browserDB is one database of a browser profile.
+type browserDB struct {
+	files []string // Candidate paths relative to the profile; the first that exists is used
+	steps []dbStep
+	after []string // Statements run once the rows are gone (not counted)
+}
+
+var (
+	chromiumHistoryDB = browserDB{
+		files: []string{"History"},
+		steps: []dbStep{
+			{table: "visits"}, {table: "visit_source"}, {table: "urls"},
+			{table: "keyword_search_terms"}, {table: "segments"}, {table: "segment_usage"},
+			{table: "downloads"}, {table: "downloads_url_chains"}, {table: "downloads_slices"},
+		},
+	}
+	chromiumCookiesDB = browserDB{
+		files: []string{filepath.Join("Network", "Cookies"), "Cookies"},
+		steps: []dbStep{{table: "cookies", hostColumn: "host_key"}},
+	}
+	chromiumFormsDB = browserDB{
+		files: []string{"Web Data"},
+		steps: []dbStep{{table: "autofill"}},
+	}
+
+	// Bookmarked places are referenced from moz_bookmarks (foreign_count)
+	// and must survive, so only their visit data is reset.
+	firefoxHistoryDB = browserDB{
+		files: []string{"places.sqlite"},
+		steps: []dbStep{
+			{table: "moz_historyvisits"},
+			{table: "moz_inputhistory"},
+			{table: "moz_places", where: "foreign_count = 0"},
+			{table: "moz_origins", where: "id NOT IN (SELECT origin_id FROM moz_places)"},
+		},
+		after: []string{"UPDATE moz_places SET visit_count = 0, last_visit_date = NULL"},
+	}
+	firefoxCookiesDB = browserDB{
+		files: []string{"cookies.sqlite"},
+		steps: []dbStep{{table: "moz_cookies", hostColumn: "host"}},
+	}
+	firefoxFormsDB = browserDB{
+		files: []string{"formhistory.sqlite"},
+		steps: []dbStep{{table: "moz_formhistory"}},
+	}
+)
+
+// Session files, relative to the profile directory.
+var (
+	chromiumSessionFiles = []string{"Sessions", "Session Storage", "Current Session", "Current Tabs", "Last Session", "Last Tabs"}
+	firefoxSessionFiles  = []string{"sessionstore.jsonlz4", "sessionstore-backups"}
+)
+
+// browserProcesses are the processes of every browser the privacy
+// categories handle; any of them may hold the databases open.
+var browserProcesses = []string{"chrome", "chromium", "chromium-browser", "msedge", "brave", "opera", "firefox", "firefox-bin"}
+
+// privacyDatabases returns a CleanFunc editing the given database of every
+// selected Chromium and Firefox profile.
+func privacyDatabases(chromiumDB, firefoxDB browserDB) CleanFunc {
+	return func(c Category, opts CleanOptions) CleanResult {
+		result := CleanResult{}
+		if sqliteDriver == "" {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: this build cannot edit browser databases (built without cgo)", c.Name))
+			return result
+		}
+		sw := newSweeper(c, opts)
+		for _, profile := range selectedProfiles(opts) {
+			if sw.stopped(&result) {
+				return result
+			}
+			db := chromiumDB
+			if profile.kind == firefoxBrowser {
+				db = firefoxDB
+			}
+			part := CleanResult{}
+			cleanBrowserDB(profile.Dir, db, opts.KeepDomains, sw, &part)
+			result.mergeProfile(c, profile, part)
+		}
+		return result
+	}
+}
+
+// cleanBrowserDB removes the rows selected by db from the database in
+// profile, then compacts it. A database the browser holds open is skipped
+// and counted as locked.
+func cleanBrowserDB(profile string, db browserDB, keep []string, sw sweeper, result *CleanResult) {
+	path := ""
+	for _, name := range db.files {
+		if info, err := os.Stat(filepath.Join(profile, name)); err == nil && info.Mode().IsRegular() {
+			path = filepath.Join(profile, name)
+			break
+		}
+	}
+	if path == "" || !sw.allowed(path, result) {
+		return
+	}
+
+	before := sqliteSize(path)
+	records, err := editBrowserDB(sw.context(), path, db, keep, sw.dryRun)
+	if err != nil {
+		ce := ClassifyError(path, err)
+		if ce.Type == ErrorLocked {
+			log.Printf("[SysCleaner] Skipping %s: in use, close the browser first", path)
+			result.SkippedFiles++
+			result.LockedFiles++
+		} else {
+			result.Errors = append(result.Errors, ce)
+		}
+		sw.skipped(path, 0, ce.Err.Error())
+		return
+	}
+
+	result.RecordsDeleted += records
+	if sw.dryRun {
+		if records > 0 {
+			result.DryRunItems = append(result.DryRunItems, fmt.Sprintf("%s (%d records)", path, records))
+		}
+		return
+	}
+	freed := before - sqliteSize(path)
+	if freed < 0 {
+		freed = 0
+	}
+	result.SpaceFreed += freed
+	if records > 0 {
+		log.Printf("[SysCleaner] Removed %d records from %s", records, path)
+	}
+	sw.removed(path, freed)
+}
+
+// editBrowserDB runs db's steps against the database at path and returns the
+// number of rows removed, or that would be removed when dryRun is set.
+func editBrowserDB(ctx context.Context, path string, db browserDB, keep []string, dryRun bool) (int64, error) {
+	conn, err := sql.Open(sqliteDriver, fmt.Sprintf("%s?_busy_timeout=%d", path, sqliteBusyTimeoutMS))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	// VACUUM and the checkpoint must see the committed edit on the same
+	// connection, and SQLite serializes writers anyway.
+	conn.SetMaxOpenConns(1)
+
+	tables, err := sqliteTables(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var records int64
+	for _, step := range db.steps {
+		if !tables[step.table] {
+			continue
+		}
+		where, args, err := step.condition(ctx, tx, keep)
+		if err != nil {
+			return 0, err
+		}
+		if dryRun {
+			var n int64
+			if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+step.table+where, args...).Scan(&n); err != nil {
+				return 0, fmt.Errorf("counting %s: %w", step.table, err)
+			}
+			records += n
+			continue
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM "+step.table+where, args...)
+		if err != nil {
+			return 0, fmt.Errorf("clearing %s: %w", step.table, err)
+		}
+		n, _ := res.RowsAffected()
+		records += n
+	}
+	if dryRun {
+		return records, nil
+	}
+	for _, stmt := range db.after {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	// Compacting is what actually returns the space; a failure here leaves
+	// a correct, just larger, database.
+	if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+		log.Printf("[SysCleaner] Could not compact %s: %v", path, err)
+	}
+	conn.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	return records, nil
+}
+
+// condition returns the WHERE clause of the step, adding the hosts to keep
+// for cookie tables.
+func (s dbStep) condition(ctx context.Context, tx *sql.Tx, keep []string) (string, []any, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	if s.where != "" {
+		conds = append(conds, "("+s.where+")")
+	}
+	if s.hostColumn != "" && len(keep) > 0 {
+		rows, err := tx.QueryContext(ctx, "SELECT DISTINCT "+s.hostColumn+" FROM "+s.table)
+		if err != nil {
+			return "", nil, fmt.Errorf("reading %s: %w", s.table, err)
+		}
+		var kept []string
+		for rows.Next() {
+			var host string
+			if err := rows.Scan(&host); err != nil {
+				rows.Close()
+				return "", nil, err
+			}
+			if keepHost(host, keep) {
+				kept = append(kept, host)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return "", nil, err
+		}
+		if len(kept) > 0 {
+			conds = append(conds, s.hostColumn+" NOT IN (?"+strings.Repeat(", ?", len(kept)-1)+")")
+			for _, h := range kept {
+				args = append(args, h)
+			}
+		}
+	}
+	if len(conds) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args, nil
+}
+
+// keepHost reports whether a cookie host belongs to one of the kept domains.
+// Cookie hosts carry a leading dot for domain cookies; "example.com" keeps
+// example.com and all its subdomains.
+func keepHost(host string, keep []string) bool {
+	host = normalizeDomain(host)
+	for _, d := range keep {
+		d = normalizeDomain(d)
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeDomain(d string) string {
+	d = strings.ToLower(strings.TrimSpace(d))
+	d = strings.TrimPrefix(d, "*")
+	return strings.TrimPrefix(d, ".")
+}
+
+// sqliteTables returns the names of the tables in the database.
+func sqliteTables(ctx context.Context, conn *sql.DB) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables[name] = true
+	}
+	return tables, rows.Err()
+}
+
+// sqliteSize returns the size of a database together with its journal and
+// write-ahead log.
+func sqliteSize(path string) int64 {
+	var size int64
+	for _, suffix := range []string{"", "-wal", "-journal"} {
+		if info, err := os.Stat(path + suffix); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// cleanBrowserSessions removes the saved sessions and tabs of every
+// selected profile.
+func cleanBrowserSessions(c Category, opts CleanOptions) CleanResult {
+	result := CleanResult{}
+	sw := newSweeper(c, opts)
+	for _, profile := range selectedProfiles(opts) {
+		names := chromiumSessionFiles
+		if profile.kind == firefoxBrowser {
+			names = firefoxSessionFiles
+		}
+		part := CleanResult{}
+		for _, name := range names {
+			path := filepath.Join(profile.Dir, name)
+			info, err := os.Lstat(path)
+			switch {
+			case err != nil:
+				continue
+			case info.IsDir():
+				part.merge(cleanDirectoryFiltered(path, fileFilter{}, sw))
+			default:
+				sw.remove(path, info, "browser session", &part)
+			}
+		}
+		result.mergeProfile(c, profile, part)
+	}
+	return result
+}
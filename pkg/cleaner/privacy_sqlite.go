@@ -0,0 +1,9 @@
+//go:build cgo
+
+package cleaner
+
+import _ "github.com/mattn/go-sqlite3"
+
+func init() {
+	sqliteDriver = "sqlite3"
+}
@@ -0,0 +1,164 @@
+//go:build cgo
+
+package cleaner
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// createDB creates a SQLite database at path and runs the statements.
+func createDB(t *testing.T, path string, stmts ...string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+func queryStrings(t *testing.T, path, query string) []string {
+	t.Helper()
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// ---------- Privacy category tests ----------
+
+func TestBrowserCookies_KeepsKeepListDomains(t *testing.T) {
+	chrome, firefox := browserHome(t)
+	chromeDB := filepath.Join(chrome, "Network", "Cookies")
+	createDB(t, chromeDB,
+		"CREATE TABLE cookies (host_key TEXT, name TEXT)",
+		"INSERT INTO cookies VALUES ('.tracker.net', 'id'), ('sso.example.com', 'session'), ('.example.com', 'auth'), ('notexample.com', 'x')")
+	firefoxDB := filepath.Join(firefox, "cookies.sqlite")
+	createDB(t, firefoxDB,
+		"CREATE TABLE moz_cookies (host TEXT, name TEXT)",
+		"INSERT INTO moz_cookies VALUES ('.ads.org', 'id'), ('login.example.com', 'token')")
+
+	c, _ := LookupCategory("browser_cookies")
+	opts := CleanOptions{DryRun: true, KeepDomains: []string{"Example.com"}}
+	if dry := c.run(opts); dry.RecordsDeleted != 3 || len(dry.DryRunItems) != 2 {
+		t.Errorf("dry run should count 3 records in 2 databases, got %d %v", dry.RecordsDeleted, dry.DryRunItems)
+	}
+	if got := queryStrings(t, chromeDB, "SELECT name FROM cookies"); len(got) != 4 {
+		t.Fatalf("dry run must not edit the database, got %v", got)
+	}
+
+	opts.DryRun = false
+	result := c.run(opts)
+	if result.RecordsDeleted != 3 || len(result.Errors) != 0 {
+		t.Errorf("expected 3 records removed without errors, got %d %v", result.RecordsDeleted, result.Errors)
+	}
+	if got := queryStrings(t, chromeDB, "SELECT name FROM cookies ORDER BY name"); len(got) != 2 || got[0] != "auth" || got[1] != "session" {
+		t.Errorf("expected only the example.com cookies to remain, got %v", got)
+	}
+	if got := queryStrings(t, firefoxDB, "SELECT name FROM moz_cookies"); len(got) != 1 || got[0] != "token" {
+		t.Errorf("expected only the login.example.com cookie to remain, got %v", got)
+	}
+}
+
+func TestBrowserHistory_KeepsBookmarks(t *testing.T) {
+	chrome, firefox := browserHome(t)
+	chromeDB := filepath.Join(chrome, "History")
+	createDB(t, chromeDB,
+		"CREATE TABLE urls (id INTEGER PRIMARY KEY, url TEXT)",
+		"CREATE TABLE visits (id INTEGER PRIMARY KEY, url INTEGER)",
+		"INSERT INTO urls VALUES (1, 'https://a'), (2, 'https://b')",
+		"INSERT INTO visits VALUES (1, 1), (2, 2), (3, 2)")
+	firefoxDB := filepath.Join(firefox, "places.sqlite")
+	createDB(t, firefoxDB,
+		"CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url TEXT, foreign_count INTEGER, visit_count INTEGER, last_visit_date INTEGER, origin_id INTEGER)",
+		"CREATE TABLE moz_historyvisits (id INTEGER PRIMARY KEY, place_id INTEGER)",
+		"CREATE TABLE moz_origins (id INTEGER PRIMARY KEY, host TEXT)",
+		"CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, fk INTEGER)",
+		"INSERT INTO moz_origins VALUES (1, 'bookmarked.org'), (2, 'visited.org')",
+		"INSERT INTO moz_places VALUES (1, 'https://bookmarked.org', 1, 5, 100, 1), (2, 'https://visited.org', 0, 2, 100, 2)",
+		"INSERT INTO moz_historyvisits VALUES (1, 1), (2, 2)",
+		"INSERT INTO moz_bookmarks VALUES (1, 1)")
+
+	c, _ := LookupCategory("browser_history")
+	result := c.run(CleanOptions{})
+	if len(result.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if got := queryStrings(t, chromeDB, "SELECT url FROM urls"); len(got) != 0 {
+		t.Errorf("expected the Chrome history to be empty, got %v", got)
+	}
+	if got := queryStrings(t, firefoxDB, "SELECT url || ':' || visit_count FROM moz_places"); len(got) != 1 || got[0] != "https://bookmarked.org:0" {
+		t.Errorf("expected only the bookmarked place, without visits, got %v", got)
+	}
+	if got := queryStrings(t, firefoxDB, "SELECT host FROM moz_origins"); len(got) != 1 || got[0] != "bookmarked.org" {
+		t.Errorf("expected the unused origin to be removed, got %v", got)
+	}
+	if got := queryStrings(t, firefoxDB, "SELECT CAST(id AS TEXT) FROM moz_bookmarks"); len(got) != 1 {
+		t.Errorf("bookmarks must survive, got %v", got)
+	}
+	// urls 2 + visits 3 in Chrome; visits 2 + place 1 + origin 1 in Firefox.
+	if result.RecordsDeleted != 9 {
+		t.Errorf("expected 9 records removed, got %d", result.RecordsDeleted)
+	}
+}
+
+func TestBrowserSessions_RemovesSessionFiles(t *testing.T) {
+	chrome, firefox := browserHome(t)
+	writeFile(t, filepath.Join(chrome, "Sessions", "Session_1"), "x")
+	writeFile(t, filepath.Join(chrome, "Current Tabs"), "x")
+	writeFile(t, filepath.Join(chrome, "Preferences"), "x")
+	writeFile(t, filepath.Join(firefox, "sessionstore.jsonlz4"), "x")
+	writeFile(t, filepath.Join(firefox, "prefs.js"), "x")
+
+	c, _ := LookupCategory("browser_sessions")
+	result := c.run(CleanOptions{})
+	if result.FilesDeleted != 3 {
+		t.Errorf("expected 3 session files removed, got %d", result.FilesDeleted)
+	}
+	if !exists(filepath.Join(chrome, "Preferences")) || !exists(filepath.Join(firefox, "prefs.js")) {
+		t.Error("profile settings must be kept")
+	}
+}
+
+func TestKeepHost(t *testing.T) {
+	keep := []string{"example.com", "*.corp.net", ".Login.Org"}
+	for host, want := range map[string]bool{
+		"example.com":      true,
+		".example.com":     true,
+		"sso.example.com":  true,
+		"notexample.com":   false,
+		"a.corp.net":       true,
+		"login.org":        true,
+		"evil-login.org":   false,
+		"example.com.evil": false,
+	} {
+		if got := keepHost(host, keep); got != want {
+			t.Errorf("keepHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// emptiedDirs records the folders a directory clean removed files from, with
+// their modification time from before the first removal, so that the folders
+// the run left empty can be pruned afterwards. Without the pruning, Temp and
+// cache trees fill up with empty folders that every later walk has to cross.
+type emptiedDirs map[string]time.Time
+
+// note records the folder of path before a file is removed from it.
+func (e emptiedDirs) note(path string) {
+	dir := filepath.Dir(path)
+	if _, ok := e[dir]; ok {
+		return
+	}
+	if info, err := os.Lstat(dir); err == nil {
+		e[dir] = info.ModTime()
+	}
+}
+
+// prune removes the recorded folders below root that are now empty, and the
+// parents that are left empty in turn, deepest first. root itself is never
+// removed. Folders matching filter's exclusions, protected folders and
+// folders modified more recently than policy's MinAge before the run are
+// kept. The age is always the modification time: walking a folder reads it,
+// so its access time says nothing. It returns the number of folders removed.
+func (e emptiedDirs) prune(root string, filter fileFilter, policy RetentionPolicy, sw sweeper) int64 {
+	below := func(dir string) bool {
+		return pathWithin(dir, root) && !pathWithin(root, dir)
+	}
+
+	dirs := make(map[string]time.Time, len(e))
+	for dir, mtime := range e {
+		dirs[dir] = mtime
+	}
+	for dir := range e {
+		for d := filepath.Dir(dir); below(d); d = filepath.Dir(d) {
+			if _, ok := dirs[d]; ok {
+				continue
+			}
+			info, err := os.Lstat(d)
+			if err != nil {
+				break
+			}
+			dirs[d] = info.ModTime()
+		}
+	}
+
+	// A folder's path is longer than its parent's, so longest first is
+	// bottom-up.
+	order := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		if below(dir) {
+			order = append(order, dir)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return len(order[i]) > len(order[j])
+	})
+
+	now := time.Now()
+	var removed int64
+	for _, dir := range order {
+		if sw.context().Err() != nil {
+			break
+		}
+		if policy.MinAge > 0 && now.Sub(dirs[dir]) < policy.MinAge {
+			continue
+		}
+		if filter.excluded(root, dir, true) || sw.guard.check(dir) != nil {
+			continue
+		}
+		if sw.readOnly {
+			addWritePermission(filepath.Dir(dir))
+		}
+		if os.Remove(dir) == nil {
+			removed++
+		}
+	}
+	return removed
+}
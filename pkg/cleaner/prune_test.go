@@ -0,0 +1,64 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ---------- Empty directory pruning tests ----------
+
+func TestPrune_RemovesEmptiedDirsBottomUp(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a", "b", "c", "x.tmp"), "x")
+	writeFile(t, filepath.Join(root, "a", "y.tmp"), "y")
+	writeFile(t, filepath.Join(root, "top.tmp"), "t")
+	writeFile(t, filepath.Join(root, "keep", "z.log"), "z")
+	if err := os.MkdirAll(filepath.Join(root, "untouched"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	filter := fileFilter{Exclude: []string{"*.log"}}
+	dry := cleanDirectoryFiltered(root, filter, sweeper{dryRun: true, guard: newPathGuard(nil, nil)})
+	if dry.DirsRemoved != 0 || !exists(filepath.Join(root, "a", "b", "c")) {
+		t.Fatalf("a dry run must not remove folders, got %d", dry.DirsRemoved)
+	}
+
+	result := cleanDirectoryFiltered(root, filter, sweeper{guard: newPathGuard(nil, nil)})
+	if result.FilesDeleted != 3 || result.DirsRemoved != 3 {
+		t.Errorf("expected 3 files and 3 folders removed, got %d files and %d folders", result.FilesDeleted, result.DirsRemoved)
+	}
+	if exists(filepath.Join(root, "a")) {
+		t.Error("expected the emptied tree to be removed")
+	}
+	for _, kept := range []string{root, filepath.Join(root, "keep", "z.log"), filepath.Join(root, "untouched")} {
+		if !exists(kept) {
+			t.Errorf("expected %s to be kept", kept)
+		}
+	}
+	if rep := NewReport(result, false); rep.DirsRemoved != 3 {
+		t.Errorf("expected dirs_removed 3 in the report, got %d", rep.DirsRemoved)
+	}
+}
+
+func TestPrune_KeepsRecentlyModifiedDirs(t *testing.T) {
+	const day = 24 * time.Hour
+	root := t.TempDir()
+	old := time.Now().Add(-40 * day)
+	for _, dir := range []string{"stale", "fresh"} {
+		path := filepath.Join(root, dir, "old.tmp")
+		writeFile(t, path, "x")
+		os.Chtimes(path, old, old)
+	}
+	os.Chtimes(filepath.Join(root, "stale"), old, old)
+
+	// Both files are past the category's age, but only the stale folder is.
+	result := cleanDirectoryFiltered(root, fileFilter{MaxAge: 30 * day}, sweeper{guard: newPathGuard(nil, nil)})
+	if result.FilesDeleted != 2 || result.DirsRemoved != 1 {
+		t.Errorf("expected 2 files and 1 folder removed, got %d files and %d folders", result.FilesDeleted, result.DirsRemoved)
+	}
+	if exists(filepath.Join(root, "stale")) || !exists(filepath.Join(root, "fresh")) {
+		t.Error("expected only the folder older than the category's age to be removed")
+	}
+}
@@ -0,0 +1,433 @@
+package cleaner
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Quarantine is a size-capped store that cleaned files can be moved into
+// instead of being deleted. Every clean run that quarantines files gets its
+// own directory holding the files and a manifest:
+//
+//	<dir>/<run-id>/manifest.jsonl   one QuarantineEntry per line
+//	<dir>/<run-id>/files/<n>        the quarantined file contents
+//
+// Entries are appended to the manifest as files are moved, so an interrupted
+// run can still be restored.
+type Quarantine struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	runID   string
+	next    int
+	used    int64 // Bytes currently in the store
+	full    int64 // Files left in place because the store was full
+	started bool
+}
+
+// QuarantineEntry records one quarantined file.
+type QuarantineEntry struct {
+	Original      string      `json:"original"`
+	Stored        string      `json:"stored"` // Relative to the run directory
+	Size          int64       `json:"size"`
+	Mode          os.FileMode `json:"mode"`
+	ModTime       time.Time   `json:"mtime"`
+	Category      string      `json:"category"`
+	QuarantinedAt time.Time   `json:"quarantined_at"`
+}
+
+// QuarantineRun summarizes one run in the store.
+type QuarantineRun struct {
+	ID         string    `json:"id"`
+	Created    time.Time `json:"created"`
+	Files      int       `json:"files"`
+	Size       int64     `json:"size"`
+	Categories []string  `json:"categories"`
+}
+
+// ErrQuarantineFull is returned when a file does not fit in the store even
+// after older runs have been evicted. The file is left in place.
+var ErrQuarantineFull = errors.New("quarantine store is full")
+
+const (
+	quarantineManifest = "manifest.jsonl"
+	quarantineRunTime  = "20060102-150405"
+)
+
+// OpenQuarantine opens (creating on first use) the quarantine store in dir.
+// maxBytes caps the total size of the store; 0 means no cap.
+func OpenQuarantine(dir string, maxBytes int64) (*Quarantine, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating quarantine directory: %w", err)
+	}
+	q := &Quarantine{dir: dir, maxBytes: maxBytes}
+	runs, err := q.Runs()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range runs {
+		q.used += r.Size
+	}
+	return q, nil
+}
+
+// Dir returns the store directory.
+func (q *Quarantine) Dir() string {
+	return q.dir
+}
+
+// RunID returns the ID of the run files are currently quarantined into, or ""
+// if nothing has been quarantined since the store was opened.
+func (q *Quarantine) RunID() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.started {
+		return ""
+	}
+	return q.runID
+}
+
+// LeftInPlace returns how many files could not be quarantined because the
+// store was full.
+func (q *Quarantine) LeftInPlace() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.full
+}
+
+// Add moves a file into the current run and records it in the manifest.
+func (q *Quarantine) Add(category, path string, info os.FileInfo) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.maxBytes > 0 && q.used+info.Size() > q.maxBytes {
+		q.evict(info.Size())
+		if q.used+info.Size() > q.maxBytes {
+			q.full++
+			return ErrQuarantineFull
+		}
+	}
+	if err := q.startRun(); err != nil {
+		return err
+	}
+
+	runDir := filepath.Join(q.dir, q.runID)
+	stored := filepath.Join("files", strconv.Itoa(q.next))
+	q.next++
+	if err := moveFile(path, filepath.Join(runDir, stored)); err != nil {
+		return err
+	}
+
+	entry := QuarantineEntry{
+		Original:      path,
+		Stored:        filepath.ToSlash(stored),
+		Size:          info.Size(),
+		Mode:          info.Mode(),
+		ModTime:       info.ModTime(),
+		Category:      category,
+		QuarantinedAt: time.Now(),
+	}
+	if err := appendManifest(filepath.Join(runDir, quarantineManifest), entry); err != nil {
+		// Put the file back rather than keep it without a manifest entry.
+		moveFile(filepath.Join(runDir, stored), path)
+		return err
+	}
+	q.used += info.Size()
+	return nil
+}
+
+// startRun creates the run directory on the first Add. Callers hold q.mu.
+func (q *Quarantine) startRun() error {
+	if q.started {
+		return nil
+	}
+	base := time.Now().Format(quarantineRunTime)
+	id := base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(q.dir, id)); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	if err := os.MkdirAll(filepath.Join(q.dir, id, "files"), 0700); err != nil {
+		return fmt.Errorf("creating quarantine run: %w", err)
+	}
+	q.runID, q.started = id, true
+	log.Printf("[SysCleaner] Quarantining files into run %s", id)
+	return nil
+}
+
+// evict removes the oldest runs, never the current one, until need more
+// bytes fit under the cap. Callers hold q.mu.
+func (q *Quarantine) evict(need int64) {
+	runs, err := q.Runs()
+	if err != nil {
+		return
+	}
+	for _, r := range runs {
+		if q.used+need <= q.maxBytes {
+			return
+		}
+		if q.started && r.ID == q.runID {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(q.dir, r.ID)); err == nil {
+			q.used -= r.Size
+			log.Printf("[SysCleaner] Evicted quarantine run %s (%s) to stay under the size cap", r.ID, FormatBytes(r.Size))
+		}
+	}
+}
+
+// Runs lists the runs in the store, oldest first.
+func (q *Quarantine) Runs() ([]QuarantineRun, error) {
+	dirs, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading quarantine directory: %w", err)
+	}
+	var runs []QuarantineRun
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		entries, err := q.Entries(d.Name())
+		if err != nil {
+			continue
+		}
+		run := QuarantineRun{ID: d.Name(), Files: len(entries)}
+		seen := map[string]bool{}
+		for _, e := range entries {
+			run.Size += e.Size
+			if run.Created.IsZero() || e.QuarantinedAt.Before(run.Created) {
+				run.Created = e.QuarantinedAt
+			}
+			if !seen[e.Category] {
+				seen[e.Category] = true
+				run.Categories = append(run.Categories, e.Category)
+			}
+		}
+		if run.Created.IsZero() {
+			if info, err := d.Info(); err == nil {
+				run.Created = info.ModTime()
+			}
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Created.Before(runs[j].Created) })
+	return runs, nil
+}
+
+// Entries returns the manifest of a run.
+func (q *Quarantine) Entries(runID string) ([]QuarantineEntry, error) {
+	if runID != filepath.Base(runID) || runID == "." || runID == ".." {
+		return nil, fmt.Errorf("invalid run ID %q", runID)
+	}
+	f, err := os.Open(filepath.Join(q.dir, runID, quarantineManifest))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("quarantine run %q not found", runID)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []QuarantineEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e QuarantineEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// A torn final line from an interrupted run is ignored.
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Restore moves every file of a run back to its original location with its
+// original mode and mtime. Files whose original path is occupied again are
+// left in the store. The run is removed once it is empty.
+func (q *Quarantine) Restore(runID string) (int, []error) {
+	entries, err := q.Entries(runID)
+	if err != nil {
+		return 0, []error{err}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	runDir := filepath.Join(q.dir, runID)
+	var (
+		restored int
+		left     []QuarantineEntry
+		errs     []error
+	)
+	for _, e := range entries {
+		if err := restoreEntry(runDir, e); err != nil {
+			errs = append(errs, err)
+			left = append(left, e)
+			continue
+		}
+		restored++
+		q.used -= e.Size
+	}
+
+	if len(left) == 0 {
+		if err := os.RemoveAll(runDir); err != nil {
+			errs = append(errs, err)
+		}
+	} else if err := writeManifest(filepath.Join(runDir, quarantineManifest), left); err != nil {
+		errs = append(errs, err)
+	}
+	return restored, errs
+}
+
+func restoreEntry(runDir string, e QuarantineEntry) error {
+	if _, err := os.Lstat(e.Original); err == nil {
+		return fmt.Errorf("%s: a file already exists at the original path", e.Original)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.Original), 0755); err != nil {
+		return fmt.Errorf("%s: %w", e.Original, err)
+	}
+	if err := moveFile(filepath.Join(runDir, filepath.FromSlash(e.Stored)), e.Original); err != nil {
+		return fmt.Errorf("%s: %w", e.Original, err)
+	}
+	if e.Mode&os.ModeSymlink == 0 {
+		// Both would apply to a link's target rather than the link.
+		os.Chmod(e.Original, e.Mode.Perm())
+		os.Chtimes(e.Original, e.ModTime, e.ModTime)
+	}
+	return nil
+}
+
+// Purge permanently deletes runs created more than olderThan ago and returns
+// the number of runs and bytes removed.
+func (q *Quarantine) Purge(olderThan time.Duration) (int, int64, error) {
+	runs, err := q.Runs()
+	if err != nil {
+		return 0, 0, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var (
+		removed int
+		freed   int64
+	)
+	cutoff := time.Now().Add(-olderThan)
+	for _, r := range runs {
+		if !r.Created.Before(cutoff) || (q.started && r.ID == q.runID) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(q.dir, r.ID)); err != nil {
+			return removed, freed, err
+		}
+		removed++
+		freed += r.Size
+		q.used -= r.Size
+	}
+	return removed, freed, nil
+}
+
+func appendManifest(path string, e QuarantineEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("writing quarantine manifest: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing quarantine manifest: %w", err)
+	}
+	return f.Close()
+}
+
+func writeManifest(path string, entries []QuarantineEntry) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("writing quarantine manifest: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// moveFile renames src to dst, falling back to copy and delete when they are
+// on different volumes. Symlinks are moved as links.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	if info, err := os.Lstat(src); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(target, dst); err != nil {
+			return err
+		}
+		if err := os.Remove(src); err != nil {
+			os.Remove(dst)
+			return err
+		}
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	os.Chtimes(dst, info.ModTime(), info.ModTime())
+	in.Close()
+	if err := os.Remove(src); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package cleaner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ---------- Quarantine tests ----------
+
+func TestQuarantine_CleanAndRestore(t *testing.T) {
+	src := t.TempDir()
+	a := filepath.Join(src, "a.log")
+	b := filepath.Join(src, "sub", "b.log")
+	writeFile(t, a, "aaaa")
+	writeFile(t, b, "bb")
+	mtime := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(a, mtime, mtime)
+	os.Chmod(a, 0600)
+
+	q, err := OpenQuarantine(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("OpenQuarantine failed: %v", err)
+	}
+	c := Category{ID: "test_quarantine", Quarantine: true, Paths: []PathResolver{func() []string { return []string{src} }}}
+	result := c.run(CleanOptions{Quarantine: q})
+
+	if result.FilesQuarantined != 2 || result.SpaceQuarantined != 6 {
+		t.Errorf("expected 2 files / 6 bytes quarantined, got %d / %d", result.FilesQuarantined, result.SpaceQuarantined)
+	}
+	if result.FilesDeleted != 0 {
+		t.Errorf("quarantined files must not be counted as deleted, got %d", result.FilesDeleted)
+	}
+	if exists(a) || exists(b) {
+		t.Fatal("files should have been moved out of the cleaned directory")
+	}
+
+	runs, err := q.Runs()
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expected 1 run, got %v (%v)", runs, err)
+	}
+	if runs[0].ID != q.RunID() || runs[0].Files != 2 || runs[0].Categories[0] != "test_quarantine" {
+		t.Errorf("unexpected run summary %+v", runs[0])
+	}
+
+	restored, errs := q.Restore(runs[0].ID)
+	if restored != 2 || len(errs) != 0 {
+		t.Fatalf("expected 2 files restored, got %d (%v)", restored, errs)
+	}
+	info, err := os.Stat(a)
+	if err != nil {
+		t.Fatalf("a.log was not restored: %v", err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("expected mtime %v, got %v", mtime, info.ModTime())
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+	if !exists(b) {
+		t.Error("sub/b.log was not restored")
+	}
+	if runs, _ := q.Runs(); len(runs) != 0 {
+		t.Errorf("expected the restored run to be removed, got %v", runs)
+	}
+}
+
+func TestQuarantine_RestoreKeepsConflicts(t *testing.T) {
+	src := t.TempDir()
+	path := filepath.Join(src, "a.txt")
+	writeFile(t, path, "old")
+	info, _ := os.Stat(path)
+
+	q, _ := OpenQuarantine(t.TempDir(), 0)
+	if err := q.Add("test", path, info); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	writeFile(t, path, "new")
+
+	restored, errs := q.Restore(q.RunID())
+	if restored != 0 || len(errs) != 1 {
+		t.Errorf("expected the conflicting file to stay quarantined, got %d restored, %v", restored, errs)
+	}
+	if entries, _ := q.Entries(q.RunID()); len(entries) != 1 {
+		t.Errorf("expected the manifest to keep the unrestored entry, got %d", len(entries))
+	}
+}
+
+func TestQuarantine_SizeCapEvictsOldRuns(t *testing.T) {
+	dir := t.TempDir()
+	src := t.TempDir()
+	add := func(q *Quarantine, name string, size int) error {
+		path := filepath.Join(src, name)
+		writeFile(t, path, string(make([]byte, size)))
+		info, _ := os.Stat(path)
+		return q.Add("test", path, info)
+	}
+
+	first, _ := OpenQuarantine(dir, 100)
+	if err := add(first, "one", 60); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	second, _ := OpenQuarantine(dir, 100)
+	if err := add(second, "two", 60); err != nil {
+		t.Fatalf("expected the old run to be evicted, got %v", err)
+	}
+	runs, _ := second.Runs()
+	if len(runs) != 1 || runs[0].ID != second.RunID() {
+		t.Errorf("expected only the new run to remain, got %+v", runs)
+	}
+
+	if err := add(second, "three", 60); !errors.Is(err, ErrQuarantineFull) {
+		t.Errorf("expected ErrQuarantineFull, got %v", err)
+	}
+	if !exists(filepath.Join(src, "three")) {
+		t.Error("a file that does not fit must be left in place")
+	}
+}
+
+func TestQuarantine_Purge(t *testing.T) {
+	src := t.TempDir()
+	path := filepath.Join(src, "a.txt")
+	writeFile(t, path, "x")
+	info, _ := os.Stat(path)
+
+	q, _ := OpenQuarantine(t.TempDir(), 0)
+	q.Add("test", path, info)
+	id := q.RunID()
+
+	other, _ := OpenQuarantine(q.Dir(), 0)
+	if n, _, _ := other.Purge(7 * 24 * time.Hour); n != 0 {
+		t.Errorf("expected recent run to be kept, purged %d", n)
+	}
+	if n, freed, err := other.Purge(0); n != 1 || freed != 1 || err != nil {
+		t.Errorf("expected 1 run purged, got %d (%d bytes, %v)", n, freed, err)
+	}
+	if _, err := other.Entries(id); err == nil {
+		t.Error("expected purged run to be gone")
+	}
+}
+
+func TestCleanOptions_Quarantined(t *testing.T) {
+	q, _ := OpenQuarantine(t.TempDir(), 0)
+	risky := Category{ID: "risky", Quarantine: true}
+	safe := Category{ID: "safe"}
+
+	if (CleanOptions{}).Quarantined(risky) {
+		t.Error("no store means nothing is quarantined")
+	}
+	opts := CleanOptions{Quarantine: q}
+	if !opts.Quarantined(risky) || opts.Quarantined(safe) {
+		t.Error("expected category defaults to apply")
+	}
+	opts.QuarantineCategories = map[string]bool{"risky": false, "safe": true}
+	if opts.Quarantined(risky) || !opts.Quarantined(safe) {
+		t.Error("expected per-category overrides to win")
+	}
+}
@@ -0,0 +1,716 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Group is the top-level section a category is listed under in the CLI and GUI.
+type Group string
+
+const (
+	GroupSystem       Group = "system"
+	GroupBrowsers     Group = "browsers"
+	GroupPrivacy      Group = "privacy" // Browser history, cookies and sessions
+	GroupApplications Group = "apps"
+	GroupDeveloper    Group = "dev"     // Toolchain caches and stale dependencies
+	GroupCustom       Group = "custom"  // User-defined rules
+	GroupWinapp2      Group = "winapp2" // Imported winapp2.ini entries
+)
+
+// Groups lists every category group in display order.
+var Groups = []Group{GroupSystem, GroupBrowsers, GroupPrivacy, GroupApplications, GroupDeveloper, GroupCustom, GroupWinapp2}
+
+// InAll reports whether "clean everything" (--all) selects the group. Only
+// the system, browser and application groups are; the others are opt-in and
+// have to be selected by their own flag. Privacy removes browsing history,
+// cookies and sessions rather than junk, Developer removes caches that take
+// long to download again, and custom and winapp2 rules are the user's own.
+func (g Group) InAll() bool {
+	switch g {
+	case GroupSystem, GroupBrowsers, GroupApplications:
+		return true
+	default:
+		return false
+	}
+}
+
+// DisplayName returns the human-readable group name.
+func (g Group) DisplayName() string {
+	switch g {
+	case GroupSystem:
+		return "System"
+	case GroupBrowsers:
+		return "Browsers"
+	case GroupPrivacy:
+		return "Privacy"
+	case GroupApplications:
+		return "Applications"
+	case GroupDeveloper:
+		return "Developer"
+	case GroupCustom:
+		return "Custom Rules"
+	case GroupWinapp2:
+		return "Community (winapp2)"
+	default:
+		return string(g)
+	}
+}
+
+// RiskLevel describes how disruptive cleaning a category can be.
+type RiskLevel int
+
+const (
+	RiskLow    RiskLevel = iota // Pure caches, safe to remove at any time
+	RiskMedium                  // Rebuilt on demand but may cost time or bandwidth
+	RiskHigh                    // Removes data the user may want back
+)
+
+func (r RiskLevel) String() string {
+	switch r {
+	case RiskLow:
+		return "low"
+	case RiskMedium:
+		return "medium"
+	case RiskHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// PathResolver returns the directories a category cleans. Resolvers are
+// evaluated at clean time so that environment changes are picked up.
+type PathResolver func() []string
+
+// CleanFunc overrides the default directory cleaning for a category.
+type CleanFunc func(c Category, opts CleanOptions) CleanResult
+
+// Category describes a single cleaning target. Every consumer (PerformClean,
+// config serialization, CLI flags and the GUI) reads from the registry, so
+// adding a category only requires adding an entry to builtinCategories.
+type Category struct {
+	ID          string         // Stable key used in config files and CleanOptions
+	Name        string         // Display name
+	Flag        string         // CLI flag name
+	Description string         // CLI help text
+	Group       Group          // Section the category is listed under
+	Platforms   []string       // GOOS values the category runs on; empty means all
+	Paths       []PathResolver // Directories cleaned by the default implementation
+	MaxAge      time.Duration  // Only remove files older than this (0 = no filter)
+	Risk        RiskLevel      // Used by the GUI to decide what is pre-selected
+	Default     bool           // Enabled in the default config and profile
+	Clean       CleanFunc      // Optional override for non-directory categories
+	Detect      func() bool    // Reports whether the target is installed (nil = always)
+	Quarantine  bool           // Quarantine instead of deleting unless overridden
+	Logs        bool           // Log files, archived first when archiving is on
+	// Processes are the executable names of the application owning the
+	// category's files ("chrome" matches chrome.exe too). While one runs,
+	// CleanOptions.RunningPolicyFor decides whether the category is cleaned.
+	Processes []string
+
+	// Action describes categories that do something other than remove files
+	// (e.g. "Flush the DNS resolver cache"). Scan plans them as one step.
+	Action string
+	// Execute optionally overrides how planned files are removed.
+	Execute func(c Category, files []PlanFile, opts CleanOptions) CleanResult
+}
+
+// Supported reports whether the category can run on the current platform.
+func (c Category) Supported() bool {
+	if len(c.Platforms) == 0 {
+		return true
+	}
+	for _, p := range c.Platforms {
+		if p == runtime.GOOS {
+			return true
+		}
+	}
+	return false
+}
+
+// Detected reports whether the category's target is present on this machine.
+func (c Category) Detected() bool {
+	return c.Detect == nil || c.Detect()
+}
+
+// ResolvePaths evaluates the category's path resolvers and returns the
+// de-duplicated list of directories.
+func (c Category) ResolvePaths() []string {
+	var paths []string
+	for _, resolve := range c.Paths {
+		paths = append(paths, resolve()...)
+	}
+	return dedup(paths)
+}
+
+// run executes the category using its override or the default directory cleaner.
+func (c Category) run(opts CleanOptions) CleanResult {
+	if c.Clean != nil {
+		return c.Clean(c, opts)
+	}
+	return cleanPaths(c, opts)
+}
+
+// cleanPaths cleans every resolved directory of a category with its MaxAge.
+func cleanPaths(c Category, opts CleanOptions) CleanResult {
+	result := CleanResult{}
+	for _, dir := range c.ResolvePaths() {
+		result.merge(cleanDirectoryFiltered(dir, fileFilter{MaxAge: c.MaxAge}, newSweeper(c, opts)))
+	}
+	return result
+}
+
+var (
+	categoriesMu  sync.RWMutex
+	categoryList  []Category
+	categoryIndex = map[string]int{}
+)
+
+func init() {
+	for _, c := range builtinCategories {
+		if err := Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ReservedIDs are the keys a saved clean profile uses for its settings. The
+// profile keeps the category selection in the same JSON object, so no
+// category may use one of them as its ID.
+var ReservedIDs = []string{"dry_run", "trash_min_age", "timeout", "dir_timeout", "retention"}
+
+// IsReservedID reports whether id is one of ReservedIDs.
+func IsReservedID(id string) bool {
+	for _, r := range ReservedIDs {
+		if id == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Register adds a category to the registry. IDs and flag names must be unique.
+func Register(c Category) error {
+	if c.ID == "" {
+		return fmt.Errorf("category has no ID")
+	}
+	if IsReservedID(c.ID) {
+		return fmt.Errorf("category ID %q is reserved for a profile setting", c.ID)
+	}
+	categoriesMu.Lock()
+	defer categoriesMu.Unlock()
+
+	if _, exists := categoryIndex[c.ID]; exists {
+		return fmt.Errorf("category %q is already registered", c.ID)
+	}
+	if c.Flag != "" {
+		for _, other := range categoryList {
+			if other.Flag == c.Flag {
+				return fmt.Errorf("category %q: flag --%s is already used by %q", c.ID, c.Flag, other.ID)
+			}
+		}
+	}
+	categoryIndex[c.ID] = len(categoryList)
+	categoryList = append(categoryList, c)
+	return nil
+}
+
+// Categories returns all registered categories in registration order.
+func Categories() []Category {
+	categoriesMu.RLock()
+	defer categoriesMu.RUnlock()
+	return append([]Category(nil), categoryList...)
+}
+
+// CategoriesInGroup returns the registered categories belonging to a group.
+func CategoriesInGroup(g Group) []Category {
+	var out []Category
+	for _, c := range Categories() {
+		if c.Group == g {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// LookupCategory returns the category with the given ID.
+func LookupCategory(id string) (Category, bool) {
+	categoriesMu.RLock()
+	defer categoriesMu.RUnlock()
+	i, ok := categoryIndex[id]
+	if !ok {
+		return Category{}, false
+	}
+	return categoryList[i], true
+}
+
+// DefaultCategories returns the IDs of categories enabled by default.
+func DefaultCategories() []string {
+	var ids []string
+	for _, c := range Categories() {
+		if c.Default {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// ---------------------------------------------------------------------------
+// Path resolvers
+// ---------------------------------------------------------------------------
+
+var (
+	windowsOnly     = []string{"windows"}
+	linuxOnly       = []string{"linux"}
+	windowsAndLinux = []string{"windows", "linux"}
+)
+
+// envPath resolves to a path under the directory named by an environment
+// variable, or to nothing when the variable is unset.
+func envPath(key string, elem ...string) PathResolver {
+	return func() []string {
+		base := os.Getenv(key)
+		if base == "" {
+			return nil
+		}
+		return []string{filepath.Join(append([]string{base}, elem...)...)}
+	}
+}
+
+// homePath resolves to a path below the user's home directory.
+func homePath(elem ...string) PathResolver {
+	return func() []string {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		return []string{filepath.Join(append([]string{home}, elem...)...)}
+	}
+}
+
+// xdgPath resolves to a path under an XDG base directory. As the spec
+// requires, unset or relative values fall back to the default below $HOME.
+func xdgPath(key, fallback string, elem ...string) PathResolver {
+	return func() []string {
+		base := os.Getenv(key)
+		if !filepath.IsAbs(base) {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil
+			}
+			base = filepath.Join(home, filepath.FromSlash(fallback))
+		}
+		return []string{filepath.Join(append([]string{base}, elem...)...)}
+	}
+}
+
+func xdgCache(elem ...string) PathResolver  { return xdgPath("XDG_CACHE_HOME", ".cache", elem...) }
+func xdgConfig(elem ...string) PathResolver { return xdgPath("XDG_CONFIG_HOME", ".config", elem...) }
+func xdgData(elem ...string) PathResolver   { return xdgPath("XDG_DATA_HOME", ".local/share", elem...) }
+
+// ---------------------------------------------------------------------------
+// Built-in categories
+// ---------------------------------------------------------------------------
+
+var builtinCategories = []Category{
+	// System categories
+	{
+		ID: "windows_temp", Name: "Windows Temp", Flag: "win-temp",
+		Description: "Windows Temp directory",
+		Group:       GroupSystem, Platforms: windowsOnly, Default: true,
+		Paths: []PathResolver{envPath("WINDIR", "Temp")},
+	},
+	{
+		ID: "user_temp", Name: "User Temp", Flag: "user-temp",
+		Description: "User Temp directories",
+		Group:       GroupSystem, Default: true,
+		Paths: []PathResolver{envPath("TEMP"), envPath("TMP"), envPath("LOCALAPPDATA", "Temp")},
+	},
+	{
+		ID: "windows_update", Name: "Windows Update Cache", Flag: "wupdate",
+		Description: "Windows Update cache",
+		Group:       GroupSystem, Platforms: windowsOnly, Risk: RiskMedium,
+		Paths: []PathResolver{envPath("WINDIR", "SoftwareDistribution", "Download")},
+	},
+	{
+		ID: "windows_installer", Name: "Windows Installer Cache", Flag: "installer",
+		Description: "Windows Installer cache",
+		Group:       GroupSystem, Platforms: windowsOnly, Risk: RiskMedium,
+		Paths: []PathResolver{envPath("WINDIR", "Installer", "$PatchCache$")},
+	},
+	{
+		ID: "prefetch", Name: "Prefetch (30+ days)", Flag: "prefetch",
+		Description: "Prefetch data (files older than 30 days)",
+		Group:       GroupSystem, Platforms: windowsOnly, Default: true,
+		Paths:  []PathResolver{envPath("WINDIR", "Prefetch")},
+		MaxAge: 30 * 24 * time.Hour,
+	},
+	{
+		ID: "crash_dumps", Name: "Crash Dumps", Flag: "crashdumps",
+		Description: "Crash dump files",
+		Group:       GroupSystem, Platforms: windowsOnly,
+		Paths: []PathResolver{envPath("LOCALAPPDATA", "CrashDumps"), envPath("WINDIR", "Minidump")},
+		Clean: cleanCrashDumps,
+	},
+	{
+		ID: "error_reports", Name: "Error Reports", Flag: "wer",
+		Description: "Windows Error Reports",
+		Group:       GroupSystem, Platforms: windowsOnly,
+		Paths: []PathResolver{
+			envPath("LOCALAPPDATA", "Microsoft", "Windows", "WER"),
+			envPath("ProgramData", "Microsoft", "Windows", "WER"),
+		},
+	},
+	{
+		ID: "thumbnail_cache", Name: "Thumbnail Cache", Flag: "thumbcache",
+		Description: "Thumbnail cache",
+		Group:       GroupSystem, Platforms: windowsOnly, Default: true,
+		Paths: []PathResolver{envPath("LOCALAPPDATA", "Microsoft", "Windows", "Explorer")},
+		Clean: cleanThumbnailCache,
+	},
+	{
+		ID: "icon_cache", Name: "Icon Cache", Flag: "iconcache",
+		Description: "Icon cache",
+		Group:       GroupSystem, Platforms: windowsOnly, Risk: RiskMedium,
+		Clean: cleanIconCache,
+	},
+	{
+		ID: "font_cache", Name: "Font Cache", Flag: "fontcache",
+		Description: "Font cache",
+		Group:       GroupSystem, Platforms: windowsOnly, Risk: RiskMedium,
+		Paths: []PathResolver{envPath("WINDIR", "ServiceProfiles", "LocalService", "AppData", "Local", "FontCache")},
+	},
+	{
+		ID: "shader_cache", Name: "Shader Cache", Flag: "shadercache",
+		Description: "DirectX shader cache",
+		Group:       GroupSystem, Platforms: windowsOnly,
+		Paths: []PathResolver{
+			envPath("LOCALAPPDATA", "D3DSCache"),
+			envPath("LOCALAPPDATA", "NVIDIA", "DXCache"),
+			envPath("LOCALAPPDATA", "NVIDIA", "GLCache"),
+			envPath("LOCALAPPDATA", "AMD", "DxCache"),
+		},
+	},
+	{
+		ID: "dns_cache", Name: "DNS Cache", Flag: "dnscache",
+		Description: "DNS cache (flush)",
+		Group:       GroupSystem, Platforms: windowsOnly, Risk: RiskMedium, Default: true,
+		Clean: cleanDNSCache, Action: "Flush the DNS resolver cache",
+	},
+	{
+		ID: "windows_logs", Name: "Windows Logs", Flag: "winlogs",
+		Description: "Windows log files",
+		Group:       GroupSystem, Platforms: windowsOnly,
+		Paths: []PathResolver{
+			envPath("WINDIR", "Logs"),
+			envPath("WINDIR", "Debug"),
+			envPath("WINDIR", "Panther"),
+		},
+		MaxAge: 30 * 24 * time.Hour,
+		Logs:   true,
+	},
+	{
+		ID: "event_logs", Name: "Event Logs", Flag: "eventlogs",
+		Description: "Windows Event Logs",
+		Group:       GroupSystem, Platforms: windowsOnly, Risk: RiskHigh,
+		Clean: cleanEventLogs, Action: "Clear the System and Application event logs",
+	},
+	{
+		ID: "delivery_optimization", Name: "Delivery Optimization", Flag: "deliveryopt",
+		Description: "Delivery Optimization cache",
+		Group:       GroupSystem, Platforms: windowsOnly, Risk: RiskMedium,
+		Paths: []PathResolver{envPath("WINDIR", "SoftwareDistribution", "DeliveryOptimization")},
+	},
+	{
+		ID: "recycle_bin", Name: "Recycle Bin", Flag: "recyclebin",
+		Description: "Recycle Bin",
+		Group:       GroupSystem, Platforms: windowsOnly, Risk: RiskHigh,
+		Clean: cleanRecycleBin, Action: "Empty the Recycle Bin",
+	},
+	{
+		ID: "xdg_cache", Name: "Application Caches", Flag: "xdg-cache",
+		Description: "Per-application caches in $XDG_CACHE_HOME",
+		Group:       GroupSystem, Platforms: linuxOnly, Risk: RiskMedium,
+		Paths: []PathResolver{xdgCache()},
+		Clean: cleanXDGCache,
+	},
+	{
+		ID: "xdg_thumbnails", Name: "Thumbnails", Flag: "thumbnails",
+		Description: "Freedesktop thumbnail cache",
+		Group:       GroupSystem, Platforms: linuxOnly, Default: true,
+		Paths: []PathResolver{xdgCache("thumbnails"), homePath(".thumbnails")},
+	},
+	{
+		ID: "trash", Name: "Trash", Flag: "trash",
+		Description: "Freedesktop Trash, including per-volume trash directories",
+		Group:       GroupSystem, Platforms: linuxOnly, Risk: RiskHigh,
+		Paths: []PathResolver{trashDirs},
+		Clean: cleanTrash, Execute: executeTrashPlan,
+	},
+	{
+		ID: "var_tmp", Name: "/var/tmp (30+ days)", Flag: "vartmp",
+		Description: "Files in /var/tmp older than 30 days",
+		Group:       GroupSystem, Platforms: linuxOnly,
+		Paths:  []PathResolver{func() []string { return []string{"/var/tmp"} }},
+		MaxAge: 30 * 24 * time.Hour,
+	},
+
+	// Browser categories
+	{
+		ID: "chrome_cache", Name: "Chrome", Flag: "chrome",
+		Description: "Chrome cache",
+		Group:       GroupBrowsers, Platforms: windowsAndLinux, Default: true,
+		Processes: []string{"chrome"},
+		Paths:     []PathResolver{browserCacheDirs("Chrome")},
+		Clean:     cleanBrowserCache("Chrome"),
+	},
+	{
+		ID: "chromium_cache", Name: "Chromium", Flag: "chromium",
+		Description: "Chromium cache",
+		Group:       GroupBrowsers, Platforms: windowsAndLinux,
+		Processes: []string{"chromium", "chromium-browser"},
+		Paths:     []PathResolver{browserCacheDirs("Chromium")},
+		Clean:     cleanBrowserCache("Chromium"),
+	},
+	{
+		ID: "firefox_cache", Name: "Firefox", Flag: "firefox",
+		Description: "Firefox cache",
+		Group:       GroupBrowsers, Platforms: windowsAndLinux, Default: true,
+		Processes: []string{"firefox", "firefox-bin"},
+		Paths:     []PathResolver{browserCacheDirs("Firefox")},
+		Clean:     cleanBrowserCache("Firefox"),
+	},
+	{
+		ID: "edge_cache", Name: "Edge", Flag: "edge",
+		Description: "Edge cache",
+		Group:       GroupBrowsers, Platforms: windowsAndLinux, Default: true,
+		Processes: []string{"msedge"},
+		Paths:     []PathResolver{browserCacheDirs("Edge")},
+		Clean:     cleanBrowserCache("Edge"),
+	},
+	{
+		ID: "brave_cache", Name: "Brave", Flag: "brave",
+		Description: "Brave cache",
+		Group:       GroupBrowsers, Platforms: windowsAndLinux,
+		Processes: []string{"brave"},
+		Paths:     []PathResolver{browserCacheDirs("Brave")},
+		Clean:     cleanBrowserCache("Brave"),
+	},
+	{
+		ID: "opera_cache", Name: "Opera", Flag: "opera",
+		Description: "Opera cache",
+		Group:       GroupBrowsers, Platforms: windowsOnly,
+		Processes: []string{"opera"},
+		Paths:     []PathResolver{browserCacheDirs("Opera", "Opera GX")},
+		Clean:     cleanBrowserCache("Opera", "Opera GX"),
+	},
+
+	// Privacy categories
+	{
+		ID: "browser_history", Name: "Browsing History", Flag: "browser-history",
+		Description: "Browsing and download history of Chromium-based browsers and Firefox (bookmarks are kept)",
+		Group:       GroupPrivacy, Platforms: windowsAndLinux, Risk: RiskHigh,
+		Processes: browserProcesses,
+		Clean:     privacyDatabases(chromiumHistoryDB, firefoxHistoryDB),
+		Action:    "Remove browsing and download history from the browser databases",
+	},
+	{
+		ID: "browser_cookies", Name: "Cookies", Flag: "browser-cookies",
+		Description: "Browser cookies, except those of the configured keep-list domains",
+		Group:       GroupPrivacy, Platforms: windowsAndLinux, Risk: RiskHigh,
+		Processes: browserProcesses,
+		Clean:     privacyDatabases(chromiumCookiesDB, firefoxCookiesDB),
+		Action:    "Remove cookies outside the keep-list from the browser databases",
+	},
+	{
+		ID: "browser_forms", Name: "Form Data", Flag: "browser-forms",
+		Description: "Saved form entries of Chromium-based browsers and Firefox",
+		Group:       GroupPrivacy, Platforms: windowsAndLinux, Risk: RiskHigh,
+		Processes: browserProcesses,
+		Clean:     privacyDatabases(chromiumFormsDB, firefoxFormsDB),
+		Action:    "Remove saved form entries from the browser databases",
+	},
+	{
+		ID: "browser_sessions", Name: "Sessions", Flag: "browser-sessions",
+		Description: "Saved sessions and open tabs of Chromium-based browsers and Firefox",
+		Group:       GroupPrivacy, Platforms: windowsAndLinux, Risk: RiskHigh,
+		Processes: browserProcesses,
+		Clean:     cleanBrowserSessions,
+	},
+
+	// Application categories
+	{
+		ID: "discord_cache", Name: "Discord", Flag: "discord",
+		Description: "Discord cache",
+		Group:       GroupApplications, Platforms: windowsOnly,
+		Processes: []string{"Discord"},
+		Paths: []PathResolver{
+			envPath("APPDATA", "discord", "Cache"),
+			envPath("APPDATA", "discord", "Code Cache"),
+			envPath("APPDATA", "discord", "GPUCache"),
+		},
+	},
+	{
+		ID: "spotify_cache", Name: "Spotify", Flag: "spotify",
+		Description: "Spotify cache",
+		Group:       GroupApplications, Platforms: windowsOnly,
+		Processes: []string{"Spotify"},
+		Paths:     []PathResolver{envPath("LOCALAPPDATA", "Spotify", "Storage")},
+	},
+	{
+		ID: "steam_cache", Name: "Steam", Flag: "steam",
+		Description: "Steam cache",
+		Group:       GroupApplications, Platforms: windowsOnly,
+		Processes: []string{"steam", "steamwebhelper"},
+		Paths:     []PathResolver{envPath("LOCALAPPDATA", "Steam", "htmlcache")},
+	},
+	{
+		ID: "steam_games", Name: "Steam Game Caches", Flag: "steam-games",
+		Description: "Shader caches, download leftovers and workshop content of uninstalled games in every Steam library",
+		Group:       GroupApplications, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Processes: []string{"steam", "steamwebhelper"},
+		Detect:    func() bool { return len(steamLibraries()) > 0 },
+		Clean:     cleanLeftovers(steamLeftovers),
+		Execute:   executeLeftovers(steamLeftovers),
+	},
+	{
+		ID: "epic_games", Name: "Epic Games", Flag: "epic-games",
+		Description: "Download staging of games in the Epic Games Launcher manifests and the launcher web cache",
+		Group:       GroupApplications, Platforms: windowsOnly, Risk: RiskMedium,
+		Processes: []string{"EpicGamesLauncher", "EpicWebHelper"},
+		Clean:     cleanLeftovers(epicLeftovers),
+		Execute:   executeLeftovers(epicLeftovers),
+	},
+	{
+		ID: "battlenet_cache", Name: "Battle.net", Flag: "battlenet",
+		Description: "Battle.net launcher cache",
+		Group:       GroupApplications, Platforms: windowsOnly,
+		Processes: []string{"Battle.net"},
+		Paths: []PathResolver{
+			envPath("ProgramData", "Blizzard Entertainment", "Battle.net", "Cache"),
+			envPath("LOCALAPPDATA", "Battle.net", "Cache"),
+		},
+	},
+	{
+		ID: "teams_cache", Name: "Teams", Flag: "teams",
+		Description: "Teams cache",
+		Group:       GroupApplications, Platforms: windowsOnly,
+		Processes: []string{"Teams", "ms-teams"},
+		Paths: []PathResolver{
+			envPath("APPDATA", "Microsoft", "Teams", "Cache"),
+			envPath("APPDATA", "Microsoft", "Teams", "blob_storage"),
+			envPath("APPDATA", "Microsoft", "Teams", "GPUCache"),
+		},
+	},
+	{
+		ID: "vscode_cache", Name: "VS Code", Flag: "vscode",
+		Description: "VS Code cache",
+		Group:       GroupApplications, Platforms: windowsOnly,
+		Processes: []string{"Code"},
+		Paths: []PathResolver{
+			envPath("APPDATA", "Code", "Cache"),
+			envPath("APPDATA", "Code", "CachedData"),
+			envPath("APPDATA", "Code", "CachedExtensions"),
+		},
+	},
+	{
+		ID: "java_cache", Name: "Java", Flag: "java",
+		Description: "Java cache",
+		Group:       GroupApplications, Platforms: windowsOnly,
+		Processes: []string{"java", "javaw", "javaws", "jp2launcher"},
+		Paths:     []PathResolver{envPath("USERPROFILE", "AppData", "LocalLow", "Sun", "Java", "Deployment", "cache")},
+	},
+
+	// Developer categories. Locations are resolved like the tools do, see
+	// developer.go.
+	{
+		ID: "go_build_cache", Name: "Go Build Cache", Flag: "go-cache",
+		Description: "Go build and test cache (GOCACHE)",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Paths: []PathResolver{firstOf(goSetting("GOCACHE"), envPath("LOCALAPPDATA", "go-build"), xdgCache("go-build"))},
+	},
+	{
+		ID: "go_mod_cache", Name: "Go Module Cache", Flag: "go-modcache",
+		Description: "Downloaded Go modules (GOMODCACHE)",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Paths:   []PathResolver{firstOf(goSetting("GOMODCACHE"), subPath(goPath, "pkg", "mod"))},
+		Clean:   cleanReadOnlyTree,
+		Execute: executeReadOnlyTree,
+	},
+	{
+		ID: "npm_cache", Name: "npm Cache", Flag: "npm-cache",
+		Description: "npm package cache",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Paths: []PathResolver{firstOf(
+			envSetting("npm_config_cache"), envSetting("NPM_CONFIG_CACHE"), fileSetting("cache", npmrc),
+			envPath("LOCALAPPDATA", "npm-cache"), homePath(".npm"),
+		)},
+	},
+	{
+		ID: "yarn_cache", Name: "Yarn Cache", Flag: "yarn-cache",
+		Description: "Yarn package cache (classic and Berry global cache)",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Paths: []PathResolver{
+			firstOf(envSetting("YARN_CACHE_FOLDER"), fileSetting("cache-folder", yarnrc),
+				envPath("LOCALAPPDATA", "Yarn", "Cache"), xdgCache("yarn")),
+			subPath(firstOf(envSetting("YARN_GLOBAL_FOLDER"), fileSetting("globalFolder", yarnrcV2),
+				envPath("LOCALAPPDATA", "Yarn", "Berry"), homePath(".yarn", "berry")), "cache"),
+		},
+	},
+	{
+		ID: "pnpm_store", Name: "pnpm Store", Flag: "pnpm-store",
+		Description: "pnpm content-addressable store and metadata cache",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Paths: []PathResolver{
+			firstOf(envSetting("npm_config_store_dir"), fileSetting("store-dir", pnpmrc, npmrc),
+				envPath("LOCALAPPDATA", "pnpm", "store"), xdgData("pnpm", "store")),
+			firstOf(envSetting("npm_config_cache_dir"), fileSetting("cache-dir", pnpmrc, npmrc),
+				envPath("LOCALAPPDATA", "pnpm-cache"), xdgCache("pnpm")),
+		},
+	},
+	{
+		ID: "pip_cache", Name: "pip Cache", Flag: "pip-cache",
+		Description: "pip download and wheel cache",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Paths: []PathResolver{firstOf(
+			envSetting("PIP_CACHE_DIR"), fileSetting("cache-dir", pipConfigFiles...),
+			envPath("LOCALAPPDATA", "pip", "Cache"), xdgCache("pip"),
+		)},
+	},
+	{
+		ID: "cargo_cache", Name: "Cargo Registry", Flag: "cargo-cache",
+		Description: "Downloaded crates and git checkouts in the Cargo home (CARGO_HOME)",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Paths: []PathResolver{
+			subPath(firstOf(envSetting("CARGO_HOME"), homePath(".cargo")), "registry", "cache"),
+			subPath(firstOf(envSetting("CARGO_HOME"), homePath(".cargo")), "registry", "src"),
+			subPath(firstOf(envSetting("CARGO_HOME"), homePath(".cargo")), "git", "checkouts"),
+		},
+	},
+	{
+		ID: "gradle_cache", Name: "Gradle Cache", Flag: "gradle-cache",
+		Description: "Gradle dependency and build caches (GRADLE_USER_HOME)",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Paths: []PathResolver{subPath(firstOf(
+			envSetting("GRADLE_USER_HOME"), javaProperty("gradle.user.home", "GRADLE_OPTS"), homePath(".gradle"),
+		), "caches")},
+	},
+	{
+		ID: "maven_repository", Name: "Maven Repository", Flag: "maven-repo",
+		Description: "Maven local repository (localRepository in settings.xml)",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Paths: []PathResolver{mavenRepository()},
+	},
+	{
+		ID: "stale_node_modules", Name: "Stale node_modules", Flag: "node-modules",
+		Description: "node_modules of projects in the workspace roots untouched for the configured number of days",
+		Group:       GroupDeveloper, Platforms: windowsAndLinux, Risk: RiskMedium,
+		Clean:   cleanStaleNodeModules,
+		Execute: executeNodeModules,
+	},
+}
@@ -0,0 +1,176 @@
+package cleaner
+
+import "errors"
+
+// String returns the stable name used for the error type in reports.
+func (t ErrorType) String() string {
+	switch t {
+	case ErrorLocked:
+		return "locked"
+	case ErrorPermissionDenied:
+		return "permission_denied"
+	case ErrorTimeout:
+		return "timeout"
+	case ErrorNotFound:
+		return "not_found"
+	case ErrorProtected:
+		return "protected"
+	case ErrorExcluded:
+		return "excluded"
+	default:
+		return "other"
+	}
+}
+
+// Outcome summarizes a whole run.
+type Outcome string
+
+const (
+	OutcomeOK      Outcome = "ok"      // Every category completed without failures
+	OutcomePartial Outcome = "partial" // Some files were removed, but not everything went through
+	OutcomeFailed  Outcome = "failed"  // Something went wrong and nothing was removed
+)
+
+// Outcome classifies the run. Permission errors, refused protected paths,
+// other errors and categories that did not complete are failures. Files
+// in use, paths matching an exclusion glob and categories skipped because
+// their application was running are skipped by design and are not.
+func (r CleanResult) Outcome() Outcome {
+	failures := r.PermissionFiles + int64(len(r.Errors)-len(r.ErrorsOfType(ErrorExcluded)))
+	for _, st := range r.Categories {
+		if st.Status != StatusCompleted && st.Status != StatusSkipped {
+			failures++
+		}
+	}
+	switch {
+	case failures == 0:
+		return OutcomeOK
+	case r.FilesDeleted+r.FilesQuarantined+r.RecordsDeleted == 0:
+		return OutcomeFailed
+	default:
+		return OutcomePartial
+	}
+}
+
+// Report is the machine-readable form of a CleanResult, written by
+// "syscleaner clean --output json". Its field names are a stable interface
+// for scripts: fields may be added, but existing ones keep their name and
+// meaning. Lists are empty rather than null.
+type Report struct {
+	Outcome          Outcome          `json:"outcome"`
+	DryRun           bool             `json:"dry_run"`
+	FilesDeleted     int64            `json:"files_deleted"`
+	FilesSkipped     int64            `json:"files_skipped"`
+	FilesLocked      int64            `json:"files_locked"`
+	FilesDenied      int64            `json:"files_permission_denied"`
+	FilesChanged     int64            `json:"files_changed"`
+	DirsRemoved      int64            `json:"dirs_removed"` // Folders left empty by the run
+	BytesFreed       int64            `json:"bytes_freed"`
+	BytesReclaimed   int64            `json:"bytes_reclaimed"`
+	DiskFreeDelta    int64            `json:"disk_free_delta"`
+	FilesQuarantined int64            `json:"files_quarantined"`
+	BytesQuarantined int64            `json:"bytes_quarantined"`
+	QuarantineRun    string           `json:"quarantine_run,omitempty"`
+	FilesArchived    int64            `json:"files_archived"`
+	BytesArchived    int64            `json:"bytes_archived"` // Before compression
+	ArchiveBytes     int64            `json:"archive_bytes"`  // Size of the bundles written
+	NetReclaimed     int64            `json:"net_bytes_reclaimed"`
+	Archives         []string         `json:"archives"`
+	RecordsDeleted   int64            `json:"records_deleted"`
+	DurationMS       int64            `json:"duration_ms"`
+	Categories       []CategoryReport `json:"categories"`
+	ErrorCounts      map[string]int   `json:"error_counts"` // By ErrorType name
+	Errors           []ErrorReport    `json:"errors"`
+	SkippedLinks     []string         `json:"skipped_links"`
+	SkippedMounts    []string         `json:"skipped_mounts"`
+	DryRunItems      []string         `json:"dry_run_items"`
+	// BrowserProfiles lists what the browser and privacy categories did in
+	// each browser profile.
+	BrowserProfiles []BrowserProfileResult `json:"browser_profiles"`
+	// Games lists what the game launcher categories did per game.
+	Games []GameResult `json:"games"`
+}
+
+// CategoryReport is the machine-readable form of a CategoryStatus.
+type CategoryReport struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Status       RunStatus `json:"status"`
+	FilesDeleted int64     `json:"files_deleted"`
+	BytesFreed   int64     `json:"bytes_freed"`
+	DurationMS   int64     `json:"duration_ms"`
+	Reason       string    `json:"reason,omitempty"` // Why it was skipped, or that its application was running
+}
+
+// ErrorReport is one error of a run. Type is an ErrorType name; errors
+// that were not classified have type "other" and no path.
+type ErrorReport struct {
+	Type    string `json:"type"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// NewReport builds the Report of a run.
+func NewReport(r CleanResult, dryRun bool) Report {
+	rep := Report{
+		Outcome:          r.Outcome(),
+		DryRun:           dryRun,
+		FilesDeleted:     r.FilesDeleted,
+		FilesSkipped:     r.SkippedFiles,
+		FilesLocked:      r.LockedFiles,
+		FilesDenied:      r.PermissionFiles,
+		FilesChanged:     r.ChangedFiles,
+		DirsRemoved:      r.DirsRemoved,
+		BytesFreed:       r.SpaceFreed,
+		BytesReclaimed:   r.SpaceReclaimed,
+		DiskFreeDelta:    r.DiskFreeDelta,
+		FilesQuarantined: r.FilesQuarantined,
+		BytesQuarantined: r.SpaceQuarantined,
+		QuarantineRun:    r.QuarantineRun,
+		FilesArchived:    r.FilesArchived,
+		BytesArchived:    r.SpaceArchived,
+		ArchiveBytes:     r.ArchiveSize,
+		NetReclaimed:     r.NetReclaimed(),
+		Archives:         append([]string{}, r.Archives...),
+		RecordsDeleted:   r.RecordsDeleted,
+		DurationMS:       r.Duration.Milliseconds(),
+		Categories:       []CategoryReport{},
+		ErrorCounts:      map[string]int{},
+		Errors:           []ErrorReport{},
+		SkippedLinks:     append([]string{}, r.SkippedLinks...),
+		SkippedMounts:    append([]string{}, r.SkippedMounts...),
+		DryRunItems:      append([]string{}, r.DryRunItems...),
+		BrowserProfiles:  append([]BrowserProfileResult{}, r.BrowserProfiles...),
+		Games:            append([]GameResult{}, r.Games...),
+	}
+	for _, st := range r.Categories {
+		rep.Categories = append(rep.Categories, CategoryReport{
+			ID:           st.ID,
+			Name:         st.Name,
+			Status:       st.Status,
+			FilesDeleted: st.FilesDeleted,
+			BytesFreed:   st.SpaceFreed,
+			DurationMS:   st.Duration.Milliseconds(),
+			Reason:       st.Reason,
+		})
+	}
+	for _, er := range ErrorReports(r.Errors) {
+		rep.Errors = append(rep.Errors, er)
+		rep.ErrorCounts[er.Type]++
+	}
+	return rep
+}
+
+// ErrorReports converts errors to their reports. It never returns nil.
+func ErrorReports(errs []error) []ErrorReport {
+	reports := []ErrorReport{}
+	for _, err := range errs {
+		er := ErrorReport{Type: ErrorOther.String(), Message: err.Error()}
+		var ce *CleanError
+		if errors.As(err, &ce) {
+			er.Type, er.Path = ce.Type.String(), ce.Path
+		}
+		reports = append(reports, er)
+	}
+	return reports
+}
@@ -0,0 +1,74 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// ---------- Report tests ----------
+
+func TestCleanResult_Outcome(t *testing.T) {
+	completed := []CategoryStatus{{ID: "a", Status: StatusCompleted}}
+	tests := []struct {
+		name   string
+		result CleanResult
+		want   Outcome
+	}{
+		{"clean run", CleanResult{FilesDeleted: 3, Categories: completed}, OutcomeOK},
+		{"nothing to do", CleanResult{Categories: completed}, OutcomeOK},
+		{"locked files only", CleanResult{FilesDeleted: 1, LockedFiles: 2, Categories: completed}, OutcomeOK},
+		{"exclusions only", CleanResult{Errors: []error{&CleanError{Type: ErrorExcluded}}, Categories: completed}, OutcomeOK},
+		{"some errors", CleanResult{FilesDeleted: 1, Errors: []error{errors.New("boom")}}, OutcomePartial},
+		{"quarantined with denied files", CleanResult{FilesQuarantined: 1, PermissionFiles: 1}, OutcomePartial},
+		{"stopped early", CleanResult{FilesDeleted: 1, Categories: []CategoryStatus{{Status: StatusPartial}}}, OutcomePartial},
+		{"nothing removed", CleanResult{PermissionFiles: 4}, OutcomeFailed},
+		{"cancelled before start", CleanResult{Categories: []CategoryStatus{{Status: StatusCancelled}}}, OutcomeFailed},
+	}
+	for _, tt := range tests {
+		if got := tt.result.Outcome(); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestNewReport_CategorizesErrors(t *testing.T) {
+	result := CleanResult{
+		FilesDeleted: 1,
+		Errors: []error{
+			&CleanError{Path: "/a", Type: ErrorProtected, Err: ErrProtectedPath},
+			&CleanError{Path: "/b", Type: ErrorExcluded, Err: ErrExcludedPath},
+			errors.New("walk error"),
+		},
+	}
+	rep := NewReport(result, false)
+
+	if rep.Outcome != OutcomePartial {
+		t.Errorf("expected partial outcome, got %q", rep.Outcome)
+	}
+	if rep.ErrorCounts["protected"] != 1 || rep.ErrorCounts["excluded"] != 1 || rep.ErrorCounts["other"] != 1 {
+		t.Errorf("unexpected error counts %v", rep.ErrorCounts)
+	}
+	if rep.Errors[0].Type != "protected" || rep.Errors[0].Path != "/a" || rep.Errors[2].Path != "" {
+		t.Errorf("unexpected errors %+v", rep.Errors)
+	}
+}
+
+func TestNewReport_EmptyListsAreNotNull(t *testing.T) {
+	data, err := json.Marshal(NewReport(CleanResult{}, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"categories", "errors", "error_counts", "skipped_links", "skipped_mounts", "dry_run_items"} {
+		if fields[key] == nil {
+			t.Errorf("expected %q to be present and not null in %s", key, data)
+		}
+	}
+	if _, ok := fields["quarantine_run"]; ok {
+		t.Errorf("expected quarantine_run to be omitted without a run, got %s", data)
+	}
+}
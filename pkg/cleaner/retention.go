@@ -0,0 +1,252 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AgeBasis is the timestamp a retention policy measures a file's age by.
+type AgeBasis string
+
+const (
+	AgeModified AgeBasis = "mtime"
+	AgeAccessed AgeBasis = "atime"
+)
+
+// ParseAgeBasis parses "mtime" or "atime". The empty string means mtime.
+func ParseAgeBasis(s string) (AgeBasis, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "mtime", "modified":
+		return AgeModified, nil
+	case "atime", "accessed":
+		return AgeAccessed, nil
+	default:
+		return "", fmt.Errorf("unknown age basis %q (want mtime or atime)", s)
+	}
+}
+
+// RetentionPolicy narrows down which of the files a category selects in a
+// directory are removed. Every field keeps files back:
+//
+//   - MinAge keeps files modified (or, with AgeBy atime, read) more recently.
+//     Zero keeps the category's built-in age, such as 30 days for Prefetch.
+//   - MinSize keeps files smaller than this.
+//   - KeepNewest keeps the newest N files of every folder. The files MinAge
+//     and MinSize keep count towards N.
+//   - MaxSize turns the clean into a size cap: the remaining candidates are
+//     removed oldest first only until the directory is no larger than this.
+//
+// The zero value removes everything the category selects.
+type RetentionPolicy struct {
+	MinAge     time.Duration
+	AgeBy      AgeBasis // Empty = AgeModified
+	MinSize    int64
+	KeepNewest int
+	MaxSize    int64
+}
+
+// IsZero reports whether the policy keeps nothing back.
+func (p RetentionPolicy) IsZero() bool {
+	return p == RetentionPolicy{} || p == RetentionPolicy{AgeBy: AgeModified}
+}
+
+// WithDefaultAge returns p with MinAge set to age when p does not set one.
+func (p RetentionPolicy) WithDefaultAge(age time.Duration) RetentionPolicy {
+	if p.MinAge == 0 {
+		p.MinAge = age
+	}
+	return p
+}
+
+// timestamp is the time of a file the policy measures age and newness by.
+func (p RetentionPolicy) timestamp(info os.FileInfo) time.Time {
+	if p.AgeBy == AgeAccessed {
+		return accessTime(info)
+	}
+	return info.ModTime()
+}
+
+// conditions describes the policy, one clause per field that is set.
+func (p RetentionPolicy) conditions() []string {
+	var conds []string
+	if p.MinAge > 0 {
+		verb := "older than "
+		if p.AgeBy == AgeAccessed {
+			verb = "not read for "
+		}
+		conds = append(conds, verb+FormatAge(p.MinAge))
+	}
+	if p.MinSize > 0 {
+		conds = append(conds, "at least "+FormatBytes(p.MinSize))
+	}
+	if p.KeepNewest > 0 {
+		conds = append(conds, fmt.Sprintf("keeping the newest %d per folder", p.KeepNewest))
+	}
+	if p.MaxSize > 0 {
+		conds = append(conds, "oldest first down to "+FormatBytes(p.MaxSize))
+	}
+	return conds
+}
+
+// String describes the policy, such as "older than 30d; at least 1.00 MB".
+func (p RetentionPolicy) String() string {
+	if conds := p.conditions(); len(conds) > 0 {
+		return strings.Join(conds, "; ")
+	}
+	return "everything"
+}
+
+// retentionJSON is the serialized form of RetentionPolicy, with ages and
+// sizes written the way ParseAge and ParseSize read them.
+type retentionJSON struct {
+	MinAge     string   `json:"min_age,omitempty"`
+	AgeBy      AgeBasis `json:"age_by,omitempty"`
+	MinSize    string   `json:"min_size,omitempty"`
+	KeepNewest int      `json:"keep_newest,omitempty"`
+	MaxSize    string   `json:"max_size,omitempty"`
+}
+
+// MarshalJSON writes the policy as {"min_age": "30d", "max_size": "500M", ...}.
+func (p RetentionPolicy) MarshalJSON() ([]byte, error) {
+	j := retentionJSON{AgeBy: p.AgeBy, KeepNewest: p.KeepNewest}
+	if p.MinAge > 0 {
+		j.MinAge = FormatAge(p.MinAge)
+	}
+	if p.MinSize > 0 {
+		j.MinSize = FormatSize(p.MinSize)
+	}
+	if p.MaxSize > 0 {
+		j.MaxSize = FormatSize(p.MaxSize)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON reads the form written by MarshalJSON.
+func (p *RetentionPolicy) UnmarshalJSON(data []byte) error {
+	var j retentionJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	var (
+		q   RetentionPolicy
+		err error
+	)
+	if q.MinAge, err = ParseAge(j.MinAge); err != nil {
+		return fmt.Errorf("min_age: %w", err)
+	}
+	if j.AgeBy != "" {
+		if q.AgeBy, err = ParseAgeBasis(string(j.AgeBy)); err != nil {
+			return fmt.Errorf("age_by: %w", err)
+		}
+	}
+	if q.MinSize, err = ParseSize(j.MinSize); err != nil {
+		return fmt.Errorf("min_size: %w", err)
+	}
+	if q.MaxSize, err = ParseSize(j.MaxSize); err != nil {
+		return fmt.Errorf("max_size: %w", err)
+	}
+	if j.KeepNewest < 0 {
+		return fmt.Errorf("keep_newest: must not be negative")
+	}
+	q.KeepNewest = j.KeepNewest
+	*p = q
+	return nil
+}
+
+// retentionFile is a file of a cleaned directory, with the timestamp the
+// policy orders it by and whether MinAge and MinSize let it go.
+type retentionFile struct {
+	path     string
+	info     os.FileInfo
+	when     time.Time
+	eligible bool
+}
+
+// retentionSelector collects the files of one cleaned directory so that the
+// policy's KeepNewest and MaxSize can be applied once the whole directory
+// has been seen.
+type retentionSelector struct {
+	policy     RetentionPolicy
+	now        time.Time
+	total      int64           // Size of every file in the directory, candidates or not
+	candidates []retentionFile // Every file the category selects, eligible or not
+}
+
+func newRetentionSelector(p RetentionPolicy) *retentionSelector {
+	return &retentionSelector{policy: p, now: time.Now()}
+}
+
+// streaming reports whether files can be removed as soon as they are
+// visited, because no rule depends on the other files of the directory.
+func (s *retentionSelector) streaming() bool {
+	return s.policy.KeepNewest == 0 && s.policy.MaxSize == 0
+}
+
+// eligible applies the per-file rules, MinAge and MinSize.
+func (s *retentionSelector) eligible(info os.FileInfo) bool {
+	if s.policy.MinAge > 0 && s.now.Sub(s.policy.timestamp(info)) < s.policy.MinAge {
+		return false
+	}
+	return info.Size() >= s.policy.MinSize
+}
+
+// add records a file the category selects for selected, whether or not it
+// is eligible: KeepNewest ranks it among the others either way.
+func (s *retentionSelector) add(path string, info os.FileInfo) {
+	s.candidates = append(s.candidates, retentionFile{path, info, s.policy.timestamp(info), s.eligible(info)})
+}
+
+// selected calls remove for the eligible candidates that are not among the
+// newest KeepNewest of their folder, oldest first. remove reports whether
+// the file is gone, which counts towards MaxSize.
+func (s *retentionSelector) selected(remove func(path string, info os.FileInfo) bool) {
+	files := s.candidates
+	sort.SliceStable(files, func(i, j int) bool {
+		if !files[i].when.Equal(files[j].when) {
+			return files[i].when.After(files[j].when)
+		}
+		return files[i].path > files[j].path
+	})
+	kept := map[string]int{}
+	var rest []retentionFile
+	for _, f := range files {
+		if dir := filepath.Dir(f.path); kept[dir] < s.policy.KeepNewest {
+			kept[dir]++
+			continue
+		}
+		if f.eligible {
+			rest = append(rest, f)
+		}
+	}
+	files = rest
+
+	total := s.total
+	for i := len(files) - 1; i >= 0; i-- {
+		if s.policy.MaxSize > 0 && total <= s.policy.MaxSize {
+			return
+		}
+		if remove(files[i].path, files[i].info) {
+			total -= files[i].info.Size()
+		}
+	}
+}
+
+// FormatSize is the inverse of ParseSize. Whole multiples of a unit are
+// written with its suffix ("500M"), anything else in bytes.
+func FormatSize(n int64) string {
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}} {
+		if n >= u.size && n%u.size == 0 {
+			return strconv.FormatInt(n/u.size, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
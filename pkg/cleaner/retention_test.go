@@ -0,0 +1,147 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// agedFile writes a file of size bytes last modified and read age ago.
+func agedFile(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	writeFile(t, path, strings.Repeat("x", size))
+	when := time.Now().Add(-age)
+	if err := os.Chtimes(path, when, when); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// retentionClean cleans dir under policy, with maxAge as the category's
+// built-in age, and returns the names of the files left.
+func retentionClean(t *testing.T, dir string, maxAge time.Duration, policy RetentionPolicy) []string {
+	t.Helper()
+	sw := newSweeper(Category{ID: "test_retention"}, CleanOptions{Retention: map[string]RetentionPolicy{"test_retention": policy}})
+	cleanDirectoryFiltered(dir, fileFilter{MaxAge: maxAge}, sw)
+	var left []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			left = append(left, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(left)
+	return left
+}
+
+// ---------- Retention tests ----------
+
+func TestRetention_AgeAndSize(t *testing.T) {
+	const day = 24 * time.Hour
+	dir := t.TempDir()
+	agedFile(t, filepath.Join(dir, "old-big.log"), 100, 40*day)
+	agedFile(t, filepath.Join(dir, "old-small.log"), 10, 40*day)
+	agedFile(t, filepath.Join(dir, "week.log"), 100, 8*day)
+	agedFile(t, filepath.Join(dir, "new.log"), 100, time.Hour)
+
+	// The category's built-in age applies until a policy sets its own.
+	if got := retentionClean(t, dir, 30*day, RetentionPolicy{MinSize: 50}); strings.Join(got, ",") != "new.log,old-small.log,week.log" {
+		t.Errorf("expected only the old big file removed, got %v", got)
+	}
+	if got := retentionClean(t, dir, 30*day, RetentionPolicy{MinAge: 7 * day}); strings.Join(got, ",") != "new.log" {
+		t.Errorf("expected files older than a week removed, got %v", got)
+	}
+}
+
+func TestRetention_AccessTime(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "read.log")
+	writeFile(t, path, "x")
+	old, now := time.Now().Add(-48*time.Hour), time.Now()
+	if err := os.Chtimes(path, now, old); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(path)
+	if accessTime(info).Equal(info.ModTime()) {
+		t.Skip("access times are not available on this platform")
+	}
+
+	// Modified two days ago, read just now: kept by atime, removed by mtime.
+	if got := retentionClean(t, dir, 0, RetentionPolicy{MinAge: 24 * time.Hour, AgeBy: AgeAccessed}); len(got) != 1 {
+		t.Errorf("expected the recently read file kept, got %v", got)
+	}
+	if got := retentionClean(t, dir, 0, RetentionPolicy{MinAge: 24 * time.Hour}); len(got) != 0 {
+		t.Errorf("expected the file modified two days ago removed, got %v", got)
+	}
+}
+
+func TestRetention_KeepNewestPerFolder(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 4; i++ {
+		agedFile(t, filepath.Join(dir, "a", string(rune('0'+i))+".log"), 10, time.Duration(i)*time.Hour)
+	}
+	agedFile(t, filepath.Join(dir, "b", "1.log"), 10, 10*time.Hour)
+
+	got := retentionClean(t, dir, 0, RetentionPolicy{KeepNewest: 2})
+	if strings.Join(got, ",") != "a/1.log,a/2.log,b/1.log" {
+		t.Errorf("expected the 2 newest files of each folder kept, got %v", got)
+	}
+}
+
+func TestRetention_KeepNewestCountsYoungFiles(t *testing.T) {
+	const day = 24 * time.Hour
+	dir := t.TempDir()
+	for _, age := range []int{1, 2, 40, 50, 60} {
+		agedFile(t, filepath.Join(dir, fmt.Sprintf("%02d.log", age)), 10, time.Duration(age)*day)
+	}
+
+	// The two newest files are young anyway, so every old one goes.
+	got := retentionClean(t, dir, 0, RetentionPolicy{MinAge: 30 * day, KeepNewest: 2})
+	if strings.Join(got, ",") != "01.log,02.log" {
+		t.Errorf("expected only the 2 newest files kept, got %v", got)
+	}
+}
+
+func TestRetention_SizeCap(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 5; i++ {
+		agedFile(t, filepath.Join(dir, string(rune('0'+i))+".log"), 100, time.Duration(i)*time.Hour)
+	}
+
+	// 500 bytes under a 250 byte cap: the three oldest go.
+	sw := newSweeper(Category{ID: "test_retention"}, CleanOptions{DryRun: true, Retention: map[string]RetentionPolicy{"test_retention": {MaxSize: 250}}})
+	if dry := cleanDirectoryFiltered(dir, fileFilter{}, sw); dry.FilesDeleted != 3 {
+		t.Errorf("expected a dry run to count 3 files, got %d", dry.FilesDeleted)
+	}
+	if got := retentionClean(t, dir, 0, RetentionPolicy{MaxSize: 250}); strings.Join(got, ",") != "1.log,2.log" {
+		t.Errorf("expected the oldest files removed down to the cap, got %v", got)
+	}
+	if got := retentionClean(t, dir, 0, RetentionPolicy{MaxSize: 250}); len(got) != 2 {
+		t.Errorf("a directory under the cap must be left alone, got %v", got)
+	}
+}
+
+func TestRetentionPolicy_JSON(t *testing.T) {
+	p := RetentionPolicy{MinAge: 14 * 24 * time.Hour, AgeBy: AgeAccessed, MinSize: 1000, KeepNewest: 3, MaxSize: 512 << 20}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"min_age":"14d","age_by":"atime","min_size":"1000","keep_newest":3,"max_size":"512M"}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+	var back RetentionPolicy
+	if err := json.Unmarshal(data, &back); err != nil || back != p {
+		t.Errorf("expected %+v back, got %+v (%v)", p, back, err)
+	}
+	for _, bad := range []string{`{"age_by":"ctime"}`, `{"max_size":"big"}`, `{"keep_newest":-1}`} {
+		if err := json.Unmarshal([]byte(bad), &back); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
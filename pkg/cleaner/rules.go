@@ -0,0 +1,196 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule is a user-defined cleaning rule, stored as one JSON file per rule:
+//
+//	{
+//	  "id": "bazel_cache",
+//	  "name": "Bazel output cache",
+//	  "paths": ["%LOCALAPPDATA%\\bazel", "$HOME/.cache/bazel"],
+//	  "include": ["*.tmp", "*.log"],
+//	  "exclude": ["*.lock"],
+//	  "min_age": "7d",
+//	  "recursive": true
+//	}
+//
+// Paths may reference environment variables as %VAR%, $VAR or ${VAR}, and may
+// start with ~ for the home directory. A path that references an unset
+// variable or does not expand to an absolute path is ignored.
+type Rule struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Paths     []string `json:"paths"`
+	Include   []string `json:"include,omitempty"`
+	Exclude   []string `json:"exclude,omitempty"`
+	MinAge    string   `json:"min_age,omitempty"`
+	Recursive bool     `json:"recursive"`
+	// Processes are the application's executables; while one runs the rule
+	// follows the running-application policy.
+	Processes []string `json:"processes,omitempty"`
+}
+
+// Validate checks that a rule can be turned into a category.
+func (r Rule) Validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("rule has no id")
+	}
+	if strings.ContainsAny(r.ID, " \t,") {
+		return fmt.Errorf("rule id %q must not contain spaces or commas", r.ID)
+	}
+	if IsReservedID(r.ID) {
+		return fmt.Errorf("rule id %q is reserved for a profile setting", r.ID)
+	}
+	if len(r.Paths) == 0 {
+		return fmt.Errorf("rule %q has no paths", r.ID)
+	}
+	for _, p := range append(append([]string(nil), r.Include...), r.Exclude...) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("rule %q: bad pattern %q: %w", r.ID, p, err)
+		}
+	}
+	if _, err := ParseAge(r.MinAge); err != nil {
+		return fmt.Errorf("rule %q: %w", r.ID, err)
+	}
+	return nil
+}
+
+// Category converts the rule into a registry category in GroupCustom. Custom
+// rules quarantine by default so that a mistyped path can be undone.
+func (r Rule) Category() (Category, error) {
+	if err := r.Validate(); err != nil {
+		return Category{}, err
+	}
+	maxAge, _ := ParseAge(r.MinAge)
+	filter := fileFilter{
+		MaxAge:  maxAge,
+		Include: r.Include,
+		Exclude: r.Exclude,
+		Shallow: !r.Recursive,
+	}
+
+	name := r.Name
+	if name == "" {
+		name = r.ID
+	}
+	paths := append([]string(nil), r.Paths...)
+
+	return Category{
+		ID:          r.ID,
+		Name:        name,
+		Description: name,
+		Group:       GroupCustom,
+		Risk:        RiskMedium,
+		Quarantine:  true,
+		Processes:   append([]string(nil), r.Processes...),
+		Paths: []PathResolver{func() []string {
+			var out []string
+			for _, p := range paths {
+				if expanded, ok := expandPath(p, os.LookupEnv); ok {
+					out = append(out, expanded)
+				}
+			}
+			return out
+		}},
+		Clean: func(c Category, opts CleanOptions) CleanResult {
+			result := CleanResult{}
+			for _, dir := range c.ResolvePaths() {
+				result.merge(cleanDirectoryFiltered(dir, filter, newSweeper(c, opts)))
+			}
+			return result
+		},
+	}, nil
+}
+
+// LoadRules reads every *.json file in dir as a Rule. Rules without an id
+// take the file name (minus extension). A missing directory yields no rules.
+// Files that fail to parse or validate are reported and skipped.
+func LoadRules(dir string) ([]Rule, []error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, []error{err}
+	}
+	sort.Strings(files)
+
+	var rules []Rule
+	var errs []error
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading rule %s: %w", file, err))
+			continue
+		}
+		var r Rule
+		if err := json.Unmarshal(data, &r); err != nil {
+			errs = append(errs, fmt.Errorf("parsing rule %s: %w", file, err))
+			continue
+		}
+		if r.ID == "" {
+			r.ID = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+		if err := r.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules, errs
+}
+
+// RegisterRules adds rules to the category registry, returning an error for
+// each rule that could not be registered.
+func RegisterRules(rules []Rule) []error {
+	var errs []error
+	for _, r := range rules {
+		c, err := r.Category()
+		if err == nil {
+			err = Register(c)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+var percentVar = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_()]*)%`)
+
+// expandPath expands %VAR%, $VAR, ${VAR} and a leading ~ using lookup. It
+// returns false if any referenced variable is unset or the result is not an
+// absolute path, so a typo can never turn into a relative or root path.
+func expandPath(p string, lookup func(string) (string, bool)) (string, bool) {
+	ok := true
+	get := func(name string) string {
+		v, found := lookup(name)
+		if !found || v == "" {
+			ok = false
+		}
+		return v
+	}
+
+	p = percentVar.ReplaceAllStringFunc(p, func(m string) string {
+		return get(m[1 : len(m)-1])
+	})
+	if strings.Contains(p, "$") {
+		p = os.Expand(p, get)
+	}
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		p = home + p[1:]
+	}
+	if !ok || !filepath.IsAbs(p) {
+		return "", false
+	}
+	return filepath.Clean(p), true
+}
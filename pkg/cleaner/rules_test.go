@@ -0,0 +1,214 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// helper: writeFile creates a file with the given content, creating parents.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ---------- LoadRules tests ----------
+
+func TestLoadRules_ParsesAndDefaultsID(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "build_cache.json"), `{"name": "Build cache", "paths": ["/tmp/x"], "min_age": "2d"}`)
+	writeFile(t, filepath.Join(dir, "broken.json"), `{not json`)
+	writeFile(t, filepath.Join(dir, "nopaths.json"), `{"id": "nopaths"}`)
+	writeFile(t, filepath.Join(dir, "readme.txt"), `ignored`)
+
+	rules, errs := LoadRules(dir)
+
+	if len(rules) != 1 {
+		t.Fatalf("expected 1 valid rule, got %d", len(rules))
+	}
+	if rules[0].ID != "build_cache" {
+		t.Errorf("expected ID derived from file name, got %q", rules[0].ID)
+	}
+	if len(errs) != 2 {
+		t.Errorf("expected 2 errors (broken, nopaths), got %d: %v", len(errs), errs)
+	}
+}
+
+func TestLoadRules_MissingDir(t *testing.T) {
+	rules, errs := LoadRules(filepath.Join(t.TempDir(), "missing"))
+	if len(rules) != 0 || len(errs) != 0 {
+		t.Errorf("expected no rules and no errors, got %v / %v", rules, errs)
+	}
+}
+
+func TestRuleValidate_RejectsReservedIDs(t *testing.T) {
+	for _, id := range ReservedIDs {
+		rule := Rule{ID: id, Paths: []string{t.TempDir()}}
+		if err := rule.Validate(); err == nil {
+			t.Errorf("expected rule id %q to be rejected", id)
+		}
+	}
+	if err := Register(Category{ID: "retention", Name: "Retention"}); err == nil {
+		t.Error("expected registering a reserved ID to fail")
+	}
+}
+
+func TestRuleCategory_IncludeExcludeRecursion(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.tmp"), "x")
+	writeFile(t, filepath.Join(root, "keep.lock.tmp"), "x")
+	writeFile(t, filepath.Join(root, "b.txt"), "x")
+	writeFile(t, filepath.Join(root, "sub", "c.tmp"), "x")
+
+	rule := Rule{
+		ID:      "test_rule_filter",
+		Paths:   []string{root},
+		Include: []string{"*.tmp"},
+		Exclude: []string{"keep.*"},
+	}
+	c, err := rule.Category()
+	if err != nil {
+		t.Fatalf("Category failed: %v", err)
+	}
+
+	result := c.run(CleanOptions{})
+
+	if result.FilesDeleted != 1 {
+		t.Errorf("expected 1 file deleted, got %d", result.FilesDeleted)
+	}
+	if exists(filepath.Join(root, "a.tmp")) {
+		t.Error("a.tmp should have been deleted")
+	}
+	for _, keep := range []string{"keep.lock.tmp", "b.txt", filepath.Join("sub", "c.tmp")} {
+		if !exists(filepath.Join(root, keep)) {
+			t.Errorf("%s should have been kept", keep)
+		}
+	}
+
+	rule.ID = "test_rule_recursive"
+	rule.Recursive = true
+	c, _ = rule.Category()
+	c.run(CleanOptions{})
+	if exists(filepath.Join(root, "sub", "c.tmp")) {
+		t.Error("sub/c.tmp should have been deleted by a recursive rule")
+	}
+}
+
+func TestRegisterRules_SelectableByID(t *testing.T) {
+	root := t.TempDir()
+	createTempFiles(t, root, 2)
+
+	errs := RegisterRules([]Rule{{ID: "test_rule_registered", Paths: []string{root}}})
+	if len(errs) != 0 {
+		t.Fatalf("RegisterRules failed: %v", errs)
+	}
+	c, ok := LookupCategory("test_rule_registered")
+	if !ok {
+		t.Fatal("rule was not registered")
+	}
+	if c.Group != GroupCustom {
+		t.Errorf("expected GroupCustom, got %s", c.Group)
+	}
+
+	opts := CleanOptions{DryRun: true}
+	opts.Enable("test_rule_registered")
+	if result := PerformClean(opts); result.FilesDeleted != 2 {
+		t.Errorf("expected 2 files found, got %d", result.FilesDeleted)
+	}
+
+	if errs := RegisterRules([]Rule{{ID: "windows_temp", Paths: []string{root}}}); len(errs) != 1 {
+		t.Error("expected an error when a rule reuses a built-in ID")
+	}
+}
+
+// ---------- expandPath tests ----------
+
+func TestExpandPath(t *testing.T) {
+	abs := t.TempDir()
+	env := map[string]string{"BASE": abs, "EMPTY": ""}
+	lookup := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"%BASE%/cache", filepath.Join(abs, "cache"), true},
+		{"$BASE/cache", filepath.Join(abs, "cache"), true},
+		{"${BASE}/cache", filepath.Join(abs, "cache"), true},
+		{"%MISSING%/cache", "", false},
+		{"%EMPTY%/cache", "", false},
+		{"relative/path", "", false},
+	}
+	for _, tc := range tests {
+		got, ok := expandPath(tc.in, lookup)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("expandPath(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// ---------- ParseAge tests ----------
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"36h", 36 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"1.5d", 36 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"7days", 0, true},
+		{"-1d", 0, true},
+	}
+	for _, tc := range tests {
+		got, err := ParseAge(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseAge(%q) = %v, %v; want %v, err=%v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+// ---------- ParseSize tests ----------
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"500", 500, false},
+		{"500B", 500, false},
+		{"64K", 64 << 10, false},
+		{"1.5MB", 3 << 19, false},
+		{"2gib", 2 << 30, false},
+		{"1T", 1 << 40, false},
+		{"MB", 0, true},
+		{"10 apples", 0, true},
+		{"-1K", 0, true},
+	}
+	for _, tc := range tests {
+		got, err := ParseSize(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseSize(%q) = %v, %v; want %v, err=%v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
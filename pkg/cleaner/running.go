@@ -0,0 +1,211 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// RunningPolicy decides what happens to a category whose owning application
+// (Category.Processes) is running when the category is about to be cleaned.
+// Cleaning a browser cache under a running browser fails on locked files at
+// best and corrupts the profile at worst.
+type RunningPolicy string
+
+const (
+	RunningSkip  RunningPolicy = "skip"  // Leave the category alone (default)
+	RunningWarn  RunningPolicy = "warn"  // Clean anyway and note it in the result
+	RunningClose RunningPolicy = "close" // Ask to close the application gracefully, then clean
+)
+
+// ParseRunningPolicy parses a policy name; the empty string is RunningSkip.
+func ParseRunningPolicy(s string) (RunningPolicy, error) {
+	switch p := RunningPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return RunningSkip, nil
+	case RunningSkip, RunningWarn, RunningClose:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown running-application policy %q (want skip, warn or close)", s)
+	}
+}
+
+// RunningProcess is a running instance of a category's owning application.
+type RunningProcess struct {
+	Name string `json:"name"`
+	PID  int32  `json:"pid"`
+}
+
+// ConfirmCloseFunc is asked before the close policy closes the running
+// processes of category c. It may block, for example on a dialog.
+type ConfirmCloseFunc func(c Category, procs []RunningProcess) bool
+
+// CloseTimeout is how long the close policy waits for an application it
+// asked to close to exit.
+const CloseTimeout = 15 * time.Second
+
+// processTable is the process list of a run. It is read when the first
+// category with owning processes is cleaned and updated as applications are
+// closed. The lock is held for the whole policy decision so that two
+// categories of the same application never ask to close it twice.
+type processTable struct {
+	mu     sync.Mutex
+	loaded bool
+	procs  []RunningProcess
+}
+
+func newProcessTable() *processTable {
+	return &processTable{}
+}
+
+// find returns the running processes with one of the given names. Names
+// are matched case-insensitively and without an ".exe" suffix, so "chrome"
+// matches chrome.exe on Windows and chrome on Linux. The caller holds t.mu.
+func (t *processTable) find(names []string) []RunningProcess {
+	if !t.loaded {
+		procs, err := listProcesses()
+		if err != nil {
+			log.Printf("[SysCleaner] Cannot list running processes: %v", err)
+		}
+		t.procs, t.loaded = procs, true
+	}
+	want := map[string]bool{}
+	for _, n := range names {
+		want[processKey(n)] = true
+	}
+	var found []RunningProcess
+	for _, p := range t.procs {
+		if want[processKey(p.Name)] {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// forget drops processes that were closed from the table.
+func (t *processTable) forget(closed []RunningProcess) {
+	gone := map[int32]bool{}
+	for _, p := range closed {
+		gone[p.PID] = true
+	}
+	kept := t.procs[:0]
+	for _, p := range t.procs {
+		if !gone[p.PID] {
+			kept = append(kept, p)
+		}
+	}
+	t.procs = kept
+}
+
+func processKey(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
+
+// listProcesses returns every running process. It is a variable so tests
+// can fake the process list.
+var listProcesses = func() ([]RunningProcess, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	var out []RunningProcess
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+		out = append(out, RunningProcess{Name: name, PID: p.Pid})
+	}
+	return out, nil
+}
+
+// closeProcesses asks processes to exit gracefully and waits up to timeout
+// for them to go. It is a variable so tests can fake closing.
+var closeProcesses = func(ctx context.Context, procs []RunningProcess, timeout time.Duration) error {
+	requestClose(procs)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		alive := 0
+		for _, p := range procs {
+			if ok, _ := process.PidExistsWithContext(ctx, p.PID); ok {
+				alive++
+			}
+		}
+		if alive == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d process(es) still running after %s", alive, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNames lists the distinct names of procs for messages.
+func processNames(procs []RunningProcess) string {
+	seen := map[string]bool{}
+	var names []string
+	for _, p := range procs {
+		if !seen[p.Name] {
+			seen[p.Name] = true
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// checkRunning applies the running-application policy to category c before
+// it is cleaned. It reports whether the category may run and the reason to
+// record on its status; the reason is empty when no owning process runs.
+// Dry runs never close anything.
+func checkRunning(c Category, opts CleanOptions) (bool, string) {
+	if len(c.Processes) == 0 || opts.running == nil {
+		return true, ""
+	}
+	t := opts.running
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	procs := t.find(c.Processes)
+	if len(procs) == 0 {
+		return true, ""
+	}
+	names := processNames(procs)
+
+	switch opts.RunningPolicyFor(c) {
+	case RunningWarn:
+		log.Printf("[SysCleaner] Warning: cleaning %s while %s is running", c.Name, names)
+		return true, fmt.Sprintf("cleaned while %s was running", names)
+	case RunningClose:
+		if opts.DryRun {
+			return true, fmt.Sprintf("%s is running and would be asked to close", names)
+		}
+		if opts.ConfirmClose == nil || !opts.ConfirmClose(c, procs) {
+			return false, fmt.Sprintf("skipped: %s is running and was not closed", names)
+		}
+		log.Printf("[SysCleaner] Closing %s before cleaning %s", names, c.Name)
+		ctx := opts.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if err := closeProcesses(ctx, procs, CloseTimeout); err != nil {
+			return false, fmt.Sprintf("skipped: %s did not close: %v", names, err)
+		}
+		t.forget(procs)
+		return true, fmt.Sprintf("closed %s before cleaning", names)
+	default:
+		log.Printf("[SysCleaner] Skipping %s: %s is running", c.Name, names)
+		return false, fmt.Sprintf("skipped: %s is running", names)
+	}
+}
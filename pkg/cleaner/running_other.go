@@ -0,0 +1,18 @@
+//go:build !windows
+
+package cleaner
+
+import (
+	"os"
+	"syscall"
+)
+
+// requestClose sends SIGTERM to procs, which desktop applications handle by
+// saving their state and exiting.
+func requestClose(procs []RunningProcess) {
+	for _, p := range procs {
+		if proc, err := os.FindProcess(int(p.PID)); err == nil {
+			proc.Signal(syscall.SIGTERM)
+		}
+	}
+}
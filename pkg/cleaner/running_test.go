@@ -0,0 +1,155 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProcesses replaces the process list and closing for one test. closeErr
+// is returned by every close request; closed records the closed processes.
+func fakeProcesses(t *testing.T, running []RunningProcess, closeErr error) *[]RunningProcess {
+	t.Helper()
+	var closed []RunningProcess
+	origList, origClose := listProcesses, closeProcesses
+	listProcesses = func() ([]RunningProcess, error) { return running, nil }
+	closeProcesses = func(ctx context.Context, procs []RunningProcess, timeout time.Duration) error {
+		closed = append(closed, procs...)
+		return closeErr
+	}
+	t.Cleanup(func() { listProcesses, closeProcesses = origList, origClose })
+	return &closed
+}
+
+// registerAppCategory registers a category cleaning dir that is owned by the
+// "testapp" process.
+func registerAppCategory(t *testing.T, id, dir string) {
+	t.Helper()
+	if err := Register(Category{
+		ID:        id,
+		Name:      id,
+		Group:     GroupApplications,
+		Processes: []string{"testapp"},
+		Paths:     []PathResolver{func() []string { return []string{dir} }},
+	}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+}
+
+// ---------- Running application tests ----------
+
+func TestRunningPolicy_SkipsCategoryOfRunningApp(t *testing.T) {
+	fakeProcesses(t, []RunningProcess{{Name: "TestApp.exe", PID: 42}, {Name: "other", PID: 7}}, nil)
+	dir := t.TempDir()
+	createTempFiles(t, dir, 2)
+	registerAppCategory(t, "test_running_skip", dir)
+
+	opts := CleanOptions{}
+	opts.Enable("test_running_skip")
+	result := PerformClean(opts)
+
+	if result.FilesDeleted != 0 {
+		t.Errorf("expected nothing removed while the app runs, got %d", result.FilesDeleted)
+	}
+	if len(result.Categories) != 1 || result.Categories[0].Status != StatusSkipped {
+		t.Fatalf("expected the category to be skipped, got %+v", result.Categories)
+	}
+	if reason := result.Categories[0].Reason; !strings.Contains(reason, "TestApp.exe is running") {
+		t.Errorf("expected the running process in the reason, got %q", reason)
+	}
+	if result.Outcome() != OutcomeOK {
+		t.Errorf("a skipped category is not a failure, got %s", result.Outcome())
+	}
+	if rep := NewReport(result, false); rep.Categories[0].Reason == "" {
+		t.Error("expected the reason in the report")
+	}
+}
+
+func TestRunningPolicy_Warn(t *testing.T) {
+	fakeProcesses(t, []RunningProcess{{Name: "testapp", PID: 42}}, nil)
+	dir := t.TempDir()
+	createTempFiles(t, dir, 2)
+	registerAppCategory(t, "test_running_warn", dir)
+
+	opts := CleanOptions{RunningPolicies: map[string]RunningPolicy{"test_running_warn": RunningWarn}}
+	opts.Enable("test_running_warn")
+	result := PerformClean(opts)
+
+	if result.FilesDeleted != 2 {
+		t.Errorf("expected the category to be cleaned anyway, got %d files", result.FilesDeleted)
+	}
+	st := result.Categories[0]
+	if st.Status != StatusCompleted || !strings.Contains(st.Reason, "while testapp was running") {
+		t.Errorf("expected a completed category with a warning, got %+v", st)
+	}
+}
+
+func TestRunningPolicy_Close(t *testing.T) {
+	dir := t.TempDir()
+	registerAppCategory(t, "test_running_close", dir)
+
+	tests := []struct {
+		name     string
+		confirm  ConfirmCloseFunc
+		closeErr error
+		dryRun   bool
+		want     RunStatus
+		closed   int
+	}{
+		{"confirmed", func(Category, []RunningProcess) bool { return true }, nil, false, StatusCompleted, 1},
+		{"declined", func(Category, []RunningProcess) bool { return false }, nil, false, StatusSkipped, 0},
+		{"nobody to ask", nil, nil, false, StatusSkipped, 0},
+		{"did not exit", func(Category, []RunningProcess) bool { return true }, errors.New("still running"), false, StatusSkipped, 1},
+		{"dry run", func(Category, []RunningProcess) bool { t.Error("a dry run must not ask"); return true }, nil, true, StatusCompleted, 0},
+	}
+	for _, tc := range tests {
+		closed := fakeProcesses(t, []RunningProcess{{Name: "testapp", PID: 42}}, tc.closeErr)
+		createTempFiles(t, dir, 1)
+		opts := CleanOptions{RunningPolicy: RunningClose, ConfirmClose: tc.confirm, DryRun: tc.dryRun}
+		opts.Enable("test_running_close")
+		result := PerformClean(opts)
+
+		if st := result.Categories[0]; st.Status != tc.want || st.Reason == "" {
+			t.Errorf("%s: expected %s with a reason, got %+v", tc.name, tc.want, st)
+		}
+		if len(*closed) != tc.closed {
+			t.Errorf("%s: expected %d processes closed, got %v", tc.name, tc.closed, *closed)
+		}
+	}
+}
+
+func TestRunningPolicy_AsksOncePerApplication(t *testing.T) {
+	closed := fakeProcesses(t, []RunningProcess{{Name: "testapp", PID: 42}}, nil)
+	registerAppCategory(t, "test_running_once_a", t.TempDir())
+	registerAppCategory(t, "test_running_once_b", t.TempDir())
+
+	asked := 0
+	opts := CleanOptions{RunningPolicy: RunningClose, ConfirmClose: func(Category, []RunningProcess) bool {
+		asked++
+		return true
+	}}
+	opts.Enable("test_running_once_a", "test_running_once_b")
+	result := PerformClean(opts)
+
+	if asked != 1 || len(*closed) != 1 {
+		t.Errorf("expected one question and one close for two categories, got %d and %v", asked, *closed)
+	}
+	for _, st := range result.Categories {
+		if st.Status != StatusCompleted {
+			t.Errorf("expected both categories cleaned, got %+v", st)
+		}
+	}
+}
+
+func TestParseRunningPolicy(t *testing.T) {
+	for in, want := range map[string]RunningPolicy{"": RunningSkip, "Warn": RunningWarn, " close ": RunningClose} {
+		if got, err := ParseRunningPolicy(in); err != nil || got != want {
+			t.Errorf("ParseRunningPolicy(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseRunningPolicy("kill"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
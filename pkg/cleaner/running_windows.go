@@ -0,0 +1,34 @@
+//go:build windows
+
+package cleaner
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32           = windows.NewLazySystemDLL("user32.dll")
+	procPostMessageW = user32.NewProc("PostMessageW")
+)
+
+const wmClose = 0x0010
+
+// requestClose posts WM_CLOSE to every top-level window of procs, which is
+// what clicking the window's close button does: the application saves its
+// state and exits on its own.
+func requestClose(procs []RunningProcess) {
+	pids := map[uint32]bool{}
+	for _, p := range procs {
+		pids[uint32(p.PID)] = true
+	}
+	cb := windows.NewCallback(func(hwnd windows.HWND, _ uintptr) uintptr {
+		var pid uint32
+		if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err == nil && pids[pid] && windows.IsWindowVisible(hwnd) {
+			procPostMessageW.Call(uintptr(hwnd), wmClose, 0, 0)
+		}
+		return 1 // Continue enumeration
+	})
+	windows.EnumWindows(cb, unsafe.Pointer(nil))
+}
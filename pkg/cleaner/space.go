@@ -0,0 +1,136 @@
+package cleaner
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileKey identifies a file on disk independently of its path.
+type fileKey struct {
+	dev, ino uint64
+}
+
+// fileAlloc is the on-disk footprint of a file: the bytes allocated to it
+// (which differs from its size for sparse, compressed and small files) and
+// how many hardlinks share that allocation.
+type fileAlloc struct {
+	key       fileKey
+	links     uint64
+	allocated int64
+	known     bool // false when the platform could not tell; allocated is the apparent size
+}
+
+// spaceTracker accounts for the space a run actually reclaims. A file with
+// several hardlinks only frees its blocks once every link has been removed,
+// so the remaining link count of each such inode is tracked across all
+// categories of the run. It also records the free space of every volume
+// before the first file on it is removed, for comparison afterwards.
+type spaceTracker struct {
+	mu      sync.Mutex
+	links   map[fileKey]uint64    // Links not yet removed, for multiply-linked files
+	volumes map[uint64]volumeFree // By device
+}
+
+type volumeFree struct {
+	path   string
+	before uint64
+}
+
+func newSpaceTracker() *spaceTracker {
+	return &spaceTracker{links: map[fileKey]uint64{}, volumes: map[uint64]volumeFree{}}
+}
+
+// prepare returns the footprint of a file about to be removed. When measure
+// is set and the file is the first on its volume, the volume's free space is
+// recorded first.
+func (t *spaceTracker) prepare(path string, info os.FileInfo, measure bool) fileAlloc {
+	a, ok := fileAllocation(path, info)
+	if !ok {
+		return fileAlloc{allocated: info.Size()}
+	}
+	a.known = true
+	if t == nil || !measure {
+		return a
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, seen := t.volumes[a.key.dev]; !seen {
+		dir := filepath.Dir(path)
+		if free, err := diskFree(dir); err == nil {
+			t.volumes[a.key.dev] = volumeFree{path: dir, before: free}
+		}
+	}
+	return a
+}
+
+// reclaim returns the allocated bytes released by removing a file prepared
+// with prepare: all of them for a file with one link, and for a hardlinked
+// file all of them once its last link has been removed, otherwise 0.
+func (t *spaceTracker) reclaim(a fileAlloc) int64 {
+	if !a.known || a.links <= 1 {
+		return a.allocated
+	}
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	left, seen := t.links[a.key]
+	if !seen {
+		left = a.links
+	}
+	left--
+	if left > 0 {
+		t.links[a.key] = left
+		return 0
+	}
+	delete(t.links, a.key)
+	return a.allocated
+}
+
+// diskFreeDelta measures the free space of every recorded volume again and
+// returns how much it grew in total.
+func (t *spaceTracker) diskFreeDelta() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var delta int64
+	for _, v := range t.volumes {
+		after, err := diskFree(v.path)
+		if err != nil {
+			log.Printf("[SysCleaner] Could not measure free space on %s: %v", v.path, err)
+			continue
+		}
+		delta += int64(after) - int64(v.before)
+	}
+	return delta
+}
+
+// treeAllocs prepares every file below path, which is about to be removed as
+// a whole.
+func (t *spaceTracker) treeAllocs(path string, measure bool) []fileAlloc {
+	var allocs []fileAlloc
+	filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			allocs = append(allocs, t.prepare(p, info, measure))
+		}
+		return nil
+	})
+	return allocs
+}
+
+// reclaimTree returns what removing the tree at path would reclaim, for dry
+// runs.
+func (s sweeper) reclaimTree(path string) int64 {
+	var total int64
+	for _, a := range s.space.treeAllocs(path, false) {
+		total += s.space.reclaim(a)
+	}
+	return total
+}
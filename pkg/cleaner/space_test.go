@@ -0,0 +1,82 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// helper: allocation returns the allocated size of a file.
+func allocation(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := fileAllocation(path, info)
+	if !ok {
+		t.Skip("allocation information not available")
+	}
+	return a.allocated
+}
+
+// ---------- space accounting tests ----------
+
+func TestSweeper_HardlinksReclaimOnce(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a.bin")
+	writeFile(t, a, string(make([]byte, 10000)))
+	if err := os.Link(a, filepath.Join(root, "b.bin")); err != nil {
+		t.Skipf("cannot create hardlinks: %v", err)
+	}
+	want := allocation(t, a)
+
+	for _, dryRun := range []bool{true, false} {
+		sw := sweeper{dryRun: dryRun, guard: newPathGuard(nil, nil), space: newSpaceTracker()}
+		result := cleanDirectoryFiltered(root, fileFilter{}, sw)
+		if result.SpaceFreed != 20000 {
+			t.Errorf("dryRun=%v: expected apparent size 20000, got %d", dryRun, result.SpaceFreed)
+		}
+		if result.SpaceReclaimed != want {
+			t.Errorf("dryRun=%v: expected the shared blocks (%d) to be counted once, got %d", dryRun, want, result.SpaceReclaimed)
+		}
+	}
+}
+
+func TestSweeper_LinkOutsideRootReclaimsNothing(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a.bin")
+	writeFile(t, a, string(make([]byte, 10000)))
+	if err := os.Link(a, filepath.Join(t.TempDir(), "kept.bin")); err != nil {
+		t.Skipf("cannot create hardlinks: %v", err)
+	}
+
+	result := cleanDirectoryFiltered(root, fileFilter{}, sweeper{guard: newPathGuard(nil, nil), space: newSpaceTracker()})
+	if result.FilesDeleted != 1 || result.SpaceReclaimed != 0 {
+		t.Errorf("expected 1 file deleted and nothing reclaimed, got %d / %d", result.FilesDeleted, result.SpaceReclaimed)
+	}
+}
+
+func TestSweeper_SparseFileReclaimsAllocatedBlocks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("files are not sparse by default on Windows")
+	}
+	root := t.TempDir()
+	path := filepath.Join(root, "sparse.img")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Truncate(64 << 20)
+	f.Close()
+	want := allocation(t, path)
+
+	result := cleanDirectoryFiltered(root, fileFilter{}, sweeper{guard: newPathGuard(nil, nil), space: newSpaceTracker()})
+	if result.SpaceFreed != 64<<20 {
+		t.Errorf("expected apparent size %d, got %d", 64<<20, result.SpaceFreed)
+	}
+	if result.SpaceReclaimed != want || want >= 64<<20 {
+		t.Errorf("expected only the allocated %d bytes to be reclaimed, got %d", want, result.SpaceReclaimed)
+	}
+}
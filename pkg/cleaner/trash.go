@@ -0,0 +1,466 @@
+package cleaner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freedesktop.org Trash specification support.
+//
+// A trash directory contains files/ (the trashed items), info/ (one
+// NAME.trashinfo per item recording the original path and deletion date) and
+// an optional directorysizes cache. The home trash lives in
+// $XDG_DATA_HOME/Trash; every other mounted volume may have
+// $topdir/.Trash/$uid (shared, sticky) or $topdir/.Trash-$uid.
+
+const trashInfoExt = ".trashinfo"
+
+// trashDateLayout is the DeletionDate format. Dates are in local time.
+const trashDateLayout = "2006-01-02T15:04:05"
+
+// trashItem is a single entry in a trash directory.
+type trashItem struct {
+	Name      string    // Name under files/
+	Original  string    // Original path from the info file ("" if unknown)
+	DeletedAt time.Time // DeletionDate, or the file's mtime when info is missing
+	HasFile   bool      // files/Name exists
+	HasInfo   bool      // info/Name.trashinfo exists
+}
+
+// trashDirs resolves to the home trash and every per-volume trash directory
+// of the current user.
+func trashDirs() []string {
+	dirs := xdgData("Trash")()
+	uid := os.Getuid()
+	if uid < 0 {
+		return dirs
+	}
+	for _, top := range mountPoints() {
+		if dir := volumeTrash(top, uid); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dedup(dirs)
+}
+
+// volumeTrash returns the trash directory for uid on the volume mounted at
+// top, or "" if there is none. $topdir/.Trash is only trusted when it is a
+// real directory with the sticky bit set, as the spec requires.
+func volumeTrash(top string, uid int) string {
+	shared := filepath.Join(top, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		dir := filepath.Join(shared, strconv.Itoa(uid))
+		if info, err := os.Lstat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	dir := filepath.Join(top, ".Trash-"+strconv.Itoa(uid))
+	if info, err := os.Lstat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return ""
+}
+
+// virtualFilesystems never hold a trash directory and are skipped when
+// looking for per-volume trash.
+var virtualFilesystems = map[string]bool{
+	"proc": true, "sysfs": true, "devtmpfs": true, "devpts": true, "cgroup": true,
+	"cgroup2": true, "securityfs": true, "debugfs": true, "tracefs": true,
+	"pstore": true, "bpf": true, "mqueue": true, "hugetlbfs": true, "configfs": true,
+	"fusectl": true, "autofs": true, "binfmt_misc": true, "efivarfs": true,
+	"nsfs": true, "squashfs": true,
+}
+
+// mountPoints returns the mount points listed in /proc/self/mounts. It
+// returns nothing on systems without procfs.
+func mountPoints() []string {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var points []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || virtualFilesystems[fields[2]] {
+			continue
+		}
+		points = append(points, unescapeMountField(fields[1]))
+	}
+	return points
+}
+
+// unescapeMountField decodes the octal escapes (\040 for space, ...) used in
+// /proc/self/mounts.
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseTrashInfo reads a .trashinfo file and returns the original path and
+// deletion date.
+func parseTrashInfo(r io.Reader) (string, time.Time, error) {
+	var (
+		original string
+		deleted  time.Time
+		inGroup  bool
+		seenDate bool
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inGroup = line == "[Trash Info]"
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Path":
+			p, err := url.PathUnescape(strings.TrimSpace(value))
+			if err != nil {
+				return "", time.Time{}, fmt.Errorf("invalid Path %q: %w", value, err)
+			}
+			original = p
+		case "DeletionDate":
+			t, err := time.ParseInLocation(trashDateLayout, strings.TrimSpace(value), time.Local)
+			if err != nil {
+				return "", time.Time{}, fmt.Errorf("invalid DeletionDate %q: %w", value, err)
+			}
+			deleted, seenDate = t, true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	if !seenDate {
+		return "", time.Time{}, fmt.Errorf("missing DeletionDate")
+	}
+	return original, deleted, nil
+}
+
+// listTrash returns every item in a trash directory. Items whose info file is
+// missing or unreadable fall back to the mtime of the trashed file.
+func listTrash(dir string) ([]trashItem, []error) {
+	filesDir := filepath.Join(dir, "files")
+	infoDir := filepath.Join(dir, "info")
+	items := map[string]*trashItem{}
+	var errs []error
+
+	infos, err := os.ReadDir(infoDir)
+	if err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	for _, entry := range infos {
+		name, ok := strings.CutSuffix(entry.Name(), trashInfoExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		item := &trashItem{Name: name, HasInfo: true}
+		items[name] = item
+
+		f, err := os.Open(filepath.Join(infoDir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		item.Original, item.DeletedAt, err = parseTrashInfo(f)
+		f.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Join(infoDir, entry.Name()), err))
+		}
+	}
+
+	files, err := os.ReadDir(filesDir)
+	if err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	for _, entry := range files {
+		item, ok := items[entry.Name()]
+		if !ok {
+			item = &trashItem{Name: entry.Name()}
+			items[entry.Name()] = item
+		}
+		item.HasFile = true
+		if item.DeletedAt.IsZero() {
+			if info, err := entry.Info(); err == nil {
+				item.DeletedAt = info.ModTime()
+			}
+		}
+	}
+
+	out := make([]trashItem, 0, len(items))
+	for _, item := range items {
+		out = append(out, *item)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, errs
+}
+
+// orphanInfoAge is how old an info file without a trashed file must be
+// before it is removed.
+const orphanInfoAge = time.Hour
+
+// purgeTrash removes every item of a trash directory deleted at least minAge
+// ago, along with stale info files that have no trashed file. In dry-run mode
+// the items are only counted and listed in DryRunItems.
+func purgeTrash(dir string, minAge time.Duration, sw sweeper) CleanResult {
+	result := CleanResult{}
+	now := time.Now()
+	if !sw.allowed(dir, &result) {
+		return result
+	}
+
+	items, errs := listTrash(dir)
+	result.Errors = append(result.Errors, errs...)
+
+	purged := 0
+	for _, item := range items {
+		if sw.stopped(&result) {
+			break
+		}
+		filePath := filepath.Join(dir, "files", item.Name)
+		infoPath := filepath.Join(dir, "info", item.Name+trashInfoExt)
+
+		if minAge > 0 && now.Sub(item.DeletedAt) < minAge {
+			continue
+		}
+		if !item.HasFile {
+			// Trashing writes the info file before moving the file, so only
+			// drop orphaned info files that are clearly stale.
+			if !sw.dryRun && now.Sub(item.DeletedAt) > orphanInfoAge {
+				os.Remove(infoPath)
+			}
+			continue
+		}
+
+		if !sw.allowed(filePath, &result) {
+			continue
+		}
+		if sw.dryRun {
+			files, size := treeSize(filePath)
+			label := item.Original
+			if label == "" {
+				label = filePath
+			}
+			result.DryRunItems = append(result.DryRunItems,
+				fmt.Sprintf("%s (trashed %s, %s)", label, item.DeletedAt.Format("2006-01-02"), FormatBytes(size)))
+			result.FilesDeleted += files
+			result.SpaceFreed += size
+			result.SpaceReclaimed += sw.reclaimTree(filePath)
+			sw.removed(filePath, size)
+			if info, err := os.Lstat(filePath); err == nil && sw.collect != nil {
+				sw.collect.add(sw.category, PlanFile{
+					Path: filePath, Size: size, ModTime: info.ModTime(), Dir: info.IsDir(),
+					Reason: fmt.Sprintf("%s, trashed %s", label, item.DeletedAt.Format("2006-01-02")),
+				})
+			}
+			continue
+		}
+
+		if removeTrashEntry(dir, item.Name, sw, &result) {
+			purged++
+		}
+	}
+
+	if !sw.dryRun && purged > 0 {
+		if err := updateDirectorySizes(dir); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+		log.Printf("[SysCleaner] Purged %d item(s) from %s", purged, dir)
+	}
+	return result
+}
+
+// removeTrashEntry removes files/name and then its info file from a trash
+// directory, as the spec requires. A trashed directory is emptied file by
+// file through the sweeper like any other category root, so cancellation,
+// the directory timeout, exclusions, quarantine and space accounting apply
+// to it. The info file is kept while anything of the entry is left, and it
+// reports whether the entry is gone.
+func removeTrashEntry(dir, name string, sw sweeper, result *CleanResult) bool {
+	filePath := filepath.Join(dir, "files", name)
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		result.Errors = append(result.Errors, ClassifyError(filePath, err))
+		return false
+	}
+	if info.IsDir() {
+		// Trash items are selected by their deletion date, not by the age
+		// of what they hold.
+		sw.retention = RetentionPolicy{}
+		entry := cleanDirectoryFiltered(filePath, fileFilter{}, sw)
+		result.merge(entry)
+		if !entry.incomplete && sw.guard.check(filePath) == nil {
+			result.DirsRemoved += int64(removeEmptyDirs(filePath, true))
+		}
+	} else {
+		sw.remove(filePath, info, "trashed item", result)
+	}
+	if _, err := os.Lstat(filePath); err == nil {
+		return false
+	}
+
+	infoPath := filepath.Join(dir, "info", name+trashInfoExt)
+	if err := os.Remove(infoPath); err != nil && !os.IsNotExist(err) {
+		result.Errors = append(result.Errors, err)
+	}
+	return true
+}
+
+// executeTrashPlan applies the Trash part of a plan: planned items that are
+// unchanged are purged, then every touched directorysizes cache is updated.
+func executeTrashPlan(c Category, files []PlanFile, opts CleanOptions) CleanResult {
+	result := CleanResult{}
+	sw := newSweeper(c, opts)
+	touched := map[string]bool{}
+	for _, f := range files {
+		if sw.stopped(&result) {
+			break
+		}
+		// Planned paths are <trash>/files/<name>.
+		dir := filepath.Dir(filepath.Dir(f.Path))
+		if filepath.Base(filepath.Dir(f.Path)) != "files" {
+			result.Errors = append(result.Errors, fmt.Errorf("%s is not a trash item", f.Path))
+			continue
+		}
+		if _, ok := sw.checkPlanFile(f, &result); !ok || !sw.allowed(f.Path, &result) {
+			continue
+		}
+		if opts.DryRun {
+			count, size := treeSize(f.Path)
+			result.FilesDeleted += count
+			result.SpaceFreed += size
+			result.SpaceReclaimed += sw.reclaimTree(f.Path)
+			sw.removed(f.Path, size)
+			continue
+		}
+		if removeTrashEntry(dir, filepath.Base(f.Path), sw, &result) {
+			touched[dir] = true
+		}
+	}
+	for dir := range touched {
+		if err := updateDirectorySizes(dir); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+	}
+	return result
+}
+
+// treeSize returns the number of files and total size of a file or
+// directory tree. Symlinks are counted as files and not followed.
+func treeSize(path string) (int64, int64) {
+	var files, size int64
+	filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files++
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size
+}
+
+// updateDirectorySizes drops directorysizes entries for directories that are
+// no longer in the trash. Each line is "size mtime percent-encoded-name".
+func updateDirectorySizes(dir string) error {
+	path := filepath.Join(dir, "directorysizes")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var kept []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		name, err := url.PathUnescape(fields[2])
+		if err != nil {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(dir, "files", name)); err == nil {
+			kept = append(kept, line)
+		}
+	}
+
+	out := strings.Join(kept, "\n")
+	if len(kept) > 0 {
+		out += "\n"
+	}
+	if out == string(data) {
+		return nil
+	}
+
+	// Write to a temporary file and rename it so readers never see a
+	// half-written cache, as the spec recommends.
+	tmp, err := os.CreateTemp(dir, "directorysizes.")
+	if err != nil {
+		return fmt.Errorf("updating %s: %w", path, err)
+	}
+	if _, err := tmp.WriteString(out); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("updating %s: %w", path, err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("updating %s: %w", path, err)
+	}
+	return nil
+}
+
+// cleanTrash purges every trash directory of the current user. Items are
+// only removed once their DeletionDate is older than opts.TrashMinAge, or the
+// category's MaxAge when that is not set.
+func cleanTrash(c Category, opts CleanOptions) CleanResult {
+	minAge := c.MaxAge
+	if opts.TrashMinAge > 0 {
+		minAge = opts.TrashMinAge
+	}
+	result := CleanResult{}
+	for _, dir := range c.ResolvePaths() {
+		result.merge(purgeTrash(dir, minAge, newSweeper(c, opts)))
+	}
+	return result
+}
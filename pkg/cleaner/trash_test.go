@@ -0,0 +1,188 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helper: trashFile puts a file (or a directory when dir is set) in the trash
+// at root with an info file dated deleted.
+func trashFile(t *testing.T, root, name string, dir bool, deleted time.Time) {
+	t.Helper()
+	path := filepath.Join(root, "files", name)
+	if dir {
+		writeFile(t, filepath.Join(path, "a.txt"), "aaaa")
+		writeFile(t, filepath.Join(path, "sub", "b.txt"), "bb")
+	} else {
+		writeFile(t, path, "content")
+	}
+	info := "[Trash Info]\nPath=/home/user/" + strings.ReplaceAll(name, " ", "%20") +
+		"\nDeletionDate=" + deleted.Format(trashDateLayout) + "\n"
+	writeFile(t, filepath.Join(root, "info", name+trashInfoExt), info)
+}
+
+// ---------- parseTrashInfo tests ----------
+
+func TestParseTrashInfo(t *testing.T) {
+	orig, deleted, err := parseTrashInfo(strings.NewReader(
+		"[Trash Info]\nPath=/home/user/My%20File.txt\nDeletionDate=2024-03-01T10:20:30\n"))
+	if err != nil {
+		t.Fatalf("parseTrashInfo failed: %v", err)
+	}
+	if orig != "/home/user/My File.txt" {
+		t.Errorf("unexpected path %q", orig)
+	}
+	want := time.Date(2024, 3, 1, 10, 20, 30, 0, time.Local)
+	if !deleted.Equal(want) {
+		t.Errorf("expected %v, got %v", want, deleted)
+	}
+
+	for _, bad := range []string{
+		"[Trash Info]\nPath=/x\n",
+		"[Trash Info]\nDeletionDate=yesterday\n",
+		"[Other]\nDeletionDate=2024-03-01T10:20:30\n",
+	} {
+		if _, _, err := parseTrashInfo(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+// ---------- purgeTrash tests ----------
+
+func TestPurgeTrash_AgeFilterAndDirectorySizes(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	trashFile(t, root, "old.txt", false, now.Add(-40*24*time.Hour))
+	trashFile(t, root, "old dir", true, now.Add(-40*24*time.Hour))
+	trashFile(t, root, "new.txt", false, now.Add(-24*time.Hour))
+	trashFile(t, root, "new dir", true, now.Add(-24*time.Hour))
+	writeFile(t, filepath.Join(root, "info", "gone.txt"+trashInfoExt),
+		"[Trash Info]\nPath=/x\nDeletionDate="+now.Add(-40*24*time.Hour).Format(trashDateLayout)+"\n")
+	writeFile(t, filepath.Join(root, "directorysizes"), "6 1700000000 old%20dir\n6 1700000000 new%20dir\n")
+
+	result := purgeTrash(root, 30*24*time.Hour, sweeper{})
+
+	if result.FilesDeleted != 3 {
+		t.Errorf("expected 3 files purged (1 file + 2 in dir), got %d", result.FilesDeleted)
+	}
+	if result.SpaceFreed != int64(len("content")+6) {
+		t.Errorf("unexpected space freed %d", result.SpaceFreed)
+	}
+	for _, gone := range []string{"files/old.txt", "info/old.txt.trashinfo", "files/old dir", "info/old dir.trashinfo", "info/gone.txt.trashinfo"} {
+		if exists(filepath.Join(root, gone)) {
+			t.Errorf("%s should have been purged", gone)
+		}
+	}
+	for _, kept := range []string{"files/new.txt", "info/new.txt.trashinfo", "files/new dir"} {
+		if !exists(filepath.Join(root, kept)) {
+			t.Errorf("%s should have been kept", kept)
+		}
+	}
+
+	sizes, err := os.ReadFile(filepath.Join(root, "directorysizes"))
+	if err != nil {
+		t.Fatalf("failed to read directorysizes: %v", err)
+	}
+	if string(sizes) != "6 1700000000 new%20dir\n" {
+		t.Errorf("unexpected directorysizes content %q", sizes)
+	}
+}
+
+func TestPurgeTrash_DryRunListsItems(t *testing.T) {
+	root := t.TempDir()
+	trashFile(t, root, "old.txt", false, time.Now().Add(-40*24*time.Hour))
+	trashFile(t, root, "new.txt", false, time.Now())
+
+	result := purgeTrash(root, 7*24*time.Hour, sweeper{dryRun: true})
+
+	if len(result.DryRunItems) != 1 || !strings.HasPrefix(result.DryRunItems[0], "/home/user/old.txt") {
+		t.Errorf("expected only old.txt to be listed, got %v", result.DryRunItems)
+	}
+	if !exists(filepath.Join(root, "files", "old.txt")) {
+		t.Error("dry run should not remove anything")
+	}
+}
+
+func TestPurgeTrash_DirectoriesGoThroughTheSweeper(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	trashFile(t, root, "partly excluded", true, old)
+
+	// An excluded file inside a trashed directory is kept, and with it the
+	// entry and its info file.
+	result := purgeTrash(root, 0, sweeper{guard: newPathGuard(nil, []string{"b.txt"})})
+	if result.FilesDeleted != 1 || len(result.ErrorsOfType(ErrorExcluded)) != 1 {
+		t.Errorf("expected 1 file removed and 1 excluded, got %d removed, errors %v", result.FilesDeleted, result.Errors)
+	}
+	for _, kept := range []string{"files/partly excluded/sub/b.txt", "info/partly excluded.trashinfo"} {
+		if !exists(filepath.Join(root, kept)) {
+			t.Errorf("%s should have been kept", kept)
+		}
+	}
+
+	// Quarantine applies to trashed directories too.
+	q, err := OpenQuarantine(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = purgeTrash(root, 0, sweeper{quarantine: q, category: "trash"})
+	if result.FilesQuarantined != 1 || result.FilesDeleted != 0 {
+		t.Errorf("expected the remaining file quarantined, got %d quarantined, %d deleted", result.FilesQuarantined, result.FilesDeleted)
+	}
+	for _, gone := range []string{"files/partly excluded", "info/partly excluded.trashinfo"} {
+		if exists(filepath.Join(root, gone)) {
+			t.Errorf("%s should have been purged", gone)
+		}
+	}
+}
+
+func TestPurgeTrash_OrphanFileUsesMtime(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "files", "orphan.txt")
+	writeFile(t, path, "x")
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	os.Chtimes(path, old, old)
+
+	if result := purgeTrash(root, 30*24*time.Hour, sweeper{}); result.FilesDeleted != 1 {
+		t.Errorf("expected orphaned file to be purged, got %d", result.FilesDeleted)
+	}
+}
+
+// ---------- volumeTrash tests ----------
+
+func TestVolumeTrash(t *testing.T) {
+	top := t.TempDir()
+	if dir := volumeTrash(top, 1000); dir != "" {
+		t.Errorf("expected no trash, got %q", dir)
+	}
+
+	private := filepath.Join(top, ".Trash-1000")
+	os.Mkdir(private, 0700)
+	if dir := volumeTrash(top, 1000); dir != private {
+		t.Errorf("expected %q, got %q", private, dir)
+	}
+
+	// A shared .Trash without the sticky bit must be ignored.
+	shared := filepath.Join(top, ".Trash")
+	os.MkdirAll(filepath.Join(shared, "1000"), 0755)
+	if dir := volumeTrash(top, 1000); dir != private {
+		t.Errorf("expected non-sticky .Trash to be ignored, got %q", dir)
+	}
+
+	if err := os.Chmod(shared, 0777|os.ModeSticky); err != nil {
+		t.Skipf("cannot set sticky bit: %v", err)
+	}
+	if dir := volumeTrash(top, 1000); dir != filepath.Join(shared, "1000") {
+		t.Errorf("expected shared trash, got %q", dir)
+	}
+}
+
+func TestUnescapeMountField(t *testing.T) {
+	if got := unescapeMountField(`/media/My\040Disk`); got != "/media/My Disk" {
+		t.Errorf("unexpected %q", got)
+	}
+}
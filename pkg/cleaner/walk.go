@@ -0,0 +1,111 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// walker walks the tree below a category root without ever leaving it:
+//
+//   - symlinks are never followed. A link to a file (or a dangling link) is
+//     visited as the link itself, so removing it removes the link and not
+//     its target.
+//   - directory symlinks, junctions and other reparse points are not entered
+//     and are recorded in CleanResult.SkippedLinks.
+//   - directories on another filesystem than the root (mount points) are not
+//     entered unless crossFS is set, and are recorded in SkippedMounts.
+//     Device IDs are not available on Windows, where mount points are
+//     reparse points and are skipped as links instead.
+//
+// The walk stops as soon as ctx is done and marks the result incomplete.
+type walker struct {
+	ctx     context.Context
+	crossFS bool
+	result  *CleanResult
+}
+
+// walk calls visit for every file and file link below root with its Lstat
+// information. skipDir is consulted for every directory below root;
+// returning true skips the directory and everything in it.
+func (w walker) walk(root string, skipDir func(path string) bool, visit func(path string, info os.FileInfo)) {
+	rootInfo, err := os.Stat(root)
+	if err != nil {
+		w.result.Errors = append(w.result.Errors, ClassifyError(root, err))
+		return
+	}
+	rootDev, haveDev := deviceID(rootInfo)
+
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if w.ctx.Err() != nil {
+			w.result.incomplete = true
+			return filepath.SkipAll
+		}
+		if err != nil {
+			// Skip inaccessible directories gracefully
+			if d != nil && d.IsDir() {
+				log.Printf("[SysCleaner] Skipping inaccessible directory: %s", path)
+				return filepath.SkipDir
+			}
+			w.result.Errors = append(w.result.Errors, fmt.Errorf("walk error %s: %w", path, err))
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+
+		if d.IsDir() {
+			if skipDir(path) {
+				return filepath.SkipDir
+			}
+			if haveDev && !w.crossFS {
+				if info, err := d.Info(); err == nil {
+					if dev, ok := deviceID(info); ok && dev != rootDev {
+						log.Printf("[SysCleaner] Not crossing into mounted filesystem: %s", path)
+						w.result.SkippedMounts = append(w.result.SkippedMounts, path)
+						return filepath.SkipDir
+					}
+				}
+			}
+			return nil
+		}
+
+		switch {
+		case d.Type()&os.ModeSymlink != 0:
+			// Directory links (and, before Go 1.23, junctions) are left alone;
+			// links to files and dangling links are removed as links.
+			if target, err := os.Stat(path); err == nil && target.IsDir() {
+				w.result.SkippedLinks = append(w.result.SkippedLinks, path)
+				return nil
+			}
+		case d.Type()&os.ModeIrregular != 0:
+			// Junctions, mount points and other reparse points on Windows.
+			w.result.SkippedLinks = append(w.result.SkippedLinks, path)
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			w.result.Errors = append(w.result.Errors, err)
+			return nil
+		}
+		visit(path, info)
+		return nil
+	})
+
+	if err != nil {
+		w.result.Errors = append(w.result.Errors, err)
+	}
+}
+
+// WalkFiles walks the files below root with the cleaner's walker, for scans
+// that live outside this package. The returned result holds the walk errors
+// and the links and mounts that were not entered; the walk stops when ctx is
+// done.
+func WalkFiles(ctx context.Context, root string, crossFS bool, visit func(path string, info os.FileInfo)) CleanResult {
+	var result CleanResult
+	walker{ctx: ctx, crossFS: crossFS, result: &result}.walk(root, func(string) bool { return false }, visit)
+	return result
+}
@@ -0,0 +1,90 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// helper: symlink creates a symlink or skips the test where that needs
+// privileges (Windows without developer mode).
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+}
+
+// ---------- walker tests ----------
+
+func TestCleanDirectory_RemovesFileLinksAsLinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	target := filepath.Join(outside, "precious.txt")
+	writeFile(t, target, "keep me")
+	link := filepath.Join(root, "link.txt")
+	symlink(t, target, link)
+	symlink(t, filepath.Join(outside, "missing"), filepath.Join(root, "dangling"))
+
+	result := cleanDirectory(root, 0, false)
+
+	if result.FilesDeleted != 2 {
+		t.Errorf("expected both links to be removed, got %d", result.FilesDeleted)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Error("the link should have been removed")
+	}
+	if !exists(target) {
+		t.Error("the link target must not be touched")
+	}
+}
+
+func TestCleanDirectory_DoesNotFollowDirectoryLinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "a.txt"), "a")
+	link := filepath.Join(root, "elsewhere")
+	symlink(t, outside, link)
+	writeFile(t, filepath.Join(root, "b.tmp"), "b")
+
+	result := cleanDirectory(root, 0, false)
+
+	if result.FilesDeleted != 1 {
+		t.Errorf("expected only b.tmp to be removed, got %d", result.FilesDeleted)
+	}
+	if !exists(filepath.Join(outside, "a.txt")) {
+		t.Error("files behind a directory link must not be touched")
+	}
+	if len(result.SkippedLinks) != 1 || result.SkippedLinks[0] != link {
+		t.Errorf("expected the directory link to be reported, got %v", result.SkippedLinks)
+	}
+	if _, err := os.Lstat(link); err != nil {
+		t.Error("a skipped directory link must be left in place")
+	}
+}
+
+func TestCleanDirectory_FollowsLinkedRoot(t *testing.T) {
+	target := t.TempDir()
+	writeFile(t, filepath.Join(target, "a.tmp"), "a")
+	root := filepath.Join(t.TempDir(), "cache")
+	symlink(t, target, root)
+
+	if result := cleanDirectory(root, 0, false); result.FilesDeleted != 1 {
+		t.Errorf("expected the link target to be cleaned, got %d (%v)", result.FilesDeleted, result.Errors)
+	}
+	if _, err := os.Lstat(root); err != nil {
+		t.Error("the root link itself must be kept")
+	}
+}
+
+func TestDeviceID(t *testing.T) {
+	info, err := os.Stat(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := deviceID(info)
+	if ok != (runtime.GOOS != "windows") {
+		t.Errorf("unexpected deviceID availability %v on %s", ok, runtime.GOOS)
+	}
+}
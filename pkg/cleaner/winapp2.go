@@ -0,0 +1,503 @@
+package cleaner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// winapp2.ini is the community-maintained cleaning definition file used by
+// CCleaner and BleachBit. Each [Section] describes one application:
+//
+//	[Example App *]
+//	DetectFile=%LocalAppData%\Example
+//	FileKey1=%LocalAppData%\Example\Cache|*.*|RECURSE
+//	FileKey2=%LocalAppData%\Example\Logs|*.log;*.tmp
+//	ExcludeKey1=FILE|%LocalAppData%\Example\Cache\|index.dat
+//
+// Only file cleaning is supported; registry keys (RegKey) are ignored.
+// Imported entries are registered as Windows-only categories in GroupWinapp2.
+
+// Winapp2Entry is a section of a winapp2.ini file that SysCleaner can run.
+type Winapp2Entry struct {
+	Section     string
+	detect      []string // Registry keys, any of which marks the app as installed
+	detectFiles []string // Paths (with variables and wildcards), same semantics
+	fileKeys    []winapp2FileKey
+	excludes    []winapp2Exclude
+}
+
+// Winapp2Skip records a section that could not be imported and why.
+type Winapp2Skip struct {
+	Section string
+	Reason  string
+}
+
+type winapp2FileKey struct {
+	path       string
+	patterns   []string
+	recurse    bool
+	removeSelf bool
+}
+
+type winapp2Exclude struct {
+	path    string // Directory (may contain variables and wildcards)
+	pattern string // File pattern; empty excludes the whole directory tree
+	tree    bool   // PATH excludes apply at any depth, FILE only directly inside
+}
+
+// winapp2SpecialDetect maps SpecialDetect values to equivalent DetectFile paths.
+var winapp2SpecialDetect = map[string]string{
+	"DET_CHROME":      `%LocalAppData%\Google\Chrome\User Data`,
+	"DET_MOZILLA":     `%AppData%\Mozilla\Firefox`,
+	"DET_THUNDERBIRD": `%AppData%\Thunderbird`,
+	"DET_OPERA":       `%AppData%\Opera Software`,
+}
+
+// winapp2Vars maps winapp2 variable names (lower case) to the environment
+// variables they expand to. %ProgramFiles% covers both Program Files folders.
+var winapp2Vars = map[string][]string{
+	"appdata":            {"APPDATA"},
+	"localappdata":       {"LOCALAPPDATA"},
+	"locallowappdata":    {"USERPROFILE|AppData\\LocalLow"},
+	"commonappdata":      {"ProgramData"},
+	"programdata":        {"ProgramData"},
+	"documents":          {"USERPROFILE|Documents"},
+	"pictures":           {"USERPROFILE|Pictures"},
+	"music":              {"USERPROFILE|Music"},
+	"video":              {"USERPROFILE|Videos"},
+	"userprofile":        {"USERPROFILE"},
+	"public":             {"PUBLIC"},
+	"programfiles":       {"ProgramFiles", "ProgramFiles(x86)"},
+	"commonprogramfiles": {"CommonProgramFiles", "CommonProgramFiles(x86)"},
+	"windir":             {"WINDIR"},
+	"systemroot":         {"SystemRoot"},
+	"systemdrive":        {"SYSTEMDRIVE"},
+	"homedrive":          {"HOMEDRIVE"},
+	"temp":               {"TEMP"},
+	"tmp":                {"TMP"},
+}
+
+// ParseWinapp2 reads a winapp2.ini file and returns the sections that can be
+// run as categories along with the sections that were skipped.
+func ParseWinapp2(r io.Reader) ([]Winapp2Entry, []Winapp2Skip, error) {
+	type section struct {
+		name string
+		keys [][2]string
+	}
+	var sections []section
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			sections = append(sections, section{name: strings.TrimSpace(text[1 : len(text)-1])})
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok || len(sections) == 0 {
+			return nil, nil, fmt.Errorf("line %d: expected key=value inside a section", line)
+		}
+		cur := &sections[len(sections)-1]
+		cur.keys = append(cur.keys, [2]string{strings.TrimSpace(key), strings.TrimSpace(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var entries []Winapp2Entry
+	var skipped []Winapp2Skip
+	seen := map[string]bool{}
+	for _, sec := range sections {
+		entry, reason := parseWinapp2Section(sec.name, sec.keys)
+		if reason == "" && seen[entry.ID()] {
+			reason = "duplicate section"
+		}
+		if reason != "" {
+			skipped = append(skipped, Winapp2Skip{Section: sec.name, Reason: reason})
+			continue
+		}
+		seen[entry.ID()] = true
+		entries = append(entries, entry)
+	}
+	return entries, skipped, nil
+}
+
+func parseWinapp2Section(name string, keys [][2]string) (Winapp2Entry, string) {
+	entry := Winapp2Entry{Section: name}
+	regKeys := 0
+
+	for _, kv := range keys {
+		key, value := strings.ToLower(kv[0]), kv[1]
+		switch {
+		case strings.HasPrefix(key, "detectfile"):
+			if err := checkWinapp2Vars(value); err != "" {
+				return entry, err
+			}
+			entry.detectFiles = append(entry.detectFiles, value)
+		case strings.HasPrefix(key, "detectos"):
+			// OS version ranges are not enforced
+		case strings.HasPrefix(key, "detect"):
+			entry.detect = append(entry.detect, value)
+		case key == "specialdetect":
+			path, ok := winapp2SpecialDetect[strings.ToUpper(value)]
+			if !ok {
+				return entry, fmt.Sprintf("unsupported SpecialDetect %s", value)
+			}
+			entry.detectFiles = append(entry.detectFiles, path)
+		case strings.HasPrefix(key, "filekey"):
+			fk, err := parseWinapp2FileKey(value)
+			if err != "" {
+				return entry, fmt.Sprintf("%s: %s", kv[0], err)
+			}
+			entry.fileKeys = append(entry.fileKeys, fk)
+		case strings.HasPrefix(key, "excludekey"):
+			ex, skip, err := parseWinapp2Exclude(value)
+			if err != "" {
+				return entry, fmt.Sprintf("%s: %s", kv[0], err)
+			}
+			if !skip {
+				entry.excludes = append(entry.excludes, ex)
+			}
+		case strings.HasPrefix(key, "regkey"):
+			regKeys++
+		}
+	}
+
+	if len(entry.fileKeys) == 0 {
+		if regKeys > 0 {
+			return entry, "registry-only entry (RegKey cleaning is not supported)"
+		}
+		return entry, "no FileKey entries"
+	}
+	return entry, ""
+}
+
+// parseWinapp2FileKey parses "path|pattern1;pattern2|FLAG".
+func parseWinapp2FileKey(value string) (winapp2FileKey, string) {
+	parts := strings.Split(value, "|")
+	fk := winapp2FileKey{path: strings.TrimSpace(parts[0])}
+	if fk.path == "" {
+		return fk, "missing path"
+	}
+	if err := checkWinapp2Vars(fk.path); err != "" {
+		return fk, err
+	}
+
+	patterns := "*.*"
+	if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+		patterns = parts[1]
+	}
+	for _, p := range strings.Split(patterns, ";") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		// In winapp2, *.* matches every file including ones without an extension
+		if p == "*.*" {
+			p = "*"
+		}
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fk, fmt.Sprintf("bad pattern %q", p)
+		}
+		fk.patterns = append(fk.patterns, p)
+	}
+
+	for _, flag := range parts[min(2, len(parts)):] {
+		switch strings.ToUpper(strings.TrimSpace(flag)) {
+		case "RECURSE":
+			fk.recurse = true
+		case "REMOVESELF":
+			fk.recurse = true
+			fk.removeSelf = true
+		case "":
+		default:
+			return fk, fmt.Sprintf("unsupported flag %s", flag)
+		}
+	}
+	return fk, ""
+}
+
+// parseWinapp2Exclude parses "FILE|dir|pattern", "PATH|dir" or "PATH|dir|pattern".
+// Registry excludes are accepted but ignored (skip is true).
+func parseWinapp2Exclude(value string) (winapp2Exclude, bool, string) {
+	parts := strings.Split(value, "|")
+	kind := strings.ToUpper(strings.TrimSpace(parts[0]))
+	if kind == "REG" {
+		return winapp2Exclude{}, true, ""
+	}
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		return winapp2Exclude{}, false, "missing path"
+	}
+
+	ex := winapp2Exclude{path: strings.TrimSpace(parts[1])}
+	if len(parts) > 2 {
+		ex.pattern = strings.TrimSpace(parts[2])
+	}
+	switch kind {
+	case "PATH":
+		ex.tree = true
+	case "FILE":
+		// FILE|C:\dir\file.ext names a single file
+		if ex.pattern == "" {
+			p := winapp2Path(ex.path)
+			ex.path, ex.pattern = filepath.Dir(p), filepath.Base(p)
+		}
+	default:
+		return ex, false, fmt.Sprintf("unsupported exclude type %s", kind)
+	}
+	if err := checkWinapp2Vars(ex.path); err != "" {
+		return ex, false, err
+	}
+	return ex, false, ""
+}
+
+// checkWinapp2Vars returns a skip reason if p uses an unknown variable.
+func checkWinapp2Vars(p string) string {
+	for _, m := range percentVar.FindAllStringSubmatch(p, -1) {
+		if _, ok := winapp2Vars[strings.ToLower(m[1])]; !ok {
+			return fmt.Sprintf("unknown variable %%%s%%", m[1])
+		}
+	}
+	return ""
+}
+
+// winapp2Path converts winapp2's backslash-separated paths to the native form.
+func winapp2Path(p string) string {
+	return filepath.FromSlash(strings.ReplaceAll(p, `\`, "/"))
+}
+
+// expandWinapp2 expands the variables in p using lookup, returning every
+// candidate path (a variable can map to more than one folder). Wildcards are
+// left in place.
+func expandWinapp2(p string, lookup func(string) (string, bool)) []string {
+	candidates := []string{p}
+	for _, m := range percentVar.FindAllStringSubmatch(p, -1) {
+		var values []string
+		for _, spec := range winapp2Vars[strings.ToLower(m[1])] {
+			env, sub, _ := strings.Cut(spec, "|")
+			if v, ok := lookup(env); ok && v != "" {
+				if sub != "" {
+					v = v + `\` + sub
+				}
+				values = append(values, v)
+			}
+		}
+		var next []string
+		for _, c := range candidates {
+			for _, v := range values {
+				next = append(next, strings.Replace(c, m[0], v, 1))
+			}
+		}
+		candidates = next
+	}
+
+	var out []string
+	for _, c := range candidates {
+		c = winapp2Path(c)
+		if filepath.IsAbs(c) {
+			out = append(out, filepath.Clean(c))
+		}
+	}
+	return out
+}
+
+// globWinapp2 expands variables and wildcards, returning existing paths.
+func globWinapp2(p string, lookup func(string) (string, bool)) []string {
+	var out []string
+	for _, c := range expandWinapp2(p, lookup) {
+		matches, err := filepath.Glob(c)
+		if err != nil {
+			continue
+		}
+		out = append(out, matches...)
+	}
+	sort.Strings(out)
+	return dedup(out)
+}
+
+// ID returns the registry ID used for the entry, e.g. "winapp2_adobe_reader".
+func (e Winapp2Entry) ID() string {
+	var b strings.Builder
+	b.WriteString("winapp2_")
+	underscore := false
+	for _, r := range strings.ToLower(e.Section) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimRight(b.String(), "_")
+}
+
+// Name returns the display name, without winapp2's trailing " *" marker.
+func (e Winapp2Entry) Name() string {
+	return strings.TrimSpace(strings.TrimSuffix(e.Section, "*"))
+}
+
+// detected reports whether any Detect or DetectFile matches. Entries without
+// detection keys always apply.
+func (e Winapp2Entry) detected(lookup func(string) (string, bool)) bool {
+	if len(e.detect) == 0 && len(e.detectFiles) == 0 {
+		return true
+	}
+	for _, key := range e.detect {
+		if registryKeyExists(key) {
+			return true
+		}
+	}
+	for _, p := range e.detectFiles {
+		if len(globWinapp2(p, lookup)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// skip reports whether path is protected by one of the entry's ExcludeKeys.
+func (e Winapp2Entry) skip(path string, isDir bool, lookup func(string) (string, bool)) bool {
+	for _, ex := range e.excludes {
+		for _, dir := range expandWinapp2(ex.path, lookup) {
+			if ex.pattern == "" {
+				if ex.tree && pathUnder(path, dir) {
+					return true
+				}
+				continue
+			}
+			if isDir {
+				continue
+			}
+			parentOK := pathMatches(filepath.Dir(path), dir)
+			if ex.tree {
+				parentOK = pathUnder(filepath.Dir(path), dir)
+			}
+			if parentOK && matchGlobs([]string{ex.pattern}, filepath.Dir(path), path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathMatches reports whether path matches the (possibly wildcarded) pattern,
+// compared component by component.
+func pathMatches(path, pattern string) bool {
+	pp, dp := splitPath(path), splitPath(pattern)
+	return len(pp) == len(dp) && componentsMatch(pp, dp)
+}
+
+// pathUnder reports whether path is pattern or lies below it.
+func pathUnder(path, pattern string) bool {
+	pp, dp := splitPath(path), splitPath(pattern)
+	return len(pp) >= len(dp) && componentsMatch(pp[:len(dp)], dp)
+}
+
+func componentsMatch(parts, patterns []string) bool {
+	for i := range patterns {
+		a, b := parts[i], patterns[i]
+		if runtime.GOOS == "windows" {
+			a, b = strings.ToLower(a), strings.ToLower(b)
+		}
+		if ok, _ := filepath.Match(b, a); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func splitPath(p string) []string {
+	return strings.FieldsFunc(filepath.Clean(p), func(r rune) bool {
+		return r == filepath.Separator
+	})
+}
+
+// Category converts the entry into a Windows-only registry category in
+// GroupWinapp2. Detection is evaluated when the category runs. Community
+// definitions quarantine by default since they are not reviewed by us.
+func (e Winapp2Entry) Category() Category {
+	return e.category(os.LookupEnv)
+}
+
+func (e Winapp2Entry) category(lookup func(string) (string, bool)) Category {
+	return Category{
+		ID:          e.ID(),
+		Name:        e.Name(),
+		Description: "winapp2: " + e.Section,
+		Group:       GroupWinapp2,
+		Platforms:   windowsOnly,
+		Risk:        RiskMedium,
+		Quarantine:  true,
+		Detect:      func() bool { return e.detected(lookup) },
+		Paths: []PathResolver{func() []string {
+			var dirs []string
+			for _, fk := range e.fileKeys {
+				dirs = append(dirs, globWinapp2(fk.path, lookup)...)
+			}
+			return dirs
+		}},
+		Clean: func(c Category, opts CleanOptions) CleanResult {
+			return e.clean(c, opts, lookup)
+		},
+	}
+}
+
+func (e Winapp2Entry) clean(c Category, opts CleanOptions, lookup func(string) (string, bool)) CleanResult {
+	result := CleanResult{}
+	for _, fk := range e.fileKeys {
+		filter := fileFilter{
+			Include: fk.patterns,
+			Shallow: !fk.recurse,
+			Skip: func(path string, isDir bool) bool {
+				return e.skip(path, isDir, lookup)
+			},
+		}
+		for _, dir := range globWinapp2(fk.path, lookup) {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+			sw := newSweeper(c, opts)
+			result.merge(cleanDirectoryFiltered(dir, filter, sw))
+			if fk.removeSelf && !opts.DryRun && sw.guard.check(dir) == nil {
+				result.DirsRemoved += int64(removeEmptyDirs(dir, true))
+			}
+		}
+	}
+	return result
+}
+
+// LoadWinapp2File parses a winapp2.ini file from disk.
+func LoadWinapp2File(path string) ([]Winapp2Entry, []Winapp2Skip, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return ParseWinapp2(f)
+}
+
+// RegisterWinapp2 adds winapp2 entries to the category registry, returning
+// an error for each entry that could not be registered.
+func RegisterWinapp2(entries []Winapp2Entry) []error {
+	var errs []error
+	for _, e := range entries {
+		if err := Register(e.Category()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,153 @@
+package cleaner
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ---------- winapp2 tests ----------
+
+const sampleWinapp2 = "\ufeff; winapp2.ini sample\n" + `
+[Example App *]
+LangSecRef=3021
+DetectFile=%LocalAppData%\Example
+Default=False
+FileKey1=%LocalAppData%\Example\Cache|*.*|RECURSE
+FileKey2=%LocalAppData%\Example\Logs|*.log;*.tmp
+FileKey3=%LocalAppData%\Example\Crash|*.*|REMOVESELF
+ExcludeKey1=FILE|%LocalAppData%\Example\Cache\|index.dat
+ExcludeKey2=PATH|%LocalAppData%\Example\Cache\Keep\
+
+[Registry Only]
+RegKey1=HKCU\Software\Example
+
+[Weird Variable]
+FileKey1=%NotAVariable%\Cache|*.*
+
+[Special Detect]
+SpecialDetect=DET_NETSCAPE
+FileKey1=%AppData%\Netscape|*.*
+
+[Bad Flag]
+FileKey1=%AppData%\Foo|*.*|RECURSE|SOMEFLAG
+`
+
+func TestParseWinapp2_UnderstoodAndSkipped(t *testing.T) {
+	entries, skipped, err := ParseWinapp2(strings.NewReader(sampleWinapp2))
+	if err != nil {
+		t.Fatalf("ParseWinapp2 failed: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 understood entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.ID() != "winapp2_example_app" {
+		t.Errorf("unexpected ID %q", e.ID())
+	}
+	if e.Name() != "Example App" {
+		t.Errorf("unexpected name %q", e.Name())
+	}
+	if len(e.fileKeys) != 3 || len(e.excludes) != 2 {
+		t.Errorf("expected 3 FileKeys and 2 ExcludeKeys, got %d and %d", len(e.fileKeys), len(e.excludes))
+	}
+
+	reasons := map[string]string{}
+	for _, s := range skipped {
+		reasons[s.Section] = s.Reason
+	}
+	want := map[string]string{
+		"Registry Only":  "registry-only",
+		"Weird Variable": "unknown variable %NotAVariable%",
+		"Special Detect": "unsupported SpecialDetect",
+		"Bad Flag":       "unsupported flag",
+	}
+	for section, substr := range want {
+		if !strings.Contains(reasons[section], substr) {
+			t.Errorf("section %q: expected reason containing %q, got %q", section, substr, reasons[section])
+		}
+	}
+}
+
+func TestParseWinapp2_KeyOutsideSection(t *testing.T) {
+	if _, _, err := ParseWinapp2(strings.NewReader("FileKey1=C:\\foo|*.*\n")); err == nil {
+		t.Error("expected an error for a key outside any section")
+	}
+}
+
+func TestWinapp2Entry_Clean(t *testing.T) {
+	local := t.TempDir()
+	lookup := func(k string) (string, bool) {
+		if k == "LOCALAPPDATA" {
+			return local, true
+		}
+		return "", false
+	}
+	base := filepath.Join(local, "Example")
+	writeFile(t, filepath.Join(base, "Cache", "a.bin"), "x")
+	writeFile(t, filepath.Join(base, "Cache", "index.dat"), "x")
+	writeFile(t, filepath.Join(base, "Cache", "deep", "b.bin"), "x")
+	writeFile(t, filepath.Join(base, "Cache", "Keep", "c.bin"), "x")
+	writeFile(t, filepath.Join(base, "Logs", "app.log"), "x")
+	writeFile(t, filepath.Join(base, "Logs", "app.cfg"), "x")
+	writeFile(t, filepath.Join(base, "Logs", "old", "older.log"), "x")
+	writeFile(t, filepath.Join(base, "Crash", "sub", "dump.dmp"), "x")
+
+	entries, _, err := ParseWinapp2(strings.NewReader(sampleWinapp2))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ParseWinapp2 failed: %v", err)
+	}
+	c := entries[0].category(lookup)
+
+	if !c.Detected() {
+		t.Fatal("expected entry to be detected via DetectFile")
+	}
+	result := c.Clean(c, CleanOptions{})
+
+	for _, gone := range []string{
+		filepath.Join("Cache", "a.bin"),
+		filepath.Join("Cache", "deep", "b.bin"),
+		filepath.Join("Logs", "app.log"),
+		"Crash",
+	} {
+		if exists(filepath.Join(base, gone)) {
+			t.Errorf("%s should have been removed", gone)
+		}
+	}
+	for _, kept := range []string{
+		filepath.Join("Cache", "index.dat"),
+		filepath.Join("Cache", "Keep", "c.bin"),
+		filepath.Join("Logs", "app.cfg"),
+		filepath.Join("Logs", "old", "older.log"),
+	} {
+		if !exists(filepath.Join(base, kept)) {
+			t.Errorf("%s should have been kept", kept)
+		}
+	}
+	if result.FilesDeleted != 4 {
+		t.Errorf("expected 4 files deleted, got %d", result.FilesDeleted)
+	}
+}
+
+func TestWinapp2Entry_NotDetected(t *testing.T) {
+	entries, _, _ := ParseWinapp2(strings.NewReader(sampleWinapp2))
+	c := entries[0].category(func(string) (string, bool) { return t.TempDir(), true })
+	if c.Detected() {
+		t.Error("expected entry not to be detected when its DetectFile is missing")
+	}
+}
+
+func TestExpandWinapp2_ProgramFilesHasTwoCandidates(t *testing.T) {
+	env := map[string]string{"ProgramFiles": "/pf", "ProgramFiles(x86)": "/pf86"}
+	got := expandWinapp2(`%ProgramFiles%\App`, func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 candidates, got %v", got)
+	}
+	if got[0] != filepath.FromSlash("/pf/App") || got[1] != filepath.FromSlash("/pf86/App") {
+		t.Errorf("unexpected candidates %v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"path/filepath"
+
+	"syscleaner/pkg/cleaner"
+)
+
+const (
+	defaultArchiveKeep      = 10
+	defaultArchiveMaxSizeMB = 1024
+)
+
+// ArchiveDir returns the directory archive bundles are written to: the
+// configured directory, or ConfigDir()/archives.
+func ArchiveDir(cfg *Config) (string, error) {
+	if cfg.Archive.Dir != "" {
+		return cfg.Archive.Dir, nil
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "archives"), nil
+}
+
+// archiveLimits returns the rotation limits of s for cleaner.NewArchiver,
+// where 0 means no limit. Unset limits take the defaults, so that a config
+// with only "enabled" does not grow the archive without bound; negative
+// limits turn rotation off.
+func archiveLimits(s ArchiveSettings) (int, int64) {
+	keep, maxMB := s.Keep, s.MaxSizeMB
+	switch {
+	case keep == 0:
+		keep = defaultArchiveKeep
+	case keep < 0:
+		keep = 0
+	}
+	switch {
+	case maxMB == 0:
+		maxMB = defaultArchiveMaxSizeMB
+	case maxMB < 0:
+		maxMB = 0
+	}
+	return keep, maxMB * 1024 * 1024
+}
+
+// ApplyArchive prepares archiving into the archive directory and sets it,
+// together with the per-category overrides from cfg, on opts. The archive
+// directory is protected from cleaning. Call it for every clean: each clean
+// writes its own bundles.
+func ApplyArchive(cfg *Config, opts *cleaner.CleanOptions) error {
+	dir, err := ArchiveDir(cfg)
+	if err != nil {
+		return err
+	}
+	format, err := cleaner.ParseArchiveFormat(string(cfg.Archive.Format))
+	if err != nil {
+		return err
+	}
+	keep, maxBytes := archiveLimits(cfg.Archive)
+	a, err := cleaner.NewArchiver(dir, format, keep, maxBytes)
+	if err != nil {
+		return err
+	}
+	opts.Archive = a
+	opts.ProtectedPaths = append(opts.ProtectedPaths, a.Dir())
+	if opts.ArchiveCategories == nil {
+		opts.ArchiveCategories = map[string]bool{}
+	}
+	for id, on := range cfg.Archive.Categories {
+		if _, set := opts.ArchiveCategories[id]; !set {
+			opts.ArchiveCategories[id] = on
+		}
+	}
+	return nil
+}
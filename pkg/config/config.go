@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"syscleaner/pkg/cleaner"
 )
@@ -20,6 +21,68 @@ type UIPreferences struct {
 	LastActiveTab string `json:"last_active_tab"`
 }
 
+// QuarantineSettings configures the quarantine store cleaned files can be
+// moved into instead of being deleted.
+type QuarantineSettings struct {
+	MaxSizeMB int64 `json:"max_size_mb"` // Store size cap; oldest runs are evicted first
+	// Categories overrides, per category ID, whether files are quarantined.
+	// Categories not listed use their built-in default (custom rules and
+	// winapp2 entries quarantine, everything else deletes).
+	Categories map[string]bool `json:"categories"`
+}
+
+// ArchiveSettings configures archive-before-delete: when Enabled, the files
+// of log categories are compressed into dated bundles in Dir before they are
+// removed. Beyond Keep bundles per category or MaxSizeMB in total, the
+// oldest bundles are rotated out.
+type ArchiveSettings struct {
+	Enabled   bool                  `json:"enabled"`
+	Dir       string                `json:"dir"`         // Empty = ConfigDir()/archives
+	Format    cleaner.ArchiveFormat `json:"format"`      // "zip" or "tar.gz"
+	Keep      int                   `json:"keep"`        // 0 = 10, negative = no limit
+	MaxSizeMB int64                 `json:"max_size_mb"` // 0 = 1024, negative = no cap
+	// Categories overrides, per category ID, whether files are archived.
+	// Categories not listed use their built-in default (only log
+	// categories are archived).
+	Categories map[string]bool `json:"categories"`
+}
+
+// PendingSettings configures the queue of files a clean could not remove
+// because they were in use. Queued files are retried by the next clean.
+type PendingSettings struct {
+	// DeleteOnReboot also schedules queued files for deletion at the next
+	// reboot. Windows only; it needs administrator rights.
+	DeleteOnReboot bool `json:"delete_on_reboot"`
+}
+
+// RunningAppSettings configures what happens to a category whose owning
+// application is running: "skip" it (the default), "warn" and clean anyway,
+// or ask to "close" the application first.
+type RunningAppSettings struct {
+	Policy cleaner.RunningPolicy `json:"policy"`
+	// Categories overrides Policy per category ID.
+	Categories map[string]cleaner.RunningPolicy `json:"categories"`
+}
+
+// BrowserProfileSettings selects the browser profiles the browser and
+// privacy categories clean. Patterns match a profile's name, its directory
+// name, "Browser:Name" or its full directory. An empty Include means every
+// profile; Exclude always wins.
+type BrowserProfileSettings struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// DeveloperSettings configures the Developer categories. WorkspaceRoots are
+// searched for projects whose node_modules are removed once the project has
+// not been touched for StaleDays days.
+type DeveloperSettings struct {
+	WorkspaceRoots []string `json:"workspace_roots"`
+	StaleDays      int      `json:"stale_days"`
+}
+
+const defaultStaleDays = 90
+
 // Config is the top-level application configuration.
 type Config struct {
 	ProcessWhitelist    []string
@@ -27,6 +90,22 @@ type Config struct {
 	RAMMonitor          RAMMonitorSettings
 	UIPreferences       UIPreferences
 	ActiveProfile       string
+	Quarantine          QuarantineSettings
+	Archive             ArchiveSettings
+	Pending             PendingSettings
+	RunningApps         RunningAppSettings
+	BrowserProfiles     BrowserProfileSettings
+	Developer           DeveloperSettings
+
+	// ProtectedPaths are directories the cleaner never touches, in addition
+	// to the built-in filesystem roots, home and system directories.
+	ProtectedPaths []string
+	// Exclusions are glob patterns, matched against a file's name or full
+	// path, for files and directories no category may remove.
+	Exclusions []string
+	// PrivacyKeepDomains are domains (with their subdomains) whose cookies
+	// the Cookies category never removes, such as a single sign-on provider.
+	PrivacyKeepDomains []string
 }
 
 // ConfigDir returns the path to the SysCleaner configuration directory.
@@ -101,17 +180,8 @@ func SaveConfig(cfg *Config) error {
 // DefaultConfig returns a Config populated with sensible default values.
 func DefaultConfig() *Config {
 	return &Config{
-		ProcessWhitelist: []string{},
-		DefaultCleanOptions: cleaner.CleanOptions{
-			WindowsTemp:    true,
-			UserTemp:       true,
-			Prefetch:       true,
-			ThumbnailCache: true,
-			DNSCache:       true,
-			ChromeCache:    true,
-			FirefoxCache:   true,
-			EdgeCache:      true,
-		},
+		ProcessWhitelist:    []string{},
+		DefaultCleanOptions: defaultCleanOptions(),
 		RAMMonitor: RAMMonitorSettings{
 			FreeThresholdPercent:    15.0,
 			StandbyThresholdPercent: 50.0,
@@ -120,145 +190,139 @@ func DefaultConfig() *Config {
 			LastActiveTab: "dashboard",
 		},
 		ActiveProfile: "default",
+		Quarantine: QuarantineSettings{
+			MaxSizeMB:  defaultQuarantineMaxSizeMB,
+			Categories: map[string]bool{},
+		},
+		Archive: ArchiveSettings{
+			Format:     cleaner.ArchiveZip,
+			Keep:       defaultArchiveKeep,
+			MaxSizeMB:  defaultArchiveMaxSizeMB,
+			Categories: map[string]bool{},
+		},
+		RunningApps: RunningAppSettings{
+			Policy:     cleaner.RunningSkip,
+			Categories: map[string]cleaner.RunningPolicy{},
+		},
+		BrowserProfiles: BrowserProfileSettings{
+			Include: []string{},
+			Exclude: []string{},
+		},
+		Developer: DeveloperSettings{
+			WorkspaceRoots: []string{},
+			StaleDays:      defaultStaleDays,
+		},
+		ProtectedPaths:     []string{},
+		Exclusions:         []string{},
+		PrivacyKeepDomains: []string{},
+	}
+}
+
+// ApplyExclusions sets the protected paths, exclusion globs, cookie
+// keep-list and browser profile selection from cfg on opts.
+func ApplyExclusions(cfg *Config, opts *cleaner.CleanOptions) {
+	opts.ProtectedPaths = append(opts.ProtectedPaths, cfg.ProtectedPaths...)
+	opts.Exclude = append(opts.Exclude, cfg.Exclusions...)
+	opts.KeepDomains = append(opts.KeepDomains, cfg.PrivacyKeepDomains...)
+	opts.BrowserProfiles = append(opts.BrowserProfiles, cfg.BrowserProfiles.Include...)
+	opts.ExcludeBrowserProfiles = append(opts.ExcludeBrowserProfiles, cfg.BrowserProfiles.Exclude...)
+}
+
+// ApplyDeveloper sets the workspace roots and stale project age from cfg on
+// opts. An age already set on opts wins.
+func ApplyDeveloper(cfg *Config, opts *cleaner.CleanOptions) {
+	opts.WorkspaceRoots = append(opts.WorkspaceRoots, cfg.Developer.WorkspaceRoots...)
+	if opts.StaleProjectAge == 0 && cfg.Developer.StaleDays > 0 {
+		opts.StaleProjectAge = time.Duration(cfg.Developer.StaleDays) * 24 * time.Hour
+	}
+}
+
+// ApplyRunningPolicy sets the running-application policies from cfg on opts.
+// Per-category policies already set on opts win.
+func ApplyRunningPolicy(cfg *Config, opts *cleaner.CleanOptions) {
+	if opts.RunningPolicy == "" {
+		opts.RunningPolicy = cfg.RunningApps.Policy
 	}
+	if opts.RunningPolicies == nil {
+		opts.RunningPolicies = map[string]cleaner.RunningPolicy{}
+	}
+	for id, p := range cfg.RunningApps.Categories {
+		if _, set := opts.RunningPolicies[id]; !set {
+			opts.RunningPolicies[id] = p
+		}
+	}
+}
+
+const defaultQuarantineMaxSizeMB = 2048
+
+// defaultCleanOptions enables every registry category marked as Default.
+func defaultCleanOptions() cleaner.CleanOptions {
+	opts := cleaner.CleanOptions{}
+	opts.Enable(cleaner.DefaultCategories()...)
+	return opts
 }
 
 // ---------------------------------------------------------------------------
 // JSON serialization helpers
 //
 // cleaner.CleanOptions contains a ProgressFunc field (function type) that
-// encoding/json cannot marshal. The types below mirror the serializable
-// fields so that Config can be round-tripped through JSON transparently.
+// encoding/json cannot marshal. configData stores the serializable part as a
+// ProfileCleanOptions so that Config can be round-tripped through JSON
+// transparently.
 // ---------------------------------------------------------------------------
 
-// cleanOptionsData is a JSON-safe mirror of cleaner.CleanOptions.
-type cleanOptionsData struct {
-	// System categories
-	WindowsTemp          bool `json:"windows_temp"`
-	UserTemp             bool `json:"user_temp"`
-	WindowsUpdate        bool `json:"windows_update"`
-	WindowsInstaller     bool `json:"windows_installer"`
-	Prefetch             bool `json:"prefetch"`
-	CrashDumps           bool `json:"crash_dumps"`
-	ErrorReports         bool `json:"error_reports"`
-	ThumbnailCache       bool `json:"thumbnail_cache"`
-	IconCache            bool `json:"icon_cache"`
-	FontCache            bool `json:"font_cache"`
-	ShaderCache          bool `json:"shader_cache"`
-	DNSCache             bool `json:"dns_cache"`
-	WindowsLogs          bool `json:"windows_logs"`
-	EventLogs            bool `json:"event_logs"`
-	DeliveryOptimization bool `json:"delivery_optimization"`
-	RecycleBin           bool `json:"recycle_bin"`
-
-	// Application categories
-	ChromeCache  bool `json:"chrome_cache"`
-	FirefoxCache bool `json:"firefox_cache"`
-	EdgeCache    bool `json:"edge_cache"`
-	BraveCache   bool `json:"brave_cache"`
-	OperaCache   bool `json:"opera_cache"`
-	DiscordCache bool `json:"discord_cache"`
-	SpotifyCache bool `json:"spotify_cache"`
-	SteamCache   bool `json:"steam_cache"`
-	TeamsCache   bool `json:"teams_cache"`
-	VSCodeCache  bool `json:"vscode_cache"`
-	JavaCache    bool `json:"java_cache"`
-
-	// Execution options
-	DryRun bool `json:"dry_run"`
-}
-
 // configData is the JSON-serializable representation of Config.
 type configData struct {
-	ProcessWhitelist    []string           `json:"process_whitelist"`
-	DefaultCleanOptions cleanOptionsData   `json:"default_clean_options"`
-	RAMMonitor          RAMMonitorSettings `json:"ram_monitor"`
-	UIPreferences       UIPreferences      `json:"ui_preferences"`
-	ActiveProfile       string             `json:"active_profile"`
-}
-
-func toCleanOptionsData(o cleaner.CleanOptions) cleanOptionsData {
-	return cleanOptionsData{
-		WindowsTemp:          o.WindowsTemp,
-		UserTemp:             o.UserTemp,
-		WindowsUpdate:        o.WindowsUpdate,
-		WindowsInstaller:     o.WindowsInstaller,
-		Prefetch:             o.Prefetch,
-		CrashDumps:           o.CrashDumps,
-		ErrorReports:         o.ErrorReports,
-		ThumbnailCache:       o.ThumbnailCache,
-		IconCache:            o.IconCache,
-		FontCache:            o.FontCache,
-		ShaderCache:          o.ShaderCache,
-		DNSCache:             o.DNSCache,
-		WindowsLogs:          o.WindowsLogs,
-		EventLogs:            o.EventLogs,
-		DeliveryOptimization: o.DeliveryOptimization,
-		RecycleBin:           o.RecycleBin,
-		ChromeCache:          o.ChromeCache,
-		FirefoxCache:         o.FirefoxCache,
-		EdgeCache:            o.EdgeCache,
-		BraveCache:           o.BraveCache,
-		OperaCache:           o.OperaCache,
-		DiscordCache:         o.DiscordCache,
-		SpotifyCache:         o.SpotifyCache,
-		SteamCache:           o.SteamCache,
-		TeamsCache:           o.TeamsCache,
-		VSCodeCache:          o.VSCodeCache,
-		JavaCache:            o.JavaCache,
-		DryRun:               o.DryRun,
-	}
-}
-
-func fromCleanOptionsData(d cleanOptionsData) cleaner.CleanOptions {
-	return cleaner.CleanOptions{
-		WindowsTemp:          d.WindowsTemp,
-		UserTemp:             d.UserTemp,
-		WindowsUpdate:        d.WindowsUpdate,
-		WindowsInstaller:     d.WindowsInstaller,
-		Prefetch:             d.Prefetch,
-		CrashDumps:           d.CrashDumps,
-		ErrorReports:         d.ErrorReports,
-		ThumbnailCache:       d.ThumbnailCache,
-		IconCache:            d.IconCache,
-		FontCache:            d.FontCache,
-		ShaderCache:          d.ShaderCache,
-		DNSCache:             d.DNSCache,
-		WindowsLogs:          d.WindowsLogs,
-		EventLogs:            d.EventLogs,
-		DeliveryOptimization: d.DeliveryOptimization,
-		RecycleBin:           d.RecycleBin,
-		ChromeCache:          d.ChromeCache,
-		FirefoxCache:         d.FirefoxCache,
-		EdgeCache:            d.EdgeCache,
-		BraveCache:           d.BraveCache,
-		OperaCache:           d.OperaCache,
-		DiscordCache:         d.DiscordCache,
-		SpotifyCache:         d.SpotifyCache,
-		SteamCache:           d.SteamCache,
-		TeamsCache:           d.TeamsCache,
-		VSCodeCache:          d.VSCodeCache,
-		JavaCache:            d.JavaCache,
-		DryRun:               d.DryRun,
-	}
+	ProcessWhitelist    []string               `json:"process_whitelist"`
+	DefaultCleanOptions ProfileCleanOptions    `json:"default_clean_options"`
+	RAMMonitor          RAMMonitorSettings     `json:"ram_monitor"`
+	UIPreferences       UIPreferences          `json:"ui_preferences"`
+	ActiveProfile       string                 `json:"active_profile"`
+	Quarantine          QuarantineSettings     `json:"quarantine"`
+	Archive             ArchiveSettings        `json:"archive"`
+	Pending             PendingSettings        `json:"pending"`
+	RunningApps         RunningAppSettings     `json:"running_apps"`
+	BrowserProfiles     BrowserProfileSettings `json:"browser_profiles"`
+	Developer           DeveloperSettings      `json:"developer"`
+	ProtectedPaths      []string               `json:"protected_paths"`
+	Exclusions          []string               `json:"exclusions"`
+	PrivacyKeepDomains  []string               `json:"privacy_keep_domains"`
 }
 
 func toConfigData(c *Config) configData {
 	return configData{
 		ProcessWhitelist:    c.ProcessWhitelist,
-		DefaultCleanOptions: toCleanOptionsData(c.DefaultCleanOptions),
+		DefaultCleanOptions: NewProfileCleanOptions(c.DefaultCleanOptions),
 		RAMMonitor:          c.RAMMonitor,
 		UIPreferences:       c.UIPreferences,
 		ActiveProfile:       c.ActiveProfile,
+		Quarantine:          c.Quarantine,
+		Archive:             c.Archive,
+		Pending:             c.Pending,
+		RunningApps:         c.RunningApps,
+		BrowserProfiles:     c.BrowserProfiles,
+		Developer:           c.Developer,
+		ProtectedPaths:      c.ProtectedPaths,
+		Exclusions:          c.Exclusions,
+		PrivacyKeepDomains:  c.PrivacyKeepDomains,
 	}
 }
 
 func fromConfigData(d configData) *Config {
 	return &Config{
 		ProcessWhitelist:    d.ProcessWhitelist,
-		DefaultCleanOptions: fromCleanOptionsData(d.DefaultCleanOptions),
+		DefaultCleanOptions: d.DefaultCleanOptions.CleanOptions(),
 		RAMMonitor:          d.RAMMonitor,
 		UIPreferences:       d.UIPreferences,
 		ActiveProfile:       d.ActiveProfile,
+		Quarantine:          d.Quarantine,
+		Archive:             d.Archive,
+		Pending:             d.Pending,
+		RunningApps:         d.RunningApps,
+		BrowserProfiles:     d.BrowserProfiles,
+		Developer:           d.Developer,
+		ProtectedPaths:      d.ProtectedPaths,
+		Exclusions:          d.Exclusions,
+		PrivacyKeepDomains:  d.PrivacyKeepDomains,
 	}
 }
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"syscleaner/pkg/cleaner"
 )
@@ -24,36 +27,36 @@ func TestDefaultConfig_ReturnsReasonableDefaults(t *testing.T) {
 
 	// Some sensible cleaning options should be enabled by default.
 	opts := cfg.DefaultCleanOptions
-	if !opts.WindowsTemp {
+	if !opts.Enabled("windows_temp") {
 		t.Error("expected WindowsTemp to be true by default")
 	}
-	if !opts.UserTemp {
+	if !opts.Enabled("user_temp") {
 		t.Error("expected UserTemp to be true by default")
 	}
-	if !opts.Prefetch {
+	if !opts.Enabled("prefetch") {
 		t.Error("expected Prefetch to be true by default")
 	}
-	if !opts.ThumbnailCache {
+	if !opts.Enabled("thumbnail_cache") {
 		t.Error("expected ThumbnailCache to be true by default")
 	}
-	if !opts.DNSCache {
+	if !opts.Enabled("dns_cache") {
 		t.Error("expected DNSCache to be true by default")
 	}
-	if !opts.ChromeCache {
+	if !opts.Enabled("chrome_cache") {
 		t.Error("expected ChromeCache to be true by default")
 	}
-	if !opts.FirefoxCache {
+	if !opts.Enabled("firefox_cache") {
 		t.Error("expected FirefoxCache to be true by default")
 	}
-	if !opts.EdgeCache {
+	if !opts.Enabled("edge_cache") {
 		t.Error("expected EdgeCache to be true by default")
 	}
 
 	// Potentially destructive or heavyweight options should be off by default.
-	if opts.RecycleBin {
+	if opts.Enabled("recycle_bin") {
 		t.Error("expected RecycleBin to be false by default")
 	}
-	if opts.EventLogs {
+	if opts.Enabled("event_logs") {
 		t.Error("expected EventLogs to be false by default")
 	}
 	if opts.DryRun {
@@ -107,6 +110,12 @@ func TestSaveAndLoadConfig_RoundTrip(t *testing.T) {
 			LastActiveTab: "cleaner",
 		},
 		ActiveProfile: "gaming",
+		ProtectedPaths: []string{"~/Projects"},
+		Exclusions:     []string{"*.kdbx"},
+		PrivacyKeepDomains: []string{"login.example.com"},
+		BrowserProfiles:    BrowserProfileSettings{Exclude: []string{"Chrome:Work"}},
+		Archive:            ArchiveSettings{Enabled: true, Format: cleaner.ArchiveTarGz, Keep: 3},
+		Pending:            PendingSettings{DeleteOnReboot: true},
 	}
 
 	// Save.
@@ -145,15 +154,33 @@ func TestSaveAndLoadConfig_RoundTrip(t *testing.T) {
 	}
 
 	// Verify clean options survived the round-trip.
-	if !loaded.DefaultCleanOptions.SteamCache {
+	if !loaded.DefaultCleanOptions.Enabled("steam_cache") {
 		t.Error("expected SteamCache=true after round-trip")
 	}
 	if !loaded.DefaultCleanOptions.DryRun {
 		t.Error("expected DryRun=true after round-trip")
 	}
-	if loaded.DefaultCleanOptions.RecycleBin {
+	if loaded.DefaultCleanOptions.Enabled("recycle_bin") {
 		t.Error("expected RecycleBin=false after round-trip")
 	}
+	if len(loaded.ProtectedPaths) != 1 || loaded.ProtectedPaths[0] != "~/Projects" {
+		t.Errorf("expected protected paths to survive the round-trip, got %v", loaded.ProtectedPaths)
+	}
+	if len(loaded.Exclusions) != 1 || loaded.Exclusions[0] != "*.kdbx" {
+		t.Errorf("expected exclusions to survive the round-trip, got %v", loaded.Exclusions)
+	}
+	if len(loaded.PrivacyKeepDomains) != 1 || loaded.PrivacyKeepDomains[0] != "login.example.com" {
+		t.Errorf("expected the cookie keep-list to survive the round-trip, got %v", loaded.PrivacyKeepDomains)
+	}
+	if ex := loaded.BrowserProfiles.Exclude; len(ex) != 1 || ex[0] != "Chrome:Work" {
+		t.Errorf("expected the excluded browser profiles to survive the round-trip, got %v", ex)
+	}
+	if a := loaded.Archive; !a.Enabled || a.Format != cleaner.ArchiveTarGz || a.Keep != 3 {
+		t.Errorf("expected the archive settings to survive the round-trip, got %+v", a)
+	}
+	if !loaded.Pending.DeleteOnReboot {
+		t.Error("expected pending.delete_on_reboot to survive the round-trip")
+	}
 }
 
 func TestLoadConfig_ReturnsDefaultWhenNoFileExists(t *testing.T) {
@@ -182,7 +209,7 @@ func TestLoadConfig_ReturnsDefaultWhenNoFileExists(t *testing.T) {
 	if cfg.ActiveProfile != def.ActiveProfile {
 		t.Errorf("expected ActiveProfile=%s, got %s", def.ActiveProfile, cfg.ActiveProfile)
 	}
-	if cfg.DefaultCleanOptions.WindowsTemp != def.DefaultCleanOptions.WindowsTemp {
+	if cfg.DefaultCleanOptions.Enabled("windows_temp") != def.DefaultCleanOptions.Enabled("windows_temp") {
 		t.Error("loaded config WindowsTemp does not match default")
 	}
 	if cfg.RAMMonitor.FreeThresholdPercent != def.RAMMonitor.FreeThresholdPercent {
@@ -190,13 +217,124 @@ func TestLoadConfig_ReturnsDefaultWhenNoFileExists(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_FlatCleanOptionsFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalXDG := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Cleanup(func() {
+		if originalXDG == "" {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		} else {
+			os.Setenv("XDG_CONFIG_HOME", originalXDG)
+		}
+	})
+
+	// Config files store clean options as a flat object keyed by category ID.
+	dir, err := ConfigDir()
+	if err != nil {
+		t.Fatalf("ConfigDir failed: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+	data := `{"default_clean_options": {"windows_temp": false, "spotify_cache": true, "dry_run": true, "trash_min_age": "30d", "timeout": "10m",
+		"retention": {"windows_logs": {"min_age": "14d", "age_by": "atime", "min_size": "1M", "keep_newest": 5, "max_size": "1.5G"}}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	opts := cfg.DefaultCleanOptions
+	if opts.Enabled("windows_temp") {
+		t.Error("expected windows_temp=false")
+	}
+	if !opts.Enabled("spotify_cache") {
+		t.Error("expected spotify_cache=true")
+	}
+	if !opts.DryRun {
+		t.Error("expected DryRun=true")
+	}
+	if opts.TrashMinAge != 30*24*time.Hour {
+		t.Errorf("expected TrashMinAge=30 days, got %s", opts.TrashMinAge)
+	}
+	if opts.Timeout != 10*time.Minute || opts.DirTimeout != 0 {
+		t.Errorf("expected Timeout=10m and the default DirTimeout, got %s / %s", opts.Timeout, opts.DirTimeout)
+	}
+	want := cleaner.RetentionPolicy{MinAge: 14 * 24 * time.Hour, AgeBy: cleaner.AgeAccessed, MinSize: 1 << 20, KeepNewest: 5, MaxSize: 3 << 29}
+	if got := opts.Retention["windows_logs"]; got != want {
+		t.Errorf("expected retention %+v, got %+v", want, got)
+	}
+	if opts.Enabled("retention") {
+		t.Error("the retention key must not be read as a category")
+	}
+
+	// Saving writes every registered category back out as a flat key.
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	var saved struct {
+		DefaultCleanOptions map[string]interface{} `json:"default_clean_options"`
+	}
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		t.Fatalf("failed to parse saved config: %v", err)
+	}
+	for _, c := range cleaner.Categories() {
+		if _, ok := saved.DefaultCleanOptions[c.ID]; !ok {
+			t.Errorf("saved config missing category key %q", c.ID)
+		}
+	}
+	if saved.DefaultCleanOptions["dry_run"] != true {
+		t.Error("expected dry_run=true in saved config")
+	}
+	if saved.DefaultCleanOptions["trash_min_age"] != "30d" {
+		t.Errorf("expected trash_min_age=30d in saved config, got %v", saved.DefaultCleanOptions["trash_min_age"])
+	}
+	retention, _ := saved.DefaultCleanOptions["retention"].(map[string]interface{})
+	if policy, _ := retention["windows_logs"].(map[string]interface{}); policy["max_size"] != "1536M" || policy["min_age"] != "14d" {
+		t.Errorf("expected the retention policy in saved config, got %v", saved.DefaultCleanOptions["retention"])
+	}
+
+	data = `{"default_clean_options": {"retention": {"windows_logs": {"min_size": "lots"}}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := LoadConfig(); err == nil {
+		t.Error("expected an invalid retention size to be rejected")
+	}
+}
+
+func TestArchiveLimits_DefaultsWhenUnset(t *testing.T) {
+	keep, maxBytes := archiveLimits(ArchiveSettings{Enabled: true})
+	if keep != defaultArchiveKeep || maxBytes != defaultArchiveMaxSizeMB*1024*1024 {
+		t.Errorf("expected the default limits for an unset archive config, got keep %d, %d bytes", keep, maxBytes)
+	}
+	if keep, maxBytes := archiveLimits(ArchiveSettings{Keep: -1, MaxSizeMB: -1}); keep != 0 || maxBytes != 0 {
+		t.Errorf("expected negative limits to turn rotation off, got keep %d, %d bytes", keep, maxBytes)
+	}
+	if keep, maxBytes := archiveLimits(ArchiveSettings{Keep: 3, MaxSizeMB: 2}); keep != 3 || maxBytes != 2*1024*1024 {
+		t.Errorf("expected the configured limits, got keep %d, %d bytes", keep, maxBytes)
+	}
+}
+
+func TestProfileKeys_AreReservedIDs(t *testing.T) {
+	for _, key := range []string{dryRunKey, trashMinAgeKey, timeoutKey, dirTimeoutKey, retentionKey} {
+		if !cleaner.IsReservedID(key) {
+			t.Errorf("profile key %q is missing from cleaner.ReservedIDs", key)
+		}
+	}
+}
+
 // defaultCleanOptionsForTest returns a CleanOptions with a mix of enabled fields
 // for testing serialization round-trips.
 func defaultCleanOptionsForTest() cleaner.CleanOptions {
-	return cleaner.CleanOptions{
-		WindowsTemp: true,
-		UserTemp:    true,
-		SteamCache:  true,
-		DryRun:      true,
-	}
+	opts := cleaner.CleanOptions{DryRun: true}
+	opts.Enable("windows_temp", "user_temp", "steam_cache")
+	return opts
 }
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"syscleaner/pkg/cleaner"
+)
+
+// HistoryPath returns the path to the clean history file, which is
+// ConfigDir()/history.jsonl.
+func HistoryPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// RecordHistory appends a finished run to the history file under the active
+// profile of cfg.
+func RecordHistory(cfg *Config, result cleaner.CleanResult, dryRun bool) error {
+	path, err := HistoryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	rec := cleaner.NewHistoryRecord(result, cfg.ActiveProfile, dryRun, time.Now())
+	return cleaner.AppendHistory(path, rec)
+}
+
+// LoadHistory reads every recorded run, oldest first.
+func LoadHistory() ([]cleaner.HistoryRecord, error) {
+	path, err := HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	return cleaner.LoadHistory(path)
+}
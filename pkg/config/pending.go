@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"syscleaner/pkg/cleaner"
+)
+
+// PendingPath returns the path to the queue of files a clean found in use,
+// which is ConfigDir()/pending.json.
+func PendingPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pending.json"), nil
+}
+
+// LoadPending reads the pending queue.
+func LoadPending() ([]cleaner.PendingFile, error) {
+	path, err := PendingPath()
+	if err != nil {
+		return nil, err
+	}
+	return cleaner.LoadPending(path)
+}
+
+// QueueLocked adds the files a clean found in use to the pending queue so
+// the next clean retries them. With pending.delete_on_reboot set they are
+// also scheduled for deletion at the next reboot where that is supported.
+func QueueLocked(cfg *Config, result cleaner.CleanResult) error {
+	if len(result.Locked) == 0 {
+		return nil
+	}
+	path, err := PendingPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := cleaner.AddPending(path, result.Locked); err != nil {
+		return err
+	}
+	if cfg.Pending.DeleteOnReboot {
+		if _, err := cleaner.SchedulePendingOnReboot(path); err != nil && !errors.Is(err, cleaner.ErrRebootDeleteUnsupported) {
+			return err
+		}
+	}
+	return nil
+}
+
+// RetryPending retries the pending queue with the protected paths,
+// exclusions, running-application policies, quarantine and timeouts of cfg.
+func RetryPending(ctx context.Context, cfg *Config) (cleaner.CleanResult, error) {
+	path, err := PendingPath()
+	if err != nil {
+		return cleaner.CleanResult{}, err
+	}
+	if queue, err := cleaner.LoadPending(path); err != nil || len(queue) == 0 {
+		return cleaner.CleanResult{}, err
+	}
+
+	opts := cleaner.CleanOptions{
+		Timeout:    cfg.DefaultCleanOptions.Timeout,
+		DirTimeout: cfg.DefaultCleanOptions.DirTimeout,
+	}
+	ApplyExclusions(cfg, &opts)
+	ApplyRunningPolicy(cfg, &opts)
+	if err := ApplyQuarantine(cfg, &opts); err != nil {
+		return cleaner.CleanResult{}, err
+	}
+	return cleaner.RetryPending(ctx, path, opts)
+}
+
+// SchedulePendingOnReboot schedules the queued files for deletion at the
+// next reboot (Windows only).
+func SchedulePendingOnReboot() (int, error) {
+	path, err := PendingPath()
+	if err != nil {
+		return 0, err
+	}
+	return cleaner.SchedulePendingOnReboot(path)
+}
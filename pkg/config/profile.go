@@ -6,46 +6,155 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"syscleaner/pkg/cleaner"
 )
 
-// ProfileCleanOptions mirrors cleaner.CleanOptions with only the
-// serializable fields. A separate type is used to avoid a circular
-// dependency between the config and cleaner packages when profiles
-// are loaded independently of the cleaner runtime.
+// ProfileCleanOptions is the serializable form of cleaner.CleanOptions.
+// It is written as a flat JSON object keyed by cleaner.Category ID plus
+// "dry_run", "trash_min_age", "timeout", "dir_timeout" and "retention", e.g.
+// {"windows_temp": true, "chrome_cache": false, "dry_run": false, "trash_min_age": "30d", "timeout": "5m0s"}.
+// A duration of "0" selects the cleaner's default. "retention" maps category
+// IDs to retention policies, e.g.
+// {"windows_logs": {"min_age": "14d", "keep_newest": 5, "max_size": "500M"}}.
+// Every registered category is written out so files stay easy to hand-edit;
+// unknown keys are preserved so that selections for categories which are not
+// registered in this run (such as user rules) survive a load/save cycle.
 type ProfileCleanOptions struct {
-	// System categories
-	WindowsTemp          bool `json:"windows_temp"`
-	UserTemp             bool `json:"user_temp"`
-	WindowsUpdate        bool `json:"windows_update"`
-	WindowsInstaller     bool `json:"windows_installer"`
-	Prefetch             bool `json:"prefetch"`
-	CrashDumps           bool `json:"crash_dumps"`
-	ErrorReports         bool `json:"error_reports"`
-	ThumbnailCache       bool `json:"thumbnail_cache"`
-	IconCache            bool `json:"icon_cache"`
-	FontCache            bool `json:"font_cache"`
-	ShaderCache          bool `json:"shader_cache"`
-	DNSCache             bool `json:"dns_cache"`
-	WindowsLogs          bool `json:"windows_logs"`
-	EventLogs            bool `json:"event_logs"`
-	DeliveryOptimization bool `json:"delivery_optimization"`
-	RecycleBin           bool `json:"recycle_bin"`
-
-	// Application categories
-	ChromeCache  bool `json:"chrome_cache"`
-	FirefoxCache bool `json:"firefox_cache"`
-	EdgeCache    bool `json:"edge_cache"`
-	BraveCache   bool `json:"brave_cache"`
-	OperaCache   bool `json:"opera_cache"`
-	DiscordCache bool `json:"discord_cache"`
-	SpotifyCache bool `json:"spotify_cache"`
-	SteamCache   bool `json:"steam_cache"`
-	TeamsCache   bool `json:"teams_cache"`
-	VSCodeCache  bool `json:"vscode_cache"`
-	JavaCache    bool `json:"java_cache"`
-
-	// Execution options
-	DryRun bool `json:"dry_run"`
+	Categories  map[string]bool
+	DryRun      bool
+	TrashMinAge time.Duration
+	Timeout     time.Duration
+	DirTimeout  time.Duration
+	Retention   map[string]cleaner.RetentionPolicy
+}
+
+// The setting keys; each is one of cleaner.ReservedIDs.
+const (
+	dryRunKey      = "dry_run"
+	trashMinAgeKey = "trash_min_age"
+	timeoutKey     = "timeout"
+	dirTimeoutKey  = "dir_timeout"
+	retentionKey   = "retention"
+)
+
+// durations maps the duration-valued keys to the fields they are stored in.
+func (p *ProfileCleanOptions) durations() map[string]*time.Duration {
+	return map[string]*time.Duration{
+		trashMinAgeKey: &p.TrashMinAge,
+		timeoutKey:     &p.Timeout,
+		dirTimeoutKey:  &p.DirTimeout,
+	}
+}
+
+// NewProfileCleanOptions captures the serializable fields of opts.
+func NewProfileCleanOptions(opts cleaner.CleanOptions) ProfileCleanOptions {
+	p := ProfileCleanOptions{
+		Categories:  map[string]bool{},
+		DryRun:      opts.DryRun,
+		TrashMinAge: opts.TrashMinAge,
+		Timeout:     opts.Timeout,
+		DirTimeout:  opts.DirTimeout,
+		Retention:   map[string]cleaner.RetentionPolicy{},
+	}
+	for id, policy := range opts.Retention {
+		p.Retention[id] = policy
+	}
+	for id, on := range opts.Categories {
+		if on {
+			p.Categories[id] = true
+		}
+	}
+	return p
+}
+
+// CleanOptions converts the profile selection back into cleaner options.
+func (p ProfileCleanOptions) CleanOptions() cleaner.CleanOptions {
+	opts := cleaner.CleanOptions{
+		DryRun:      p.DryRun,
+		TrashMinAge: p.TrashMinAge,
+		Timeout:     p.Timeout,
+		DirTimeout:  p.DirTimeout,
+		Retention:   map[string]cleaner.RetentionPolicy{},
+	}
+	for id, policy := range p.Retention {
+		opts.Retention[id] = policy
+	}
+	for id, on := range p.Categories {
+		if on {
+			opts.Enable(id)
+		}
+	}
+	return opts
+}
+
+// MarshalJSON writes the options as a flat object keyed by category ID.
+func (p ProfileCleanOptions) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Categories)+5)
+	for _, c := range cleaner.Categories() {
+		m[c.ID] = false
+	}
+	for id, on := range p.Categories {
+		m[id] = on
+	}
+	m[dryRunKey] = p.DryRun
+	for key, d := range p.durations() {
+		m[key] = cleaner.FormatAge(*d)
+	}
+	if p.Retention != nil {
+		m[retentionKey] = p.Retention
+	} else {
+		m[retentionKey] = map[string]cleaner.RetentionPolicy{}
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON reads the flat object written by MarshalJSON.
+func (p *ProfileCleanOptions) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	*p = ProfileCleanOptions{Categories: make(map[string]bool, len(m)), Retention: map[string]cleaner.RetentionPolicy{}}
+	if raw, ok := m[retentionKey]; ok {
+		if err := json.Unmarshal(raw, &p.Retention); err != nil {
+			return fmt.Errorf("%s: %w", retentionKey, err)
+		}
+		if p.Retention == nil {
+			p.Retention = map[string]cleaner.RetentionPolicy{}
+		}
+		delete(m, retentionKey)
+	}
+	for key, field := range p.durations() {
+		raw, ok := m[key]
+		if !ok {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		d, err := cleaner.ParseAge(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		*field = d
+		delete(m, key)
+	}
+	for key, raw := range m {
+		var on bool
+		if err := json.Unmarshal(raw, &on); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if key == dryRunKey {
+			p.DryRun = on
+		} else {
+			p.Categories[key] = on
+		}
+	}
+	return nil
 }
 
 // GamingConfig holds gaming-mode specific settings for a profile.
@@ -188,16 +297,7 @@ func DefaultProfile() *Profile {
 	return &Profile{
 		Name:             "default",
 		ProcessWhitelist: []string{},
-		CleanOptions: ProfileCleanOptions{
-			WindowsTemp:    true,
-			UserTemp:       true,
-			Prefetch:       true,
-			ThumbnailCache: true,
-			DNSCache:       true,
-			ChromeCache:    true,
-			FirefoxCache:   true,
-			EdgeCache:      true,
-		},
+		CleanOptions:     NewProfileCleanOptions(defaultCleanOptions()),
 		GamingConfig: GamingConfig{
 			UseExtremeMode: false,
 			CPUBoost:       0,
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"path/filepath"
+
+	"syscleaner/pkg/cleaner"
+)
+
+// QuarantineDir returns the path to the quarantine store, which is
+// ConfigDir()/quarantine/.
+func QuarantineDir() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "quarantine"), nil
+}
+
+// OpenQuarantine opens the quarantine store with the size cap from cfg.
+// Open a new store for every clean so that each clean gets its own run ID.
+func OpenQuarantine(cfg *Config) (*cleaner.Quarantine, error) {
+	dir, err := QuarantineDir()
+	if err != nil {
+		return nil, err
+	}
+	maxMB := cfg.Quarantine.MaxSizeMB
+	if maxMB <= 0 {
+		maxMB = defaultQuarantineMaxSizeMB
+	}
+	return cleaner.OpenQuarantine(dir, maxMB*1024*1024)
+}
+
+// ApplyQuarantine opens the quarantine store and sets it, together with the
+// per-category overrides from cfg, on opts.
+func ApplyQuarantine(cfg *Config, opts *cleaner.CleanOptions) error {
+	q, err := OpenQuarantine(cfg)
+	if err != nil {
+		return err
+	}
+	opts.Quarantine = q
+	if opts.QuarantineCategories == nil {
+		opts.QuarantineCategories = map[string]bool{}
+	}
+	for id, on := range cfg.Quarantine.Categories {
+		if _, set := opts.QuarantineCategories[id]; !set {
+			opts.QuarantineCategories[id] = on
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"syscleaner/pkg/cleaner"
+)
+
+// ApplyRetention sets the retention policies of cfg's clean options on
+// opts. Policies already set on opts win.
+func ApplyRetention(cfg *Config, opts *cleaner.CleanOptions) {
+	if opts.Retention == nil {
+		opts.Retention = map[string]cleaner.RetentionPolicy{}
+	}
+	for id, p := range cfg.DefaultCleanOptions.Retention {
+		if _, set := opts.Retention[id]; !set {
+			opts.Retention[id] = p
+		}
+	}
+}
+
+// LoadRetention returns the retention policies of the named profile, or of
+// the config file when profile is empty.
+func LoadRetention(profile string) (map[string]cleaner.RetentionPolicy, error) {
+	if profile != "" {
+		p, err := LoadProfile(profile)
+		if err != nil {
+			return nil, err
+		}
+		return nonNilRetention(p.CleanOptions.Retention), nil
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return nonNilRetention(cfg.DefaultCleanOptions.Retention), nil
+}
+
+// SetRetention stores the retention policy of a category in the named
+// profile, or in the config file when profile is empty. A zero policy
+// removes the category's policy, restoring its built-in behaviour.
+func SetRetention(profile, id string, policy cleaner.RetentionPolicy) error {
+	update := func(m map[string]cleaner.RetentionPolicy) map[string]cleaner.RetentionPolicy {
+		m = nonNilRetention(m)
+		if policy.IsZero() {
+			delete(m, id)
+		} else {
+			m[id] = policy
+		}
+		return m
+	}
+
+	if profile != "" {
+		p, err := LoadProfile(profile)
+		if err != nil {
+			return err
+		}
+		p.CleanOptions.Retention = update(p.CleanOptions.Retention)
+		return SaveProfile(p)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.DefaultCleanOptions.Retention = update(cfg.DefaultCleanOptions.Retention)
+	return SaveConfig(cfg)
+}
+
+func nonNilRetention(m map[string]cleaner.RetentionPolicy) map[string]cleaner.RetentionPolicy {
+	if m == nil {
+		return map[string]cleaner.RetentionPolicy{}
+	}
+	return m
+}
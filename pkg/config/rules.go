@@ -0,0 +1,89 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"syscleaner/pkg/cleaner"
+)
+
+// RulesDir returns the path to the user rules directory, which is
+// ConfigDir()/rules/.
+func RulesDir() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rules"), nil
+}
+
+var (
+	userRulesOnce sync.Once
+	userRulesErrs []error
+)
+
+// RegisterUserRules loads every JSON rule and imported winapp2 file in
+// RulesDir() and adds them to the cleaner category registry. Rules are only
+// loaded once per process; later calls return the problems found on the
+// first load. Skipped winapp2 sections are not reported here since
+// ImportWinapp2 already listed them.
+func RegisterUserRules() []error {
+	userRulesOnce.Do(func() {
+		dir, err := RulesDir()
+		if err != nil {
+			userRulesErrs = []error{err}
+			return
+		}
+		rules, errs := cleaner.LoadRules(dir)
+		userRulesErrs = append(errs, cleaner.RegisterRules(rules)...)
+
+		iniFiles, _ := filepath.Glob(filepath.Join(dir, "*.ini"))
+		for _, file := range iniFiles {
+			entries, _, err := cleaner.LoadWinapp2File(file)
+			if err != nil {
+				userRulesErrs = append(userRulesErrs, fmt.Errorf("parsing %s: %w", file, err))
+				continue
+			}
+			userRulesErrs = append(userRulesErrs, cleaner.RegisterWinapp2(entries)...)
+		}
+	})
+	return userRulesErrs
+}
+
+// ImportWinapp2 parses a winapp2.ini file and, if it contains at least one
+// usable entry, copies it into RulesDir() so RegisterUserRules picks it up
+// on every run. It returns the parsed entries, the skipped sections and the
+// destination path.
+func ImportWinapp2(src string) ([]cleaner.Winapp2Entry, []cleaner.Winapp2Skip, string, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("reading %s: %w", src, err)
+	}
+	entries, skipped, err := cleaner.ParseWinapp2(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("parsing %s: %w", src, err)
+	}
+	if len(entries) == 0 {
+		return entries, skipped, "", fmt.Errorf("%s contains no usable entries", src)
+	}
+
+	dir, err := RulesDir()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, "", fmt.Errorf("creating rules directory: %w", err)
+	}
+	dest := filepath.Join(dir, filepath.Base(src))
+	if !strings.EqualFold(filepath.Ext(dest), ".ini") {
+		dest += ".ini"
+	}
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return nil, nil, "", fmt.Errorf("writing %s: %w", dest, err)
+	}
+	return entries, skipped, dest, nil
+}
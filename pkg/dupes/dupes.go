@@ -0,0 +1,538 @@
+// Package dupes finds duplicate files and removes the extra copies through
+// the cleaner's deletion pipeline.
+//
+// Files are compared in stages so that as little as possible is read: only
+// files that share their size are hashed at all, only the first
+// PartialHashSize bytes are hashed first, and only files whose partial
+// hashes match are hashed in full.
+package dupes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"syscleaner/pkg/cleaner"
+)
+
+// CategoryID is the category under which removed duplicates are recorded in
+// the quarantine store, the history and progress events.
+const CategoryID = "duplicates"
+
+// PartialHashSize is the number of leading bytes hashed in the second stage.
+// Files no larger than this are fully hashed by it.
+const PartialHashSize = 16 << 10
+
+// defaultWorkers is the number of files hashed concurrently, the same limit
+// the cleaner uses for its categories.
+const defaultWorkers = 4
+
+// KeepRule decides which copy of a set of duplicates is kept.
+type KeepRule string
+
+const (
+	KeepOldest   KeepRule = "oldest"   // The copy modified longest ago
+	KeepNewest   KeepRule = "newest"   // The most recently modified copy
+	KeepPriority KeepRule = "priority" // The copy in the earliest Options.Priority directory, then the oldest
+)
+
+// ParseKeepRule parses the name of a KeepRule. The empty string is KeepOldest.
+func ParseKeepRule(s string) (KeepRule, error) {
+	switch r := KeepRule(strings.ToLower(strings.TrimSpace(s))); r {
+	case "":
+		return KeepOldest, nil
+	case KeepOldest, KeepNewest, KeepPriority:
+		return r, nil
+	}
+	return "", fmt.Errorf("invalid keep rule %q: use oldest, newest or priority", s)
+}
+
+// Options configures a search for duplicates.
+type Options struct {
+	Roots   []string // Directories to search; a file below two roots is seen once
+	MinSize int64    // Smaller files are ignored (default 1: empty files are not duplicates)
+
+	Keep KeepRule
+	// Priority lists directories from most to least preferred for
+	// KeepPriority: the copy below the earliest listed one is kept.
+	Priority []string
+
+	Workers          int  // Files hashed at once (default 4)
+	CrossFilesystems bool // Walk into mounted filesystems
+	Timeout          time.Duration
+
+	// Events, when set, receives one EventCategoryStarted and
+	// EventCategoryFinished per stage and an EventFileScanned for every file
+	// listed or hashed. Hashing events carry the stage's totals.
+	Events cleaner.EventFunc
+}
+
+// File is one copy in a Group.
+type File struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Group is a set of identical files. Keep is the copy the keep rule chose;
+// Duplicates are the others, which Remove deletes.
+type Group struct {
+	Size       int64  `json:"size"`
+	Hash       string `json:"hash"` // SHA-256 of the content, hex
+	Keep       File   `json:"keep"`
+	Duplicates []File `json:"duplicates"`
+}
+
+// Wasted returns the bytes the duplicates of g take up.
+func (g Group) Wasted() int64 {
+	return g.Size * int64(len(g.Duplicates))
+}
+
+// Result is the outcome of Find.
+type Result struct {
+	Roots          []string          `json:"roots"`
+	Status         cleaner.RunStatus `json:"status"` // partial when cancelled or stopped by the deadline
+	Keep           KeepRule          `json:"keep"`
+	FilesScanned   int64             `json:"files_scanned"`
+	BytesScanned   int64             `json:"bytes_scanned"`
+	BytesHashed    int64             `json:"bytes_hashed"` // Read from disk to compare
+	Groups         []Group           `json:"groups"`       // Most wasted space first
+	DuplicateFiles int64             `json:"duplicate_files"`
+	DuplicateBytes int64             `json:"duplicate_bytes"`
+	DurationMS     int64             `json:"duration_ms"`
+
+	SkippedLinks  []string              `json:"skipped_links"`
+	SkippedMounts []string              `json:"skipped_mounts"`
+	Errors        []cleaner.ErrorReport `json:"errors"`
+}
+
+// candidate is a file that may have duplicates.
+type candidate struct {
+	path string
+	size int64
+	mod  time.Time
+	info os.FileInfo
+	hash string
+}
+
+// Find searches opts.Roots for duplicate files. Links are not followed and
+// mounts not entered, as when cleaning. When ctx is cancelled or the
+// deadline passes, Find returns the groups it had confirmed so far with a
+// partial status. Nothing is modified.
+func Find(ctx context.Context, opts Options) (*Result, error) {
+	start := time.Now()
+	if len(opts.Roots) == 0 {
+		return nil, errors.New("no directories to search")
+	}
+	if opts.Keep == "" {
+		opts.Keep = KeepOldest
+	}
+	if opts.MinSize <= 0 {
+		opts.MinSize = 1
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = cleaner.DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var roots []string
+	for _, root := range opts.Roots {
+		abs := resolve(root)
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", root)
+		}
+		roots = append(roots, abs)
+	}
+
+	f := &finder{opts: opts, events: &emitter{fn: opts.Events}}
+	f.result = Result{Roots: roots, Status: cleaner.StatusCompleted, Keep: opts.Keep}
+	for _, dir := range opts.Priority {
+		f.priority = append(f.priority, resolve(dir))
+	}
+
+	bySize := f.list(ctx, roots)
+	var sized [][]*candidate
+	for _, files := range bySize {
+		if files = distinctFiles(files); len(files) > 1 {
+			sized = append(sized, files)
+		}
+	}
+
+	partial := f.hashStage(ctx, "Comparing the first 16 KB", sized, PartialHashSize)
+	var full [][]*candidate
+	var groups [][]*candidate
+	for _, set := range partial {
+		if set[0].size <= PartialHashSize {
+			groups = append(groups, set) // Already hashed in full
+		} else {
+			full = append(full, set)
+		}
+	}
+	groups = append(groups, f.hashStage(ctx, "Comparing whole files", full, 0)...)
+
+	for _, set := range groups {
+		g := f.group(set)
+		f.result.Groups = append(f.result.Groups, g)
+		f.result.DuplicateFiles += int64(len(g.Duplicates))
+		f.result.DuplicateBytes += g.Wasted()
+	}
+	sort.Slice(f.result.Groups, func(i, j int) bool {
+		a, b := f.result.Groups[i], f.result.Groups[j]
+		if a.Wasted() != b.Wasted() {
+			return a.Wasted() > b.Wasted()
+		}
+		return a.Keep.Path < b.Keep.Path
+	})
+	if f.result.Groups == nil {
+		f.result.Groups = []Group{}
+	}
+
+	if ctx.Err() != nil {
+		f.result.Status = cleaner.StatusPartial
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Printf("[SysCleaner] Duplicate search stopped after the %s deadline", timeout)
+			f.errs = append(f.errs, fmt.Errorf("duplicate search stopped after the %s deadline", timeout))
+		}
+	}
+	f.result.SkippedLinks = append([]string{}, f.walked.SkippedLinks...)
+	f.result.SkippedMounts = append([]string{}, f.walked.SkippedMounts...)
+	f.result.Errors = cleaner.ErrorReports(append(f.walked.Errors, f.errs...))
+	f.result.DurationMS = time.Since(start).Milliseconds()
+	return &f.result, nil
+}
+
+// finder holds the state of one Find.
+type finder struct {
+	opts     Options
+	priority []string // opts.Priority, absolute with links resolved
+	events   *emitter
+	result   Result
+	walked   cleaner.CleanResult // Walk errors, skipped links and mounts
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// list walks the roots and returns the regular files of at least MinSize
+// by size.
+func (f *finder) list(ctx context.Context, roots []string) map[int64][]*candidate {
+	f.events.start("Listing files", 0, 0)
+	seen := map[string]bool{}
+	bySize := map[int64][]*candidate{}
+	for _, root := range roots {
+		r := cleaner.WalkFiles(ctx, root, f.opts.CrossFilesystems, func(path string, info os.FileInfo) {
+			if !info.Mode().IsRegular() || seen[path] {
+				return
+			}
+			seen[path] = true
+			f.result.FilesScanned++
+			f.result.BytesScanned += info.Size()
+			f.events.scanned(path, info.Size())
+			if info.Size() < f.opts.MinSize {
+				return
+			}
+			bySize[info.Size()] = append(bySize[info.Size()], &candidate{path: path, size: info.Size(), mod: info.ModTime(), info: info})
+		})
+		f.walked.Errors = append(f.walked.Errors, r.Errors...)
+		f.walked.SkippedLinks = append(f.walked.SkippedLinks, r.SkippedLinks...)
+		f.walked.SkippedMounts = append(f.walked.SkippedMounts, r.SkippedMounts...)
+	}
+	f.events.finish(ctx)
+	return bySize
+}
+
+// distinctFiles drops hardlinks to a file already in files: removing them
+// would free nothing.
+func distinctFiles(files []*candidate) []*candidate {
+	var out []*candidate
+next:
+	for _, c := range files {
+		for _, o := range out {
+			if os.SameFile(c.info, o.info) {
+				continue next
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// hashStage hashes every file of sets (the first limit bytes, or all of it
+// for limit 0) on the worker pool, and splits each set by hash. Sets of one
+// file are dropped.
+func (f *finder) hashStage(ctx context.Context, name string, sets [][]*candidate, limit int64) [][]*candidate {
+	var files []*candidate
+	var total int64
+	for _, set := range sets {
+		for _, c := range set {
+			// Files not reached before cancellation must not keep the
+			// previous stage's hash.
+			c.hash = ""
+			files = append(files, c)
+			n := c.size
+			if limit > 0 && n > limit {
+				n = limit
+			}
+			total += n
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	f.events.start(name, int64(len(files)), total)
+
+	jobs := make(chan *candidate)
+	var wg sync.WaitGroup
+	for i := 0; i < f.opts.Workers && i < len(files); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				sum, n, err := hashFile(ctx, c.path, limit)
+				f.mu.Lock()
+				f.result.BytesHashed += n
+				if err != nil && ctx.Err() == nil {
+					f.errs = append(f.errs, cleaner.ClassifyError(c.path, err))
+				}
+				f.mu.Unlock()
+				c.hash = sum
+				f.events.scanned(c.path, n)
+			}
+		}()
+	}
+	for _, c := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+	f.events.finish(ctx)
+
+	var out [][]*candidate
+	for _, set := range sets {
+		byHash := map[string][]*candidate{}
+		var order []string
+		for _, c := range set {
+			if c.hash == "" {
+				continue // Unreadable, or not reached before cancellation
+			}
+			if byHash[c.hash] == nil {
+				order = append(order, c.hash)
+			}
+			byHash[c.hash] = append(byHash[c.hash], c)
+		}
+		for _, h := range order {
+			if len(byHash[h]) > 1 {
+				out = append(out, byHash[h])
+			}
+		}
+	}
+	return out
+}
+
+// hashFile returns the SHA-256 of the first limit bytes of a file (all of
+// it for limit 0) and the number of bytes read. It stops with ctx.
+func hashFile(ctx context.Context, path string, limit int64) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if limit > 0 {
+		r = io.LimitReader(file, limit)
+	}
+	h := sha256.New()
+	buf := make([]byte, 1<<20)
+	var read int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", read, err
+		}
+		n, err := r.Read(buf)
+		h.Write(buf[:n])
+		read += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", read, err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), read, nil
+}
+
+// group applies the keep rule to a set of identical files.
+func (f *finder) group(set []*candidate) Group {
+	rank := func(c *candidate) int {
+		if f.opts.Keep != KeepPriority {
+			return 0
+		}
+		for i, dir := range f.priority {
+			if within(dir, c.path) {
+				return i
+			}
+		}
+		return len(f.priority)
+	}
+	sort.Slice(set, func(i, j int) bool {
+		a, b := set[i], set[j]
+		if ra, rb := rank(a), rank(b); ra != rb {
+			return ra < rb
+		}
+		if !a.mod.Equal(b.mod) {
+			if f.opts.Keep == KeepNewest {
+				return a.mod.After(b.mod)
+			}
+			return a.mod.Before(b.mod)
+		}
+		return a.path < b.path
+	})
+
+	g := Group{Size: set[0].size, Hash: set[0].hash, Keep: File{Path: set[0].path, ModTime: set[0].mod}}
+	for _, c := range set[1:] {
+		g.Duplicates = append(g.Duplicates, File{Path: c.path, ModTime: c.mod})
+	}
+	return g
+}
+
+// resolve makes a directory absolute and resolves links in it, so it can be
+// compared with the paths the walk reports.
+func resolve(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	return dir
+}
+
+// within reports whether path lies below (or is) dir.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// emitter serializes the events of a Find and keeps the running totals of
+// the current stage.
+type emitter struct {
+	mu                sync.Mutex
+	fn                cleaner.EventFunc
+	name              string
+	files, bytes      int64
+	totalFiles, total int64
+}
+
+func (e *emitter) send(ev cleaner.ProgressEvent) {
+	ev.Category, ev.Name = CategoryID, e.name
+	ev.Files, ev.Bytes = e.files, e.bytes
+	ev.TotalFiles, ev.TotalBytes = e.totalFiles, e.total
+	e.fn(ev)
+}
+
+func (e *emitter) start(name string, files, bytes int64) {
+	if e.fn == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.name, e.files, e.bytes, e.totalFiles, e.total = name, 0, 0, files, bytes
+	e.send(cleaner.ProgressEvent{Kind: cleaner.EventCategoryStarted})
+}
+
+func (e *emitter) scanned(path string, size int64) {
+	if e.fn == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.files++
+	e.bytes += size
+	e.send(cleaner.ProgressEvent{Kind: cleaner.EventFileScanned, Path: path, Size: size})
+}
+
+func (e *emitter) finish(ctx context.Context) {
+	if e.fn == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	status := cleaner.StatusCompleted
+	if ctx.Err() != nil {
+		status = cleaner.StatusPartial
+	}
+	e.send(cleaner.ProgressEvent{Kind: cleaner.EventCategoryFinished, Status: status})
+}
+
+// ErrChanged is reported for duplicates that are skipped by Remove because
+// they, or the copy that is kept, changed since the search.
+var ErrChanged = errors.New("changed since the duplicate search")
+
+// Remove deletes the duplicates of groups, keeping each group's Keep copy,
+// through the cleaner's deletion pipeline: protected paths and exclusions
+// are refused, opts.DryRun only counts, and the files are quarantined when
+// opts.QuarantineCategories enables CategoryID. A duplicate is skipped when
+// it or the kept copy no longer has the size and modification time seen by
+// Find, so a file edited since the search is never lost.
+func Remove(ctx context.Context, groups []Group, opts cleaner.CleanOptions) cleaner.CleanResult {
+	var (
+		paths []string
+		errs  []error
+	)
+	for _, g := range groups {
+		if err := unchanged(g.Keep, g.Size); err != nil {
+			for _, d := range g.Duplicates {
+				errs = append(errs, &cleaner.CleanError{Path: d.Path, Type: cleaner.ErrorOther,
+					Err: fmt.Errorf("kept copy %s: %w", g.Keep.Path, err)})
+			}
+			continue
+		}
+		for _, d := range g.Duplicates {
+			if err := unchanged(d, g.Size); err != nil {
+				errs = append(errs, &cleaner.CleanError{Path: d.Path, Type: cleaner.ErrorOther, Err: err})
+				continue
+			}
+			paths = append(paths, d.Path)
+		}
+	}
+
+	c := cleaner.Category{ID: CategoryID, Name: "Duplicate files"}
+	result := cleaner.RemovePaths(ctx, c, paths, "duplicate file", opts)
+	result.Errors = append(result.Errors, errs...)
+	return result
+}
+
+// unchanged checks that f is still a regular file of size bytes with the
+// modification time Find saw.
+func unchanged(f File, size int64) error {
+	info, err := os.Lstat(f.Path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() || info.Size() != size || !info.ModTime().Equal(f.ModTime) {
+		return ErrChanged
+	}
+	return nil
+}
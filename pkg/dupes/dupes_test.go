@@ -0,0 +1,254 @@
+package dupes
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"syscleaner/pkg/cleaner"
+)
+
+func writeFile(t *testing.T, path, content string, mtime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !mtime.IsZero() {
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+var (
+	day1 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 = day1.AddDate(0, 0, 1)
+	day3 = day1.AddDate(0, 0, 2)
+)
+
+// ---------- Find tests ----------
+
+func TestFind_GroupsIdenticalFiles(t *testing.T) {
+	root := t.TempDir()
+	big := strings.Repeat("a", PartialHashSize+100)
+	// Same size and first 16 KB as big, different tail.
+	bigOther := strings.Repeat("a", PartialHashSize+99) + "b"
+	writeFile(t, filepath.Join(root, "x", "setup.exe"), big, day2)
+	writeFile(t, filepath.Join(root, "y", "setup (1).exe"), big, day1)
+	writeFile(t, filepath.Join(root, "z", "other.exe"), bigOther, day1)
+	writeFile(t, filepath.Join(root, "a.txt"), "hello", day3)
+	writeFile(t, filepath.Join(root, "b.txt"), "hello", day2)
+	writeFile(t, filepath.Join(root, "c.txt"), "world", day1) // Same size, different content
+	writeFile(t, filepath.Join(root, "empty1"), "", day1)
+	writeFile(t, filepath.Join(root, "empty2"), "", day1)
+
+	res, err := Find(context.Background(), Options{Roots: []string{root}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if res.Status != cleaner.StatusCompleted {
+		t.Errorf("expected a completed search, got %s", res.Status)
+	}
+	if len(res.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", res.Groups)
+	}
+
+	g := res.Groups[0]
+	if g.Size != int64(len(big)) || g.Keep.Path != filepath.Join(root, "y", "setup (1).exe") {
+		t.Errorf("expected the oldest installer to be kept first, got %+v", g)
+	}
+	if len(g.Duplicates) != 1 || g.Duplicates[0].Path != filepath.Join(root, "x", "setup.exe") {
+		t.Errorf("expected setup.exe as the duplicate, got %+v", g.Duplicates)
+	}
+	if g = res.Groups[1]; g.Keep.Path != filepath.Join(root, "b.txt") || len(g.Duplicates) != 1 {
+		t.Errorf("expected b.txt kept with one duplicate, got %+v", g)
+	}
+	if res.DuplicateFiles != 2 || res.DuplicateBytes != int64(len(big))+5 {
+		t.Errorf("unexpected totals: %d files, %d bytes", res.DuplicateFiles, res.DuplicateBytes)
+	}
+	if res.FilesScanned != 8 {
+		t.Errorf("expected 8 files scanned, got %d", res.FilesScanned)
+	}
+}
+
+func TestFind_ReadsOnlyWhatItMust(t *testing.T) {
+	root := t.TempDir()
+	big := strings.Repeat("a", 4*PartialHashSize)
+	writeFile(t, filepath.Join(root, "unique"), strings.Repeat("u", 10*PartialHashSize), day1)
+	writeFile(t, filepath.Join(root, "p1"), big, day1)
+	writeFile(t, filepath.Join(root, "p2"), "b"+big[1:], day1) // Differs in the first 16 KB
+
+	res, err := Find(context.Background(), Options{Roots: []string{root}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(res.Groups) != 0 {
+		t.Errorf("expected no duplicates, got %+v", res.Groups)
+	}
+	if res.BytesHashed != 2*PartialHashSize {
+		t.Errorf("only the partial hashes of the two same-sized files should be read, read %d bytes", res.BytesHashed)
+	}
+}
+
+func TestFind_KeepRules(t *testing.T) {
+	root := t.TempDir()
+	docs := filepath.Join(root, "Documents")
+	downloads := filepath.Join(root, "Downloads")
+	writeFile(t, filepath.Join(downloads, "a.zip"), "same", day1)
+	writeFile(t, filepath.Join(docs, "a.zip"), "same", day3)
+	writeFile(t, filepath.Join(root, "a.zip"), "same", day2)
+
+	tests := []struct {
+		keep     KeepRule
+		priority []string
+		want     string
+	}{
+		{KeepOldest, nil, filepath.Join(downloads, "a.zip")},
+		{KeepNewest, nil, filepath.Join(docs, "a.zip")},
+		{KeepPriority, []string{docs, downloads}, filepath.Join(docs, "a.zip")},
+		{KeepPriority, []string{filepath.Join(root, "elsewhere")}, filepath.Join(downloads, "a.zip")},
+	}
+	for _, tc := range tests {
+		res, err := Find(context.Background(), Options{Roots: []string{root}, Keep: tc.keep, Priority: tc.priority})
+		if err != nil {
+			t.Fatalf("Find failed: %v", err)
+		}
+		if len(res.Groups) != 1 || res.Groups[0].Keep.Path != tc.want || len(res.Groups[0].Duplicates) != 2 {
+			t.Errorf("keep %s %v: expected %s kept, got %+v", tc.keep, tc.priority, tc.want, res.Groups)
+		}
+	}
+}
+
+func TestFind_OverlappingRootsAndHardlinks(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "sub", "a")
+	writeFile(t, a, "data", day1)
+	if err := os.Link(a, filepath.Join(root, "link")); err != nil {
+		t.Skipf("hardlinks not supported: %v", err)
+	}
+
+	res, err := Find(context.Background(), Options{Roots: []string{root, filepath.Join(root, "sub")}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(res.Groups) != 0 {
+		t.Errorf("a file seen twice or a hardlink is not a duplicate, got %+v", res.Groups)
+	}
+}
+
+func TestFind_Events(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a"), "same", day1)
+	writeFile(t, filepath.Join(root, "b"), "same", day1)
+
+	var started []string
+	var last cleaner.ProgressEvent
+	_, err := Find(context.Background(), Options{Roots: []string{root}, Events: func(e cleaner.ProgressEvent) {
+		if e.Kind == cleaner.EventCategoryStarted {
+			started = append(started, e.Name)
+		}
+		if e.Kind == cleaner.EventFileScanned {
+			last = e
+		}
+	}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(started) != 2 {
+		t.Errorf("expected listing and partial hashing stages only, got %v", started)
+	}
+	if last.Fraction() != 1 || last.TotalFiles != 2 {
+		t.Errorf("expected the hashing stage to finish at 100%% of 2 files, got %+v", last)
+	}
+}
+
+func TestFind_Cancelled(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a"), "same", day1)
+	writeFile(t, filepath.Join(root, "b"), "same", day1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	res, err := Find(ctx, Options{Roots: []string{root}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if res.Status != cleaner.StatusPartial || len(res.Groups) != 0 {
+		t.Errorf("expected a partial search without unconfirmed groups, got %s %+v", res.Status, res.Groups)
+	}
+}
+
+func TestParseKeepRule(t *testing.T) {
+	for in, want := range map[string]KeepRule{"": KeepOldest, "Newest": KeepNewest, "priority": KeepPriority} {
+		if got, err := ParseKeepRule(in); err != nil || got != want {
+			t.Errorf("ParseKeepRule(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseKeepRule("largest"); err == nil {
+		t.Error("expected an error for an unknown rule")
+	}
+}
+
+// ---------- Remove tests ----------
+
+func TestRemove_DeletesDuplicatesOnly(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "keep"), "same", day1)
+	writeFile(t, filepath.Join(root, "dup1"), "same", day2)
+	writeFile(t, filepath.Join(root, "dup2"), "same", day3)
+	res, err := Find(context.Background(), Options{Roots: []string{root}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	dry := Remove(context.Background(), res.Groups, cleaner.CleanOptions{DryRun: true})
+	if dry.FilesDeleted != 2 || !exists(filepath.Join(root, "dup1")) {
+		t.Errorf("dry run should count 2 files and keep them, got %d", dry.FilesDeleted)
+	}
+
+	result := Remove(context.Background(), res.Groups, cleaner.CleanOptions{})
+	if result.FilesDeleted != 2 || result.SpaceFreed != 8 {
+		t.Errorf("expected 2 files / 8 bytes deleted, got %d / %d", result.FilesDeleted, result.SpaceFreed)
+	}
+	if !exists(filepath.Join(root, "keep")) || exists(filepath.Join(root, "dup1")) || exists(filepath.Join(root, "dup2")) {
+		t.Error("only the kept copy should remain")
+	}
+	if len(result.Categories) != 1 || result.Categories[0].ID != CategoryID {
+		t.Errorf("expected one %s category status, got %+v", CategoryID, result.Categories)
+	}
+}
+
+func TestRemove_SkipsChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	keep := filepath.Join(root, "keep")
+	dup := filepath.Join(root, "dup")
+	writeFile(t, keep, "same", day1)
+	writeFile(t, dup, "same", day2)
+	res, err := Find(context.Background(), Options{Roots: []string{root}})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	// The kept copy is edited after the search: its duplicate is now the
+	// only copy of the old content and must stay.
+	writeFile(t, keep, "new!", day3)
+	result := Remove(context.Background(), res.Groups, cleaner.CleanOptions{})
+	if result.FilesDeleted != 0 || !exists(dup) {
+		t.Error("a duplicate whose kept copy changed must not be removed")
+	}
+	if len(result.Errors) != 1 || !errors.Is(result.Errors[0], ErrChanged) {
+		t.Errorf("expected an ErrChanged error, got %v", result.Errors)
+	}
+}
@@ -24,21 +24,21 @@ type Config struct {
 
 // Status holds current gaming mode state.
 type Status struct {
-	Enabled          bool
-	ActiveGames      []GameProcess
-	CPUUsage         float64
-	RAMUsagePercent  float64
-	RAMUsed          uint64
-	RAMTotal         uint64
-	StoppedServices  []string
+	Enabled         bool          `json:"enabled"`
+	ActiveGames     []GameProcess `json:"active_games"`
+	CPUUsage        float64       `json:"cpu_usage_percent"`
+	RAMUsagePercent float64       `json:"ram_usage_percent"`
+	RAMUsed         uint64        `json:"ram_used_bytes"`
+	RAMTotal        uint64        `json:"ram_total_bytes"`
+	StoppedServices []string      `json:"stopped_services"`
 }
 
 // GameProcess represents a detected game process.
 type GameProcess struct {
-	Name     string
-	PID      int32
-	CPUUsage float64
-	RAMUsage uint64
+	Name     string  `json:"name"`
+	PID      int32   `json:"pid"`
+	CPUUsage float64 `json:"cpu_usage_percent"`
+	RAMUsage uint64  `json:"ram_used_bytes"`
 }
 
 var (
@@ -168,7 +168,8 @@ func GetStatus() Status {
 	mu.Lock()
 	status := Status{
 		Enabled:         gamingModeEnabled,
-		StoppedServices: append([]string(nil), stoppedServices...),
+		ActiveGames:     []GameProcess{},
+		StoppedServices: append([]string{}, stoppedServices...),
 	}
 	mu.Unlock()
 
@@ -291,4 +292,3 @@ func startService(name string) error {
 	// processes that trigger AV heuristics.
 	return startServiceNative(name)
 }
-
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package gaming
+
+import "fmt"
+
+func setPowerSchemeNative(guidStr string) error {
+	return fmt.Errorf("power schemes not available on this platform")
+}
+
+func setTCPGamingParams() error {
+	return fmt.Errorf("TCP tuning not available on this platform")
+}
+
+func startExplorerNative() error {
+	return fmt.Errorf("explorer.exe not available on this platform")
+}
@@ -7,38 +7,42 @@ import (
 
 // StartupResult holds startup optimization results.
 type StartupResult struct {
-	Disabled int
-	Programs []StartupProgram
+	Disabled int              `json:"disabled"`
+	Programs []StartupProgram `json:"programs"`
 }
 
 // StartupProgram represents a startup entry.
 type StartupProgram struct {
-	Name     string
-	Path     string
-	Impact   string
-	Disabled bool
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Impact   string `json:"impact"`
+	Disabled bool   `json:"disabled"`
 }
 
 // NetworkResult holds network optimization results.
 type NetworkResult struct {
-	LatencyReduction int
-	Optimizations    []string
+	LatencyReduction int      `json:"latency_reduction_ms"`
+	Optimizations    []string `json:"optimizations"`
 }
 
 // DiskResult holds disk optimization results.
 type DiskResult struct {
-	IsSSD     bool
-	Scheduled bool
+	IsSSD     bool `json:"ssd"`
+	Scheduled bool `json:"scheduled"`
 }
 
 // OptimizeStartup disables unnecessary startup programs.
 func OptimizeStartup() StartupResult {
-	return optimizeStartupPlatform()
+	result := optimizeStartupPlatform()
+	if result.Programs == nil {
+		result.Programs = []StartupProgram{}
+	}
+	return result
 }
 
 // OptimizeNetwork optimizes network settings for low latency.
 func OptimizeNetwork() NetworkResult {
-	result := NetworkResult{}
+	result := NetworkResult{Optimizations: []string{}}
 
 	if runtime.GOOS != "windows" {
 		result.Optimizations = append(result.Optimizations, "Network optimization is only available on Windows")
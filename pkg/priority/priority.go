@@ -2,13 +2,13 @@ package priority
 
 // PriorityEntry represents a configured process priority
 type PriorityEntry struct {
-	ProcessName      string // e.g., "LeagueClient.exe"
-	CpuPriority      int    // 1-6 (no 4)
-	IoPriority       int    // 0-3
-	PagePriority     int    // 0-5
-	CpuPriorityName  string // "High", "Above Normal", etc.
-	IoPriorityName   string
-	PagePriorityName string
+	ProcessName      string `json:"process_name"`      // e.g., "LeagueClient.exe"
+	CpuPriority      int    `json:"cpu_priority"`      // 1-6 (no 4)
+	IoPriority       int    `json:"io_priority"`       // 0-3
+	PagePriority     int    `json:"page_priority"`     // 0-5
+	CpuPriorityName  string `json:"cpu_priority_name"` // "High", "Above Normal", etc.
+	IoPriorityName   string `json:"io_priority_name"`
+	PagePriorityName string `json:"page_priority_name"`
 }
 
 // GetCpuPriorityName returns human-readable name for CPU priority value